@@ -0,0 +1,230 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"cenayang-market/go-api/internal/auth"
+	"cenayang-market/go-api/internal/handlers"
+)
+
+// ============================================================================
+// ALERTS - Bounded Inbox of Significant Events for Operators
+// ============================================================================
+
+// Alert severities
+const (
+	AlertInfo     = "info"
+	AlertWarning  = "warning"
+	AlertCritical = "critical"
+)
+
+var (
+	ErrAlertNotFound     = errors.New("alert not found")
+	ErrAlertAcknowledged = errors.New("alert already acknowledged")
+)
+
+// Alert - one significant event awaiting an operator
+type Alert struct {
+	ID       uint64
+	Severity string
+	Kind     string // The event that raised it, e.g. "margin_call"
+	Message  string
+	RaisedAt int64  // Unix ns
+	AckedAt  int64  // Unix ns; 0 = unacknowledged
+	AckedBy  string // Acknowledging user
+}
+
+// alertStore - alerts oldest first, bounded by Retention.Alerts
+type alertStore struct {
+	mu     sync.Mutex
+	alerts []Alert
+	nextID uint64
+}
+
+// raiseAlert records an alert in the inbox; callers log it themselves.
+// Once the store is full, the oldest acknowledged alert makes room, or the
+// oldest of all if none is acknowledged.
+func (sm *ShardedStateManager) raiseAlert(severity, kind, message string) uint64 {
+	s := &sm.alerts
+	s.mu.Lock()
+	if limit := sm.retention.Alerts.MaxEntries; limit > 0 && len(s.alerts) >= limit {
+		evict := 0
+		for i := range s.alerts {
+			if s.alerts[i].AckedAt != 0 {
+				evict = i
+				break
+			}
+		}
+		s.alerts = append(s.alerts[:evict], s.alerts[evict+1:]...)
+	}
+	s.nextID++
+	id := s.nextID
+	s.alerts = append(s.alerts, Alert{ID: id, Severity: severity, Kind: kind, Message: message, RaisedAt: sm.clock.Now().UnixNano()})
+	s.mu.Unlock()
+	return id
+}
+
+// AckAlert marks an alert acknowledged by actor
+func (sm *ShardedStateManager) AckAlert(id uint64, actor string) (Alert, error) {
+	s := &sm.alerts
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.alerts {
+		a := &s.alerts[i]
+		if a.ID != id {
+			continue
+		}
+		if a.AckedAt != 0 {
+			return *a, ErrAlertAcknowledged
+		}
+		a.AckedAt = sm.clock.Now().UnixNano()
+		a.AckedBy = actor
+		return *a, nil
+	}
+	return Alert{}, ErrAlertNotFound
+}
+
+// Alerts returns the inbox newest first, only unacknowledged alerts if
+// unacked is set
+func (sm *ShardedStateManager) Alerts(unacked bool) []Alert {
+	s := &sm.alerts
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Alert, 0, len(s.alerts))
+	for i := len(s.alerts) - 1; i >= 0; i-- {
+		if !unacked || s.alerts[i].AckedAt == 0 {
+			out = append(out, s.alerts[i])
+		}
+	}
+	return out
+}
+
+// UnackedCriticalAlerts returns the number of critical alerts no operator
+// has acknowledged
+func (sm *ShardedStateManager) UnackedCriticalAlerts() int {
+	s := &sm.alerts
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for i := range s.alerts {
+		if s.alerts[i].Severity == AlertCritical && s.alerts[i].AckedAt == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// trimAlerts drops acknowledged alerts past their age; unacknowledged ones
+// stay until acknowledged or pushed out by the entry cap. Returns the
+// number dropped.
+func (sm *ShardedStateManager) trimAlerts(now time.Time) int {
+	cutoff := sm.retention.Alerts.cutoff(now)
+	if cutoff.IsZero() {
+		return 0
+	}
+
+	s := &sm.alerts
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.alerts[:0]
+	for _, a := range s.alerts {
+		if a.AckedAt == 0 || a.RaisedAt >= cutoff.UnixNano() {
+			kept = append(kept, a)
+		}
+	}
+	dropped := len(s.alerts) - len(kept)
+	s.alerts = kept
+	return dropped
+}
+
+// handleAlerts serves the alert inbox, newest first; ?unacked=true leaves
+// out acknowledged alerts
+func (sm *ShardedStateManager) handleAlerts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "GET required", http.StatusMethodNotAllowed)
+		return
+	}
+	unacked, _ := strconv.ParseBool(r.URL.Query().Get("unacked"))
+
+	buf := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(buf)
+
+	b := append((*buf)[:0], `{"unacked_critical":`...)
+	b = strconv.AppendInt(b, int64(sm.UnackedCriticalAlerts()), 10)
+	b = append(b, `,"alerts":[`...)
+	for i, a := range sm.Alerts(unacked) {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = appendAlert(b, &a)
+	}
+	b = append(b, `]}`...)
+
+	handlers.WriteJSON(w, r, http.StatusOK, b)
+}
+
+// handleAlertAck acknowledges one alert. 404 for an unknown or trimmed
+// alert, 409 when it is already acknowledged.
+func (sm *ShardedStateManager) handleAlertAck(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "id must be an alert ID", Field: "id"})
+		return
+	}
+	actor := "anonymous"
+	if claims := auth.GetClaims(r.Context()); claims != nil {
+		actor = claims.Username
+	}
+
+	alert, err := sm.AckAlert(id, actor)
+	switch {
+	case errors.Is(err, ErrAlertNotFound):
+		handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusNotFound, Error: err.Error(), Field: "id"})
+		return
+	case errors.Is(err, ErrAlertAcknowledged):
+		handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusConflict, Error: err.Error(), Field: "id"})
+		return
+	}
+	if sm.audit != nil {
+		details := fmt.Sprintf("id=%d kind=%s severity=%s actor=%s", alert.ID, alert.Kind, alert.Severity, actor)
+		if err := sm.audit.SaveAuditLog("alert_ack", "alerts", details, r.RemoteAddr); err != nil {
+			log.Printf("[ALERT] Failed to audit acknowledgement of #%d: %v", alert.ID, err)
+		}
+	}
+
+	buf := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(buf)
+	handlers.WriteJSON(w, r, http.StatusOK, appendAlert((*buf)[:0], &alert))
+}
+
+func appendAlert(b []byte, a *Alert) []byte {
+	b = append(b, `{"id":`...)
+	b = strconv.AppendUint(b, a.ID, 10)
+	b = append(b, `,"severity":"`...)
+	b = append(b, a.Severity...)
+	b = append(b, `","kind":"`...)
+	b = append(b, a.Kind...)
+	b = append(b, `","message":`...)
+	b = strconv.AppendQuote(b, a.Message)
+	b = append(b, `,"raised_at_ns":`...)
+	b = strconv.AppendInt(b, a.RaisedAt, 10)
+	b = append(b, `,"acknowledged":`...)
+	b = strconv.AppendBool(b, a.AckedAt != 0)
+	if a.AckedAt != 0 {
+		b = append(b, `,"acked_at_ns":`...)
+		b = strconv.AppendInt(b, a.AckedAt, 10)
+		b = append(b, `,"acked_by":`...)
+		b = strconv.AppendQuote(b, a.AckedBy)
+	}
+	return append(b, '}')
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// A breaker trip raises a critical alert that degrades health until it is
+// acknowledged, and a full inbox makes room from acknowledged alerts first
+func TestAlertInbox(t *testing.T) {
+	cfg := testConfig()
+	cfg.Retention.Alerts = RetentionPolicy{MaxEntries: 3}
+	sm := NewShardedStateManager(cfg)
+	srv := httptest.NewServer(setupHTTPRoutes(sm))
+	defer srv.Close()
+
+	sm.checkBreaker(600, 500)
+	sm.raiseAlert(AlertWarning, "stale_feed", "quiet")
+	if n := sm.UnackedCriticalAlerts(); n != 1 {
+		t.Fatalf("%d unacknowledged critical alerts, want 1", n)
+	}
+	var health struct {
+		Status string `json:"status"`
+	}
+	getJSON(t, srv.URL+"/api/health", &health)
+	if health.Status != "degraded" {
+		t.Fatalf("health %q with a critical alert open", health.Status)
+	}
+
+	list := sm.Alerts(false)
+	if len(list) != 2 || list[0].Kind != "stale_feed" || list[1].Kind != "circuit_breaker" || list[1].Severity != AlertCritical {
+		t.Fatalf("alerts %+v, want newest first", list)
+	}
+	critical := strconv.FormatUint(list[1].ID, 10)
+	ack := func(id string) int {
+		resp, err := http.Post(srv.URL+"/api/alerts/"+id+"/ack", "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	for _, tt := range []struct {
+		id   string
+		want int
+	}{{critical, http.StatusOK}, {critical, http.StatusConflict}, {"99", http.StatusNotFound}, {"x", http.StatusBadRequest}} {
+		if code := ack(tt.id); code != tt.want {
+			t.Fatalf("ack %s: %d, want %d", tt.id, code, tt.want)
+		}
+	}
+	if sm.UnackedCriticalAlerts() != 0 {
+		t.Fatal("critical alert still open after the ack")
+	}
+	var body struct {
+		Alerts []struct {
+			Kind string `json:"kind"`
+		} `json:"alerts"`
+	}
+	getJSON(t, srv.URL+"/api/alerts?unacked=true", &body)
+	if len(body.Alerts) != 1 || body.Alerts[0].Kind != "stale_feed" {
+		t.Fatalf("unacknowledged %+v", body.Alerts)
+	}
+
+	// Two more fill the inbox of 3: the acknowledged trip goes first
+	sm.raiseAlert(AlertInfo, "a", "")
+	sm.raiseAlert(AlertInfo, "b", "")
+	if list := sm.Alerts(false); len(list) != 3 || list[2].Kind != "stale_feed" {
+		t.Fatalf("alerts %+v after eviction", list)
+	}
+}
+
+// getJSON fetches url and decodes its JSON body into v
+func getJSON(t *testing.T, url string, v any) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+
+	"cenayang-market/go-api/internal/allocator"
+	"cenayang-market/go-api/internal/handlers"
+)
+
+// ============================================================================
+// STRATEGY CAPITAL ALLOCATION - Risk Integration and Endpoints
+// ============================================================================
+
+// allocationReason maps an allocator error to its risk reason code
+func allocationReason(err error) RiskReason {
+	switch {
+	case errors.Is(err, allocator.ErrStrategyHalted):
+		return ReasonStrategyHalted
+	case errors.Is(err, allocator.ErrUnknownStrategy):
+		return ReasonUnknownStrategy
+	default:
+		return ReasonStrategyAllocation
+	}
+}
+
+// StrategyLimits - per-strategy circuit breaker defaults, each strategy
+// halted independently of the others and of the account-wide kill switch
+type StrategyLimits struct {
+	MaxDrawdownPct float64 // Since the last rebalance (0 = off)
+	DailyLossLimit float64 // Realized loss since the last rebalance (0 = off)
+}
+
+// strategyLimits applies the default limits to strategies that set none
+// of their own. A negative limit on a strategy disables it.
+func strategyLimits(cfg Config) []allocator.Strategy {
+	out := make([]allocator.Strategy, len(cfg.Strategies))
+	for i, s := range cfg.Strategies {
+		if s.MaxDrawdownPct == 0 {
+			s.MaxDrawdownPct = cfg.StrategyLimits.MaxDrawdownPct
+		}
+		if s.DailyLossLimit == 0 {
+			s.DailyLossLimit = toFixed(cfg.StrategyLimits.DailyLossLimit)
+		}
+		out[i] = s
+	}
+	return out
+}
+
+// recordStrategyPnL books realized PnL against a strategy's loss limits
+func (sm *ShardedStateManager) recordStrategyPnL(strategy string, pnl int64) {
+	if cause := sm.allocator.RecordPnL(strategy, pnl); cause != "" {
+		log.Printf("[ALLOCATOR] Strategy %s halted: %s limit reached", strategy, cause)
+	}
+}
+
+// holdCapital records capital a strategy reserved as backing the open
+// position on symbolHash - caller holds the shard lock
+func (shard *StateShard) holdCapital(symbolHash uint64, strategy string, amount int64) {
+	if amount == 0 {
+		return
+	}
+	held := shard.capital[symbolHash]
+	if held == nil {
+		held = make(map[string]int64, 1)
+		shard.capital[symbolHash] = held
+	}
+	held[strategy] += amount
+}
+
+// releaseCapital frees each strategy's share of the capital behind closed
+// of the position's quantity, exactly what it reserved once the position
+// is flat - caller holds the shard lock
+func (sm *ShardedStateManager) releaseCapital(shard *StateShard, symbolHash uint64, closed, quantity int64) {
+	held := shard.capital[symbolHash]
+	for strategy, amount := range held {
+		sm.allocator.Release(strategy, takeShare(&amount, closed, quantity))
+		if amount == 0 {
+			delete(held, strategy)
+		} else {
+			held[strategy] = amount
+		}
+	}
+	if len(held) == 0 {
+		delete(shard.capital, symbolHash)
+	}
+}
+
+// handleAllocator serves per-strategy capital
+func (sm *ShardedStateManager) handleAllocator(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "GET required", http.StatusMethodNotAllowed)
+		return
+	}
+	sm.writeAllocations(w, r)
+}
+
+// handleAllocatorRebalance reallocates capital from current equity
+func (sm *ShardedStateManager) handleAllocatorRebalance(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+	equity := atomic.LoadInt64(&sm.state.Equity)
+	sm.allocator.Rebalance(equity)
+	log.Printf("[ALLOCATOR] Rebalanced on equity %d", equity)
+	sm.writeAllocations(w, r)
+}
+
+// writeAllocations serializes the allocator snapshot
+func (sm *ShardedStateManager) writeAllocations(w http.ResponseWriter, r *http.Request) {
+	buf := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(buf)
+
+	b := append((*buf)[:0], `{"strategies":[`...)
+	for i, a := range sm.allocator.Snapshot() {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, `{"name":`...)
+		b = strconv.AppendQuote(b, a.Name)
+		b = append(b, `,"weight":`...)
+		b = sm.appendFloat(b, a.Weight, -1)
+		b = append(b, `,"capital":`...)
+		b = appendFixed(b, a.Capital)
+		b = append(b, `,"used":`...)
+		b = appendFixed(b, a.Used)
+		b = append(b, `,"available":`...)
+		b = appendFixed(b, a.Available)
+		b = append(b, `,"pnl":`...)
+		b = appendFixed(b, a.PnL)
+		b = append(b, `,"drawdown_bps":`...)
+		b = strconv.AppendInt(b, a.DrawdownBps, 10)
+		b = append(b, `,"max_drawdown_bps":`...)
+		b = strconv.AppendInt(b, a.MaxDrawdownBps, 10)
+		b = append(b, `,"daily_loss_limit":`...)
+		b = appendFixed(b, a.DailyLossLimit)
+		b = append(b, `,"halted":`...)
+		b = strconv.AppendBool(b, a.Halted)
+		if a.Halted {
+			b = append(b, `,"halted_on":"`...)
+			b = append(b, a.HaltedOn...)
+			b = append(b, '"')
+		}
+		b = append(b, '}')
+	}
+	b = append(b, `]}`...)
+
+	handlers.WriteJSON(w, r, http.StatusOK, b)
+}
+
+// appendStrategyBreakers serializes each strategy's loss limits and
+// standing against them, for the health endpoint
+func appendStrategyBreakers(b []byte, allocs []allocator.Allocation) []byte {
+	b = append(b, '[')
+	for i, a := range allocs {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, `{"name":`...)
+		b = strconv.AppendQuote(b, a.Name)
+		b = append(b, `,"drawdown_bps":`...)
+		b = strconv.AppendInt(b, a.DrawdownBps, 10)
+		b = append(b, `,"max_drawdown_bps":`...)
+		b = strconv.AppendInt(b, a.MaxDrawdownBps, 10)
+		b = append(b, `,"daily_pnl":`...)
+		b = appendFixed(b, a.PnL)
+		b = append(b, `,"daily_loss_limit":`...)
+		b = appendFixed(b, a.DailyLossLimit)
+		b = append(b, `,"halted":`...)
+		b = strconv.AppendBool(b, a.Halted)
+		b = append(b, '}')
+	}
+	return append(b, ']')
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+
+	"cenayang-market/go-api/internal/allocator"
+)
+
+// Strategy capital reserved by an order backs the position it opens and is
+// released exactly, under the strategy that reserved it, whatever the
+// fill and close prices
+func TestStrategyCapitalReleased(t *testing.T) {
+	type step struct {
+		strategy string
+		side     uint8
+		quantity float64
+		limit    float64
+		fill     float64
+		want     map[string]float64 // Used after the fill
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"open and close at other prices", []step{
+			{"gann", 0, 10, 100, 99, map[string]float64{"gann": 1000}},
+			{"gann", 1, 10, 110, 111, map[string]float64{"gann": 0}},
+		}},
+		{"closed by an unallocated order", []step{
+			{"gann", 0, 10, 100, 99, map[string]float64{"gann": 1000}},
+			{"", 1, 10, 110, 110, map[string]float64{"gann": 0}},
+		}},
+		{"closed in parts by another strategy", []step{
+			{"gann", 0, 10, 100, 100, map[string]float64{"gann": 1000}},
+			{"ehlers", 1, 4, 105, 104, map[string]float64{"gann": 600, "ehlers": 0}},
+			{"ehlers", 1, 6, 105, 106, map[string]float64{"gann": 0, "ehlers": 0}},
+		}},
+		{"flip", []step{
+			{"gann", 0, 10, 100, 100, map[string]float64{"gann": 1000}},
+			{"ehlers", 1, 15, 100, 101, map[string]float64{"gann": 0, "ehlers": 500}}, // Holds for the 5 short
+			{"", 0, 5, 90, 90, map[string]float64{"gann": 0, "ehlers": 0}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.Strategies = []allocator.Strategy{{Name: "gann", Weight: 0.5}, {Name: "ehlers", Weight: 0.5}}
+			sm := NewShardedStateManager(cfg)
+			h := sm.symbols.Hash("CAPUSD")
+			for i, s := range tt.steps {
+				order := &OrderOptimized{SymbolHash: h, Side: s.side, Quantity: fx(s.quantity), Price: fx(s.limit), Strategy: s.strategy}
+				if res, err := sm.SubmitOrder(order); err != nil || !res.Approved {
+					t.Fatalf("step %d: %v %v", i+1, res.Reason, err)
+				}
+				sm.ApplyFill(&FillEvent{OrderID: order.ID, SymbolHash: h, Side: s.side, Quantity: order.Quantity, Price: fx(s.fill)})
+				for _, a := range sm.allocator.Snapshot() {
+					if want, ok := s.want[a.Name]; ok && a.Used != fx(want) {
+						t.Fatalf("step %d: %s uses %d, want %d", i+1, a.Name, a.Used, fx(want))
+					}
+				}
+			}
+		})
+	}
+}
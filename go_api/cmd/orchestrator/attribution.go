@@ -0,0 +1,118 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"cenayang-market/go-api/internal/handlers"
+)
+
+// ============================================================================
+// ENTRY ATTRIBUTION - The Fills Behind Each Open Position
+// ============================================================================
+
+// Fill roles in a position
+const (
+	FillOpen   uint8 = iota // Opened the position, or the remainder of a flip
+	FillAdd                 // Added on the position's side
+	FillReduce              // Closed part of the position
+)
+
+var fillRoleNames = [...]string{FillOpen: "OPEN", FillAdd: "ADD", FillReduce: "REDUCE"}
+
+// fillRef - one fill's part in a position
+type fillRef struct {
+	OrderID   uint64
+	Role      uint8
+	Quantity  int64 // Fixed-point, the part that applied to this position
+	Price     int64 // Fixed-point
+	Timestamp int64 // Fill time, unix ns
+}
+
+// positionFills - an open position's fills, oldest first, capped at
+// RetentionConfig.PositionFills with the oldest dropped
+type positionFills struct {
+	refs    []fillRef
+	dropped int
+}
+
+// recordPositionFill appends a fill's part to its position's list - caller
+// holds the shard lock. An OPEN starts a new list.
+func (sm *ShardedStateManager) recordPositionFill(shard *StateShard, fill *FillEvent, role uint8, quantity int64) {
+	pf := shard.fills[fill.SymbolHash]
+	if role == FillOpen {
+		pf = positionFills{refs: pf.refs[:0]}
+	}
+	if limit := sm.retention.PositionFills.MaxEntries; limit > 0 && len(pf.refs) >= limit {
+		n := copy(pf.refs, pf.refs[len(pf.refs)-limit+1:])
+		pf.dropped += len(pf.refs) - n
+		pf.refs = pf.refs[:n]
+	}
+	ts := fill.Timestamp
+	if ts == 0 {
+		ts = sm.clock.Now().UnixNano()
+	}
+	pf.refs = append(pf.refs, fillRef{OrderID: fill.OrderID, Role: role, Quantity: quantity, Price: fill.Price, Timestamp: ts})
+	shard.fills[fill.SymbolHash] = pf
+}
+
+// handlePositionFills serves the fills behind an open position, so its
+// blended entry can be reconciled against them. 404 when the symbol is
+// flat.
+func (sm *ShardedStateManager) handlePositionFills(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "GET required", http.StatusMethodNotAllowed)
+		return
+	}
+
+	hash := sm.symbols.Hash(r.PathValue("symbol"))
+	shard := sm.GetShard(hash)
+	shard.mu.RLock()
+	p, ok := shard.positions[hash]
+	var pos PositionOptimized
+	var pf positionFills
+	if ok {
+		pos = *p
+		pf = shard.fills[hash]
+		pf.refs = append([]fillRef(nil), pf.refs...)
+	}
+	shard.mu.RUnlock()
+	if !ok {
+		handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusNotFound, Error: "no open position", Field: "symbol"})
+		return
+	}
+
+	buf := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(buf)
+
+	b := append((*buf)[:0], `{"symbol":`...)
+	b = strconv.AppendQuote(b, sm.symbols.Name(hash))
+	b = append(b, `,"quantity":`...)
+	b = appendFixed(b, pos.Quantity)
+	b = append(b, `,"entry_price":`...)
+	b = appendFixed(b, pos.EntryPrice)
+	b = append(b, `,"cost_basis":"`...)
+	b = append(b, sm.costBasis...)
+	b = append(b, `","dropped":`...)
+	b = strconv.AppendInt(b, int64(pf.dropped), 10)
+	b = append(b, `,"fills":[`...)
+	for i, f := range pf.refs {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, `{"order_id":"`...)
+		b = strconv.AppendUint(b, f.OrderID, 10)
+		b = append(b, `","role":"`...)
+		b = append(b, fillRoleNames[f.Role]...)
+		b = append(b, `","quantity":`...)
+		b = appendFixed(b, f.Quantity)
+		b = append(b, `,"price":`...)
+		b = appendFixed(b, f.Price)
+		b = append(b, `,"timestamp_ns":`...)
+		b = strconv.AppendInt(b, f.Timestamp, 10)
+		b = append(b, '}')
+	}
+	b = append(b, `]}`...)
+
+	handlers.WriteJSON(w, r, http.StatusOK, b)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync/atomic"
+
+	"cenayang-market/go-api/internal/ws"
+)
+
+// ============================================================================
+// BLOWN ACCOUNT - Equity at or Below the Floor Stops All New Risk
+// ============================================================================
+
+// ErrAccountBlown - a kill switch clear while equity is at or below the
+// floor
+var ErrAccountBlown = errors.New("kill switch held: account equity at or below the floor")
+
+// AccountBlown reports whether equity is at or below Config.BlownEquityFloor.
+// While it is, the kill switch is held and only reduce-only orders pass.
+func (sm *ShardedStateManager) AccountBlown() bool {
+	return atomic.LoadInt32(&sm.blown) != 0
+}
+
+// checkBlown engages the kill switch the first time equity falls to the
+// floor, and lifts the blown state once equity is back above it. The kill
+// switch itself is then left to an operator.
+func (sm *ShardedStateManager) checkBlown(equity int64) {
+	floor := toFixed(sm.config.BlownEquityFloor)
+	var blown int32
+	if equity <= floor {
+		blown = 1
+	}
+	if atomic.SwapInt32(&sm.blown, blown) == blown {
+		return
+	}
+	if blown == 1 {
+		sm.ApplyKillSwitch(KillSwitchRequest{Active: true, Source: KillSourceBlown})
+		log.Printf("[RISK] Account blown: equity %s at or below floor %s; kill switch engaged, only closes allowed",
+			appendFixed(nil, equity), appendFixed(nil, floor))
+		sm.raiseAlert(AlertCritical, "account_blown", fmt.Sprintf("equity %s at or below floor %s; kill switch engaged, only closes allowed",
+			appendFixed(nil, equity), appendFixed(nil, floor)))
+	} else {
+		log.Printf("[RISK] Equity %s back above floor %s; kill switch left for an operator", appendFixed(nil, equity), appendFixed(nil, floor))
+	}
+
+	seq := atomic.LoadUint64(&sm.state.SequenceID)
+	b := make([]byte, 0, 128)
+	b = append(b, `{"type":"account_blown","active":`...)
+	b = strconv.AppendBool(b, blown == 1)
+	b = append(b, `,"equity":`...)
+	b = appendFixed(b, equity)
+	b = append(b, `,"floor":`...)
+	b = appendFixed(b, floor)
+	b = append(b, `,"seq_id":`...)
+	b = strconv.AppendUint(b, seq, 10)
+	b = append(b, '}')
+	sm.publish(ws.EventAccountBlown, seq, b)
+}
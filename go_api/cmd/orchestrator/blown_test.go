@@ -0,0 +1,62 @@
+package main
+
+import (
+	"slices"
+	"sync/atomic"
+	"testing"
+)
+
+func TestAccountBlown(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxDrawdownPct = 100 // Keep the breaker out of it
+	cfg.DailyLossLimit = 1e6
+	cfg.BlownEquityFloor = 50_000
+	sm := NewShardedStateManager(cfg)
+	h := sm.symbols.Hash("BLOWUSD")
+	fill(sm, h, 0, 1000, 100, 0)
+
+	tick(sm, h, 40) // Equity 40000
+	if !sm.AccountBlown() || atomic.LoadInt32(&sm.state.KillSwitch) != 1 {
+		t.Fatal("equity under the floor did not blow the account")
+	}
+	if source, _ := sm.KillSwitchSource(); source != KillSourceBlown {
+		t.Fatalf("kill switch source %q, want %q", source, KillSourceBlown)
+	}
+	if alerts := sm.Alerts(false); len(alerts) != 1 || alerts[0].Kind != "account_blown" || alerts[0].Severity != AlertCritical {
+		t.Fatalf("alerts %+v", alerts)
+	}
+
+	open := sm.RiskCheckAll(&OrderOptimized{SymbolHash: h, Quantity: fx(1), Price: fx(40)})
+	if open.Approved || !slices.Contains(open.Violations, ReasonKillSwitch) || !slices.Contains(open.Violations, ReasonAccountBlown) {
+		t.Fatalf("opening order %v %v", open.Approved, open.Violations)
+	}
+	if res := sm.RiskCheckFast(&OrderOptimized{SymbolHash: h, Side: 1, Quantity: fx(100), Price: fx(40), ReduceOnly: true}); !res.Approved {
+		t.Fatalf("reduce-only close rejected: %v", res.Reason)
+	}
+	for _, source := range []string{KillSourceManual, KillSourceBlown} {
+		if _, err := sm.ApplyKillSwitch(KillSwitchRequest{Source: source, Actor: "ops"}); err != ErrAccountBlown {
+			t.Fatalf("%s clear: err %v, want %v", source, err, ErrAccountBlown)
+		}
+	}
+
+	tick(sm, h, 90) // Equity 90000: no longer blown, but the switch is left set
+	if sm.AccountBlown() || atomic.LoadInt32(&sm.state.KillSwitch) != 1 {
+		t.Fatal("recovery should lift the blown state only")
+	}
+	if _, err := sm.ApplyKillSwitch(KillSwitchRequest{Source: KillSourceManual, Actor: "ops"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDrawdownBpsBounds(t *testing.T) {
+	for _, tt := range []struct{ hwm, equity, want int64 }{
+		{fx(100), fx(90), 1000},
+		{fx(100), 0, 10000},
+		{fx(100), -fx(50), 10000},
+		{0, -fx(50), 0},
+	} {
+		if got := drawdownBps(tt.hwm, tt.equity); got != tt.want {
+			t.Errorf("drawdownBps(%d, %d) = %d, want %d", tt.hwm, tt.equity, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"cenayang-market/go-api/internal/ws"
+)
+
+// ============================================================================
+// DRAWDOWN BREAKER - Reduce-Only, Then Kill Switch, No Flapping
+// ============================================================================
+
+// BreakerConfig - the drawdown tiers and how long drawdown must stay past
+// one before the breaker acts, so equity hovering at a limit does not flap
+// it. The hard tier is Config.MaxDrawdownPct.
+type BreakerConfig struct {
+	ReduceOnlyDrawdownPct float64       // Drawdown that switches the account to reduce-only ahead of the kill switch (0 = no soft tier)
+	ConfirmDuration       time.Duration // At or over a tier this long engages it (0 = at once)
+	ClearDuration         time.Duration // Under a tier this long clears it; 0 clears reduce-only at once and leaves the kill switch to an operator
+	WarmupDuration        time.Duration // After startup or a snapshot import, drawdown is computed but engages neither tier this long (0 = armed at once)
+}
+
+func (c BreakerConfig) validate(maxDrawdownPct float64) error {
+	if c.ReduceOnlyDrawdownPct < 0 {
+		return fmt.Errorf("reduce-only drawdown %v%% must not be negative", c.ReduceOnlyDrawdownPct)
+	}
+	if c.WarmupDuration < 0 {
+		return fmt.Errorf("warm-up %v must not be negative", c.WarmupDuration)
+	}
+	if c.ReduceOnlyDrawdownPct > 0 && c.ReduceOnlyDrawdownPct >= maxDrawdownPct {
+		return fmt.Errorf("reduce-only drawdown %v%% must be below max drawdown %v%%", c.ReduceOnlyDrawdownPct, maxDrawdownPct)
+	}
+	return nil
+}
+
+// drawdownBreaker - when drawdown crossed each tier, either way. Times are
+// unix ns from the state manager's clock; 0 = not crossed.
+type drawdownBreaker struct {
+	breachSince int64 // Drawdown at or over the limit since
+	clearSince  int64 // Drawdown back under the limit since, while tripped
+	tripped     int32 // The kill switch is engaged by the breaker
+
+	reduceSince      int64 // Drawdown at or over the reduce-only tier since
+	reduceClearSince int64 // Drawdown back under it since, while reduce-only
+	reduceOnly       int32 // Atomic bool: only reduce-only orders pass
+
+	armAt        int64 // End of the warm-up; 0 = armed
+	warmupLogged int32 // A suppressed breach has been logged this warm-up
+}
+
+// DrawdownReduceOnly reports whether drawdown has switched the account to
+// reduce-only
+func (sm *ShardedStateManager) DrawdownReduceOnly() bool {
+	return atomic.LoadInt32(&sm.breaker.reduceOnly) != 0
+}
+
+// checkBreaker walks drawdown through both tiers: reduce-only at
+// Breaker.ReduceOnlyDrawdownPct, then the kill switch at the limit. Each
+// engages once drawdown has stayed at or over it for
+// Breaker.ConfirmDuration. Reduce-only clears once drawdown has stayed
+// under its tier for Breaker.ClearDuration; with a ClearDuration, a kill
+// switch the breaker engaged clears the same way, while one set by an
+// operator is left alone.
+func (sm *ShardedStateManager) checkBreaker(drawdown, limit int64) {
+	if !sm.config.KillSwitchEnabled {
+		return
+	}
+	br := &sm.breaker
+	now := sm.clock.Now().UnixNano()
+	if sm.breakerWarmingUp(drawdown, limit, now) {
+		return
+	}
+	sm.checkReduceOnly(drawdown, now)
+
+	if drawdown >= limit {
+		atomic.StoreInt64(&br.clearSince, 0)
+		since := markSince(&br.breachSince, now)
+		if now-since < int64(sm.config.Breaker.ConfirmDuration) {
+			return
+		}
+		if changed, _ := sm.ApplyKillSwitch(KillSwitchRequest{Active: true, Source: KillSourceBreaker}); changed {
+			atomic.StoreInt32(&br.tripped, 1)
+			log.Printf("[CIRCUIT BREAKER] Drawdown %d bps >= limit %d bps", drawdown, limit)
+			sm.raiseAlert(AlertCritical, "circuit_breaker", fmt.Sprintf("drawdown %d bps >= limit %d bps; kill switch engaged", drawdown, limit))
+		}
+		return
+	}
+
+	atomic.StoreInt64(&br.breachSince, 0)
+	if sm.config.Breaker.ClearDuration <= 0 || atomic.LoadInt32(&br.tripped) == 0 {
+		return
+	}
+	since := markSince(&br.clearSince, now)
+	if now-since < int64(sm.config.Breaker.ClearDuration) {
+		return
+	}
+	if atomic.CompareAndSwapInt32(&br.tripped, 1, 0) {
+		atomic.StoreInt64(&br.clearSince, 0)
+		sm.ApplyKillSwitch(KillSwitchRequest{Source: KillSourceBreaker})
+		log.Printf("[CIRCUIT BREAKER] Drawdown %d bps under limit %d bps; kill switch cleared", drawdown, limit)
+	}
+}
+
+// startBreakerWarmup holds the breaker off for Breaker.WarmupDuration, so
+// a first bad tick or a stale imported snapshot cannot trip it before
+// marks settle
+func (sm *ShardedStateManager) startBreakerWarmup(reason string) {
+	d := sm.config.Breaker.WarmupDuration
+	if d <= 0 {
+		return
+	}
+	armAt := sm.clock.Now().Add(d)
+	atomic.StoreInt32(&sm.breaker.warmupLogged, 0)
+	atomic.StoreInt64(&sm.breaker.armAt, armAt.UnixNano())
+	log.Printf("[CIRCUIT BREAKER] Warm-up after %s; breaker arms at %s", reason, armAt.UTC().Format(time.RFC3339))
+}
+
+// breakerWarmingUp reports whether the breaker is still warming up. Any
+// breach seen meanwhile is forgotten, so a tier engages only on drawdown
+// confirmed after the warm-up; the first one is logged.
+func (sm *ShardedStateManager) breakerWarmingUp(drawdown, limit, now int64) bool {
+	br := &sm.breaker
+	armAt := atomic.LoadInt64(&br.armAt)
+	if armAt == 0 {
+		return false
+	}
+	if now >= armAt {
+		if atomic.CompareAndSwapInt64(&br.armAt, armAt, 0) {
+			log.Printf("[CIRCUIT BREAKER] Warm-up over; breaker armed at drawdown %d bps", drawdown)
+		}
+		return false
+	}
+	atomic.StoreInt64(&br.breachSince, 0)
+	atomic.StoreInt64(&br.reduceSince, 0)
+	if tier := int64(sm.config.Breaker.ReduceOnlyDrawdownPct * 100); tier > 0 && tier < limit {
+		limit = tier
+	}
+	if drawdown >= limit && atomic.CompareAndSwapInt32(&br.warmupLogged, 0, 1) {
+		log.Printf("[CIRCUIT BREAKER] Drawdown %d bps during warm-up; suppressed until %s",
+			drawdown, time.Unix(0, armAt).UTC().Format(time.RFC3339))
+	}
+	return true
+}
+
+// checkReduceOnly moves the account into or out of the reduce-only tier
+func (sm *ShardedStateManager) checkReduceOnly(drawdown, now int64) {
+	if sm.config.Breaker.ReduceOnlyDrawdownPct <= 0 {
+		return
+	}
+	br := &sm.breaker
+	threshold := int64(sm.config.Breaker.ReduceOnlyDrawdownPct * 100) // Basis points
+
+	if drawdown >= threshold {
+		atomic.StoreInt64(&br.reduceClearSince, 0)
+		since := markSince(&br.reduceSince, now)
+		if now-since < int64(sm.config.Breaker.ConfirmDuration) {
+			return
+		}
+		if atomic.CompareAndSwapInt32(&br.reduceOnly, 0, 1) {
+			log.Printf("[CIRCUIT BREAKER] Drawdown %d bps >= reduce-only tier %d bps; new risk blocked", drawdown, threshold)
+			sm.raiseAlert(AlertWarning, "drawdown_reduce_only", fmt.Sprintf("drawdown %d bps >= reduce-only tier %d bps; new risk blocked", drawdown, threshold))
+			sm.publishReduceOnly(true, drawdown, threshold)
+		}
+		return
+	}
+
+	atomic.StoreInt64(&br.reduceSince, 0)
+	if atomic.LoadInt32(&br.reduceOnly) == 0 {
+		return
+	}
+	since := markSince(&br.reduceClearSince, now)
+	if now-since < int64(sm.config.Breaker.ClearDuration) {
+		return
+	}
+	if atomic.CompareAndSwapInt32(&br.reduceOnly, 1, 0) {
+		atomic.StoreInt64(&br.reduceClearSince, 0)
+		log.Printf("[CIRCUIT BREAKER] Drawdown %d bps under reduce-only tier %d bps; new risk allowed", drawdown, threshold)
+		sm.publishReduceOnly(false, drawdown, threshold)
+	}
+}
+
+// publishReduceOnly broadcasts a reduce-only tier change
+func (sm *ShardedStateManager) publishReduceOnly(active bool, drawdown, threshold int64) {
+	b := make([]byte, 0, 96)
+	b = append(b, `{"type":"drawdown_reduce_only","active":`...)
+	b = strconv.AppendBool(b, active)
+	b = append(b, `,"drawdown_bps":`...)
+	b = strconv.AppendInt(b, drawdown, 10)
+	b = append(b, `,"threshold_bps":`...)
+	b = strconv.AppendInt(b, threshold, 10)
+	b = append(b, '}')
+	sm.publish(ws.EventDrawdownReduceOnly, atomic.LoadUint64(&sm.state.SequenceID), b)
+}
+
+// markSince stores now in an unset crossing time and returns the time in
+// effect
+func markSince(p *int64, now int64) int64 {
+	if atomic.CompareAndSwapInt64(p, 0, now) {
+		return now
+	}
+	return atomic.LoadInt64(p)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Drawdown past the soft tier blocks new risk but not reductions, leaves
+// the kill switch and pause alone, and clears only once confirmed
+func TestDrawdownReduceOnlyTier(t *testing.T) {
+	clk := &fakeClock{t: time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC)}
+	cfg := testConfig() // Kill switch at 500 bps
+	cfg.Clock = clk
+	cfg.Breaker = BreakerConfig{ReduceOnlyDrawdownPct: 3, ConfirmDuration: time.Minute, ClearDuration: time.Minute}
+	sm := NewShardedStateManager(cfg)
+	h := sm.symbols.Hash("TIERUSD")
+	fill(sm, h, 0, 1, 100, 0)
+	open := &OrderOptimized{SymbolHash: h, Side: 0, Quantity: fx(1), Price: fx(100)}
+	reduce := &OrderOptimized{SymbolHash: h, Side: 1, Quantity: fx(1), Price: fx(100), ReduceOnly: true}
+
+	steps := []struct {
+		after    time.Duration
+		drawdown int64 // Basis points
+		want     bool  // Reduce-only
+	}{
+		{0, 300, false},          // At the tier, not yet confirmed
+		{time.Minute, 350, true}, // Held for ConfirmDuration
+		{0, 100, true},           // Back under, not yet cleared
+		{30 * time.Second, 320, true},
+		{0, 100, true}, // The clear timer restarts
+		{time.Minute, 100, false},
+	}
+	for i, s := range steps {
+		clk.t = clk.t.Add(s.after)
+		sm.checkBreaker(s.drawdown, int64(cfg.MaxDrawdownPct*100))
+		if got := sm.DrawdownReduceOnly(); got != s.want {
+			t.Fatalf("step %d: reduce-only %v, want %v", i, got, s.want)
+		}
+		if s.want {
+			if res := sm.RiskCheckFast(open); res.Reason != ReasonDrawdownReduceOnly {
+				t.Fatalf("step %d: opening order %v", i, res.Reason)
+			}
+			if res := sm.RiskCheckFast(reduce); !res.Approved {
+				t.Fatalf("step %d: reducing order %v", i, res.Reason)
+			}
+		}
+		if atomic.LoadInt32(&sm.state.KillSwitch) != 0 || atomic.LoadInt32(&sm.state.TradingPaused) != 0 {
+			t.Fatalf("step %d: kill switch or pause engaged", i)
+		}
+	}
+	if res := sm.RiskCheckFast(open); !res.Approved {
+		t.Fatalf("opening order %v once cleared", res.Reason)
+	}
+
+	if err := (BreakerConfig{ReduceOnlyDrawdownPct: 5}).validate(5); err == nil {
+		t.Fatal("a soft tier at the kill switch limit validated")
+	}
+}
+
+// A breach during the warm-up is forgotten: ConfirmDuration counts from
+// when the breaker arms
+func TestBreakerWarmup(t *testing.T) {
+	clk := &fakeClock{t: time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC)}
+	cfg := testConfig() // Kill switch at 500 bps
+	cfg.Clock = clk
+	cfg.Breaker = BreakerConfig{ConfirmDuration: 30 * time.Second, WarmupDuration: time.Minute}
+	sm := NewShardedStateManager(cfg)
+
+	steps := []struct {
+		after time.Duration
+		want  int32 // Kill switch
+	}{
+		{0, 0},
+		{50 * time.Second, 0}, // Breached 50s, all of it warming up
+		{10 * time.Second, 0}, // Armed: the breach starts counting now
+		{29 * time.Second, 0},
+		{time.Second, 1},
+	}
+	for i, s := range steps {
+		clk.t = clk.t.Add(s.after)
+		sm.checkBreaker(600, int64(cfg.MaxDrawdownPct*100))
+		if got := atomic.LoadInt32(&sm.state.KillSwitch); got != s.want {
+			t.Fatalf("step %d: kill switch %d, want %d", i, got, s.want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"cenayang-market/go-api/internal/handlers"
+)
+
+// ============================================================================
+// CLOSE ESTIMATE - Cost of Flattening a Position at the Touch
+// ============================================================================
+
+// CloseEstimate - what closing a position with a market order now would
+// realize, priced the way the fill would be booked (fixed-point)
+type CloseEstimate struct {
+	Side         uint8 // Of the closing order: 1 = sell a long, 0 = buy back a short
+	Quantity     int64
+	Bid          int64 // 0 = side empty
+	Ask          int64
+	MarkPrice    int64
+	ExitPrice    int64 // The touch on the closing side, else the last trade, else the mark
+	RealizedPnL  int64 // Against the entry, before commission
+	Commission   int64 // Taker fee on the exit, from the fee schedule
+	Slippage     int64 // PnL given up against the mark by exiting at ExitPrice
+	LowLiquidity bool  // Closing side empty, or its size short of the quantity
+}
+
+// EstimateClose prices a market close of the symbol's position against
+// the latest tick; false when the symbol is flat. The whole quantity is
+// assumed to fill at the touch - when the book cannot show that, the
+// estimate is flagged low-liquidity rather than walked down the book.
+func (sm *ShardedStateManager) EstimateClose(symbolHash uint64) (CloseEstimate, bool) {
+	shard := sm.GetShard(symbolHash)
+	shard.mu.RLock()
+	p, ok := shard.positions[symbolHash]
+	var pos PositionOptimized
+	if ok {
+		pos = *p
+	}
+	shard.mu.RUnlock()
+	if !ok || pos.Quantity <= 0 {
+		return CloseEstimate{}, false
+	}
+	spec := sm.symbols.Get(symbolHash)
+
+	est := CloseEstimate{Side: 1 - pos.Side, Quantity: pos.Quantity, MarkPrice: markPrice(&pos)}
+	m, ticked := sm.feed.Microstructure(symbolHash)
+	est.Bid, est.Ask = m.Bid, m.Ask
+	touch, size := m.Bid, m.BidSize // A long sells into the bid
+	if pos.Side != 0 {
+		touch, size = m.Ask, m.AskSize
+	}
+	switch {
+	case touch > 0:
+		est.ExitPrice = touch
+		est.LowLiquidity = size > 0 && size < pos.Quantity // Size 0 = not reported
+	case ticked && m.LastPrice > 0:
+		est.ExitPrice, est.LowLiquidity = m.LastPrice, true
+	default:
+		est.ExitPrice, est.LowLiquidity = est.MarkPrice, true
+	}
+
+	est.RealizedPnL = unrealizedPnL(spec, &pos, est.ExitPrice)
+	est.Commission = scheduledCommission(spec, pos.Quantity, est.ExitPrice, LiquidityTaker)
+	est.Slippage = unrealizedPnL(spec, &pos, est.MarkPrice) - est.RealizedPnL
+	return est, true
+}
+
+// handleCloseEstimate serves EstimateClose for the path's symbol; 404 when
+// it is flat
+func (sm *ShardedStateManager) handleCloseEstimate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "GET required", http.StatusMethodNotAllowed)
+		return
+	}
+
+	hash := sm.symbols.Hash(r.PathValue("symbol"))
+	est, ok := sm.EstimateClose(hash)
+	if !ok {
+		handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusNotFound, Error: "no open position", Field: "symbol"})
+		return
+	}
+
+	buf := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(buf)
+
+	b := append((*buf)[:0], `{"symbol":`...)
+	b = strconv.AppendQuote(b, sm.symbols.Name(hash))
+	b = append(b, `,"side":"`...)
+	if est.Side == 0 {
+		b = append(b, `BUY`...)
+	} else {
+		b = append(b, `SELL`...)
+	}
+	b = append(b, `","quantity":`...)
+	b = appendFixed(b, est.Quantity)
+	b = append(b, `,"bid":`...)
+	b = appendFixed(b, est.Bid)
+	b = append(b, `,"ask":`...)
+	b = appendFixed(b, est.Ask)
+	b = append(b, `,"mark_price":`...)
+	b = appendFixed(b, est.MarkPrice)
+	b = append(b, `,"exit_price":`...)
+	b = appendFixed(b, est.ExitPrice)
+	b = append(b, `,"realized_pnl":`...)
+	b = appendFixed(b, est.RealizedPnL)
+	b = append(b, `,"commission":`...)
+	b = appendFixed(b, est.Commission)
+	b = append(b, `,"slippage":`...)
+	b = appendFixed(b, est.Slippage)
+	b = append(b, `,"net_pnl":`...)
+	b = appendFixed(b, est.RealizedPnL-est.Commission)
+	b = append(b, `,"low_liquidity":`...)
+	b = strconv.AppendBool(b, est.LowLiquidity)
+	b = append(b, '}')
+
+	handlers.WriteJSON(w, r, http.StatusOK, b)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// 10 held at 100, marked at the last trade of 105, with a 10 bps taker fee
+func TestCloseEstimate(t *testing.T) {
+	tests := []struct {
+		name             string
+		side             uint8 // Of the position
+		bid, ask         float64
+		bidSize, askSize float64
+		wantExit         float64
+		wantPnL          float64
+		wantSlippage     float64
+		wantLow          bool
+	}{
+		{"long sells into the bid", 0, 104, 106, 20, 20, 104, 40, 10, false},
+		{"short buys the ask", 1, 104, 106, 20, 20, 106, -60, 10, false},
+		{"touch too thin", 0, 104, 106, 5, 20, 104, 40, 10, true},
+		{"size not reported", 0, 104, 106, 0, 0, 104, 40, 10, false},
+		{"closing side empty", 1, 104, 0, 20, 0, 105, -50, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.Symbols = []SymbolMeta{{Symbol: "EXITUSD", TickSize: 0.01, LotSize: 1, Multiplier: 1, Currency: "USD", TakerFeeBps: 10}}
+			sm := NewShardedStateManager(cfg)
+			h := sm.symbols.Hash("EXITUSD")
+			fill(sm, h, tt.side, 10, 100, 0)
+			sm.feed.OnTick(&MarketTickOptimized{SymbolHash: h, BidPrice: fx(tt.bid), AskPrice: fx(tt.ask),
+				BidSize: fx(tt.bidSize), AskSize: fx(tt.askSize), LastPrice: fx(105)})
+
+			est, ok := sm.EstimateClose(h)
+			if !ok || est.Side != 1-tt.side || est.Quantity != fx(10) {
+				t.Fatalf("estimate %+v, ok %v", est, ok)
+			}
+			if est.ExitPrice != fx(tt.wantExit) || est.RealizedPnL != fx(tt.wantPnL) || est.Slippage != fx(tt.wantSlippage) || est.LowLiquidity != tt.wantLow {
+				t.Fatalf("exit %d pnl %d slippage %d low %v, want %d %d %d %v", est.ExitPrice, est.RealizedPnL, est.Slippage, est.LowLiquidity,
+					fx(tt.wantExit), fx(tt.wantPnL), fx(tt.wantSlippage), tt.wantLow)
+			}
+			if want := fx(tt.wantExit / 100); est.Commission != want { // 10 bps of 10 × exit
+				t.Fatalf("commission %d, want %d", est.Commission, want)
+			}
+		})
+	}
+
+	sm := NewShardedStateManager(testConfig())
+	srv := httptest.NewServer(setupHTTPRoutes(sm))
+	defer srv.Close()
+	h := sm.symbols.Hash("EXITUSD")
+	resp, err := http.Get(srv.URL + "/api/positions/EXITUSD/close-estimate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("flat: %s", resp.Status)
+	}
+	fill(sm, h, 0, 10, 100, 0)
+	tick(sm, h, 110) // No book: exit at the last trade
+	body := smokeGet(t, srv.URL+"/api/positions/EXITUSD/close-estimate")
+	if body["side"] != "SELL" || body["exit_price"] != 110.0 || body["net_pnl"] != 100.0 || body["low_liquidity"] != true {
+		t.Fatalf("response %v", body)
+	}
+}
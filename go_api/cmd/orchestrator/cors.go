@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ============================================================================
+// CORS - Origin Allowlist for Browsers, Shared With the WebSocket Upgrade
+// ============================================================================
+
+// corsPolicy - the origins a browser may call the API from. A listed origin
+// is echoed back with credentials allowed; "*" admits any other origin
+// without them, since browsers refuse credentials on a wildcard.
+type corsPolicy struct {
+	allowed  map[string]bool
+	wildcard bool
+}
+
+func newCORSPolicy(origins []string) *corsPolicy {
+	p := &corsPolicy{allowed: make(map[string]bool, len(origins))}
+	for _, origin := range origins {
+		switch origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin {
+		case "":
+		case "*":
+			p.wildcard = true
+		default:
+			p.allowed[origin] = true
+		}
+	}
+	return p
+}
+
+// allow returns the Access-Control-Allow-Origin value for origin, "" if it
+// is not allowed, and whether credentials may be sent
+func (p *corsPolicy) allow(origin string) (string, bool) {
+	switch {
+	case p.allowed[origin]:
+		return origin, true
+	case p.wildcard:
+		return "*", false
+	}
+	return "", false
+}
+
+// checkOrigin admits a WebSocket upgrade from an allowed origin, or from a
+// client that sends none - browsers always do, other clients are not
+// subject to CORS
+func (p *corsPolicy) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	allowOrigin, _ := p.allow(origin)
+	return allowOrigin != ""
+}
+
+// corsMiddleware adds CORS headers for allowed origins. A disallowed
+// origin gets none, which the browser enforces as a denial.
+func corsMiddleware(origins []string, next http.Handler) http.Handler {
+	policy := newCORSPolicy(origins)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" {
+			h := w.Header()
+			h.Add("Vary", "Origin")
+			if allowOrigin, credentials := policy.allow(origin); allowOrigin != "" {
+				h.Set("Access-Control-Allow-Origin", allowOrigin)
+				if credentials {
+					h.Set("Access-Control-Allow-Credentials", "true")
+				}
+				h.Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+				h.Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			}
+		}
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
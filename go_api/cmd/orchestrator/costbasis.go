@@ -0,0 +1,97 @@
+package main
+
+// ============================================================================
+// COST BASIS - Average, FIFO and LIFO Realization
+// ============================================================================
+
+// Cost basis methods
+const (
+	CostBasisAverage = "AVG"  // Close against the blended entry price (default)
+	CostBasisFIFO    = "FIFO" // Close the oldest lots first
+	CostBasisLIFO    = "LIFO" // Close the newest lots first
+)
+
+// costLot - one opening fill's open quantity
+type costLot struct {
+	Quantity int64 // Fixed-point
+	Price    int64 // Fixed-point
+}
+
+// positionLots returns a position's open lots - caller holds the shard
+// lock. Under average cost no lots are kept. A position whose lots do not
+// add up to its quantity - restored from a snapshot or the database, which
+// store only the blended entry - is treated as one lot at that entry.
+func (sm *ShardedStateManager) positionLots(shard *StateShard, pos *PositionOptimized) []costLot {
+	if sm.costBasis == CostBasisAverage {
+		return nil
+	}
+	lots := shard.lots[pos.SymbolHash]
+	var total int64
+	for _, lot := range lots {
+		total += lot.Quantity
+	}
+	if total != pos.Quantity {
+		lots = append(lots[:0], costLot{Quantity: pos.Quantity, Price: pos.EntryPrice})
+		shard.lots[pos.SymbolHash] = lots
+	}
+	return lots
+}
+
+// openLot records added quantity - caller holds the shard lock and has not
+// yet added quantity to pos
+func (sm *ShardedStateManager) openLot(shard *StateShard, pos *PositionOptimized, quantity, price int64) {
+	if sm.costBasis == CostBasisAverage {
+		return
+	}
+	shard.lots[pos.SymbolHash] = append(sm.positionLots(shard, pos), costLot{Quantity: quantity, Price: price})
+}
+
+// closeLots consumes closed quantity in method order and returns its entry
+// price: the blended entry under average cost, else the closed lots' own
+// weighted price. The position's entry moves to what remains open.
+// Caller holds the shard lock and has not yet reduced pos.
+func (sm *ShardedStateManager) closeLots(shard *StateShard, pos *PositionOptimized, closed int64) int64 {
+	lots := sm.positionLots(shard, pos)
+	if lots == nil {
+		return pos.EntryPrice
+	}
+
+	var cost int64 // Σ price × qty of the closed pieces
+	for left := closed; left > 0; {
+		i := 0 // FIFO takes from the front
+		if sm.costBasis == CostBasisLIFO {
+			i = len(lots) - 1
+		}
+		take := min(left, lots[i].Quantity)
+		cost += mulDiv(lots[i].Price, take, PriceScale)
+		left -= take
+		if lots[i].Quantity -= take; lots[i].Quantity == 0 {
+			lots = append(lots[:i], lots[i+1:]...)
+		}
+	}
+
+	if len(lots) == 0 {
+		delete(shard.lots, pos.SymbolHash)
+	} else {
+		shard.lots[pos.SymbolHash] = lots
+		var open, value int64
+		for _, lot := range lots {
+			open += lot.Quantity
+			value += mulDiv(lot.Price, lot.Quantity, PriceScale)
+		}
+		pos.EntryPrice = mulDiv(value, PriceScale, open)
+	}
+	return mulDiv(cost, PriceScale, closed)
+}
+
+// resetLots drops a closed or flipped position's lots, seeding the flip's
+// remainder as its first lot - caller holds the shard lock
+func (sm *ShardedStateManager) resetLots(shard *StateShard, pos *PositionOptimized) {
+	if sm.costBasis == CostBasisAverage {
+		return
+	}
+	delete(shard.lots, pos.SymbolHash)
+	if pos.Quantity > 0 {
+		shard.lots[pos.SymbolHash] = []costLot{{Quantity: pos.Quantity, Price: pos.EntryPrice}}
+	}
+}
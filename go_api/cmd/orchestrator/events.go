@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"cenayang-market/go-api/internal/ws"
+)
+
+// ============================================================================
+// STATE EVENTS - Published Through the Hub (WebSocket + gRPC Streams)
+// ============================================================================
+
+// DefaultHeartbeatInterval is how often an idle-proof heartbeat is
+// broadcast when Config.HeartbeatInterval is zero
+const DefaultHeartbeatInterval = 5 * time.Second
+
+// publish hands an event to the hub - non-blocking, drops when saturated -
+// and to the event journal. data is retained by both, so it must not come
+// from bufferPool.
+func (sm *ShardedStateManager) publish(eventType uint8, seqID uint64, data []byte) {
+	event := ws.BinaryEvent{
+		Type:      eventType,
+		SeqID:     seqID,
+		Timestamp: time.Now().UnixNano(),
+		Data:      data,
+	}
+	sm.hub.Broadcast(event)
+	sm.journalEvent(&event)
+}
+
+// appendFloat formats a float for a JSON payload. NaN and ±Inf have no
+// JSON form and would leave the whole payload unparseable, so they go out
+// as null; the first is alerted on, every one is counted.
+func (sm *ShardedStateManager) appendFloat(b []byte, f float64, prec int) []byte {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		atomic.AddUint64(&sm.marshalErrors, 1)
+		sm.marshalAlert.Do(func() {
+			log.Printf("[ALERT] Non-finite float %v in an outbound payload, sent as null; further ones are only counted", f)
+		})
+		return append(b, `null`...)
+	}
+	return strconv.AppendFloat(b, f, 'f', prec, 64)
+}
+
+// MarshalErrors returns the number of non-finite floats nulled in payloads
+func (sm *ShardedStateManager) MarshalErrors() uint64 {
+	return atomic.LoadUint64(&sm.marshalErrors)
+}
+
+// publishFill broadcasts an applied fill. The payload carries everything
+// a standby needs to book it identically, and the PnL it realized under the
+// configured cost basis for the trade ledger.
+func (sm *ShardedStateManager) publishFill(fill *FillEvent, reduceOnly bool, realized int64, seqID uint64) {
+	b := make([]byte, 0, 256)
+	b = append(b, `{"type":"fill","order_id":"`...)
+	b = strconv.AppendUint(b, fill.OrderID, 10)
+	b = append(b, `","symbol":`...)
+	b = strconv.AppendQuote(b, sm.symbols.Name(fill.SymbolHash))
+	b = append(b, `,"symbol_hash":"`...)
+	b = strconv.AppendUint(b, fill.SymbolHash, 16)
+	b = append(b, `","side":"`...)
+	if fill.Side == 0 {
+		b = append(b, `BUY`...)
+	} else {
+		b = append(b, `SELL`...)
+	}
+	b = append(b, `","quantity":`...)
+	b = appendFixed(b, fill.Quantity)
+	b = append(b, `,"price":`...)
+	b = appendFixed(b, fill.Price)
+	b = append(b, `,"commission":`...)
+	b = appendFixed(b, fill.Commission)
+	b = append(b, `,"liquidity":"`...)
+	b = append(b, liquidityName(fill.Liquidity)...)
+	b = append(b, '"')
+	if reduceOnly {
+		b = append(b, `,"reduce_only":true`...)
+	}
+	b = append(b, `,"realized_pnl":`...)
+	b = appendFixed(b, realized)
+	b = append(b, `,"cost_basis":"`...)
+	b = append(b, sm.costBasis...)
+	b = append(b, `","fill_seq_id":`...)
+	b = strconv.AppendUint(b, fill.SeqID, 10)
+	b = append(b, `,"fill_timestamp_ns":`...)
+	b = strconv.AppendInt(b, fill.Timestamp, 10)
+	b = append(b, `,"seq_id":`...)
+	b = strconv.AppendUint(b, seqID, 10)
+	b = append(b, '}')
+
+	sm.publish(ws.EventFill, seqID, b)
+}
+
+// publishLimitBreach flags a fill priced worse than its order's limit.
+// The fill is still booked - the venue executed it - but a limit order
+// should never fill through its limit, so operators must look.
+func (sm *ShardedStateManager) publishLimitBreach(fill *FillEvent, limit int64) {
+	log.Printf("[FILL] Order %d %s filled at %s through its limit %s",
+		fill.OrderID, sm.symbols.Name(fill.SymbolHash), appendFixed(nil, fill.Price), appendFixed(nil, limit))
+	sm.raiseAlert(AlertWarning, "limit_breach", fmt.Sprintf("order %d %s filled at %s through its limit %s",
+		fill.OrderID, sm.symbols.Name(fill.SymbolHash), appendFixed(nil, fill.Price), appendFixed(nil, limit)))
+
+	seq := atomic.LoadUint64(&sm.state.SequenceID)
+	b := make([]byte, 0, 192)
+	b = append(b, `{"type":"limit_breach","order_id":"`...)
+	b = strconv.AppendUint(b, fill.OrderID, 10)
+	b = append(b, `","symbol":`...)
+	b = strconv.AppendQuote(b, sm.symbols.Name(fill.SymbolHash))
+	b = append(b, `,"side":"`...)
+	if fill.Side == 0 {
+		b = append(b, `BUY`...)
+	} else {
+		b = append(b, `SELL`...)
+	}
+	b = append(b, `","fill_price":`...)
+	b = appendFixed(b, fill.Price)
+	b = append(b, `,"limit_price":`...)
+	b = appendFixed(b, limit)
+	b = append(b, `,"quantity":`...)
+	b = appendFixed(b, fill.Quantity)
+	b = append(b, `,"seq_id":`...)
+	b = strconv.AppendUint(b, seq, 10)
+	b = append(b, '}')
+	sm.publish(ws.EventLimitBreach, seq, b)
+}
+
+// publishOrder broadcasts an accepted or cancelled order
+func (sm *ShardedStateManager) publishOrder(order *OrderOptimized) {
+	sm.publish(ws.EventOrder, order.SequenceID, sm.orderEvent(order))
+}
+
+// publishOrderWait broadcasts an order event that must not be dropped,
+// waiting for room in the hub until ctx is done
+func (sm *ShardedStateManager) publishOrderWait(ctx context.Context, order *OrderOptimized) {
+	event := ws.BinaryEvent{
+		Type:      ws.EventOrder,
+		SeqID:     order.SequenceID,
+		Timestamp: time.Now().UnixNano(),
+		Data:      sm.orderEvent(order),
+	}
+	sm.hub.BroadcastWait(ctx, event)
+	sm.journalEvent(&event)
+}
+
+func (sm *ShardedStateManager) orderEvent(order *OrderOptimized) []byte {
+	b := make([]byte, 0, 384)
+	b = append(b, `{"type":"order","symbol_hash":"`...)
+	b = strconv.AppendUint(b, order.SymbolHash, 16)
+	b = append(b, `","order":`...)
+	b = appendOrder(b, order, sm.symbols.Name(order.SymbolHash))
+	return append(b, '}')
+}
+
+// publishHeartbeat broadcasts a liveness event with the current sequence
+// ID. Clients that see no heartbeat for a few intervals should treat the
+// stream as stale, whether or not the socket is still up.
+func (sm *ShardedStateManager) publishHeartbeat() {
+	seq := atomic.LoadUint64(&sm.state.SequenceID)
+	b := make([]byte, 0, 80)
+	b = append(b, `{"type":"heartbeat","seq_id":`...)
+	b = strconv.AppendUint(b, seq, 10)
+	b = append(b, `,"timestamp_ns":`...)
+	b = strconv.AppendInt(b, time.Now().UnixNano(), 10)
+	b = append(b, '}')
+	sm.publish(ws.EventHeartbeat, seq, b)
+}
+
+// SetTradingPaused pauses or resumes new risk-taking orders and broadcasts
+// the change. Returns whether the state changed.
+func (sm *ShardedStateManager) SetTradingPaused(paused bool) bool {
+	var v int32
+	if paused {
+		v = 1
+	}
+	if atomic.SwapInt32(&sm.state.TradingPaused, v) == v {
+		return false
+	}
+
+	b := make([]byte, 0, 48)
+	b = append(b, `{"type":"trading_paused","paused":`...)
+	b = strconv.AppendBool(b, paused)
+	b = append(b, '}')
+	sm.publish(ws.EventTradingPaused, atomic.LoadUint64(&sm.state.SequenceID), b)
+	return true
+}
+
+// compactSnapshot captures the essential portfolio view compact WebSocket
+// clients receive in place of full portfolio reads
+func (sm *ShardedStateManager) compactSnapshot() *ws.CompactSnapshot {
+	snap := &ws.CompactSnapshot{Timestamp: time.Now().UnixNano()}
+	sm.lockShards()
+	for i := range sm.shards {
+		for _, pos := range sm.shards[i].positions {
+			qty := pos.Quantity
+			if pos.Side != 0 {
+				qty = -qty
+			}
+			snap.Positions = append(snap.Positions, ws.CompactPosition{
+				SymbolHash:    pos.SymbolHash,
+				Quantity:      qty,
+				UnrealizedPnL: pos.UnrealizedPnL,
+			})
+		}
+	}
+	st := &sm.state
+	snap.SeqID = atomic.LoadUint64(&st.SequenceID)
+	snap.Equity = atomic.LoadInt64(&st.Equity)
+	snap.DrawdownBps = int32(atomic.LoadInt64(&st.CurrentDrawdown))
+	if atomic.LoadInt32(&st.KillSwitch) != 0 {
+		snap.Flags |= ws.CompactKillSwitch
+	}
+	if atomic.LoadInt32(&st.TradingPaused) != 0 {
+		snap.Flags |= ws.CompactTradingPaused
+	}
+	sm.unlockShards()
+	return snap
+}
+
+// publishCompactSnapshot broadcasts a compact snapshot, skipped while no
+// client asked for one
+func (sm *ShardedStateManager) publishCompactSnapshot() {
+	if sm.hub.CompactClients() == 0 {
+		return
+	}
+	sm.hub.BroadcastCompact(sm.compactSnapshot())
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+
+	"cenayang-market/go-api/internal/handlers"
+)
+
+// ============================================================================
+// EXECUTION QUALITY - Implementation Shortfall vs Decision Price
+// ============================================================================
+
+// ExecutionStats - shortfall aggregate (fixed-point, quote currency).
+// Shortfall is positive when execution was worse than the decision price:
+// a buy filled above it or a sell filled below it.
+//
+// Limit-order fills are also measured against their limit. Improvement is
+// positive when the venue did better than the limit - a buy below it or a
+// sell above it - and negative only for a limit breach, which is counted
+// separately as an anomaly.
+type ExecutionStats struct {
+	Fills            uint64
+	FilledOrders     uint64
+	Shortfall        int64 // Σ signed (fill - decision) × qty × multiplier
+	DecisionNotional int64 // Σ decision × qty × multiplier
+
+	LimitFills       uint64 // Fills of orders with a limit price
+	ImprovedFills    uint64 // Filled better than the limit
+	LimitBreaches    uint64 // Filled worse than the limit
+	PriceImprovement int64  // Σ signed (limit - fill) × qty × multiplier, negated for sells
+	LimitNotional    int64  // Σ limit × qty × multiplier
+
+	MakerFills    uint64 // Every booked fill, with or without a working order
+	TakerFills    uint64
+	MakerNotional int64
+	TakerNotional int64
+}
+
+// ShortfallBps returns shortfall relative to the decision notional
+func (s ExecutionStats) ShortfallBps() int64 {
+	return mulDiv(s.Shortfall, 10000, s.DecisionNotional)
+}
+
+// PriceImprovementBps returns improvement relative to the limit notional
+func (s ExecutionStats) PriceImprovementBps() int64 {
+	return mulDiv(s.PriceImprovement, 10000, s.LimitNotional)
+}
+
+func (s *ExecutionStats) add(o *ExecutionStats) {
+	s.Fills += o.Fills
+	s.FilledOrders += o.FilledOrders
+	s.Shortfall += o.Shortfall
+	s.DecisionNotional += o.DecisionNotional
+	s.LimitFills += o.LimitFills
+	s.ImprovedFills += o.ImprovedFills
+	s.LimitBreaches += o.LimitBreaches
+	s.PriceImprovement += o.PriceImprovement
+	s.LimitNotional += o.LimitNotional
+	s.MakerFills += o.MakerFills
+	s.TakerFills += o.TakerFills
+	s.MakerNotional += o.MakerNotional
+	s.TakerNotional += o.TakerNotional
+}
+
+// ExecutionTracker aggregates shortfall per symbol
+type ExecutionTracker struct {
+	mu       sync.Mutex
+	bySymbol map[uint64]*ExecutionStats
+}
+
+func NewExecutionTracker() *ExecutionTracker {
+	return &ExecutionTracker{bySymbol: make(map[uint64]*ExecutionStats, 16)}
+}
+
+// decisionPrice is the price an order is measured against: the symbol's
+// last trade when it was accepted, else its limit price
+func (sm *ShardedStateManager) decisionPrice(order *OrderOptimized) int64 {
+	if last, ok := sm.feed.LastPrice(order.SymbolHash); ok {
+		return last
+	}
+	return order.Price
+}
+
+// shortfall returns the signed cost of filling quantity at price against
+// decision; positive is adverse
+func shortfall(spec *SymbolSpec, side uint8, quantity, price, decision int64) int64 {
+	cost := spec.ApplyMultiplier(mulDiv(price-decision, quantity, PriceScale))
+	if side != 0 { // Sell: a lower price is worse
+		cost = -cost
+	}
+	return cost
+}
+
+// Record books one fill of an order and reports whether it breached the
+// order's limit price. Shortfall is skipped for fills without a decision
+// price and improvement for market orders; neither is measurable.
+func (t *ExecutionTracker) Record(spec *SymbolSpec, order *OrderOptimized, fill *FillEvent, complete bool) (breached bool) {
+	var delta ExecutionStats
+	if order.DecisionPrice > 0 {
+		delta.Fills = 1
+		if complete {
+			delta.FilledOrders = 1
+		}
+		delta.Shortfall = shortfall(spec, order.Side, fill.Quantity, fill.Price, order.DecisionPrice)
+		delta.DecisionNotional = notionalValue(spec, fill.Quantity, order.DecisionPrice)
+	}
+	if order.Price > 0 {
+		improvement := -shortfall(spec, order.Side, fill.Quantity, fill.Price, order.Price)
+		delta.LimitFills = 1
+		delta.PriceImprovement = improvement
+		delta.LimitNotional = notionalValue(spec, fill.Quantity, order.Price)
+		breached = fillBreachesLimit(order.Side, fill.Price, order.Price)
+		switch {
+		case breached:
+			delta.LimitBreaches = 1
+		case improvement > 0:
+			delta.ImprovedFills = 1
+		}
+	}
+	if delta.Fills == 0 && delta.LimitFills == 0 {
+		return false
+	}
+
+	t.add(order.SymbolHash, &delta)
+	return breached
+}
+
+func (t *ExecutionTracker) add(symbolHash uint64, delta *ExecutionStats) {
+	t.mu.Lock()
+	s, ok := t.bySymbol[symbolHash]
+	if !ok {
+		s = &ExecutionStats{}
+		t.bySymbol[symbolHash] = s
+	}
+	s.add(delta)
+	t.mu.Unlock()
+}
+
+// RecordLiquidity books a fill's notional as maker or taker volume
+func (t *ExecutionTracker) RecordLiquidity(spec *SymbolSpec, fill *FillEvent) {
+	var delta ExecutionStats
+	if fill.Liquidity == LiquidityMaker {
+		delta.MakerFills, delta.MakerNotional = 1, notionalValue(spec, fill.Quantity, fill.Price)
+	} else {
+		delta.TakerFills, delta.TakerNotional = 1, notionalValue(spec, fill.Quantity, fill.Price)
+	}
+	t.add(fill.SymbolHash, &delta)
+}
+
+// fillBreachesLimit reports whether price is worse than limit for side: a
+// buy above it or a sell below it. Compared on price rather than PnL so a
+// breach too small to move the rounded amount is still caught.
+func fillBreachesLimit(side uint8, price, limit int64) bool {
+	if side == 0 {
+		return price > limit
+	}
+	return price < limit
+}
+
+// Snapshot returns per-symbol stats and their total
+func (t *ExecutionTracker) Snapshot() (map[uint64]ExecutionStats, ExecutionStats) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out := make(map[uint64]ExecutionStats, len(t.bySymbol))
+	var total ExecutionStats
+	for hash, s := range t.bySymbol {
+		out[hash] = *s
+		total.add(s)
+	}
+	return out, total
+}
+
+// orderShortfallBps returns an order's shortfall so far from its average
+// fill price, 0 if unfilled or unmeasured
+func orderShortfallBps(o *OrderOptimized) int64 {
+	if o.FilledQty == 0 || o.DecisionPrice <= 0 {
+		return 0
+	}
+	bps := mulDiv(o.AvgFillPrice-o.DecisionPrice, 10000, o.DecisionPrice)
+	if o.Side != 0 {
+		bps = -bps
+	}
+	return bps
+}
+
+// handleExecutionQuality serves shortfall, price improvement and maker /
+// taker volume aggregates per symbol and total
+func (sm *ShardedStateManager) handleExecutionQuality(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "GET required", http.StatusMethodNotAllowed)
+		return
+	}
+
+	bySymbol, total := sm.execution.Snapshot()
+	type row struct {
+		symbol string
+		stats  ExecutionStats
+	}
+	rows := make([]row, 0, len(bySymbol))
+	for hash, s := range bySymbol {
+		rows = append(rows, row{sm.symbols.Name(hash), s})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].symbol < rows[j].symbol })
+
+	buf := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(buf)
+
+	b := append((*buf)[:0], `{"total":`...)
+	b = appendExecutionStats(b, total)
+	b = append(b, `,"symbols":[`...)
+	for i, row := range rows {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, `{"symbol":`...)
+		b = strconv.AppendQuote(b, row.symbol)
+		b = append(b, `,"stats":`...)
+		b = appendExecutionStats(b, row.stats)
+		b = append(b, '}')
+	}
+	b = append(b, `]}`...)
+
+	handlers.WriteJSON(w, r, http.StatusOK, b)
+}
+
+func appendExecutionStats(b []byte, s ExecutionStats) []byte {
+	b = append(b, `{"fills":`...)
+	b = strconv.AppendUint(b, s.Fills, 10)
+	b = append(b, `,"filled_orders":`...)
+	b = strconv.AppendUint(b, s.FilledOrders, 10)
+	b = append(b, `,"shortfall":`...)
+	b = appendFixed(b, s.Shortfall)
+	b = append(b, `,"decision_notional":`...)
+	b = appendFixed(b, s.DecisionNotional)
+	b = append(b, `,"shortfall_bps":`...)
+	b = strconv.AppendInt(b, s.ShortfallBps(), 10)
+	b = append(b, `,"limit_fills":`...)
+	b = strconv.AppendUint(b, s.LimitFills, 10)
+	b = append(b, `,"improved_fills":`...)
+	b = strconv.AppendUint(b, s.ImprovedFills, 10)
+	b = append(b, `,"limit_breaches":`...)
+	b = strconv.AppendUint(b, s.LimitBreaches, 10)
+	b = append(b, `,"price_improvement":`...)
+	b = appendFixed(b, s.PriceImprovement)
+	b = append(b, `,"limit_notional":`...)
+	b = appendFixed(b, s.LimitNotional)
+	b = append(b, `,"price_improvement_bps":`...)
+	b = strconv.AppendInt(b, s.PriceImprovementBps(), 10)
+	b = append(b, `,"maker_fills":`...)
+	b = strconv.AppendUint(b, s.MakerFills, 10)
+	b = append(b, `,"taker_fills":`...)
+	b = strconv.AppendUint(b, s.TakerFills, 10)
+	b = append(b, `,"maker_notional":`...)
+	b = appendFixed(b, s.MakerNotional)
+	b = append(b, `,"taker_notional":`...)
+	b = appendFixed(b, s.TakerNotional)
+	return append(b, '}')
+}
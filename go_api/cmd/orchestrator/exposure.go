@@ -0,0 +1,155 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+
+	"cenayang-market/go-api/internal/handlers"
+)
+
+// ============================================================================
+// EXPOSURE - Shared Notional Math for Risk Checks and Reporting
+// ============================================================================
+
+// notionalValue returns |quantity × price| scaled by the contract multiplier
+func notionalValue(spec *SymbolSpec, quantity, price int64) int64 {
+	return spec.ApplyMultiplier(mulDiv(quantity, price, PriceScale))
+}
+
+// positionCost returns the cash a fill of quantity at price ties up in the
+// position: its notional for a buy, negated for a short sale, which is
+// credited the proceeds
+func positionCost(spec *SymbolSpec, side uint8, quantity, price int64) int64 {
+	cost := notionalValue(spec, quantity, price)
+	if side == 1 {
+		return -cost
+	}
+	return cost
+}
+
+// maxQuantity returns the largest quantity, before lot rounding, whose
+// notional at price stays within maxNotional
+func maxQuantity(spec *SymbolSpec, maxNotional, price int64) int64 {
+	return mulDiv(mulDiv(maxNotional, PriceScale, spec.Multiplier), PriceScale, price)
+}
+
+// maxPositionNotional returns the order notional cap: MaxPositionSize, or
+// MaxPositionPctOfEquity of live equity when that is tighter. With only
+// the percentage set, it alone applies.
+func (sm *ShardedStateManager) maxPositionNotional() int64 {
+	limit := int64(sm.config.MaxPositionSize * float64(PriceScale))
+	if sm.config.MaxPositionPctOfEquity <= 0 {
+		return limit
+	}
+	equity := max(atomic.LoadInt64(&sm.state.Equity), 0)
+	pctLimit := mulDiv(equity, toFixed(sm.config.MaxPositionPctOfEquity), 100*PriceScale)
+	if limit <= 0 || pctLimit < limit {
+		return pctLimit
+	}
+	return limit
+}
+
+// markPrice returns the price a position is valued at
+func markPrice(pos *PositionOptimized) int64 {
+	if pos.CurrentPrice > 0 {
+		return pos.CurrentPrice
+	}
+	return pos.EntryPrice // No tick yet
+}
+
+// unrealizedPnL marks a position to price in quote currency
+func unrealizedPnL(spec *SymbolSpec, pos *PositionOptimized, price int64) int64 {
+	var pnl int64
+	if pos.Side == 0 { // Long
+		pnl = mulDiv(price-pos.EntryPrice, pos.Quantity, PriceScale)
+	} else { // Short
+		pnl = mulDiv(pos.EntryPrice-price, pos.Quantity, PriceScale)
+	}
+	return spec.RoundMoney(spec.ApplyMultiplier(pnl))
+}
+
+// drawdownBps returns equity's drawdown from hwm in basis points: 0
+// without a positive mark, and at most 10000 - a total loss - however far
+// equity has gone negative
+func drawdownBps(hwm, equity int64) int64 {
+	switch {
+	case hwm <= 0:
+		return 0
+	case equity <= 0:
+		return 10000
+	}
+	return mulDiv(hwm-equity, 10000, hwm) // No overflow on large accounts
+}
+
+// Exposure - aggregate book exposure (fixed-point notionals)
+type Exposure struct {
+	Gross      int64 // Σ |notional|
+	Net        int64 // Σ signed notional (long +, short -)
+	Long       int64
+	Short      int64 // Reported as a positive amount
+	LongCount  int
+	ShortCount int
+}
+
+// Exposure sums marked notional across all shards
+func (sm *ShardedStateManager) Exposure() Exposure {
+	var exp Exposure
+	for i := 0; i < NumShards; i++ {
+		shard := &sm.shards[i]
+		shard.mu.RLock()
+		for _, pos := range shard.positions {
+			notional := notionalValue(sm.symbols.Get(pos.SymbolHash), pos.Quantity, markPrice(pos))
+			if pos.Side == 0 { // Long
+				exp.Long += notional
+				exp.LongCount++
+			} else { // Short
+				exp.Short += notional
+				exp.ShortCount++
+			}
+		}
+		shard.mu.RUnlock()
+	}
+	exp.Gross = exp.Long + exp.Short
+	exp.Net = exp.Long - exp.Short
+	return exp
+}
+
+// handleExposure serves gross/net exposure, the per-side breakdown and
+// each symbol group against its cap
+func (sm *ShardedStateManager) handleExposure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "GET required", http.StatusMethodNotAllowed)
+		return
+	}
+
+	exp := sm.Exposure()
+	equity := atomic.LoadInt64(&sm.state.Equity)
+
+	buf := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(buf)
+
+	b := append((*buf)[:0], `{"gross":`...)
+	b = appendFixed(b, exp.Gross)
+	b = append(b, `,"net":`...)
+	b = appendFixed(b, exp.Net)
+	b = append(b, `,"long":`...)
+	b = appendFixed(b, exp.Long)
+	b = append(b, `,"short":`...)
+	b = appendFixed(b, exp.Short)
+	b = append(b, `,"long_count":`...)
+	b = strconv.AppendInt(b, int64(exp.LongCount), 10)
+	b = append(b, `,"short_count":`...)
+	b = strconv.AppendInt(b, int64(exp.ShortCount), 10)
+	b = append(b, `,"gross_leverage":`...)
+	if equity > 0 {
+		b = appendFixed(b, mulDiv(exp.Gross, PriceScale, equity))
+	} else {
+		b = append(b, `null`...)
+	}
+	b = append(b, `,"groups":`...)
+	b = sm.appendSymbolGroups(b)
+	b = append(b, '}')
+
+	handlers.WriteJSON(w, r, http.StatusOK, b)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"cenayang-market/go-api/internal/ws"
+)
+
+// ============================================================================
+// FEED BUS HEALTH - Transport-Side Drops, Distinct From Our Own Backpressure
+// ============================================================================
+
+const (
+	// SlowConsumerSustained is how long slow-consumer reports must keep
+	// arriving before health reports the feed degraded
+	SlowConsumerSustained = 30 * time.Second
+	// SlowConsumerClear is the quiet period after which the condition ends
+	SlowConsumerClear = 10 * time.Second
+)
+
+// busHealth tracks slow-consumer reports from the message bus. The bus
+// drops messages on its side when our subscription falls behind; those
+// never reach the ingester's sequencing, so they are counted here.
+type busHealth struct {
+	mu      sync.Mutex
+	since   int64            // Start of the current slow-consumer episode, 0 = none
+	last    int64            // Most recent report
+	dropped map[string]int64 // Last cumulative drop count per subject
+
+	slowConsumer uint64 // Atomic; reports received
+	droppedMsgs  uint64 // Atomic; messages the bus discarded
+	reconnects   uint64 // Atomic; connection re-established
+}
+
+// OnSlowConsumer records a slow-consumer report for subject. droppedTotal
+// is the subscription's cumulative drop count as the bus reports it, or -1
+// if unknown; only the increase since the last report is counted.
+func (f *FeedIngester) OnSlowConsumer(subject string, droppedTotal int64) {
+	bus := &f.bus
+	now := f.sm.clock.Now().UnixNano()
+
+	bus.mu.Lock()
+	var delta int64
+	if droppedTotal >= 0 {
+		if bus.dropped == nil {
+			bus.dropped = make(map[string]int64, 4)
+		}
+		prev, ok := bus.dropped[subject]
+		delta = droppedTotal - prev
+		if !ok || delta < 0 { // First report, or the subscription was recreated
+			delta = droppedTotal
+		}
+		bus.dropped[subject] = droppedTotal
+	}
+	onset := bus.since == 0 || now-bus.last >= int64(SlowConsumerClear)
+	if onset {
+		bus.since = now
+	}
+	bus.last = now
+	bus.mu.Unlock()
+
+	reports := atomic.AddUint64(&bus.slowConsumer, 1)
+	dropped := atomic.AddUint64(&bus.droppedMsgs, uint64(delta))
+	if onset {
+		log.Printf("[FEED] Bus reports slow consumer on %q: %d messages dropped", subject, delta)
+		f.sm.raiseAlert(AlertWarning, "feed_slow_consumer", fmt.Sprintf("bus reports slow consumer on %q: %d messages dropped", subject, delta))
+	}
+
+	seq := atomic.LoadUint64(&f.sm.state.SequenceID)
+	b := make([]byte, 0, 160)
+	b = append(b, `{"type":"feed_slow_consumer","subject":`...)
+	b = strconv.AppendQuote(b, subject)
+	b = append(b, `,"dropped":`...)
+	b = strconv.AppendInt(b, delta, 10)
+	b = append(b, `,"nats_slow_consumer":`...)
+	b = strconv.AppendUint(b, reports, 10)
+	b = append(b, `,"nats_dropped_msgs":`...)
+	b = strconv.AppendUint(b, dropped, 10)
+	b = append(b, '}')
+	f.sm.publish(ws.EventFeedSlowConsumer, seq, b)
+}
+
+// SlowConsumerSustained reports whether the bus has kept reporting a slow
+// consumer for at least SlowConsumerSustained without a quiet gap
+func (f *FeedIngester) SlowConsumerSustained() bool {
+	bus := &f.bus
+	now := f.sm.clock.Now().UnixNano()
+
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	return bus.since != 0 && now-bus.last < int64(SlowConsumerClear) && now-bus.since >= int64(SlowConsumerSustained)
+}
+
+// NATSSlowConsumer returns the number of slow-consumer reports from NATS
+func (f *FeedIngester) NATSSlowConsumer() uint64 {
+	return atomic.LoadUint64(&f.bus.slowConsumer)
+}
+
+// NATSDroppedMsgs returns the number of messages NATS dropped for us
+func (f *FeedIngester) NATSDroppedMsgs() uint64 {
+	return atomic.LoadUint64(&f.bus.droppedMsgs)
+}
+
+// NATSErrorHandler returns the async error callback to register on the
+// NATS connection (nats.ErrorHandler) carrying the feed subscriptions
+func (f *FeedIngester) NATSErrorHandler() nats.ErrHandler {
+	return func(_ *nats.Conn, sub *nats.Subscription, err error) {
+		if !errors.Is(err, nats.ErrSlowConsumer) {
+			log.Printf("[FEED] NATS async error: %v", err)
+			return
+		}
+		subject, dropped := "", int64(-1)
+		if sub != nil {
+			subject = sub.Subject
+			if n, err := sub.Dropped(); err == nil {
+				dropped = int64(n)
+			}
+		}
+		f.OnSlowConsumer(subject, dropped)
+	}
+}
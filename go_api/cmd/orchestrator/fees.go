@@ -0,0 +1,65 @@
+package main
+
+import "strings"
+
+// ============================================================================
+// FEES - Maker/Taker Liquidity and the Per-Symbol Fee Schedule
+// ============================================================================
+
+// Fill liquidity
+const (
+	LiquidityTaker uint8 = 0 // Removed liquidity; the default when unflagged
+	LiquidityMaker uint8 = 1 // Rested on the book and was hit
+)
+
+// liquidityName returns the wire name of a liquidity flag
+func liquidityName(liquidity uint8) string {
+	if liquidity == LiquidityMaker {
+		return "MAKER"
+	}
+	return "TAKER"
+}
+
+// parseLiquidity reads a wire liquidity flag; anything but MAKER is taker
+func parseLiquidity(s string) uint8 {
+	if strings.EqualFold(s, "MAKER") {
+		return LiquidityMaker
+	}
+	return LiquidityTaker
+}
+
+// feeRate returns the symbol's fee on a fill's notional as a fixed-point
+// fraction, negative for a rebate
+func (spec *SymbolSpec) feeRate(liquidity uint8) int64 {
+	if liquidity == LiquidityMaker {
+		return spec.MakerFeeRate
+	}
+	return spec.TakerFeeRate
+}
+
+// estimatedCommission returns the commission an order of notional would
+// be charged, at the taker rate since a resting order may still take. A
+// rebate is not counted on, so the estimate is never negative.
+func estimatedCommission(spec *SymbolSpec, notional int64) int64 {
+	if spec.TakerFeeRate <= 0 {
+		return 0
+	}
+	return spec.RoundMoney(mulDiv(notional, spec.TakerFeeRate, PriceScale))
+}
+
+// scheduledCommission returns the symbol's fee on a fill, negative for a
+// rebate
+func scheduledCommission(spec *SymbolSpec, quantity, price int64, liquidity uint8) int64 {
+	return spec.RoundMoney(mulDiv(notionalValue(spec, quantity, price), spec.feeRate(liquidity), PriceScale))
+}
+
+// applyFeeSchedule charges a fill without a venue-reported commission at
+// its symbol's maker or taker rate. Symbols without a schedule, and fills
+// that already carry a commission, are left alone.
+func (sm *ShardedStateManager) applyFeeSchedule(fill *FillEvent) {
+	spec := sm.symbols.Get(fill.SymbolHash)
+	if fill.Commission != 0 || (spec.MakerFeeRate == 0 && spec.TakerFeeRate == 0) {
+		return
+	}
+	fill.Commission = scheduledCommission(spec, fill.Quantity, fill.Price, fill.Liquidity)
+}
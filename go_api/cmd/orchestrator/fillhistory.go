@@ -0,0 +1,216 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"cenayang-market/go-api/internal/handlers"
+)
+
+// ============================================================================
+// FILL HISTORY - Raw Booked Fills by Time, for Post-Trade Analysis
+// ============================================================================
+
+// Fill history page sizes
+const (
+	DefaultFillsPage = 100
+	MaxFillsPage     = 1000
+)
+
+// bookedFill - a fill as booked, numbered in booking order
+type bookedFill struct {
+	ID          uint64 // Position in the history; the pagination cursor
+	Fill        FillEvent
+	ReduceOnly  bool  // As booked, including the order's own flag
+	RealizedPnL int64 // Fixed-point
+	SeqID       uint64
+	BookedAt    int64 // Unix ns from the state manager's clock; the time index
+}
+
+// fillHistory - booked fills oldest first, bounded by Retention.Fills
+type fillHistory struct {
+	mu     sync.RWMutex
+	fills  []bookedFill
+	nextID uint64
+}
+
+// recordFill appends a booked fill to the history, dropping the oldest
+// beyond the entry cap
+func (sm *ShardedStateManager) recordFill(fill *FillEvent, reduceOnly bool, realized int64, seq uint64) {
+	h := &sm.fillHistory
+	h.mu.Lock()
+	if limit := sm.retention.Fills.MaxEntries; limit > 0 && len(h.fills) >= limit {
+		h.fills = h.fills[len(h.fills)-limit+1:] // Reallocated by a later append
+	}
+	h.nextID++
+	h.fills = append(h.fills, bookedFill{
+		ID:          h.nextID,
+		Fill:        *fill,
+		ReduceOnly:  reduceOnly,
+		RealizedPnL: realized,
+		SeqID:       seq,
+		BookedAt:    sm.clock.Now().UnixNano(),
+	})
+	h.mu.Unlock()
+}
+
+// FillsQuery - a page of the fill history
+type FillsQuery struct {
+	SymbolHash uint64 // 0 = every symbol
+	From, To   int64  // Booked in [From, To), unix ns; To 0 = no end
+	After      uint64 // Only fills with a greater ID
+	Limit      int
+}
+
+// Fills returns up to q.Limit fills matching q in booking order, and
+// whether more follow the last one
+func (sm *ShardedStateManager) Fills(q FillsQuery) ([]bookedFill, bool) {
+	h := &sm.fillHistory
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	i := sort.Search(len(h.fills), func(i int) bool { return h.fills[i].BookedAt >= q.From })
+	if len(h.fills) > 0 && q.After >= h.fills[0].ID {
+		i = max(i, int(q.After-h.fills[0].ID)+1) // IDs are contiguous
+	}
+	var out []bookedFill
+	for ; i < len(h.fills); i++ {
+		f := &h.fills[i]
+		if q.To != 0 && f.BookedAt >= q.To {
+			break
+		}
+		if q.SymbolHash != 0 && f.Fill.SymbolHash != q.SymbolHash {
+			continue
+		}
+		if len(out) == q.Limit {
+			return out, true
+		}
+		out = append(out, *f)
+	}
+	return out, false
+}
+
+// trimFillHistory drops fills booked before the history's age limit; the
+// entry cap is enforced on insert. Returns the number dropped.
+func (sm *ShardedStateManager) trimFillHistory(now time.Time) int {
+	cutoff := sm.retention.Fills.cutoff(now)
+	if cutoff.IsZero() {
+		return 0
+	}
+
+	h := &sm.fillHistory
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := sort.Search(len(h.fills), func(i int) bool { return h.fills[i].BookedAt >= cutoff.UnixNano() })
+	h.fills = h.fills[n:]
+	return n
+}
+
+// handleFills serves booked fills in booking order. Optional: symbol;
+// from and to (RFC 3339, to exclusive) bound the booking time; limit
+// (default DefaultFillsPage, at most MaxFillsPage) and after, the
+// next_after of the previous page, paginate.
+func (sm *ShardedStateManager) handleFills(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "GET required", http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := r.URL.Query()
+	q := FillsQuery{Limit: DefaultFillsPage}
+	if v := params.Get("symbol"); v != "" {
+		q.SymbolHash = sm.symbols.Hash(v)
+	}
+	for _, p := range []struct {
+		name string
+		dst  *int64
+	}{{"from", &q.From}, {"to", &q.To}} {
+		if v := params.Get(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: p.name + " must be an RFC 3339 time", Field: p.name})
+				return
+			}
+			*p.dst = t.UnixNano()
+		}
+	}
+	if q.To != 0 && q.To <= q.From {
+		handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "to must be after from", Field: "to"})
+		return
+	}
+	if v := params.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > MaxFillsPage {
+			handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "limit must be between 1 and " + strconv.Itoa(MaxFillsPage), Field: "limit"})
+			return
+		}
+		q.Limit = n
+	}
+	if v := params.Get("after"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "after must be a fill ID", Field: "after"})
+			return
+		}
+		q.After = n
+	}
+
+	fills, more := sm.Fills(q)
+
+	buf := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(buf)
+
+	b := append((*buf)[:0], `{"fills":[`...)
+	for i := range fills {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = sm.appendBookedFill(b, &fills[i])
+	}
+	b = append(b, `],"next_after":`...)
+	if more {
+		b = strconv.AppendUint(b, fills[len(fills)-1].ID, 10)
+	} else {
+		b = append(b, `null`...)
+	}
+	b = append(b, '}')
+
+	handlers.WriteJSON(w, r, http.StatusOK, b)
+}
+
+func (sm *ShardedStateManager) appendBookedFill(b []byte, f *bookedFill) []byte {
+	b = append(b, `{"id":`...)
+	b = strconv.AppendUint(b, f.ID, 10)
+	b = append(b, `,"order_id":"`...)
+	b = strconv.AppendUint(b, f.Fill.OrderID, 10)
+	b = append(b, `","symbol":`...)
+	b = strconv.AppendQuote(b, sm.symbols.Name(f.Fill.SymbolHash))
+	b = append(b, `,"side":"`...)
+	if f.Fill.Side == 0 {
+		b = append(b, `BUY`...)
+	} else {
+		b = append(b, `SELL`...)
+	}
+	b = append(b, `","quantity":`...)
+	b = appendFixed(b, f.Fill.Quantity)
+	b = append(b, `,"price":`...)
+	b = appendFixed(b, f.Fill.Price)
+	b = append(b, `,"commission":`...)
+	b = appendFixed(b, f.Fill.Commission)
+	b = append(b, `,"liquidity":"`...)
+	b = append(b, liquidityName(f.Fill.Liquidity)...)
+	b = append(b, `","reduce_only":`...)
+	b = strconv.AppendBool(b, f.ReduceOnly)
+	b = append(b, `,"realized_pnl":`...)
+	b = appendFixed(b, f.RealizedPnL)
+	b = append(b, `,"seq_id":`...)
+	b = strconv.AppendUint(b, f.SeqID, 10)
+	b = append(b, `,"timestamp_ns":`...)
+	b = strconv.AppendInt(b, f.Fill.Timestamp, 10)
+	b = append(b, `,"booked_at_ns":`...)
+	b = strconv.AppendInt(b, f.BookedAt, 10)
+	return append(b, '}')
+}
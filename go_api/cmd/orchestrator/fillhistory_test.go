@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+// Five fills a minute apart, alternating between two symbols
+func TestFillHistory(t *testing.T) {
+	t0 := time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC)
+	clk := &fakeClock{t: t0}
+	cfg := testConfig()
+	cfg.Clock = clk
+	cfg.Retention.Fills = RetentionPolicy{MaxEntries: 100, MaxAge: time.Hour}
+	sm := NewShardedStateManager(cfg)
+	a, b := sm.symbols.Hash("AUSD"), sm.symbols.Hash("BUSD")
+	for i := 0; i < 5; i++ {
+		h := a
+		if i%2 == 1 {
+			h = b
+		}
+		fill(sm, h, 0, 1, 10, 0)
+		clk.t = clk.t.Add(time.Minute)
+	}
+
+	ids := func(fills []bookedFill) []uint64 {
+		out := make([]uint64, len(fills))
+		for i := range fills {
+			out[i] = fills[i].ID
+		}
+		return out
+	}
+	tests := []struct {
+		name     string
+		q        FillsQuery
+		want     []uint64
+		wantMore bool
+	}{
+		{"all", FillsQuery{Limit: 10}, []uint64{1, 2, 3, 4, 5}, false},
+		{"window, to exclusive", FillsQuery{From: t0.Add(time.Minute).UnixNano(), To: t0.Add(3 * time.Minute).UnixNano(), Limit: 10}, []uint64{2, 3}, false},
+		{"symbol", FillsQuery{SymbolHash: b, Limit: 10}, []uint64{2, 4}, false},
+		{"first page", FillsQuery{Limit: 2}, []uint64{1, 2}, true},
+		{"next page", FillsQuery{After: 2, Limit: 2}, []uint64{3, 4}, true},
+		{"last page", FillsQuery{After: 4, Limit: 2}, []uint64{5}, false},
+		{"symbol page", FillsQuery{SymbolHash: a, After: 1, Limit: 1}, []uint64{3}, true},
+	}
+	for _, tt := range tests {
+		fills, more := sm.Fills(tt.q)
+		if got := ids(fills); !slices.Equal(got, tt.want) || more != tt.wantMore {
+			t.Fatalf("%s: ids %v more %v, want %v more %v", tt.name, got, more, tt.want, tt.wantMore)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	sm.handleFills(rec, httptest.NewRequest(http.MethodGet, "/api/fills?limit=0", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("limit=0: status %d", rec.Code)
+	}
+
+	// An hour after the third fill, the first three are past MaxAge
+	if n := sm.trimFillHistory(t0.Add(2*time.Minute + time.Hour + time.Second)); n != 3 {
+		t.Fatalf("trimmed %d, want 3", n)
+	}
+	if fills, _ := sm.Fills(FillsQuery{After: 2, Limit: 10}); len(fills) != 2 || fills[0].ID != 4 {
+		t.Fatalf("after the trim %v, want IDs 4 and 5", ids(fills))
+	}
+}
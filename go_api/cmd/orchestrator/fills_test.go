@@ -0,0 +1,238 @@
+package main
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// fill applies a fill of quantity at price, charging commission
+func fill(sm *ShardedStateManager, symbolHash uint64, side uint8, quantity, price, commission float64) {
+	sm.ApplyFill(&FillEvent{SymbolHash: symbolHash, Side: side, Quantity: fx(quantity), Price: fx(price), Commission: fx(commission)})
+}
+
+// Starting from DefaultStartingEquity's 100k, a short round trip at one
+// price costs only its commissions
+func TestShortCashFlow(t *testing.T) {
+	tests := []struct {
+		name      string
+		cover     float64
+		wantOpen  float64 // Cash after the short sale
+		wantCash  float64 // After the cover
+		wantValue float64
+	}{
+		{"flat", 100, 100_999, 99_998, 99_998},
+		{"cover lower", 90, 100_999, 100_098, 100_098},
+		{"cover higher", 110, 100_999, 99_898, 99_898},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := NewShardedStateManager(testConfig())
+			h := sm.symbols.Hash("SHORTUSD")
+
+			fill(sm, h, 1, 10, 100, 1) // Credited 1000 of proceeds
+			if cash := atomic.LoadInt64(&sm.state.Cash); cash != fx(tt.wantOpen) {
+				t.Fatalf("cash after the sale %d, want %d", cash, fx(tt.wantOpen))
+			}
+			fill(sm, h, 0, 10, tt.cover, 1) // Debited the cost to cover
+			if cash := atomic.LoadInt64(&sm.state.Cash); cash != fx(tt.wantCash) {
+				t.Fatalf("cash after the cover %d, want %d", cash, fx(tt.wantCash))
+			}
+			if value := atomic.LoadInt64(&sm.bookValue); value != fx(tt.wantValue) {
+				t.Fatalf("book value %d, want %d", value, fx(tt.wantValue))
+			}
+			if _, open := sm.GetShard(h).positions[h]; open {
+				t.Fatal("position still open after the cover")
+			}
+		})
+	}
+}
+
+// Two buys of 1 at 100 and a sale of 1 at 110, each paying 1 of commission
+func TestBreakevenPrice(t *testing.T) {
+	sm := NewShardedStateManager(testConfig())
+	const h = 1
+	steps := []struct {
+		side           uint8
+		wantCommission float64 // Entry commission still held
+		wantBreakeven  float64
+	}{
+		{0, 1, 101},
+		{0, 2, 101},
+		{1, 1, 101}, // Half the entry commission leaves with the sale
+	}
+	for i, step := range steps {
+		price := 100.0
+		if step.side == 1 {
+			price = 110
+		}
+		fill(sm, h, step.side, 1, price, 1)
+		pos := sm.GetShard(h).positions[h]
+		if pos.Commission != fx(step.wantCommission) || pos.BreakevenPrice != fx(step.wantBreakeven) {
+			t.Fatalf("fill %d: commission %d breakeven %d, want %d and %d", i+1, pos.Commission, pos.BreakevenPrice, fx(step.wantCommission), fx(step.wantBreakeven))
+		}
+	}
+	if cash := atomic.LoadInt64(&sm.state.Cash); cash != fx(99_907) { // The long of 1 still holds 100
+		t.Fatalf("cash %d, want %d", cash, fx(99_907))
+	}
+	if value := atomic.LoadInt64(&sm.bookValue); value != fx(100_007) {
+		t.Fatalf("book value %d, want %d", value, fx(100_007))
+	}
+
+	// Short breakeven sits below entry
+	fill(sm, 2, 1, 2, 50, 1)
+	if pos := sm.GetShard(2).positions[2]; pos.BreakevenPrice != fx(49.5) {
+		t.Fatalf("short breakeven %d, want %d", pos.BreakevenPrice, fx(49.5))
+	}
+}
+
+// A reduce-only fill with nothing opposite to reduce is left out of the book
+func TestQuarantineReduceWithoutPosition(t *testing.T) {
+	sm := NewShardedStateManager(testConfig())
+	h := SymbolHash("BTCUSD")
+	fill(sm, h, 0, 1, 100, 0)
+	cash := atomic.LoadInt64(&sm.state.Cash)
+
+	sm.ApplyFill(&FillEvent{SymbolHash: h, Side: 0, Quantity: fx(1), Price: fx(100), ReduceOnly: true})
+	if pos := sm.GetShard(h).positions[h]; pos.Quantity != fx(1) {
+		t.Fatalf("quantity %d, want the long of 1 untouched", pos.Quantity)
+	}
+	if got := atomic.LoadInt64(&sm.state.Cash); got != cash {
+		t.Fatalf("cash %d, want %d", got, cash)
+	}
+	if n := sm.QuarantinedFills(); n != 1 || sm.quarantine.fills[0].Reason != QuarantineReduceWithoutPosition {
+		t.Fatalf("%d fills quarantined, want 1 %s", n, QuarantineReduceWithoutPosition)
+	}
+}
+
+// Lots of 1 at 100, 110 and 130, then two sales of 1.5 at 120
+func TestCostBasis(t *testing.T) {
+	tests := []struct {
+		basis     string
+		wantPnL   float64 // Realized by the first sale
+		wantEntry float64 // Of the 1.5 left
+	}{
+		{CostBasisAverage, 10, 113.333333},               // (120-113.33) × 1.5
+		{CostBasisFIFO, 25, 123.333333},                  // 20 + 5; 0.5@110 and 1@130 left
+		{CostBasisLIFO, -5, 103.333333},                  // -10 + 5; 1@100 and 0.5@110 left
+		{strings.ToLower(CostBasisFIFO), 25, 123.333333}, // Case-insensitive
+	}
+	for _, tt := range tests {
+		t.Run(tt.basis, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.CostBasis = tt.basis
+			sm := NewShardedStateManager(cfg)
+			h := sm.symbols.Hash("LOTUSD")
+			for _, price := range []float64{100, 110, 130} {
+				fill(sm, h, 0, 1, price, 0)
+			}
+			if cash := atomic.LoadInt64(&sm.state.Cash); cash != fx(99_660) {
+				t.Fatalf("cash %d after the buys, want %d", cash, fx(99_660))
+			}
+
+			fill(sm, h, 1, 1.5, 120, 0)
+			pos := sm.GetShard(h).positions[h]
+			if pos.RealizedPnL != fx(tt.wantPnL) {
+				t.Fatalf("realized %d, want %d", pos.RealizedPnL, fx(tt.wantPnL))
+			}
+			if d := pos.EntryPrice - fx(tt.wantEntry); d < -fx(1e-6) || d > fx(1e-6) {
+				t.Fatalf("entry %d, want %d", pos.EntryPrice, fx(tt.wantEntry))
+			}
+			if value := atomic.LoadInt64(&sm.bookValue); value != fx(100_000+tt.wantPnL) {
+				t.Fatalf("book value %d, want %d", value, fx(100_000+tt.wantPnL))
+			}
+
+			// Flat, every method has realized the same 20 in all
+			fill(sm, h, 1, 1.5, 120, 0)
+			if _, open := sm.GetShard(h).positions[h]; open || len(sm.GetShard(h).lots) != 0 {
+				t.Fatal("not flat after the second sale")
+			}
+			if cash := atomic.LoadInt64(&sm.state.Cash); cash != fx(100_020) {
+				t.Fatalf("cash %d when flat, want %d", cash, fx(100_020))
+			}
+		})
+	}
+}
+
+// A reducing fill past the position closes it, books PnL on the closed
+// quantity only, and opens the other side with the rest at the fill price
+func TestPositionFlip(t *testing.T) {
+	tests := []struct {
+		name       string
+		open       uint8 // Side of the 100 opened at 10
+		price      float64
+		reduceOnly bool
+		wantSide   uint8
+		wantQty    float64 // 0 = flat
+		wantPnL    float64
+		wantCash   float64
+	}{
+		{"long to short", 0, 11, false, 1, 50, 100, 100_650},
+		{"short to long", 1, 9, false, 0, 50, 100, 99_650},
+		{"reduce-only never flips", 0, 11, true, 0, 0, 100, 100_100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := NewShardedStateManager(testConfig())
+			h := sm.symbols.Hash("FLIPUSD")
+			fill(sm, h, tt.open, 100, 10, 0)
+			sm.bookFill(&FillEvent{SymbolHash: h, Side: 1 - tt.open, Quantity: fx(150), Price: fx(tt.price), ReduceOnly: tt.reduceOnly})
+
+			pos, open := sm.GetShard(h).positions[h]
+			if tt.wantQty == 0 {
+				if open {
+					t.Fatalf("side %d quantity %d left open", pos.Side, pos.Quantity)
+				}
+			} else {
+				if !open || pos.Side != tt.wantSide || pos.Quantity != fx(tt.wantQty) || pos.EntryPrice != fx(tt.price) {
+					t.Fatalf("position %+v, want side %d quantity %d at %d", pos, tt.wantSide, fx(tt.wantQty), fx(tt.price))
+				}
+				if pos.RealizedPnL != fx(tt.wantPnL) {
+					t.Fatalf("realized %d, want %d on the closed 100", pos.RealizedPnL, fx(tt.wantPnL))
+				}
+				if want := positionCost(sm.symbols.Get(h), tt.wantSide, fx(tt.wantQty), fx(tt.price)); pos.Cost != want {
+					t.Fatalf("cost %d, want %d", pos.Cost, want)
+				}
+			}
+			if cash := atomic.LoadInt64(&sm.state.Cash); cash != fx(tt.wantCash) {
+				t.Fatalf("cash %d, want %d", cash, fx(tt.wantCash))
+			}
+			if value := atomic.LoadInt64(&sm.bookValue); value != fx(100_000+tt.wantPnL) {
+				t.Fatalf("book value %d, want %d", value, fx(100_000+tt.wantPnL))
+			}
+		})
+	}
+}
+
+// A flip's commission is split pro rata between the tag that closes and
+// the tag that opens
+func TestPositionFlipCommissionByTag(t *testing.T) {
+	sm := NewShardedStateManager(testConfig())
+	h := sm.symbols.Hash("FLIPUSD")
+	book := func(tag string, side uint8, quantity, price, commission float64) {
+		order := &OrderOptimized{SymbolHash: h, Side: side, Quantity: fx(quantity), Price: fx(price), Tag: tag}
+		if res, err := sm.SubmitOrder(order); err != nil || !res.Approved {
+			t.Fatal(res.Reason, err)
+		}
+		sm.ApplyFill(&FillEvent{OrderID: order.ID, SymbolHash: h, Side: side, Quantity: order.Quantity, Price: order.Price, Commission: fx(commission)})
+	}
+	book("gann", 0, 100, 10, 0)
+	book("ehlers", 1, 150, 11, 3) // 2 for the 100 closed, 1 for the 50 opened
+
+	want := map[string]int64{"gann": fx(98), "ehlers": -fx(1)}
+	tags := sm.PnLByTag()
+	if len(tags) != len(want) {
+		t.Fatalf("tags %+v", tags)
+	}
+	for _, tag := range tags {
+		if tag.Realized != want[tag.Tag] {
+			t.Errorf("%s realized %d, want %d", tag.Tag, tag.Realized, want[tag.Tag])
+		}
+	}
+	if pos := sm.GetShard(h).positions[h]; pos.Tag != "ehlers" || pos.Commission != fx(1) {
+		t.Fatalf("flipped position tag %q commission %d", pos.Tag, pos.Commission)
+	}
+	if value := atomic.LoadInt64(&sm.bookValue); value != fx(100_097) {
+		t.Fatalf("book value %d, want the whole 3 charged", value)
+	}
+}
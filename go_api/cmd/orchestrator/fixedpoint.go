@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// ============================================================================
+// FIXED-POINT HELPERS - 128-bit Intermediates, Zero Allocation
+// ============================================================================
+
+// mulDiv returns a*b/c truncated toward zero without intermediate overflow.
+// Results outside the int64 range saturate; c == 0 yields 0.
+func mulDiv(a, b, c int64) int64 {
+	if c == 0 {
+		return 0
+	}
+	neg := (a < 0) != (b < 0) != (c < 0)
+
+	hi, lo := bits.Mul64(absU64(a), absU64(b))
+	uc := absU64(c)
+	if hi >= uc {
+		return saturate(neg)
+	}
+	q, _ := bits.Div64(hi, lo, uc)
+
+	if neg {
+		if q > 1<<63 {
+			return math.MinInt64
+		}
+		return -int64(q)
+	}
+	if q > math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(q)
+}
+
+func absU64(x int64) uint64 {
+	if x < 0 {
+		return uint64(^x) + 1
+	}
+	return uint64(x)
+}
+
+func saturate(neg bool) int64 {
+	if neg {
+		return math.MinInt64
+	}
+	return math.MaxInt64
+}
+
+// appendFixed formats a fixed-point value exactly, trimming trailing zeros
+func appendFixed(b []byte, v int64) []byte {
+	u := absU64(v)
+	if v < 0 {
+		b = append(b, '-')
+	}
+	b = strconv.AppendUint(b, u/uint64(PriceScale), 10)
+
+	frac := u % uint64(PriceScale)
+	if frac == 0 {
+		return b
+	}
+	var digits [8]byte
+	for i := len(digits) - 1; i >= 0; i-- {
+		digits[i] = byte('0' + frac%10)
+		frac /= 10
+	}
+	end := len(digits)
+	for end > 0 && digits[end-1] == '0' {
+		end--
+	}
+	b = append(b, '.')
+	return append(b, digits[:end]...)
+}
+
+var errFixedSyntax = errors.New("invalid fixed-point number")
+
+// parseFixed parses a decimal as written by appendFixed, exactly. More than
+// PriceDecimals fractional digits, exponents and overflow are errors.
+func parseFixed(s string) (int64, error) {
+	neg := strings.HasPrefix(s, "-")
+	if neg {
+		s = s[1:]
+	}
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" || len(frac) > PriceDecimals {
+		return 0, errFixedSyntax
+	}
+	w, err := strconv.ParseUint(whole, 10, 63)
+	if err != nil || w > math.MaxInt64/uint64(PriceScale) {
+		return 0, errFixedSyntax
+	}
+	var f uint64
+	for i := 0; i < PriceDecimals; i++ {
+		f *= 10
+		if i < len(frac) {
+			if frac[i] < '0' || frac[i] > '9' {
+				return 0, errFixedSyntax
+			}
+			f += uint64(frac[i] - '0')
+		}
+	}
+	v := int64(w)*PriceScale + int64(f)
+	if v < 0 {
+		return 0, errFixedSyntax
+	}
+	if neg {
+		v = -v
+	}
+	return v, nil
+}
+
+// jsonFixed decodes a JSON number exactly with parseFixed
+type jsonFixed int64
+
+func (v *jsonFixed) UnmarshalJSON(b []byte) error {
+	x, err := parseFixed(string(b))
+	*v = jsonFixed(x)
+	return err
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"cenayang-market/go-api/internal/exec"
+)
+
+// ============================================================================
+// EXECUTION CHANNEL - Approved Orders Forwarded to the Gateway
+// ============================================================================
+
+// ExecConfig - where approved orders are forwarded and how
+type ExecConfig struct {
+	Transport exec.Transport // nil = approved orders are not forwarded
+	Publisher exec.Config    // Batching, compression and retry; zero fields take the defaults
+}
+
+// newForwarder returns nil when cfg has no transport
+func newForwarder(cfg ExecConfig) *exec.Publisher {
+	if cfg.Transport == nil {
+		return nil
+	}
+	return exec.NewPublisher(cfg.Transport, cfg.Publisher)
+}
+
+// forwardOrder hands an accepted order to the execution channel, or fills
+// it synthetically in paper mode. An order the channel refuses stays
+// working; the refusal is logged here and counted by the publisher.
+func (sm *ShardedStateManager) forwardOrder(order *OrderOptimized) {
+	if sm.PaperTrading() {
+		sm.paperFill(order)
+		return
+	}
+	if sm.forwarder == nil {
+		return
+	}
+	side := "BUY"
+	if order.Side != 0 {
+		side = "SELL"
+	}
+	err := sm.forwarder.Submit(exec.Order{
+		ID:         order.ID,
+		SymbolHash: order.SymbolHash,
+		Symbol:     sm.symbols.Name(order.SymbolHash),
+		Side:       side,
+		Quantity:   order.Quantity,
+		Price:      order.Price,
+		ReduceOnly: order.ReduceOnly,
+		Strategy:   order.Strategy,
+		SeqID:      order.SequenceID,
+		Timestamp:  order.Timestamp,
+	})
+	if err != nil {
+		log.Printf("[EXEC] Order %d not forwarded: %v", order.ID, err)
+	}
+}
+
+// ErrOrderNotWorking - a gateway reject named an order that is unknown or
+// already final
+var ErrOrderNotWorking = errors.New("order is not working")
+
+// RejectOrder applies the gateway's refusal of a forwarded order: the order
+// moves to REJECTED, leaves the working book and frees the capital its
+// unfilled remainder reserved, and the change is broadcast like a cancel.
+// Whatever of a partial order already filled stays booked.
+func (sm *ShardedStateManager) RejectOrder(orderID uint64, reason string) error {
+	if sm.Standby() {
+		return ErrStandbyOrders
+	}
+	v, ok := sm.orderIndex.Load(orderID)
+	if !ok {
+		return fmt.Errorf("order %d: %w", orderID, ErrOrderNotWorking)
+	}
+	shard := sm.GetShard(v.(orderIndexEntry).symbolHash)
+
+	shard.mu.Lock()
+	order, ok := shard.orders[orderID]
+	if !ok {
+		status := sm.retiredStatus(shard, orderID)
+		shard.mu.Unlock()
+		return fmt.Errorf("order %d %s: %w", orderID, orderStatusName(status), ErrOrderNotWorking)
+	}
+	if !sm.transitionOrder(order, OrderRejected) {
+		shard.mu.Unlock()
+		return fmt.Errorf("order %d: %w", orderID, ErrOrderNotWorking)
+	}
+	order.SequenceID = sm.nextSequence()
+	order.Timestamp = time.Now().UnixNano()
+	rejected := *order
+	sm.retireOrderLocked(shard, order, OrderRejected)
+	shard.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), CancelBroadcastTimeout)
+	sm.publishOrderWait(ctx, &rejected)
+	cancel()
+
+	symbol := sm.symbols.Name(rejected.SymbolHash)
+	log.Printf("[EXEC] Gateway rejected order %d (%s): %s", orderID, symbol, reason)
+	sm.raiseAlert(AlertWarning, "order_rejected", fmt.Sprintf("gateway rejected order %d (%s): %s", orderID, symbol, reason))
+	return nil
+}
+
+// NATSRejectHandler returns the subscription callback for gateway rejects
+// (exec.Reject on exec.RejectSubject). A request is answered with
+// exec.AckReply once the reject is applied or found stale, so the gateway
+// can tell a reject that never arrived from one with nothing to do.
+func (sm *ShardedStateManager) NATSRejectHandler() nats.MsgHandler {
+	return func(msg *nats.Msg) {
+		var reject exec.Reject
+		if err := json.Unmarshal(msg.Data, &reject); err != nil || reject.ID == 0 {
+			log.Printf("[EXEC] Malformed gateway reject on %q: %q", msg.Subject, msg.Data)
+			return
+		}
+		if err := sm.RejectOrder(reject.ID, reject.Reason); err != nil {
+			log.Printf("[EXEC] Gateway reject ignored: %v", err)
+		}
+		if msg.Reply != "" {
+			msg.Respond([]byte(exec.AckReply))
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+
+	"cenayang-market/go-api/internal/allocator"
+	"cenayang-market/go-api/internal/exec"
+)
+
+func TestGatewayReject(t *testing.T) {
+	cfg := testConfig()
+	cfg.Strategies = []allocator.Strategy{{Name: "gann", Weight: 1}}
+	sm := NewShardedStateManager(cfg)
+	h := sm.symbols.Hash("REJUSD")
+	handle := sm.NATSRejectHandler()
+	status := func(id uint64) uint8 {
+		shard := sm.GetShard(h)
+		shard.mu.RLock()
+		defer shard.mu.RUnlock()
+		return sm.retiredStatus(shard, id)
+	}
+
+	order := &OrderOptimized{SymbolHash: h, Quantity: fx(10), Price: fx(100), Strategy: "gann"}
+	if res, err := sm.SubmitOrder(order); err != nil || !res.Approved {
+		t.Fatal(res.Reason, err)
+	}
+	handle(&nats.Msg{Subject: exec.RejectSubject, Data: []byte(`{"id":` + strconv.FormatUint(order.ID, 10) + `,"reason":"no route"}`)})
+	if s := status(order.ID); s != OrderRejected {
+		t.Fatalf("status %s, want REJECTED", orderStatusName(s))
+	}
+	if used := sm.allocator.Snapshot()[0].Used; used != 0 {
+		t.Fatalf("%d still reserved after the reject", used)
+	}
+	if alerts := sm.Alerts(false); len(alerts) != 1 || alerts[0].Kind != "order_rejected" {
+		t.Fatalf("alerts %+v", alerts)
+	}
+	if err := sm.RejectOrder(order.ID, "again"); !errors.Is(err, ErrOrderNotWorking) {
+		t.Fatalf("second reject: %v", err)
+	}
+	if err := sm.RejectOrder(1<<62, "unknown"); !errors.Is(err, ErrOrderNotWorking) {
+		t.Fatalf("unknown order: %v", err)
+	}
+
+	// A partial fill stays booked when the rest is rejected
+	partial := &OrderOptimized{SymbolHash: h, Quantity: fx(10), Price: fx(100)}
+	if res, err := sm.SubmitOrder(partial); err != nil || !res.Approved {
+		t.Fatal(res.Reason, err)
+	}
+	sm.ApplyFill(&FillEvent{OrderID: partial.ID, SymbolHash: h, Side: 0, Quantity: fx(4), Price: fx(100)})
+	if err := sm.RejectOrder(partial.ID, "venue closed"); err != nil {
+		t.Fatal(err)
+	}
+	if pos := sm.GetShard(h).positions[h]; pos == nil || pos.Quantity != fx(4) || status(partial.ID) != OrderRejected {
+		t.Fatalf("position %+v status %s after rejecting a partial", pos, orderStatusName(status(partial.ID)))
+	}
+
+	// Malformed rejects are dropped
+	handle(&nats.Msg{Subject: exec.RejectSubject, Data: []byte(`{"reason":"no id"}`)})
+	handle(&nats.Msg{Subject: exec.RejectSubject, Data: []byte(`not json`)})
+	if n := len(sm.Alerts(false)); n != 2 {
+		t.Fatalf("%d alerts, want 2", n)
+	}
+}
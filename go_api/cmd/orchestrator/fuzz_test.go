@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+
+	"cenayang-market/go-api/internal/exec"
+)
+
+// Seed corpora live in testdata/fuzz/<target>; go test runs them as
+// regression cases, go test -fuzz=<target> explores from them.
+
+// fuzzManager has a contract symbol, so fills exercise the multiplier,
+// and a fill price band
+func fuzzManager() *ShardedStateManager {
+	cfg := testConfig()
+	cfg.Symbols = []SymbolMeta{
+		{Symbol: "BTCUSD", TickSize: 0.01, LotSize: 0.001, Multiplier: 1, Currency: "USD"},
+		{Symbol: "ES", TickSize: 0.25, LotSize: 1, Multiplier: 50, Currency: "USD"},
+	}
+	cfg.FillPriceBandPct = 10
+	return NewShardedStateManager(cfg)
+}
+
+// checkBook fails on a position that is not long or short a positive
+// quantity, or a book value that is not cash plus the open cost
+func checkBook(t *testing.T, sm *ShardedStateManager) {
+	t.Helper()
+	var cost int64
+	for i := range sm.shards {
+		for _, pos := range sm.shards[i].positions {
+			if pos.Quantity <= 0 || pos.Side > 1 {
+				t.Fatalf("position side %d quantity %d", pos.Side, pos.Quantity)
+			}
+			cost += pos.Cost
+		}
+	}
+	if cash, value := atomic.LoadInt64(&sm.state.Cash), atomic.LoadInt64(&sm.bookValue); cash+cost != value {
+		t.Fatalf("cash %d + cost %d != book value %d", cash, cost, value)
+	}
+}
+
+func FuzzTickFromBytes(f *testing.F) {
+	f.Fuzz(func(t *testing.T, frame []byte) {
+		var tick MarketTickOptimized
+		if !tick.FromBytes(frame) {
+			if len(frame) >= TickWireSize {
+				t.Fatalf("full %d-byte frame refused", len(frame))
+			}
+			return
+		}
+		if out := tick.ToBytes(nil); !bytes.Equal(out, frame[:TickWireSize]) {
+			t.Fatalf("round trip %x, want %x", out, frame[:TickWireSize])
+		}
+		sm := fuzzManager()
+		sm.feed.OnTick(&tick)
+		checkBook(t, sm)
+	})
+}
+
+func FuzzOrderJSON(f *testing.F) {
+	f.Fuzz(func(t *testing.T, body []byte) {
+		sm := fuzzManager()
+		tick(sm, sm.symbols.Hash("BTCUSD"), 100)
+		order, errResp := sm.decodeOrder(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/orders", bytes.NewReader(body)))
+		if errResp != nil {
+			return
+		}
+		if order.Quantity <= 0 || order.Price < 0 || order.Side > 1 {
+			t.Fatalf("decoded side %d quantity %d price %d", order.Side, order.Quantity, order.Price)
+		}
+		if res, err := sm.SubmitOrder(order); err == nil && res.Approved {
+			sm.ApplyFill(&FillEvent{OrderID: order.ID, SymbolHash: order.SymbolHash, Side: order.Side, Quantity: res.Quantity, Price: order.Price})
+		}
+		checkBook(t, sm)
+	})
+}
+
+func FuzzFillJSON(f *testing.F) {
+	f.Fuzz(func(t *testing.T, first, second []byte) {
+		sm := fuzzManager()
+		handle := sm.feed.NATSFillHandler()
+		handle(&nats.Msg{Subject: exec.FillSubject, Data: first})
+		handle(&nats.Msg{Subject: exec.FillSubject, Data: second})
+		checkBook(t, sm)
+	})
+}
+
+func FuzzReplicatedFillJSON(f *testing.F) {
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fill, err := parseReplicatedFill(data)
+		if err != nil {
+			return
+		}
+		sm := fuzzManager()
+		sm.ApplyFill(fill)
+		checkBook(t, sm)
+	})
+}
+
+// Inputs the fuzzers found before the decoders were hardened
+func TestFuzzFindings(t *testing.T) {
+	sm := fuzzManager()
+	h := sm.symbols.Hash("ES")
+	for _, bad := range []FillEvent{
+		{SymbolHash: h, Side: 0, Quantity: -fx(5), Price: fx(4500)},
+		{SymbolHash: h, Side: 2, Quantity: fx(5), Price: fx(4500)},
+		{SymbolHash: h, Side: 0, Quantity: fx(5), Price: 0},
+	} {
+		sm.ApplyFill(&bad)
+	}
+	if len(sm.GetShard(h).positions) != 0 || sm.QuarantinedFills() != 3 {
+		t.Fatalf("%d quarantined, want all 3 malformed fills", sm.QuarantinedFills())
+	}
+
+	if sm.feed.OnTick(&MarketTickOptimized{SymbolHash: h, LastPrice: 0, SeqID: 1}) || sm.feed.InvalidTicks() != 1 {
+		t.Fatal("tick without a last price accepted")
+	}
+	var tick MarketTickOptimized
+	if tick.FromBytes(make([]byte, TickWireSize-1)) {
+		t.Fatal("truncated frame decoded")
+	}
+
+	for _, body := range []string{
+		`{"symbol":"ES","side":"BUY","quantity":1e300,"price":1}`,
+		`{"symbol":"ES","side":"BUY","quantity":1,"price":-1e300}`,
+	} {
+		_, errResp := sm.decodeOrder(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/orders", bytes.NewReader([]byte(body))))
+		if errResp == nil || errResp.Status != http.StatusBadRequest {
+			t.Fatalf("%s decoded, want out of range", body)
+		}
+	}
+
+	// Drawdown on a large account does not overflow
+	cfg := testConfig()
+	cfg.StartingEquity = 1e9
+	sm = NewShardedStateManager(cfg)
+	sm.ApplyFill(&FillEvent{SymbolHash: 3, Side: 0, Quantity: fx(1000), Price: fx(1e6)})
+	sm.UpdateTick(&MarketTickOptimized{SymbolHash: 3, LastPrice: fx(1)})
+	if dd := sm.state.CurrentDrawdown; dd <= 0 || dd > 10_000 {
+		t.Fatalf("drawdown %d bps", dd)
+	}
+}
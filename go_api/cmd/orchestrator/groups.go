@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// ============================================================================
+// SYMBOL GROUPS - Aggregate Notional Caps Across Related Symbols
+// ============================================================================
+
+// SymbolGroup - a set of symbols, e.g. an asset class, whose combined
+// exposure is capped. A symbol may belong to several groups; every one of
+// its groups' caps applies.
+type SymbolGroup struct {
+	Name        string
+	Symbols     []string
+	MaxNotional float64 // Cap on the group's gross marked notional
+}
+
+// symbolGroup - fixed-point view of SymbolGroup
+type symbolGroup struct {
+	name        string
+	symbols     []string // Canonical
+	members     []uint64 // Symbol hashes, parallel to symbols
+	maxNotional int64
+}
+
+// symbolGroups - the configured groups, immutable after load so reads take
+// no lock
+type symbolGroups struct {
+	all      []*symbolGroup
+	bySymbol map[uint64][]*symbolGroup
+}
+
+// newSymbolGroups resolves the groups' symbols through the registry
+func newSymbolGroups(cfg []SymbolGroup, reg *SymbolRegistry) (symbolGroups, error) {
+	groups := symbolGroups{bySymbol: make(map[uint64][]*symbolGroup)}
+	seen := make(map[string]bool, len(cfg))
+	for _, g := range cfg {
+		switch {
+		case g.Name == "":
+			return symbolGroups{}, errors.New("name required")
+		case seen[g.Name]:
+			return symbolGroups{}, fmt.Errorf("%q configured twice", g.Name)
+		case len(g.Symbols) == 0:
+			return symbolGroups{}, fmt.Errorf("%q has no symbols", g.Name)
+		case g.MaxNotional <= 0:
+			return symbolGroups{}, fmt.Errorf("%q: MaxNotional must be positive", g.Name)
+		}
+		seen[g.Name] = true
+
+		group := &symbolGroup{name: g.Name, maxNotional: toFixed(g.MaxNotional)}
+		for _, symbol := range g.Symbols {
+			hash := reg.Hash(symbol)
+			if slices.Contains(group.members, hash) {
+				continue // Listed twice, e.g. under an alias
+			}
+			group.symbols = append(group.symbols, reg.Canonical(symbol))
+			group.members = append(group.members, hash)
+			groups.bySymbol[hash] = append(groups.bySymbol[hash], group)
+		}
+		groups.all = append(groups.all, group)
+	}
+	return groups, nil
+}
+
+// markedNotional returns a symbol's position notional at its mark, 0
+// when flat
+func (sm *ShardedStateManager) markedNotional(symbolHash uint64) int64 {
+	shard := sm.GetShard(symbolHash)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+	pos, ok := shard.positions[symbolHash]
+	if !ok {
+		return 0
+	}
+	return notionalValue(sm.symbols.Get(symbolHash), pos.Quantity, markPrice(pos))
+}
+
+// groupExposure sums the group's gross marked notional
+func (sm *ShardedStateManager) groupExposure(g *symbolGroup) int64 {
+	var total int64
+	for _, hash := range g.members {
+		total += sm.markedNotional(hash)
+	}
+	return total
+}
+
+// groupLimitBreached returns the first of the order's groups whose cap the
+// order would exceed, or "" when it fits every one. The order's symbol is
+// counted at its position after the order, valued at the order's price -
+// the last price for a market order - so reductions and flips are judged
+// by what they leave. An order that shrinks a group already over its cap,
+// e.g. after a rally, passes.
+func (sm *ShardedStateManager) groupLimitBreached(symbolHash uint64, spec *SymbolSpec, side uint8, quantity, price int64) string {
+	groups := sm.groups.bySymbol[symbolHash]
+	if len(groups) == 0 {
+		return ""
+	}
+
+	shard := sm.GetShard(symbolHash)
+	shard.mu.RLock()
+	var held, mark int64 // Signed quantity, long positive
+	if pos, ok := shard.positions[symbolHash]; ok {
+		held, mark = pos.Quantity, markPrice(pos)
+		if pos.Side != 0 {
+			held = -held
+		}
+	}
+	shard.mu.RUnlock()
+	if price == 0 {
+		last, ok := sm.feed.LastPrice(symbolHash)
+		if !ok {
+			last = mark
+		}
+		price = last
+	}
+	after := held + quantity
+	if side != 0 {
+		after = held - quantity
+	}
+	if held < 0 {
+		held = -held
+	}
+	if after < 0 {
+		after = -after
+	}
+	current := notionalValue(spec, held, mark)
+	projected := notionalValue(spec, after, price)
+
+	for _, g := range groups {
+		others := sm.groupExposure(g) - sm.markedNotional(symbolHash)
+		if others+projected > g.maxNotional && projected > current {
+			return g.name
+		}
+	}
+	return ""
+}
+
+// appendSymbolGroups writes each group's exposure against its cap
+func (sm *ShardedStateManager) appendSymbolGroups(b []byte) []byte {
+	b = append(b, '[')
+	for i, g := range sm.groups.all {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, `{"name":`...)
+		b = strconv.AppendQuote(b, g.name)
+		b = append(b, `,"symbols":[`...)
+		for j, symbol := range g.symbols {
+			if j > 0 {
+				b = append(b, ',')
+			}
+			b = strconv.AppendQuote(b, symbol)
+		}
+		b = append(b, `],"exposure":`...)
+		b = appendFixed(b, sm.groupExposure(g))
+		b = append(b, `,"max_notional":`...)
+		b = appendFixed(b, g.maxNotional)
+		b = append(b, '}')
+	}
+	return append(b, ']')
+}
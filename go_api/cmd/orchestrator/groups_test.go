@@ -0,0 +1,45 @@
+package main
+
+import "testing"
+
+func TestSymbolGroupCaps(t *testing.T) {
+	cfg := testConfig()
+	cfg.SymbolGroups = []SymbolGroup{
+		{Name: "crypto", Symbols: []string{"AUSD", "BUSD"}, MaxNotional: 10_000},
+		{Name: "wide", Symbols: []string{"AUSD", "CUSD"}, MaxNotional: 50_000},
+	}
+	sm := NewShardedStateManager(cfg)
+	a, b, c := sm.symbols.Hash("AUSD"), sm.symbols.Hash("BUSD"), sm.symbols.Hash("CUSD")
+	fill(sm, a, 0, 50, 100, 0) // 5000 in both groups
+
+	check := func(name string, h uint64, side uint8, quantity, price float64, wantGroup string) {
+		t.Helper()
+		res := sm.RiskCheckFast(&OrderOptimized{SymbolHash: h, Side: side, Quantity: fx(quantity), Price: fx(price)})
+		if wantGroup == "" && !res.Approved {
+			t.Fatalf("%s: rejected %v %q", name, res.Reason, res.Group)
+		}
+		if wantGroup != "" && (res.Reason != ReasonGroupLimitExceeded || res.Group != wantGroup) {
+			t.Fatalf("%s: %v %q, want %v %q", name, res.Reason, res.Group, ReasonGroupLimitExceeded, wantGroup)
+		}
+	}
+	check("fills the group", b, 0, 50, 100, "")
+	check("over the group", b, 0, 51, 100, "crypto")
+	check("other group only", c, 0, 400, 100, "")
+	check("second group", c, 0, 451, 100, "wide")
+
+	tick(sm, a, 300) // A rally leaves crypto at 15000
+	check("adding while over", a, 0, 1, 300, "crypto")
+	check("reducing while over", a, 1, 10, 300, "")
+	check("flip larger than the position", a, 1, 150, 300, "crypto") // Short 100 is 30000
+
+	for _, bad := range [][]SymbolGroup{
+		{{Symbols: []string{"AUSD"}, MaxNotional: 1}},
+		{{Name: "g", MaxNotional: 1}},
+		{{Name: "g", Symbols: []string{"AUSD"}}},
+		{{Name: "g", Symbols: []string{"AUSD"}, MaxNotional: 1}, {Name: "g", Symbols: []string{"BUSD"}, MaxNotional: 1}},
+	} {
+		if _, err := newSymbolGroups(bad, sm.symbols); err == nil {
+			t.Fatalf("%+v accepted", bad)
+		}
+	}
+}
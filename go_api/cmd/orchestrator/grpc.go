@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"cenayang-market/go-api/internal/pb"
+)
+
+// ============================================================================
+// GRPC API - Mirrors REST, Backed by the Same State Manager
+// ============================================================================
+
+// StreamStateBuffer is the per-stream event buffer; a stream that falls
+// further behind loses events, like a slow WebSocket client
+const StreamStateBuffer = 1024
+
+type grpcServer struct {
+	pb.UnimplementedOrchestratorServer
+	sm *ShardedStateManager
+}
+
+// NewGRPCServer creates a gRPC server exposing the orchestrator service
+func NewGRPCServer(sm *ShardedStateManager) *grpc.Server {
+	server := grpc.NewServer()
+	pb.RegisterOrchestratorServer(server, &grpcServer{sm: sm})
+	return server
+}
+
+func toFloat(v int64) float64 {
+	return float64(v) / float64(PriceScale)
+}
+
+// GetPortfolio returns the current portfolio state
+func (s *grpcServer) GetPortfolio(ctx context.Context, req *pb.GetPortfolioRequest) (*pb.Portfolio, error) {
+	sm := s.sm
+	return &pb.Portfolio{
+		Equity:         toFloat(atomic.LoadInt64(&sm.state.Equity)),
+		Cash:           toFloat(atomic.LoadInt64(&sm.state.Cash)),
+		StartingEquity: toFloat(sm.startingEquity),
+		TotalPnl:       toFloat(atomic.LoadInt64(&sm.state.TotalPnL)),
+		DrawdownBps:    atomic.LoadInt64(&sm.state.CurrentDrawdown),
+		KillSwitch:     atomic.LoadInt32(&sm.state.KillSwitch) != 0,
+		SeqId:          atomic.LoadUint64(&sm.state.SequenceID),
+	}, nil
+}
+
+// StreamState forwards hub events until the client goes away
+func (s *grpcServer) StreamState(req *pb.StreamStateRequest, stream pb.Orchestrator_StreamStateServer) error {
+	sub := s.sm.hub.Subscribe(StreamStateBuffer)
+	defer s.sm.hub.Unsubscribe(sub)
+
+	// Tell the client it is subscribed, e.g. so a standby can snapshot
+	if err := stream.SendHeader(nil); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-sub.C():
+			if !ok {
+				return status.Error(codes.Unavailable, "server shutting down")
+			}
+			err := stream.Send(&pb.StateEvent{
+				Type:        uint32(event.Type),
+				SeqId:       event.SeqID,
+				TimestampNs: event.Timestamp,
+				Data:        event.Data,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// CheckRisk runs the pre-trade risk check without submitting
+func (s *grpcServer) CheckRisk(ctx context.Context, req *pb.CheckRiskRequest) (*pb.CheckRiskResponse, error) {
+	side := "BUY"
+	if req.Side == pb.Side_SIDE_SELL {
+		side = "SELL"
+	}
+	order, errResp := s.sm.orderFromRequest(&orderRequest{
+		Symbol:     req.Symbol,
+		Side:       side,
+		Quantity:   req.Quantity,
+		Price:      req.Price,
+		Strategy:   req.Strategy,
+		ReduceOnly: req.ReduceOnly,
+	})
+	if errResp != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%s: %s", errResp.Field, errResp.Error)
+	}
+
+	result, err := s.sm.checkRisk(order)
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return &pb.CheckRiskResponse{
+		Approved:  result.Approved,
+		Reason:    result.Reason.String(),
+		Quantity:  toFloat(result.Quantity),
+		LatencyNs: result.LatencyNs,
+	}, nil
+}
+
+// ToggleKillSwitch activates or clears the kill switch
+func (s *grpcServer) ToggleKillSwitch(ctx context.Context, req *pb.ToggleKillSwitchRequest) (*pb.KillSwitchState, error) {
+	if _, err := s.sm.ApplyKillSwitch(KillSwitchRequest{Active: req.Active, Source: KillSourceManual, Actor: "grpc"}); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return &pb.KillSwitchState{Active: atomic.LoadInt32(&s.sm.state.KillSwitch) != 0}, nil
+}
+
+// GetSnapshot returns the state a standby warms up from
+func (s *grpcServer) GetSnapshot(ctx context.Context, req *pb.GetSnapshotRequest) (*pb.Snapshot, error) {
+	return s.sm.Snapshot(), nil
+}
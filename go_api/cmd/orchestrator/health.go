@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"cenayang-market/go-api/internal/health"
+)
+
+// ============================================================================
+// DEPENDENCY HEALTH - Reported by /api/health
+// ============================================================================
+
+// DependencyCheckInterval is how often downstream services are probed
+const DependencyCheckInterval = 10 * time.Second
+
+// appendDependencies appends dependency statuses as a JSON array
+func appendDependencies(b []byte, deps []health.DependencyStatus) []byte {
+	b = append(b, '[')
+	for i, d := range deps {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, `{"name":`...)
+		b = strconv.AppendQuote(b, d.Name)
+		b = append(b, `,"critical":`...)
+		b = strconv.AppendBool(b, d.Critical)
+		b = append(b, `,"up":`...)
+		b = strconv.AppendBool(b, d.Up)
+		b = append(b, `,"checked":`...)
+		b = strconv.AppendBool(b, d.Checked)
+		b = append(b, `,"latency_ms":`...)
+		b = strconv.AppendFloat(b, float64(d.Latency.Microseconds())/1000, 'f', 3, 64)
+		b = append(b, `,"last_success":`...)
+		if d.LastSuccess.IsZero() {
+			b = append(b, `null`...)
+		} else {
+			b = append(b, '"')
+			b = d.LastSuccess.UTC().AppendFormat(b, time.RFC3339)
+			b = append(b, '"')
+		}
+		if d.LastError != "" {
+			b = append(b, `,"error":`...)
+			b = strconv.AppendQuote(b, d.LastError)
+		}
+		b = append(b, '}')
+	}
+	return append(b, ']')
+}
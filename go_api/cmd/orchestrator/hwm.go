@@ -0,0 +1,275 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"cenayang-market/go-api/internal/auth"
+	"cenayang-market/go-api/internal/handlers"
+	"cenayang-market/go-api/internal/ws"
+)
+
+// ============================================================================
+// HIGH WATER MARK RESET - Policy, Two-Step Manual Reset, Audit
+// ============================================================================
+
+// HWM reset modes
+const (
+	HWMResetNever    = "never"    // The HWM only ever rises
+	HWMResetManual   = "manual"   // Only through the endpoint (default)
+	HWMResetSession  = "session"  // At every session boundary
+	HWMResetPeriodic = "periodic" // When the session date enters a new Period
+)
+
+// HWM reset periods
+const (
+	HWMPeriodWeekly  = "weekly"  // ISO week
+	HWMPeriodMonthly = "monthly" // Calendar month
+)
+
+// HWMConfirmTTL is how long a manual reset confirmation token stays valid
+const HWMConfirmTTL = time.Minute
+
+// Errors
+var (
+	ErrHWMResetDisabled = errors.New("high water mark reset disabled by policy")
+	ErrHWMConfirmation  = errors.New("invalid or expired confirmation token")
+)
+
+// HWMResetPolicy - when the high water mark drops back to current equity.
+// Every mode but never also allows a manual reset.
+type HWMResetPolicy struct {
+	Mode   string // HWMReset*; empty = HWMResetManual
+	Period string // HWMPeriod*, for HWMResetPeriodic
+}
+
+// validate normalizes the policy in place
+func (p *HWMResetPolicy) validate() error {
+	p.Mode, p.Period = strings.ToLower(p.Mode), strings.ToLower(p.Period)
+	switch p.Mode {
+	case "":
+		p.Mode = HWMResetManual
+	case HWMResetNever, HWMResetManual, HWMResetSession:
+	case HWMResetPeriodic:
+		if p.Period != HWMPeriodWeekly && p.Period != HWMPeriodMonthly {
+			return fmt.Errorf("periodic reset needs period %q or %q, got %q", HWMPeriodWeekly, HWMPeriodMonthly, p.Period)
+		}
+	default:
+		return fmt.Errorf("unknown reset mode %q", p.Mode)
+	}
+	return nil
+}
+
+// periodKey returns the reset period a session date ("2006-01-02") is in
+func (p *HWMResetPolicy) periodKey(date string) string {
+	if p.Period == HWMPeriodMonthly {
+		return date[:7]
+	}
+	d, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	year, week := d.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// AuditLog records operator and policy actions.
+// *database.Database and *database.Postgres implement it.
+type AuditLog interface {
+	SaveAuditLog(action, resource, details, ipAddress string) error
+}
+
+// hwmConfirmation - the pending manual reset, at most one at a time
+type hwmConfirmation struct {
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// ResetHighWaterMark lowers (or raises) the high water mark to current
+// equity, so drawdown is measured from here. The kill switch is left as
+// it is. Returns the previous and new marks.
+func (sm *ShardedStateManager) ResetHighWaterMark(cause, actor, ip string) (prev, hwm int64) {
+	hwm = atomic.LoadInt64(&sm.state.Equity)
+	prev = sm.setHighWaterMark(hwm)
+
+	details := fmt.Sprintf("cause=%s actor=%s from=%s to=%s", cause, actor, appendFixed(nil, prev), appendFixed(nil, hwm))
+	log.Printf("[RISK] High water mark reset: %s", details)
+	if sm.audit != nil {
+		if err := sm.audit.SaveAuditLog("hwm_reset", "portfolio", details, ip); err != nil {
+			log.Printf("[RISK] Failed to audit high water mark reset: %v", err)
+		}
+	}
+
+	b := make([]byte, 0, 128)
+	b = append(b, `{"type":"hwm_reset","cause":`...)
+	b = strconv.AppendQuote(b, cause)
+	b = append(b, `,"previous":`...)
+	b = appendFixed(b, prev)
+	b = append(b, `,"high_water_mark":`...)
+	b = appendFixed(b, hwm)
+	b = append(b, '}')
+	sm.publish(ws.EventHWMReset, atomic.LoadUint64(&sm.state.SequenceID), b)
+	return prev, hwm
+}
+
+// setHighWaterMark stores a new mark and the drawdown against it,
+// returning the old mark
+func (sm *ShardedStateManager) setHighWaterMark(hwm int64) int64 {
+	prev := atomic.SwapInt64(&sm.state.HighWaterMark, hwm)
+	if hwm > 0 {
+		atomic.StoreInt64(&sm.state.CurrentDrawdown, drawdownBps(hwm, atomic.LoadInt64(&sm.state.Equity)))
+	}
+	return prev
+}
+
+// restoreHighWaterMark installs an imported mark once cash and positions
+// are in. The old mark and drawdown are dropped first so marking the imported book to
+// market cannot measure it against a peak from before the import. An
+// export without a mark - older formats, where it reads as 0 - gets a
+// conservative reconstruction: imported equity, so drawdown restarts at 0
+// instead of against a peak the account may never have reached.
+func (sm *ShardedStateManager) restoreHighWaterMark(hwm int64) {
+	atomic.StoreInt64(&sm.state.HighWaterMark, 0)
+	atomic.StoreInt64(&sm.state.CurrentDrawdown, 0)
+	sm.recomputePortfolioState() // Raises the mark to imported equity
+	equity := atomic.LoadInt64(&sm.state.Equity)
+
+	switch {
+	case hwm <= 0:
+		log.Printf("[RISK] Imported state has no high water mark; reconstructed from equity %s", appendFixed(nil, equity))
+		hwm = equity
+	case hwm < equity:
+		log.Printf("[RISK] Imported high water mark %s is below equity %s; raised to equity", appendFixed(nil, hwm), appendFixed(nil, equity))
+		hwm = equity
+	}
+	sm.setHighWaterMark(hwm)
+}
+
+// applyHWMPolicy resets the mark at a session boundary when the policy
+// says so. A standby follows the active's resets instead.
+func (sm *ShardedStateManager) applyHWMPolicy(prevDate, date string) {
+	if sm.Standby() {
+		return
+	}
+	switch p := &sm.config.HWMReset; p.Mode {
+	case HWMResetSession:
+		sm.ResetHighWaterMark("session", "policy", "")
+	case HWMResetPeriodic:
+		if p.periodKey(prevDate) != p.periodKey(date) {
+			sm.ResetHighWaterMark(p.Period, "policy", "")
+		}
+	}
+}
+
+// requestHWMReset issues a confirmation token for a manual reset
+func (sm *ShardedStateManager) requestHWMReset() (string, time.Time, error) {
+	if sm.config.HWMReset.Mode == HWMResetNever {
+		return "", time.Time{}, ErrHWMResetDisabled
+	}
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", time.Time{}, err
+	}
+
+	c := &sm.hwmConfirm
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = hex.EncodeToString(raw)
+	c.expires = sm.clock.Now().Add(HWMConfirmTTL)
+	return c.token, c.expires, nil
+}
+
+// confirmHWMReset consumes a confirmation token. A token is good once.
+func (sm *ShardedStateManager) confirmHWMReset(token string) error {
+	if sm.config.HWMReset.Mode == HWMResetNever {
+		return ErrHWMResetDisabled
+	}
+
+	c := &sm.hwmConfirm
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token == "" || token != c.token || !sm.clock.Now().Before(c.expires) {
+		return ErrHWMConfirmation
+	}
+	c.token = ""
+	return nil
+}
+
+// hwmResetRequest - wire format for POST /api/risk/hwm/reset. {} asks for
+// a confirmation token; sending it back as confirm performs the reset.
+type hwmResetRequest struct {
+	Confirm string `json:"confirm,omitempty"`
+}
+
+// handleHWMReset serves the two-step manual high water mark reset
+func (sm *ShardedStateManager) handleHWMReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+	if sm.Standby() {
+		handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusConflict, Error: "standby follows the active's high water mark"})
+		return
+	}
+
+	var req hwmResetRequest
+	if errResp := handlers.DecodeJSON(w, r, &req); errResp != nil {
+		handlers.WriteError(w, r, errResp)
+		return
+	}
+
+	buf := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(buf)
+
+	if req.Confirm == "" {
+		token, expires, err := sm.requestHWMReset()
+		if err != nil {
+			handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusConflict, Error: err.Error()})
+			return
+		}
+		b := append((*buf)[:0], `{"confirm":"`...)
+		b = append(b, token...)
+		b = append(b, `","expires_at":"`...)
+		b = expires.UTC().AppendFormat(b, time.RFC3339)
+		b = append(b, `","high_water_mark":`...)
+		b = appendFixed(b, atomic.LoadInt64(&sm.state.HighWaterMark))
+		b = append(b, `,"new_high_water_mark":`...)
+		b = appendFixed(b, atomic.LoadInt64(&sm.state.Equity))
+		b = append(b, '}')
+		handlers.WriteJSON(w, r, http.StatusAccepted, b)
+		return
+	}
+
+	if err := sm.confirmHWMReset(req.Confirm); err != nil {
+		status := http.StatusConflict
+		if errors.Is(err, ErrHWMConfirmation) {
+			status = http.StatusBadRequest
+		}
+		handlers.WriteError(w, r, &handlers.ErrorResponse{Status: status, Error: err.Error(), Field: "confirm"})
+		return
+	}
+	actor := "anonymous"
+	if claims := auth.GetClaims(r.Context()); claims != nil {
+		actor = claims.Username
+	}
+	prev, hwm := sm.ResetHighWaterMark("manual", actor, r.RemoteAddr)
+
+	b := append((*buf)[:0], `{"previous":`...)
+	b = appendFixed(b, prev)
+	b = append(b, `,"high_water_mark":`...)
+	b = appendFixed(b, hwm)
+	b = append(b, `,"drawdown_bps":`...)
+	b = strconv.AppendInt(b, atomic.LoadInt64(&sm.state.CurrentDrawdown), 10)
+	b = append(b, '}')
+	handlers.WriteJSON(w, r, http.StatusOK, b)
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+// ============================================================================
+// FEED INGESTION - Sequencing Between Transport and State Engine
+// ============================================================================
+
+// feedCursor tracks the last accepted event of one stream
+type feedCursor struct {
+	seqID     uint64
+	timestamp int64
+	epoch     uint64 // FeedIngester.epoch when seqID was accepted
+}
+
+// FeedIngester validates sequencing of inbound ticks and fills before they
+// reach the state manager. SeqID is authoritative for ordering: timestamps
+// from upstream services are only used for latency and staleness, and are
+// checked against SeqID to detect clock skew. Aliased symbol hashes are
+// mapped to their canonical form before sequencing.
+type FeedIngester struct {
+	sm *ShardedStateManager
+
+	mu         sync.Mutex
+	tickCursor map[uint64]*feedCursor    // Per symbol
+	signals    map[uint64]Microstructure // Per symbol, latest accepted tick
+	samplers   map[uint64]*tickSampler   // Per throttled symbol
+	volatility map[uint64]*volWindow     // Per symbol, when the breaker is on
+	tickTimes  map[uint64]int64          // Per symbol, unix ns the latest tick was accepted
+	fillCursor feedCursor
+	epoch      uint64 // Bumped on reconnect; gaps across it are not counted
+
+	// Atomic stats
+	clockSkewEvents uint64
+	lastClockSkewNs int64
+	staleTicks      uint64
+	invalidTicks    uint64
+	snappedTicks    uint64
+	duplicateFills  uint64
+	coalescedTicks  uint64
+	feedGaps        uint64
+
+	bus busHealth // Drops on the message bus side
+}
+
+func NewFeedIngester(sm *ShardedStateManager) *FeedIngester {
+	return &FeedIngester{
+		sm:         sm,
+		tickCursor: make(map[uint64]*feedCursor, 64),
+		signals:    make(map[uint64]Microstructure, 64),
+		samplers:   make(map[uint64]*tickSampler, 16),
+		volatility: make(map[uint64]*volWindow, 16),
+		tickTimes:  make(map[uint64]int64, 64),
+	}
+}
+
+// OnTick sequences a tick, caches its microstructure signals and forwards
+// it, subject to the symbol's throttle (see throttle.go). Ticks whose SeqID
+// does not advance the symbol's stream are dropped regardless of their
+// timestamp, as are ticks without a positive last price, which would mark
+// every position to zero. With Config.SnapTickPrices, prices are first
+// rounded to the symbol's tick size.
+func (f *FeedIngester) OnTick(tick *MarketTickOptimized) bool {
+	if tick.LastPrice <= 0 || tick.BidPrice < 0 || tick.AskPrice < 0 {
+		atomic.AddUint64(&f.invalidTicks, 1)
+		return false
+	}
+	tick.SymbolHash = f.sm.symbols.CanonicalHash(tick.SymbolHash)
+	if f.sm.config.SnapTickPrices && snapTick(f.sm.symbols.Get(tick.SymbolHash), tick) {
+		atomic.AddUint64(&f.snappedTicks, 1)
+	}
+
+	f.mu.Lock()
+	cur, ok := f.tickCursor[tick.SymbolHash]
+	if !ok {
+		cur = &feedCursor{}
+		f.tickCursor[tick.SymbolHash] = cur
+	}
+	if !f.advance(cur, tick.SeqID, tick.Timestamp, "tick") {
+		f.mu.Unlock()
+		atomic.AddUint64(&f.staleTicks, 1)
+		return false
+	}
+	f.signals[tick.SymbolHash] = computeMicrostructure(tick)
+	f.tickTimes[tick.SymbolHash] = f.sm.clock.Now().UnixNano()
+	if _, stale := f.sm.staleFeeds.Load(tick.SymbolHash); stale {
+		defer f.sm.setFeedStale(tick.SymbolHash, false, 0)
+	}
+	if halt, volBps, changed := f.observeVolatility(tick); changed {
+		defer f.sm.setVolatilityHalt(tick.SymbolHash, halt, volBps)
+	}
+	if window := f.tickWindow(tick.SymbolHash); window > 0 && !f.sample(tick, window) {
+		f.mu.Unlock()
+		return true // Held; forwarded when the window closes
+	}
+	f.mu.Unlock()
+
+	f.sm.UpdateTick(tick)
+	return true
+}
+
+// OnFill sequences a fill and forwards it. A fill whose SeqID does not
+// advance the fill stream is a re-delivery and is never applied twice.
+// A standby refuses fills: it books the active's through replication.
+func (f *FeedIngester) OnFill(fill *FillEvent) bool {
+	if f.sm.Standby() {
+		return false
+	}
+	fill.SymbolHash = f.sm.symbols.CanonicalHash(fill.SymbolHash)
+
+	f.mu.Lock()
+	if !f.advance(&f.fillCursor, fill.SeqID, fill.Timestamp, "fill") {
+		f.mu.Unlock()
+		atomic.AddUint64(&f.duplicateFills, 1)
+		return false
+	}
+	f.mu.Unlock()
+
+	f.sm.ApplyFill(fill)
+	return true
+}
+
+// advance moves a cursor forward by SeqID - caller holds f.mu.
+// Events without a SeqID (0) are accepted unsequenced. A jump of more
+// than one is accepted too, and the events skipped counted as a gap.
+func (f *FeedIngester) advance(cur *feedCursor, seqID uint64, timestamp int64, stream string) bool {
+	if seqID == 0 {
+		return true
+	}
+	if cur.seqID != 0 && seqID <= cur.seqID {
+		return false
+	}
+	if cur.seqID != 0 && seqID > cur.seqID+1 && cur.epoch == f.epoch {
+		missing := seqID - cur.seqID - 1
+		atomic.AddUint64(&f.feedGaps, missing)
+		log.Printf("[FEED] Sequence gap on %s stream: %d missing (seq %d-%d)", stream, missing, cur.seqID+1, seqID-1)
+	}
+	cur.epoch = f.epoch
+	if cur.seqID != 0 && timestamp < cur.timestamp {
+		skew := cur.timestamp - timestamp
+		atomic.AddUint64(&f.clockSkewEvents, 1)
+		atomic.StoreInt64(&f.lastClockSkewNs, skew)
+		log.Printf("[FEED] Clock skew on %s stream: seq %d→%d but timestamp regressed %dns", stream, cur.seqID, seqID, skew)
+	}
+	cur.seqID = seqID
+	if timestamp > cur.timestamp {
+		cur.timestamp = timestamp // Never move the reference clock backwards
+	}
+	return true
+}
+
+// FillCursor returns the last accepted fill's SeqID and timestamp
+func (f *FeedIngester) FillCursor() (uint64, int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.fillCursor.seqID, f.fillCursor.timestamp
+}
+
+// observeFill moves the fill cursor forward past a fill accepted elsewhere,
+// so re-deliveries of it after a handoff are dropped
+func (f *FeedIngester) observeFill(seqID uint64, timestamp int64) {
+	f.mu.Lock()
+	if seqID > f.fillCursor.seqID {
+		f.fillCursor.seqID = seqID
+	}
+	if timestamp > f.fillCursor.timestamp {
+		f.fillCursor.timestamp = timestamp
+	}
+	f.mu.Unlock()
+}
+
+// ClockSkewEvents returns the number of timestamp regressions observed
+func (f *FeedIngester) ClockSkewEvents() uint64 {
+	return atomic.LoadUint64(&f.clockSkewEvents)
+}
+
+// LastClockSkewNs returns the magnitude of the most recent regression
+func (f *FeedIngester) LastClockSkewNs() int64 {
+	return atomic.LoadInt64(&f.lastClockSkewNs)
+}
+
+// StaleTicks returns the number of ticks dropped for not advancing SeqID
+func (f *FeedIngester) StaleTicks() uint64 {
+	return atomic.LoadUint64(&f.staleTicks)
+}
+
+// snapTick rounds a tick's prices to the symbol's tick size and reports
+// whether any moved. An unset bid or ask stays unset.
+func snapTick(spec *SymbolSpec, tick *MarketTickOptimized) bool {
+	bid, ask, last := spec.SnapToTick(tick.BidPrice), spec.SnapToTick(tick.AskPrice), spec.SnapToTick(tick.LastPrice)
+	if bid == tick.BidPrice && ask == tick.AskPrice && last == tick.LastPrice {
+		return false
+	}
+	tick.BidPrice, tick.AskPrice, tick.LastPrice = bid, ask, last
+	return true
+}
+
+// SnappedTicks returns the number of ticks whose prices were rounded to
+// the tick size
+func (f *FeedIngester) SnappedTicks() uint64 {
+	return atomic.LoadUint64(&f.snappedTicks)
+}
+
+// InvalidTicks returns the number of ticks dropped for impossible prices
+func (f *FeedIngester) InvalidTicks() uint64 {
+	return atomic.LoadUint64(&f.invalidTicks)
+}
+
+// DuplicateFills returns the number of re-delivered fills dropped
+func (f *FeedIngester) DuplicateFills() uint64 {
+	return atomic.LoadUint64(&f.duplicateFills)
+}
+
+// FeedGaps returns the number of ticks and fills skipped by sequence gaps
+func (f *FeedIngester) FeedGaps() uint64 {
+	return atomic.LoadUint64(&f.feedGaps)
+}
+
+// resetGaps starts gap tracking afresh, for a reconnect after which the
+// events missed meanwhile are already accounted for. The cursors keep
+// their SeqIDs, so a re-delivery is still dropped.
+func (f *FeedIngester) resetGaps() {
+	f.mu.Lock()
+	f.epoch++
+	f.mu.Unlock()
+}
@@ -0,0 +1,94 @@
+package main
+
+import "testing"
+
+func TestSnapTickPrices(t *testing.T) {
+	cfg := testConfig()
+	cfg.SnapTickPrices = true
+	cfg.Symbols = []SymbolMeta{{Symbol: "HALFUSD", TickSize: 0.5, LotSize: 1, Multiplier: 1, Currency: "USD"}}
+	sm := NewShardedStateManager(cfg)
+	h := sm.symbols.Hash("HALFUSD")
+
+	tests := []struct {
+		name             string
+		bid, ask, last   float64
+		wantBid, wantAsk float64
+		wantLast         float64
+		wantSnapped      uint64 // Running count
+	}{
+		{"on the grid", 99.5, 100, 100, 99.5, 100, 100, 0},
+		{"rounds to nearest", 99.7, 100.2, 100.1, 99.5, 100, 100, 1},
+		{"half rounds up", 99.25, 100.25, 100.75, 99.5, 100.5, 101, 2},
+		{"unset bid and ask", 0, 0, 100.3, 0, 0, 100.5, 3},
+		{"never below one tick", 0, 0, 0.1, 0, 0, 0.5, 4},
+	}
+	for _, tt := range tests {
+		tick := &MarketTickOptimized{SymbolHash: h, BidPrice: fx(tt.bid), AskPrice: fx(tt.ask), LastPrice: fx(tt.last)}
+		if !sm.feed.OnTick(tick) {
+			t.Fatalf("%s: tick refused", tt.name)
+		}
+		if tick.BidPrice != fx(tt.wantBid) || tick.AskPrice != fx(tt.wantAsk) || tick.LastPrice != fx(tt.wantLast) {
+			t.Fatalf("%s: %d/%d/%d, want %d/%d/%d", tt.name, tick.BidPrice, tick.AskPrice, tick.LastPrice, fx(tt.wantBid), fx(tt.wantAsk), fx(tt.wantLast))
+		}
+		if n := sm.feed.SnappedTicks(); n != tt.wantSnapped {
+			t.Fatalf("%s: %d snapped, want %d", tt.name, n, tt.wantSnapped)
+		}
+	}
+
+	// No tick size, or snapping off: passed through as is
+	other := &MarketTickOptimized{SymbolHash: sm.symbols.Hash("ODDUSD"), LastPrice: fx(100.123)}
+	sm.feed.OnTick(other)
+	cfg.SnapTickPrices = false
+	off := NewShardedStateManager(cfg)
+	raw := &MarketTickOptimized{SymbolHash: h, LastPrice: fx(100.1)}
+	off.feed.OnTick(raw)
+	if other.LastPrice != fx(100.123) || raw.LastPrice != fx(100.1) || sm.feed.SnappedTicks() != 4 || off.feed.SnappedTicks() != 0 {
+		t.Fatalf("snapped %d and %d", other.LastPrice, raw.LastPrice)
+	}
+}
+
+func TestFeedGaps(t *testing.T) {
+	type tickSeq struct {
+		symbol string // "" reconnects the bus, which resets gap tracking
+		seq    uint64
+	}
+	tests := []struct {
+		name  string
+		ticks []tickSeq
+		want  uint64
+	}{
+		{"contiguous", []tickSeq{{"A", 1}, {"A", 2}, {"A", 3}}, 0},
+		{"gap", []tickSeq{{"A", 1}, {"A", 2}, {"A", 5}}, 2},
+		{"per symbol", []tickSeq{{"A", 1}, {"B", 100}, {"A", 2}, {"B", 101}}, 0},
+		{"stale and duplicate dropped", []tickSeq{{"A", 1}, {"A", 5}, {"A", 3}, {"A", 5}}, 3},
+		{"unsequenced", []tickSeq{{"A", 1}, {"A", 0}, {"A", 2}}, 0},
+		{"not across a reconnect", []tickSeq{{"A", 1}, {"", 0}, {"A", 10}, {"A", 12}}, 1},
+		{"every symbol after a reconnect", []tickSeq{{"A", 1}, {"B", 1}, {"", 0}, {"B", 7}, {"A", 4}}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := NewShardedStateManager(testConfig())
+			for _, tk := range tt.ticks {
+				if tk.symbol == "" {
+					sm.feed.NATSReconnectHandler()(nil)
+					continue
+				}
+				sm.feed.OnTick(&MarketTickOptimized{SymbolHash: sm.symbols.Hash(tk.symbol), SeqID: tk.seq, LastPrice: fx(10)})
+			}
+			if gaps := sm.feed.FeedGaps(); gaps != tt.want {
+				t.Fatalf("%d gaps, want %d", gaps, tt.want)
+			}
+		})
+	}
+
+	// The fill stream has one cursor; a reset does not let a re-delivery in
+	sm := NewShardedStateManager(testConfig())
+	h := sm.symbols.Hash("A")
+	for _, seq := range []uint64{1, 4} {
+		sm.feed.OnFill(&FillEvent{OrderID: seq, SymbolHash: h, Quantity: fx(1), Price: fx(10), SeqID: seq})
+	}
+	sm.feed.resetGaps()
+	if sm.feed.OnFill(&FillEvent{OrderID: 4, SymbolHash: h, Quantity: fx(1), Price: fx(10), SeqID: 4}) || sm.feed.FeedGaps() != 2 {
+		t.Fatalf("%d gaps on the fill stream, want 2 and the re-delivery dropped", sm.feed.FeedGaps())
+	}
+}
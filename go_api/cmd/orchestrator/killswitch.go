@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"cenayang-market/go-api/internal/handlers"
+	"cenayang-market/go-api/internal/ws"
+)
+
+// ============================================================================
+// KILL SWITCH - Serialized Requests with Breaker Precedence
+// ============================================================================
+
+// Kill switch request sources
+const (
+	KillSourceManual   = "manual"   // An operator over HTTP, gRPC or WebSocket
+	KillSourceBreaker  = "breaker"  // The drawdown breaker
+	KillSourceSequence = "sequence" // Sequence exhaustion
+	KillSourceSystem   = "system"   // Replicated from the active or restored from a snapshot
+	KillSourceBlown    = "blown"    // Equity at or below the floor
+)
+
+var (
+	// ErrKillSwitchHeld - a manual clear while drawdown is at or over the
+	// limit; the breaker would only trip it again
+	ErrKillSwitchHeld = errors.New("kill switch held: drawdown is at or over the limit")
+	// ErrKillSwitchStale - a clear issued before the latest activation, so
+	// its sender had not seen it
+	ErrKillSwitchStale = errors.New("kill switch clear predates the latest activation")
+)
+
+// KillSwitchRequest - one request to activate or clear the kill switch
+type KillSwitchRequest struct {
+	Active bool
+	Source string    // KillSource*
+	Actor  string    // Who, for manual requests
+	At     time.Time // When it was issued; zero = on arrival
+}
+
+// killSwitchState - requests are applied one at a time under mu, so the
+// outcome of concurrent toggles depends only on the rules below, not on
+// which goroutine got there first
+type killSwitchState struct {
+	mu          sync.Mutex
+	activatedAt int64  // Issue time of the latest applied activation, unix ns
+	source      string // Of the last change
+	changedAt   int64  // Unix ns
+}
+
+// ApplyKillSwitch applies req under these rules:
+//   - activations always apply;
+//   - a clear issued before the latest activation is refused with
+//     ErrKillSwitchStale;
+//   - a manual clear while drawdown is at or over MaxDrawdownPct is refused
+//     with ErrKillSwitchHeld, so a breaker trip always outlasts it;
+//   - a clear while the account is blown is refused with ErrAccountBlown,
+//     whatever its source.
+//
+// Returns whether the state changed.
+func (sm *ShardedStateManager) ApplyKillSwitch(req KillSwitchRequest) (bool, error) {
+	ks := &sm.killSwitch
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	now := sm.clock.Now()
+	if req.At.IsZero() {
+		req.At = now
+	}
+	if !req.Active {
+		if req.At.UnixNano() < ks.activatedAt {
+			log.Printf("[KILL SWITCH] Refused %s clear by %q: issued before the latest activation", req.Source, req.Actor)
+			return false, ErrKillSwitchStale
+		}
+		if req.Source == KillSourceManual && sm.drawdownAtLimit() {
+			log.Printf("[KILL SWITCH] Refused manual clear by %q: drawdown %d bps at or over the limit",
+				req.Actor, atomic.LoadInt64(&sm.state.CurrentDrawdown))
+			return false, ErrKillSwitchHeld
+		}
+		if sm.AccountBlown() {
+			log.Printf("[KILL SWITCH] Refused %s clear by %q: account blown", req.Source, req.Actor)
+			return false, ErrAccountBlown
+		}
+	}
+	return sm.setKillSwitchLocked(req, now), nil
+}
+
+// SetKillSwitch activates or clears the kill switch unconditionally, as a
+// standby mirroring its active or a restored snapshot must. Returns
+// whether the state changed.
+func (sm *ShardedStateManager) SetKillSwitch(active bool) bool {
+	sm.killSwitch.mu.Lock()
+	defer sm.killSwitch.mu.Unlock()
+	now := sm.clock.Now()
+	return sm.setKillSwitchLocked(KillSwitchRequest{Active: active, Source: KillSourceSystem, At: now}, now)
+}
+
+// setKillSwitchLocked stores and broadcasts a kill switch change - caller
+// holds killSwitch.mu
+func (sm *ShardedStateManager) setKillSwitchLocked(req KillSwitchRequest, now time.Time) bool {
+	ks := &sm.killSwitch
+	var v int32
+	if req.Active {
+		v = 1
+		ks.activatedAt = max(ks.activatedAt, req.At.UnixNano())
+	}
+	if atomic.SwapInt32(&sm.state.KillSwitch, v) == v {
+		return false
+	}
+	atomic.StoreInt32(&sm.breaker.tripped, 0) // The breaker marks its own trips
+	ks.source, ks.changedAt = req.Source, now.UnixNano()
+	if req.Source == KillSourceManual && req.Active {
+		log.Printf("[KILL SWITCH] Activated by %q", req.Actor)
+	} else if req.Source == KillSourceManual {
+		log.Printf("[KILL SWITCH] Cleared by %q", req.Actor)
+	}
+
+	b := make([]byte, 0, 96)
+	b = append(b, `{"type":"kill_switch","active":`...)
+	b = strconv.AppendBool(b, req.Active)
+	b = append(b, `,"source":"`...)
+	b = append(b, req.Source...)
+	b = append(b, `"}`...)
+	sm.publish(ws.EventKillSwitch, atomic.LoadUint64(&sm.state.SequenceID), b)
+	return true
+}
+
+// KillSwitchSource returns the source of the last kill switch change and
+// when it was applied, unix ns; "" before the first
+func (sm *ShardedStateManager) KillSwitchSource() (string, int64) {
+	sm.killSwitch.mu.Lock()
+	defer sm.killSwitch.mu.Unlock()
+	return sm.killSwitch.source, sm.killSwitch.changedAt
+}
+
+// writeKillSwitch serves the kill switch state and its last change
+func (sm *ShardedStateManager) writeKillSwitch(w http.ResponseWriter, r *http.Request) {
+	source, changedAt := sm.KillSwitchSource()
+
+	buf := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(buf)
+
+	b := append((*buf)[:0], `{"active":`...)
+	b = strconv.AppendBool(b, atomic.LoadInt32(&sm.state.KillSwitch) != 0)
+	b = append(b, `,"source":`...)
+	b = strconv.AppendQuote(b, source)
+	b = append(b, `,"changed_at_ns":`...)
+	b = strconv.AppendInt(b, changedAt, 10)
+	b = append(b, '}')
+	handlers.WriteJSON(w, r, http.StatusOK, b)
+}
+
+// drawdownAtLimit reports whether the breaker's hard tier is breached
+func (sm *ShardedStateManager) drawdownAtLimit() bool {
+	return sm.config.KillSwitchEnabled &&
+		atomic.LoadInt64(&sm.state.CurrentDrawdown) >= int64(sm.config.MaxDrawdownPct*100)
+}
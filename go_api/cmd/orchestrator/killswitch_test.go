@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestKillSwitchPrecedence(t *testing.T) {
+	t0 := time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC)
+	sm := NewShardedStateManager(testConfig()) // Limit 500 bps
+	manual := func(active bool, at time.Time) KillSwitchRequest {
+		return KillSwitchRequest{Active: active, Source: KillSourceManual, Actor: "ops", At: at}
+	}
+
+	steps := []struct {
+		name     string
+		drawdown int64
+		req      KillSwitchRequest
+		wantErr  error
+		want     int32
+	}{
+		{"activate", 0, manual(true, t0), nil, 1},
+		{"clear sent before it", 0, manual(false, t0.Add(-time.Second)), ErrKillSwitchStale, 1},
+		{"clear after it", 0, manual(false, t0.Add(time.Second)), nil, 0},
+		{"breaker trips", 600, KillSwitchRequest{Active: true, Source: KillSourceBreaker, At: t0.Add(2 * time.Second)}, nil, 1},
+		{"manual clear over the limit", 600, manual(false, t0.Add(3*time.Second)), ErrKillSwitchHeld, 1},
+		{"breaker clears", 600, KillSwitchRequest{Source: KillSourceBreaker, At: t0.Add(4 * time.Second)}, nil, 0},
+		{"activate again", 100, manual(true, t0.Add(5*time.Second)), nil, 1},
+		{"manual clear under the limit", 100, manual(false, t0.Add(6*time.Second)), nil, 0},
+	}
+	for _, s := range steps {
+		atomic.StoreInt64(&sm.state.CurrentDrawdown, s.drawdown)
+		if _, err := sm.ApplyKillSwitch(s.req); err != s.wantErr {
+			t.Fatalf("%s: err %v, want %v", s.name, err, s.wantErr)
+		}
+		if got := atomic.LoadInt32(&sm.state.KillSwitch); got != s.want {
+			t.Fatalf("%s: kill switch %d, want %d", s.name, got, s.want)
+		}
+	}
+	if source, _ := sm.KillSwitchSource(); source != KillSourceManual {
+		t.Fatalf("source %q, want %q", source, KillSourceManual)
+	}
+
+	// Refusals reach HTTP as 409
+	sm.ApplyKillSwitch(manual(true, time.Time{}))
+	atomic.StoreInt64(&sm.state.CurrentDrawdown, 600)
+	rec := httptest.NewRecorder()
+	setupHTTPRoutes(sm).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/kill-switch", strings.NewReader(`{"active":false}`)))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("held clear over HTTP: %d %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"cenayang-market/go-api/internal/database"
+	"cenayang-market/go-api/internal/handlers"
+)
+
+// ============================================================================
+// LATENCY HISTORY - Per-Minute Percentile Roll-Ups
+// ============================================================================
+
+// DefaultLatencyHistory is how long per-minute latency buckets are kept
+const DefaultLatencyHistory = time.Hour
+
+// LatencyRollupInterval is the width of one history bucket
+const LatencyRollupInterval = time.Minute
+
+// histogramCounts - a point-in-time copy of a histogram's counters
+type histogramCounts struct {
+	buckets [HistogramBuckets]uint64
+	count   uint64
+	sum     int64
+}
+
+// counts copies the histogram's counters. Records racing the copy may
+// land in either side of the roll-up boundary, never both.
+func (h *LockFreeHistogram) counts(c *histogramCounts) {
+	for i := range c.buckets {
+		c.buckets[i] = atomic.LoadUint64(&h.buckets[i])
+	}
+	c.count = atomic.LoadUint64(&h.count)
+	c.sum = atomic.LoadInt64(&h.sum)
+}
+
+// LatencyStore carries the cumulative latency histograms across restarts.
+// *database.Database and *database.Postgres implement it.
+type LatencyStore interface {
+	SaveLatencyHistograms(records []database.LatencyHistogramRecord) error
+	LoadLatencyHistograms() ([]database.LatencyHistogramRecord, error)
+}
+
+// latencyHistograms names the histograms that are persisted
+func (sm *ShardedStateManager) latencyHistograms() map[string]*LockFreeHistogram {
+	return map[string]*LockFreeHistogram{
+		"ingestion":  sm.ingestionHist,
+		"processing": sm.processingHist,
+		"risk":       sm.riskHist,
+		"broadcast":  sm.broadcastHist,
+	}
+}
+
+// record copies the histogram's cumulative counters for the store
+func (h *LockFreeHistogram) record(name string) database.LatencyHistogramRecord {
+	var c histogramCounts
+	h.counts(&c)
+	h.mu.Lock()
+	minSeen, maxSeen := h.minSeen, h.maxSeen
+	h.mu.Unlock()
+	return database.LatencyHistogramRecord{
+		Name:       name,
+		MinValue:   h.minValue,
+		BucketSize: h.bucketSize,
+		Buckets:    c.buckets[:],
+		Count:      c.count,
+		Sum:        c.sum,
+		MinSeen:    minSeen,
+		MaxSeen:    maxSeen,
+	}
+}
+
+// merge adds a stored histogram's counters to h. A histogram stored with
+// a different bucket layout cannot be merged.
+func (h *LockFreeHistogram) merge(rec *database.LatencyHistogramRecord) error {
+	if rec.MinValue != h.minValue || rec.BucketSize != h.bucketSize || len(rec.Buckets) > HistogramBuckets {
+		return fmt.Errorf("bucket layout %d+%dx%d, want %d+%dx%d",
+			rec.MinValue, rec.BucketSize, len(rec.Buckets), h.minValue, h.bucketSize, HistogramBuckets)
+	}
+	for i, n := range rec.Buckets {
+		atomic.AddUint64(&h.buckets[i], n)
+	}
+	atomic.AddUint64(&h.count, rec.Count)
+	atomic.AddInt64(&h.sum, rec.Sum)
+	if rec.Count > 0 {
+		h.mu.Lock()
+		h.minSeen = min(h.minSeen, rec.MinSeen)
+		h.maxSeen = max(h.maxSeen, rec.MaxSeen)
+		h.mu.Unlock()
+	}
+	return nil
+}
+
+// latencyRecords copies every persisted histogram, nil when latency is
+// not persisted
+func (sm *ShardedStateManager) latencyRecords() []database.LatencyHistogramRecord {
+	if sm.latencyStore == nil {
+		return nil
+	}
+	hists := sm.latencyHistograms()
+	records := make([]database.LatencyHistogramRecord, 0, len(hists))
+	for name, h := range hists {
+		records = append(records, h.record(name))
+	}
+	return records
+}
+
+// restoreLatency merges the histograms a previous run saved into the live
+// ones, so percentiles span restarts. Each later snapshot writes the
+// merged totals back, so history accumulates run over run rather than
+// being replaced. Only once the load succeeds is latency saved at all:
+// saving without it would overwrite the stored history with this run's.
+func (sm *ShardedStateManager) restoreLatency() error {
+	cfg := sm.config.Persistence
+	if !cfg.LatencyHistograms || cfg.Backend == nil {
+		return nil
+	}
+	store, ok := cfg.Backend.(LatencyStore)
+	if !ok {
+		return errors.New("persistence backend cannot store latency histograms")
+	}
+	records, err := store.LoadLatencyHistograms()
+	if err != nil {
+		return err
+	}
+	hists := sm.latencyHistograms()
+	var merged uint64
+	for i := range records {
+		h, ok := hists[records[i].Name]
+		if !ok {
+			continue // A histogram no longer kept
+		}
+		if err := h.merge(&records[i]); err != nil {
+			log.Printf("[LATENCY] Stored %s histogram discarded: %v", records[i].Name, err)
+			continue
+		}
+		merged += records[i].Count
+	}
+	sm.latencyStore = store
+	if merged > 0 {
+		log.Printf("[LATENCY] Restored %d latency samples from previous runs", merged)
+	}
+	return nil
+}
+
+// latencyStats - percentiles of the values recorded between two copies
+type latencyStats struct {
+	Count uint64
+	Mean  int64
+	P50   int64
+	P99   int64
+}
+
+// deltaStats summarizes what h recorded between prev and cur
+func deltaStats(h *LockFreeHistogram, prev, cur *histogramCounts) latencyStats {
+	var s latencyStats
+	s.Count = cur.count - prev.count
+	if s.Count == 0 {
+		return s
+	}
+	s.Mean = (cur.sum - prev.sum) / int64(s.Count)
+
+	p50, p99 := (s.Count+1)/2, s.Count-s.Count/100
+	var cumulative uint64
+	for i := range cur.buckets {
+		n := cur.buckets[i] - prev.buckets[i]
+		if n == 0 {
+			continue
+		}
+		value := h.minValue + int64(i)*h.bucketSize
+		if cumulative < p50 && cumulative+n >= p50 {
+			s.P50 = value
+		}
+		cumulative += n
+		if cumulative >= p99 {
+			s.P99 = value
+			break
+		}
+	}
+	return s
+}
+
+// latencyBucket - one minute of latency
+type latencyBucket struct {
+	Start      time.Time
+	Ticks      uint64
+	Ingestion  latencyStats // Nanoseconds
+	Risk       latencyStats // Nanoseconds
+	Rejections uint64
+}
+
+// LatencyHistory rolls the cumulative latency histograms up into
+// per-minute buckets, oldest first, kept for a window
+type LatencyHistory struct {
+	mu      sync.Mutex
+	window  time.Duration
+	buckets []latencyBucket
+
+	// Counters at the last roll-up
+	ingestion, risk  histogramCounts
+	ticks, rejection uint64
+	since            time.Time
+}
+
+// NewLatencyHistory keeps buckets for window; zero takes the default,
+// negative disables the history
+func NewLatencyHistory(window time.Duration) *LatencyHistory {
+	if window == 0 {
+		window = DefaultLatencyHistory
+	}
+	return &LatencyHistory{window: window}
+}
+
+// Enabled reports whether buckets are kept
+func (lh *LatencyHistory) Enabled() bool {
+	return lh.window > 0
+}
+
+// rollLatency closes the bucket that started at the last roll-up and drops
+// buckets past the window. The first call only takes the baseline.
+func (sm *ShardedStateManager) rollLatency(now time.Time) {
+	lh := sm.latency
+	if !lh.Enabled() {
+		return
+	}
+	var ingestion, risk histogramCounts
+	sm.ingestionHist.counts(&ingestion)
+	sm.riskHist.counts(&risk)
+	ticks := atomic.LoadUint64(&sm.totalTicks)
+	rejections := atomic.LoadUint64(&sm.riskRejections)
+
+	lh.mu.Lock()
+	defer lh.mu.Unlock()
+	if !lh.since.IsZero() {
+		lh.buckets = append(lh.buckets, latencyBucket{
+			Start:      lh.since,
+			Ticks:      ticks - lh.ticks,
+			Ingestion:  deltaStats(sm.ingestionHist, &lh.ingestion, &ingestion),
+			Risk:       deltaStats(sm.riskHist, &lh.risk, &risk),
+			Rejections: rejections - lh.rejection,
+		})
+	}
+	lh.ingestion, lh.risk, lh.ticks, lh.rejection = ingestion, risk, ticks, rejections
+	lh.since = now
+
+	cutoff := now.Add(-lh.window)
+	drop := 0
+	for drop < len(lh.buckets) && lh.buckets[drop].Start.Before(cutoff) {
+		drop++
+	}
+	lh.buckets = append(lh.buckets[:0], lh.buckets[drop:]...)
+}
+
+// Since returns a copy of the buckets that started at or after from
+func (lh *LatencyHistory) Since(from time.Time) []latencyBucket {
+	lh.mu.Lock()
+	defer lh.mu.Unlock()
+	i := len(lh.buckets)
+	for i > 0 && !lh.buckets[i-1].Start.Before(from) {
+		i--
+	}
+	return append([]latencyBucket(nil), lh.buckets[i:]...)
+}
+
+// handleLatencyHistory serves per-minute latency buckets, oldest first.
+// ?window= (a duration, e.g. 15m) narrows them to the most recent span;
+// it defaults to, and is capped at, the retention window.
+func (sm *ShardedStateManager) handleLatencyHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "GET required", http.StatusMethodNotAllowed)
+		return
+	}
+	lh := sm.latency
+	if !lh.Enabled() {
+		handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusNotFound, Error: "latency history disabled"})
+		return
+	}
+
+	window := lh.window
+	if v := r.URL.Query().Get("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "window must be a positive duration, e.g. 15m", Field: "window"})
+			return
+		}
+		window = min(d, lh.window)
+	}
+	buckets := lh.Since(sm.clock.Now().Add(-window))
+
+	buf := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(buf)
+
+	b := append((*buf)[:0], `{"window_s":`...)
+	b = strconv.AppendInt(b, int64(window/time.Second), 10)
+	b = append(b, `,"bucket_s":`...)
+	b = strconv.AppendInt(b, int64(LatencyRollupInterval/time.Second), 10)
+	b = append(b, `,"buckets":[`...)
+	for i, bk := range buckets {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, `{"start":"`...)
+		b = bk.Start.UTC().AppendFormat(b, time.RFC3339)
+		b = append(b, `","ticks":`...)
+		b = strconv.AppendUint(b, bk.Ticks, 10)
+		b = append(b, `,"ingestion_p50_us":`...)
+		b = strconv.AppendInt(b, bk.Ingestion.P50/1000, 10)
+		b = append(b, `,"ingestion_p99_us":`...)
+		b = strconv.AppendInt(b, bk.Ingestion.P99/1000, 10)
+		b = append(b, `,"risk_checks":`...)
+		b = strconv.AppendUint(b, bk.Risk.Count, 10)
+		b = append(b, `,"risk_p50_ns":`...)
+		b = strconv.AppendInt(b, bk.Risk.P50, 10)
+		b = append(b, `,"risk_p99_ns":`...)
+		b = strconv.AppendInt(b, bk.Risk.P99, 10)
+		b = append(b, `,"risk_rejections":`...)
+		b = strconv.AppendUint(b, bk.Rejections, 10)
+		b = append(b, '}')
+	}
+	b = append(b, `]}`...)
+
+	handlers.WriteJSON(w, r, http.StatusOK, b)
+}
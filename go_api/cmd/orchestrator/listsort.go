@@ -0,0 +1,106 @@
+package main
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"cenayang-market/go-api/internal/handlers"
+)
+
+// ============================================================================
+// LIST ORDERING - Stable Sort Keys and Paging for List Endpoints
+// ============================================================================
+
+// sortKey - one ?sort= key of a list endpoint
+type sortKey[T any] struct {
+	name    string
+	compare func(a, b *T) int
+}
+
+// sortList orders items by the request's ?sort= key, descending with a
+// leading "-", and by keys[0] - the endpoint's default - when it is absent.
+// Ties always fall back to keys[0], so the order is the same on every call.
+func sortList[T any](r *http.Request, items []T, keys []sortKey[T]) *handlers.ErrorResponse {
+	primary, tiebreak := keys[0].compare, keys[0].compare
+	if v := r.URL.Query().Get("sort"); v != "" {
+		desc := strings.HasPrefix(v, "-")
+		name := strings.TrimPrefix(v, "-")
+		i := slices.IndexFunc(keys, func(k sortKey[T]) bool { return k.name == name })
+		if i < 0 {
+			names := make([]string, len(keys))
+			for j, k := range keys {
+				names[j] = k.name
+			}
+			return &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "sort must be one of " + strings.Join(names, ", ") + ", with - to reverse", Field: "sort"}
+		}
+		primary = keys[i].compare
+		if desc {
+			asc := primary
+			primary = func(a, b *T) int { return -asc(a, b) }
+		}
+	}
+	slices.SortFunc(items, func(a, b T) int {
+		if c := primary(&a, &b); c != 0 {
+			return c
+		}
+		return tiebreak(&a, &b)
+	})
+	return nil
+}
+
+// pageParams reads ?limit= (1..max; 0 when absent, meaning no limit) and
+// ?offset= (0 when absent)
+func pageParams(r *http.Request, maxLimit int) (limit, offset int, errResp *handlers.ErrorResponse) {
+	q := r.URL.Query()
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxLimit {
+			return 0, 0, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "limit must be between 1 and " + strconv.Itoa(maxLimit), Field: "limit"}
+		}
+		limit = n
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, 0, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "offset must be a non-negative integer", Field: "offset"}
+		}
+		offset = n
+	}
+	return limit, offset, nil
+}
+
+// positionRow - a position copied out of its shard for sorting
+type positionRow struct {
+	symbol string
+	pos    PositionOptimized
+}
+
+// positionSortKeys - symbol first, the default
+var positionSortKeys = []sortKey[positionRow]{
+	{"symbol", func(a, b *positionRow) int {
+		return cmp.Or(cmp.Compare(a.symbol, b.symbol), cmp.Compare(a.pos.SymbolHash, b.pos.SymbolHash))
+	}},
+	{"unrealized_pnl", func(a, b *positionRow) int { return cmp.Compare(a.pos.UnrealizedPnL, b.pos.UnrealizedPnL) }},
+	{"realized_pnl", func(a, b *positionRow) int { return cmp.Compare(a.pos.RealizedPnL, b.pos.RealizedPnL) }},
+	{"quantity", func(a, b *positionRow) int { return cmp.Compare(a.pos.Quantity, b.pos.Quantity) }},
+	{"updated_at", func(a, b *positionRow) int { return cmp.Compare(a.pos.UpdatedAt, b.pos.UpdatedAt) }},
+}
+
+// orderRow - a working order copied out of its shard for sorting
+type orderRow struct {
+	symbol string
+	order  OrderOptimized
+}
+
+// orderSortKeys - created_at then ID first, the default
+var orderSortKeys = []sortKey[orderRow]{
+	{"created_at", func(a, b *orderRow) int {
+		return cmp.Or(cmp.Compare(a.order.Timestamp, b.order.Timestamp), cmp.Compare(a.order.ID, b.order.ID))
+	}},
+	{"id", func(a, b *orderRow) int { return cmp.Compare(a.order.ID, b.order.ID) }},
+	{"symbol", func(a, b *orderRow) int { return cmp.Compare(a.symbol, b.symbol) }},
+	{"quantity", func(a, b *orderRow) int { return cmp.Compare(a.order.Quantity, b.order.Quantity) }},
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// Sorting comes before paging, and total counts every open position
+func TestPositionListOrder(t *testing.T) {
+	cfg := testConfig()
+	for _, symbol := range []string{"AAAUSD", "BBBUSD", "CCCUSD"} {
+		cfg.Symbols = append(cfg.Symbols, SymbolMeta{Symbol: symbol, TickSize: 0.01, LotSize: 1, Multiplier: 1, Currency: "USD"})
+	}
+	sm := NewShardedStateManager(cfg)
+	for _, p := range []struct {
+		symbol   string
+		quantity float64
+	}{{"CCCUSD", 2}, {"AAAUSD", 1}, {"BBBUSD", 3}} {
+		fill(sm, sm.symbols.Hash(p.symbol), 0, p.quantity, 10, 0)
+	}
+
+	tests := []struct {
+		query  string
+		status int
+		want   []string
+	}{
+		{"", http.StatusOK, []string{"AAAUSD", "BBBUSD", "CCCUSD"}},
+		{"?sort=-symbol", http.StatusOK, []string{"CCCUSD", "BBBUSD", "AAAUSD"}},
+		{"?sort=quantity", http.StatusOK, []string{"AAAUSD", "CCCUSD", "BBBUSD"}},
+		{"?sort=-quantity", http.StatusOK, []string{"BBBUSD", "CCCUSD", "AAAUSD"}},
+		{"?sort=realized_pnl", http.StatusOK, []string{"AAAUSD", "BBBUSD", "CCCUSD"}}, // All tied: by symbol
+		{"?sort=size", http.StatusBadRequest, nil},
+		{"?limit=2", http.StatusOK, []string{"AAAUSD", "BBBUSD"}},
+		{"?limit=2&offset=2", http.StatusOK, []string{"CCCUSD"}},
+		{"?sort=-quantity&limit=1&offset=1", http.StatusOK, []string{"CCCUSD"}}, // Sorted, then paged
+		{"?offset=5", http.StatusOK, []string{}},
+		{"?limit=1001", http.StatusBadRequest, nil},
+		{"?offset=-1", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		sm.handlePositions(rec, httptest.NewRequest(http.MethodGet, "/api/positions"+tt.query, nil))
+		if rec.Code != tt.status {
+			t.Fatalf("%q: status %d, want %d", tt.query, rec.Code, tt.status)
+		}
+		if tt.want == nil {
+			continue
+		}
+		var body struct {
+			Total     int `json:"total"`
+			Positions []struct {
+				Symbol string `json:"symbol"`
+			} `json:"positions"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Total != 3 {
+			t.Fatalf("%q: total %d, want 3", tt.query, body.Total)
+		}
+		got := []string{}
+		for _, p := range body.Positions {
+			got = append(got, p.Symbol)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Fatalf("%q: %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
@@ -25,16 +25,32 @@ package main
 import (
 	"context"
 	"encoding/binary"
+	"flag"
 	"fmt"
 	"log"
+	"math"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
 	"unsafe"
+
+	"github.com/nats-io/nats.go"
+
+	"cenayang-market/go-api/internal/allocator"
+	"cenayang-market/go-api/internal/auth"
+	"cenayang-market/go-api/internal/database"
+	"cenayang-market/go-api/internal/exec"
+	"cenayang-market/go-api/internal/handlers"
+	"cenayang-market/go-api/internal/health"
+	"cenayang-market/go-api/internal/middleware"
+	"cenayang-market/go-api/internal/ws"
 )
 
 // ============================================================================
@@ -48,13 +64,22 @@ const (
 	RingBufferSize    = 65536
 	HistogramBuckets  = 4096
 	PriceScale  int64 = 100_000_000 // 8 decimal places
+
+	DefaultStartingEquity = 100_000.0
+)
+
+// Oversize policies - what the risk check does with an order whose
+// notional exceeds the position cap
+const (
+	OversizeReject = "REJECT"
+	OversizeClamp  = "CLAMP" // Approve at the largest size within the limit
 )
 
 // Pre-computed symbol hashes
 const (
 	SymbolHashBTC  uint64 = 0xAF4F2D6E8B1C3A5F
-	SymbolHashETH  uint64 = 0xBF5G3E7F9C2D4B6A
-	SymbolHashSOL  uint64 = 0xCF6H4F8G0D3E5C7B
+	SymbolHashETH  uint64 = 0xBF5A3E7F9C2D4B6A
+	SymbolHashSOL  uint64 = 0xCF6B4F8A0D3E5C7B
 )
 
 // ============================================================================
@@ -70,39 +95,64 @@ type PortfolioStateOptimized struct {
 	HighWaterMark   int64
 	CurrentDrawdown int64 // Basis points (divide by 10000 for percent)
 	MaxDrawdown     int64
-	KillSwitch      int32 // Atomic bool: 0=false, 1=true
-	SequenceID      uint64
+	KillSwitch      int32  // Atomic bool: 0=false, 1=true
+	TradingPaused   int32  // Atomic bool: only reduce-only orders pass
+	SequenceID      uint64 // Monotonic; see nextSequence
 	Timestamp       int64
 	_padding        [24]byte // Pad to cache line
 }
 
 // PositionOptimized - Cache-line aligned
 type PositionOptimized struct {
-	SymbolHash    uint64
-	Side          uint8 // 0=Buy, 1=Sell
-	Quantity      int64 // Fixed-point
-	EntryPrice    int64 // Fixed-point
-	CurrentPrice  int64 // Fixed-point
-	UnrealizedPnL int64
-	RealizedPnL   int64
-	UpdatedAt     int64
-	_padding      [24]byte
+	SymbolHash     uint64
+	Side           uint8 // 0=Buy, 1=Sell
+	Quantity       int64 // Fixed-point
+	EntryPrice     int64 // Fixed-point
+	CurrentPrice   int64 // Fixed-point
+	UnrealizedPnL  int64
+	RealizedPnL    int64
+	UpdatedAt      int64
+	Commission     int64  // Accumulated entry commission on open quantity
+	Cost           int64  // Cash the open quantity tied up: entry notional, negative for a short
+	BreakevenPrice int64  // Entry adjusted by per-unit commission
+	OpenedAt       int64  // Unix nanos; 0 = unknown (restored)
+	Tag            string // Attribution owner, the opening order's tag
 }
 
 // OrderOptimized - Cache-line aligned
 type OrderOptimized struct {
-	ID           uint64
-	ClientHash   uint64
-	SymbolHash   uint64
-	Side         uint8
-	Status       uint8
-	Quantity     int64
-	Price        int64
-	FilledQty    int64
-	AvgFillPrice int64
-	SequenceID   uint64
-	Timestamp    int64
-	_padding     [20]byte
+	ID            uint64
+	ClientHash    uint64
+	SymbolHash    uint64
+	Side          uint8
+	Status        uint8
+	ReduceOnly    bool // May only shrink an opposing position
+	Quantity      int64
+	Price         int64
+	FilledQty     int64
+	AvgFillPrice  int64
+	DecisionPrice int64 // Last trade at acceptance, for shortfall
+	CashReserved  int64 // Cash held for a buy's unfilled quantity
+	Reserved      int64 // Strategy capital held for the unfilled quantity
+	SequenceID    uint64
+	Timestamp     int64
+	Strategy      string // Capital allocation slot ("" = unallocated)
+	Tag           string // PnL attribution tag ("" = Strategy)
+	_padding      [4]byte
+}
+
+// FillEvent - execution report applied to a position
+type FillEvent struct {
+	OrderID    uint64
+	SymbolHash uint64
+	Side       uint8 // 0=Buy, 1=Sell
+	Quantity   int64 // Fixed-point
+	Price      int64 // Fixed-point
+	Commission int64 // Fixed-point, quote currency
+	SeqID      uint64
+	Timestamp  int64
+	ReduceOnly bool  // Venue flagged the fill as closing only
+	Liquidity  uint8 // LiquidityTaker (default) or LiquidityMaker
 }
 
 // MarketTickOptimized - Binary format, cache-line aligned
@@ -120,10 +170,13 @@ type MarketTickOptimized struct {
 	Flags        uint32
 }
 
+// TickWireSize is the length of a serialized tick
+const TickWireSize = 80
+
 // Binary serialization - zero allocation
 func (t *MarketTickOptimized) ToBytes(buf []byte) []byte {
-	if len(buf) < 80 {
-		buf = make([]byte, 80)
+	if len(buf) < TickWireSize {
+		buf = make([]byte, TickWireSize)
 	}
 	binary.LittleEndian.PutUint64(buf[0:8], t.SymbolHash)
 	binary.LittleEndian.PutUint64(buf[8:16], uint64(t.BidPrice))
@@ -136,10 +189,14 @@ func (t *MarketTickOptimized) ToBytes(buf []byte) []byte {
 	binary.LittleEndian.PutUint64(buf[64:72], t.SeqID)
 	binary.LittleEndian.PutUint32(buf[72:76], uint32(t.LatencyNs))
 	binary.LittleEndian.PutUint32(buf[76:80], t.Flags)
-	return buf[:80]
+	return buf[:TickWireSize]
 }
 
-func (t *MarketTickOptimized) FromBytes(buf []byte) {
+// FromBytes decodes a serialized tick, reporting false for a short buffer
+func (t *MarketTickOptimized) FromBytes(buf []byte) bool {
+	if len(buf) < TickWireSize {
+		return false // Truncated frame
+	}
 	t.SymbolHash = binary.LittleEndian.Uint64(buf[0:8])
 	t.BidPrice = int64(binary.LittleEndian.Uint64(buf[8:16]))
 	t.AskPrice = int64(binary.LittleEndian.Uint64(buf[16:24]))
@@ -151,6 +208,7 @@ func (t *MarketTickOptimized) FromBytes(buf []byte) {
 	t.SeqID = binary.LittleEndian.Uint64(buf[64:72])
 	t.LatencyNs = int32(binary.LittleEndian.Uint32(buf[72:76]))
 	t.Flags = binary.LittleEndian.Uint32(buf[76:80])
+	return true
 }
 
 // ============================================================================
@@ -257,14 +315,15 @@ func (h *LockFreeHistogram) Record(value int64) {
 	h.mu.Unlock()
 }
 
-// Percentile returns the value at the given percentile - O(1)
+// Percentile returns the value at the given percentile - O(1). The rank
+// is rounded up, so a percentile always lands on a recorded sample.
 func (h *LockFreeHistogram) Percentile(p float64) int64 {
 	total := atomic.LoadUint64(&h.count)
 	if total == 0 {
 		return 0
 	}
 
-	target := uint64(float64(total) * p / 100.0)
+	target := max(uint64(math.Ceil(float64(total)*p/100.0)), 1)
 	var cumulative uint64
 
 	for i := 0; i < HistogramBuckets; i++ {
@@ -325,7 +384,12 @@ type StateShard struct {
 	mu        sync.RWMutex
 	positions map[uint64]*PositionOptimized
 	orders    map[uint64]*OrderOptimized
-	_         [32]byte // Padding
+	lots      map[uint64][]costLot        // Open lots per position, FIFO / LIFO only
+	scaling   map[uint64]scaleHistory     // How each open position was added to
+	fills     map[uint64]positionFills    // Fills behind each open position
+	tagPnL    map[string]int64            // Realized PnL net of fees per attribution tag
+	capital   map[uint64]map[string]int64 // Strategy capital behind each open position, by strategy
+	_         [32]byte                    // Padding
 }
 
 // ShardedStateManager with no global lock
@@ -341,16 +405,148 @@ type ShardedStateManager struct {
 	riskHist       *LockFreeHistogram
 	broadcastHist  *LockFreeHistogram
 
+	// Streaming P50/P99 estimates, unbounded by bucket range
+	ingestionQ *StreamingQuantiles
+	riskQ      *StreamingQuantiles
+	endToEndQ  *StreamingQuantiles // Tick timestamp to the tick applied
+
 	// Atomic counters
 	totalTicks      uint64
 	totalFills      uint64
 	totalOrders     uint64
 	riskRejections  uint64
+	orderAnomalies  uint64
+	marshalErrors   uint64
+	marshalAlert    sync.Once
 	broadcastDrops  uint64
 
+	// Per-reason rejection counts (reset at session boundary)
+	rejections *RejectionHistogram
+
+	// Per-minute roll-ups of the histograms above
+	latency *LatencyHistory
+
+	// Contract specifications
+	symbols *SymbolRegistry
+
+	// Order identity: generator plus ID → orderIndexEntry (uniqueness)
+	orderIDs   OrderIDGenerator
+	orderIndex sync.Map
+
+	// Inbound feed sequencing
+	feed *FeedIngester
+
+	// Fills left out of the book
+	quarantine fillQuarantine
+
+	// Bounds on the above
+	retention RetentionConfig
+
+	// Implementation shortfall
+	execution *ExecutionTracker
+
+	// Stop / limit-if-touched orders awaiting their trigger price
+	triggers *TriggerBook
+
+	// OCO groups over working orders and triggers
+	orderGroups *OrderGroupBook
+
+	// Margin requirement of the open book
+	margin marginState
+
+	// Atomic bool: equity at or below Config.BlownEquityFloor
+	blown int32
+
+	// Atomic bools: the daily profit target was hit this session, and the
+	// pause is the target's rather than an operator's
+	profitTargetHit    int32
+	profitTargetPaused int32
+
+	// Symbol hash → ID of the close order a profit target sent
+	flattening sync.Map
+
+	// Default fill-price sanity band, basis points (0 = off)
+	fillBandBps int64
+
+	// Default order notional floor, fixed-point (0 = none)
+	minNotional int64
+
+	// Cut oversize orders down to the position cap instead of rejecting
+	clampOversize bool
+
+	// Symbols halted for new risk: symbol hash → struct{}
+	haltedSymbols sync.Map
+
+	// Held symbols whose feed went silent: symbol hash → struct{}
+	staleFeeds sync.Map
+
+	// Working orders the gateway never answered: order ID → struct{}
+	staleOrders sync.Map
+
+	// Realization method, CostBasis*
+	costBasis string
+
+	// Per-strategy capital
+	allocator *allocator.Allocator
+
+	// Execution gateway (nil = it follows order events only)
+	gateway OrderGateway
+
+	// Approved orders to the gateway (nil = not forwarded)
+	forwarder *exec.Publisher
+
+	// Kill switch request serialization
+	killSwitch killSwitchState
+
+	// Risk check workers (nil = checks run on the caller's goroutine)
+	riskPool *RiskPool
+
+	// Aggregate notional caps by symbol group
+	groups symbolGroups
+
+	// Operator alert inbox
+	alerts alertStore
+
+	// Booked fills by time (/api/fills)
+	fillHistory fillHistory
+
+	// Sampled equity for the rolling Sharpe and Sortino ratios
+	equityHistory *equityHistory
+
+	// WebSocket fan-out
+	hub *ws.Hub
+
+	// Downstream service reachability
+	deps *health.Checker
+
+	// Blue/green: atomic standby flag and the replicator while following
+	standby int32
+	replica atomic.Pointer[Replicator]
+
+	// Trading hours
+	clock    Clock
+	calendar *SessionCalendar
+
+	// High water mark resets and their audit trail (nil = log only)
+	hwmConfirm hwmConfirmation
+	audit      AuditLog
+
+	// Async trade, audit and snapshot writes (nil = none)
+	persist *Persister
+
+	// Where latency histograms are saved (nil = not persisted)
+	latencyStore LatencyStore
+
+	// Drawdown breaker confirmation
+	breaker drawdownBreaker
+
 	// Configuration
-	config    Config
-	startTime time.Time
+	config         Config
+	startingEquity int64 // Fixed-point baseline for TotalPnL
+	sessionEquity  int64 // Atomic, fixed-point baseline for DailyPnL: equity at the session start
+	bookValue      int64 // Atomic, fixed-point Cash plus the Cost of open positions: equity before marking
+	cashReserved   int64 // Atomic, fixed-point cash held for working buy orders
+	startTime      time.Time
 }
 
 // NewShardedStateManager creates a lock-free state manager
@@ -360,21 +556,113 @@ func NewShardedStateManager(cfg Config) *ShardedStateManager {
 		processingHist: NewLockFreeHistogram(0, 1_000_000),   // 0-1ms
 		riskHist:       NewLockFreeHistogram(0, 100_000),     // 0-100μs
 		broadcastHist:  NewLockFreeHistogram(0, 1_000_000),   // 0-1ms
+		ingestionQ:     NewStreamingQuantiles(),
+		riskQ:          NewStreamingQuantiles(),
+		endToEndQ:      NewStreamingQuantiles(),
+		rejections:     NewRejectionHistogram(),
+		latency:        NewLatencyHistory(cfg.LatencyHistory),
+		equityHistory:  newEquityHistory(cfg.RiskAdjusted),
+		symbols:        NewSymbolRegistry(cfg.Symbols, cfg.SymbolAliases),
+		allocator:      allocator.New(strategyLimits(cfg)),
+		hub:            ws.NewHub(),
+		deps:           health.New(cfg.Dependencies, health.DefaultTimeout),
+		retention:      cfg.Retention.withDefaults(),
+		execution:      NewExecutionTracker(),
+		triggers:       NewTriggerBook(cfg.TriggerStore),
+		orderGroups:    NewOrderGroupBook(),
+		gateway:        cfg.Gateway,
+		forwarder:      newForwarder(cfg.Exec),
+		audit:          cfg.AuditLog,
+		persist:        NewPersister(cfg.Persistence),
+		fillBandBps:    int64(cfg.FillPriceBandPct * 100),
+		minNotional:    toFixed(cfg.MinNotional),
 		config:         cfg,
 		startTime:      time.Now(),
 	}
 
+	if cfg.StandbyOf != "" {
+		sm.standby = 1 // Until Follow starts replicating
+	}
+	if sm.audit == nil && sm.persist != nil {
+		sm.audit = sm.persist
+	}
+	sm.riskPool = NewRiskPool(sm, cfg.RiskPool)
+
+	switch strings.ToUpper(cfg.OversizePolicy) {
+	case "", OversizeReject:
+	case OversizeClamp:
+		sm.clampOversize = true
+	default:
+		log.Fatalf("[RISK] Unknown oversize policy %q", cfg.OversizePolicy)
+	}
+
+	switch sm.costBasis = strings.ToUpper(cfg.CostBasis); sm.costBasis {
+	case "":
+		sm.costBasis = CostBasisAverage
+	case CostBasisAverage, CostBasisFIFO, CostBasisLIFO:
+	default:
+		log.Fatalf("[PNL] Unknown cost basis %q", cfg.CostBasis)
+	}
+
+	sm.clock = cfg.Clock
+	if sm.clock == nil {
+		sm.clock = systemClock{}
+	}
+	calendar, err := NewSessionCalendar(cfg.Sessions, sm.symbols)
+	if err != nil {
+		log.Fatalf("[SESSION] %v", err)
+	}
+	sm.calendar = calendar
+	if err := sm.config.HWMReset.validate(); err != nil {
+		log.Fatalf("[RISK] High water mark %v", err)
+	}
+	if err := sm.config.Breaker.validate(cfg.MaxDrawdownPct); err != nil {
+		log.Fatalf("[RISK] Breaker %v", err)
+	}
+	sm.startBreakerWarmup("startup")
+	if sm.groups, err = newSymbolGroups(cfg.SymbolGroups, sm.symbols); err != nil {
+		log.Fatalf("[RISK] Symbol group %v", err)
+	}
+
+	sm.hub.SetCommandHandler(authorizeWSControl, sm.handleWSCommand)
+	sm.hub.SetOriginCheck(newCORSPolicy(cfg.CORSOrigins).checkOrigin)
+	if err := sm.hub.Configure(cfg.WS); err != nil {
+		log.Fatalf("[WS] %v", err)
+	}
+
 	// Initialize state
-	sm.state.Equity = 100_000_00_000_000 // $100,000 in fixed-point
-	sm.state.Cash = 100_000_00_000_000
-	sm.state.HighWaterMark = 100_000_00_000_000
+	if cfg.StartingEquity <= 0 {
+		cfg.StartingEquity = DefaultStartingEquity
+		sm.config.StartingEquity = cfg.StartingEquity
+	}
+	sm.startingEquity = toFixed(cfg.StartingEquity)
+	sm.state.Equity = sm.startingEquity
+	sm.state.Cash = sm.startingEquity
+	sm.state.HighWaterMark = sm.startingEquity
+	sm.sessionEquity = sm.startingEquity
+	sm.bookValue = sm.startingEquity
+	sm.allocator.Rebalance(sm.state.Equity)
 
 	// Initialize shards
 	for i := 0; i < NumShards; i++ {
 		sm.shards[i].positions = make(map[uint64]*PositionOptimized, 16)
+		sm.shards[i].lots = make(map[uint64][]costLot, 16)
+		sm.shards[i].scaling = make(map[uint64]scaleHistory, 16)
+		sm.shards[i].fills = make(map[uint64]positionFills, 16)
+		sm.shards[i].tagPnL = make(map[string]int64, 4)
+		sm.shards[i].capital = make(map[uint64]map[string]int64, 16)
 		sm.shards[i].orders = make(map[uint64]*OrderOptimized, 16)
 	}
 
+	sm.feed = NewFeedIngester(sm)
+
+	if err := sm.restoreTriggers(); err != nil {
+		log.Fatalf("[TRIGGER] %v", err)
+	}
+	if err := sm.restoreLatency(); err != nil {
+		log.Printf("[LATENCY] Histograms neither restored nor saved: %v", err)
+	}
+
 	return sm
 }
 
@@ -387,52 +675,263 @@ func (sm *ShardedStateManager) GetShard(symbolHash uint64) *StateShard {
 // LOCK-FREE RISK CHECK - O(1)
 // ============================================================================
 
-// RiskCheckFast performs risk validation without locks
-func (sm *ShardedStateManager) RiskCheckFast(symbolHash uint64, side uint8, quantity, price int64) (approved bool, reason string, latencyNs int64) {
+// RiskCheckResult - outcome of a pre-trade risk check
+type RiskCheckResult struct {
+	Approved   bool
+	Reason     RiskReason
+	Quantity   int64 // Order quantity after lot-size rounding, capping or clamping
+	Price      int64 // Price the order was valued at: its limit, or the last price for a market order
+	LatencyNs  int64
+	Violations []RiskReason // Every failed check, in check order; RiskCheckAll only
+	Group      string       // Symbol group over its cap, with ReasonGroupLimitExceeded
+}
+
+// riskVerdict collects failed checks: fail-fast stops at the first, a full
+// check keeps going and lists them all
+type riskVerdict struct {
+	all        bool
+	first      RiskReason
+	violations []RiskReason
+	group      string // The symbol group over its cap, if any
+}
+
+// reject records a failed check and reports whether checking stops
+func (v *riskVerdict) reject(reason RiskReason) bool {
+	if v.first == ReasonApproved {
+		v.first = reason
+	}
+	if !v.all {
+		return true
+	}
+	v.violations = append(v.violations, reason)
+	return false
+}
+
+// RiskCheckFast performs risk validation without locks, stopping at the
+// first failed check.
+// A zero price denotes a market order and skips the tick-size check.
+// Reduce-only orders read their position under the shard read lock.
+func (sm *ShardedStateManager) RiskCheckFast(order *OrderOptimized) RiskCheckResult {
+	return sm.riskCheck(order, &riskVerdict{})
+}
+
+// RiskCheckAll runs every check RiskCheckFast does without stopping at the
+// first failure, and lists each failed one in Violations, so a preview can
+// report everything wrong with an order at once. Reason is the rejection
+// RiskCheckFast would return. Checks that size the order run on its
+// unrounded quantity when lot rounding leaves nothing.
+func (sm *ShardedStateManager) RiskCheckAll(order *OrderOptimized) RiskCheckResult {
+	return sm.riskCheck(order, &riskVerdict{all: true})
+}
+
+func (sm *ShardedStateManager) riskCheck(order *OrderOptimized, v *riskVerdict) RiskCheckResult {
 	start := time.Now()
+	side, quantity := order.Side, order.Quantity
+
+	// Every check values a market order at the last price
+	price, priced := order.Price, true
+	if price == 0 {
+		price, priced = sm.feed.LastPrice(order.SymbolHash)
+	}
+
+	// Kill switch check - atomic load. A blown account may still close.
+	if atomic.LoadInt32(&sm.state.KillSwitch) != 0 && !(order.ReduceOnly && sm.AccountBlown()) && v.reject(ReasonKillSwitch) {
+		return sm.rejectRisk(ReasonKillSwitch, start)
+	}
+
+	// A standby mirrors the active's orders and never takes its own
+	if atomic.LoadInt32(&sm.standby) != 0 && v.reject(ReasonStandby) {
+		return sm.rejectRisk(ReasonStandby, start)
+	}
+
+	// Trading hours - immutable calendar
+	if !sm.calendar.IsOpen(order.SymbolHash, sm.clock.Now()) && v.reject(ReasonMarketClosed) {
+		return sm.rejectRisk(ReasonMarketClosed, start)
+	}
+
+	// Reduce-only: capped to the opposing position, rejected without one
+	if order.ReduceOnly {
+		reducible := sm.reducibleQuantity(order.SymbolHash, side)
+		if reducible <= 0 {
+			if v.reject(ReasonReduceOnlyViolation) {
+				return sm.rejectRisk(ReasonReduceOnlyViolation, start)
+			}
+		} else {
+			quantity = min(quantity, reducible)
+		}
+	}
+
+	// Contract specification checks - immutable registry
+	spec := sm.symbols.Get(order.SymbolHash)
+	if rounded := spec.RoundLot(quantity); rounded > 0 {
+		quantity = rounded
+	} else if v.reject(ReasonBelowLotSize) {
+		return sm.rejectRisk(ReasonBelowLotSize, start)
+	}
+	if order.Price != 0 && !spec.OnTick(order.Price) && v.reject(ReasonInvalidTickSize) {
+		return sm.rejectRisk(ReasonInvalidTickSize, start)
+	}
 
-	// Kill switch check - atomic load
-	if atomic.LoadInt32(&sm.state.KillSwitch) != 0 {
-		atomic.AddUint64(&sm.riskRejections, 1)
-		sm.riskHist.Record(time.Since(start).Nanoseconds())
-		return false, "KILL_SWITCH_ACTIVE", time.Since(start).Nanoseconds()
+	// Reducing risk is always allowed past the pause, minimums and exposure
+	// limits
+	if order.ReduceOnly {
+		result := sm.riskVerdictResult(v, ReasonApproved, quantity, start)
+		result.Price = price
+		return result
+	}
+
+	// A market order with no last price cannot be sized against any limit
+	if !priced && v.reject(ReasonNoMarketPrice) {
+		return sm.rejectRisk(ReasonNoMarketPrice, start)
+	}
+
+	// Order minimums
+	if reason := sm.belowMinimum(order.SymbolHash, spec, quantity, price); reason != ReasonApproved && v.reject(reason) {
+		return sm.rejectRisk(reason, start)
+	}
+
+	// Equity at or below the floor - atomic load
+	if sm.AccountBlown() && v.reject(ReasonAccountBlown) {
+		return sm.rejectRisk(ReasonAccountBlown, start)
+	}
+
+	// Pause - atomic load
+	if atomic.LoadInt32(&sm.state.TradingPaused) != 0 && v.reject(ReasonTradingPaused) {
+		return sm.rejectRisk(ReasonTradingPaused, start)
+	}
+
+	// Drawdown past the reduce-only tier - atomic load
+	if sm.DrawdownReduceOnly() && v.reject(ReasonDrawdownReduceOnly) {
+		return sm.rejectRisk(ReasonDrawdownReduceOnly, start)
+	}
+
+	// Per-symbol halt, e.g. on a volatility spike
+	if sm.SymbolHalted(order.SymbolHash) && v.reject(ReasonSymbolHalted) {
+		return sm.rejectRisk(ReasonSymbolHalted, start)
+	}
+
+	// Short selling - a sell past the long it closes opens or adds to a
+	// short, whatever the order type
+	if side != 0 && !sm.shortingAllowed(spec) && quantity > sm.reducibleQuantity(order.SymbolHash, side) && v.reject(ReasonShortingNotAllowed) {
+		return sm.rejectRisk(ReasonShortingNotAllowed, start)
 	}
 
 	// Drawdown check - atomic loads
 	drawdown := atomic.LoadInt64(&sm.state.CurrentDrawdown)
 	maxDrawdown := int64(sm.config.MaxDrawdownPct * 100) // Convert to basis points
-	if drawdown >= maxDrawdown {
-		atomic.AddUint64(&sm.riskRejections, 1)
-		sm.riskHist.Record(time.Since(start).Nanoseconds())
-		return false, "MAX_DRAWDOWN", time.Since(start).Nanoseconds()
+	if drawdown >= maxDrawdown && v.reject(ReasonMaxDrawdown) {
+		return sm.rejectRisk(ReasonMaxDrawdown, start)
+	}
+
+	// Position size check - oversize orders are rejected, or under the
+	// CLAMP policy cut to the largest whole lot within the limit
+	approval := ReasonApproved
+	notional := notionalValue(spec, quantity, price)
+	maxNotional := sm.maxPositionNotional()
+	if notional > maxNotional {
+		clamped := int64(0)
+		if sm.clampOversize {
+			clamped = spec.RoundLot(maxQuantity(spec, maxNotional, price))
+		}
+		if clamped > 0 {
+			quantity = clamped
+			notional = notionalValue(spec, quantity, price)
+			approval = ReasonPositionClamped
+		} else if v.reject(ReasonPositionTooLarge) {
+			return sm.rejectRisk(ReasonPositionTooLarge, start)
+		}
+	}
+
+	// Scale-in guardrails - adds read their position under the shard read lock
+	if spec.ScalesIn() && !sm.scaleInAllowed(spec, side, quantity, price) && v.reject(ReasonScaleInViolation) {
+		return sm.rejectRisk(ReasonScaleInViolation, start)
 	}
 
-	// Position size check
-	notional := (quantity * price) / PriceScale
-	if notional > int64(sm.config.MaxPositionSize*float64(PriceScale)) {
-		atomic.AddUint64(&sm.riskRejections, 1)
-		sm.riskHist.Record(time.Since(start).Nanoseconds())
-		return false, "POSITION_TOO_LARGE", time.Since(start).Nanoseconds()
+	// Symbol group caps - members read under their shard read locks
+	if group := sm.groupLimitBreached(order.SymbolHash, spec, side, quantity, price); group != "" {
+		if v.group == "" {
+			v.group = group
+		}
+		if v.reject(ReasonGroupLimitExceeded) {
+			result := sm.rejectRisk(ReasonGroupLimitExceeded, start)
+			result.Group = group
+			return result
+		}
 	}
 
 	// Daily loss limit check
 	dailyPnL := atomic.LoadInt64(&sm.state.DailyPnL)
-	if dailyPnL < -int64(sm.config.DailyLossLimit*float64(PriceScale)) {
-		atomic.AddUint64(&sm.riskRejections, 1)
-		sm.riskHist.Record(time.Since(start).Nanoseconds())
-		return false, "DAILY_LOSS_LIMIT", time.Since(start).Nanoseconds()
+	if dailyPnL < -int64(sm.config.DailyLossLimit*float64(PriceScale)) && v.reject(ReasonDailyLossLimit) {
+		return sm.rejectRisk(ReasonDailyLossLimit, start)
+	}
+
+	// Cash availability check - the fill debits commission on top of
+	// notional, and working buys already hold part of the cash
+	cash := atomic.LoadInt64(&sm.state.Cash) - atomic.LoadInt64(&sm.cashReserved)
+	if side == 0 && notional+estimatedCommission(spec, notional) > cash && v.reject(ReasonInsufficientCapital) { // side 0 = Buy
+		return sm.rejectRisk(ReasonInsufficientCapital, start)
+	}
+
+	// Strategy allocation check - capped even when cash is available
+	if order.Strategy != "" && sm.allocator.Enabled() {
+		if err := sm.allocator.Check(order.Strategy, notional); err != nil && v.reject(allocationReason(err)) {
+			return sm.rejectRisk(allocationReason(err), start)
+		}
+	}
+
+	result := sm.riskVerdictResult(v, approval, quantity, start)
+	result.Price = price
+	return result
+}
+
+// riskVerdictResult approves the order unless a full check collected
+// failures, in which case the first is the rejection
+func (sm *ShardedStateManager) riskVerdictResult(v *riskVerdict, approval RiskReason, quantity int64, start time.Time) RiskCheckResult {
+	if v.first == ReasonApproved {
+		return sm.approveRisk(approval, quantity, start)
 	}
+	result := sm.rejectRisk(v.first, start)
+	result.Violations = v.violations
+	result.Group = v.group
+	return result
+}
 
-	// Cash availability check
-	cash := atomic.LoadInt64(&sm.state.Cash)
-	if side == 0 && notional > cash { // side 0 = Buy
-		sm.riskHist.Record(time.Since(start).Nanoseconds())
-		return false, "INSUFFICIENT_CAPITAL", time.Since(start).Nanoseconds()
+// belowMinimum checks an order against the symbol's minimum quantity and
+// notional at price; an unpriced market order passes the notional floor,
+// rejected for want of a price instead
+func (sm *ShardedStateManager) belowMinimum(symbolHash uint64, spec *SymbolSpec, quantity, price int64) RiskReason {
+	if quantity < spec.MinQuantity {
+		return ReasonBelowMinQuantity
+	}
+	floor := spec.MinNotional
+	if floor == 0 {
+		floor = sm.minNotional
+	}
+	if floor <= 0 || price == 0 {
+		return ReasonApproved
+	}
+	if notionalValue(spec, quantity, price) < floor {
+		return ReasonBelowMinNotional
 	}
+	return ReasonApproved
+}
+
+// approveRisk records the latency of an approved check
+func (sm *ShardedStateManager) approveRisk(reason RiskReason, quantity int64, start time.Time) RiskCheckResult {
+	latency := time.Since(start).Nanoseconds()
+	sm.riskHist.Record(latency)
+	sm.riskQ.Record(latency)
+	return RiskCheckResult{Approved: true, Reason: reason, Quantity: quantity, LatencyNs: latency}
+}
 
+// rejectRisk records a rejection against the total and per-reason counters
+func (sm *ShardedStateManager) rejectRisk(reason RiskReason, start time.Time) RiskCheckResult {
+	atomic.AddUint64(&sm.riskRejections, 1)
+	sm.rejections.Record(reason)
 	latency := time.Since(start).Nanoseconds()
 	sm.riskHist.Record(latency)
-	return true, "APPROVED", latency
+	sm.riskQ.Record(latency)
+	return RiskCheckResult{Reason: reason, LatencyNs: latency}
 }
 
 // ============================================================================
@@ -441,96 +940,310 @@ func (sm *ShardedStateManager) RiskCheckFast(symbolHash uint64, side uint8, quan
 
 // UpdatePosition atomically updates a position
 func (sm *ShardedStateManager) UpdatePosition(symbolHash uint64, side uint8, quantity, price int64) {
-	shard := sm.GetShard(symbolHash)
+	sm.ApplyFill(&FillEvent{
+		SymbolHash: symbolHash,
+		Side:       side,
+		Quantity:   quantity,
+		Price:      price,
+	})
+}
+
+// ApplyFill applies an execution to its position and debits commission.
+//
+// Cash moves by notional: a buy that opens or adds pays its notional, a
+// short sale is credited its proceeds, and the position carries that
+// amount as Cost. Closing quantity returns its share of Cost along with
+// its realized PnL, so opening and closing at the same price returns cash
+// to its start minus commissions. Equity is marked from bookValue - Cash
+// plus the Cost of open positions - which moves only by realized PnL and
+// commission.
+// A fill larger than the opposing position closes it and opens the other
+// side with the remainder. A reduction that would leave less than one lot
+// closes the position outright, and a sub-lot remainder opens nothing.
+//
+// A reduce-only fill - flagged by the venue or belonging to a reduce-only
+// order - never opens or flips a position: without an opposing position it
+// is quarantined whole, and any excess over the position is quarantined
+// after the close. Quarantined quantity moves neither position nor cash.
+// A malformed fill, or one priced outside the sanity band around the last
+// market price, is quarantined before it touches its order or position, as
+// is a late fill for an order already filled, cancelled or rejected.
+func (sm *ShardedStateManager) ApplyFill(fill *FillEvent) {
+	if malformedFill(fill) {
+		sm.quarantineFill(*fill, QuarantineMalformedFill, 0)
+		return
+	}
+	if market, anomalous := sm.fillPriceAnomaly(fill); anomalous {
+		sm.quarantineFill(*fill, QuarantineFillPriceAnomaly, market)
+		return
+	}
+	sm.applyFeeSchedule(fill)
+	sm.bookFill(fill)
+}
+
+// bookFill applies a fill past the price sanity check, which a standby's
+// replicated fills already passed on the active
+func (sm *ShardedStateManager) bookFill(fill *FillEvent) {
+	shard := sm.GetShard(fill.SymbolHash)
 	shard.mu.Lock()
 
-	pos, exists := shard.positions[symbolHash]
+	if status := sm.retiredStatus(shard, fill.OrderID); orderTerminal(status) {
+		// A terminal order cannot move again, e.g. CANCELLED to PARTIAL
+		shard.mu.Unlock()
+		sm.orderAnomaly(fill.OrderID, status, OrderPartial)
+		sm.quarantineFill(*fill, QuarantineTerminalOrder, 0)
+		return
+	}
+	strategy, tag, reduceOnly, breachedLimit, reserved := sm.fillOrderLocked(shard, fill)
+	reduceOnly = reduceOnly || fill.ReduceOnly
+	if breachedLimit != 0 {
+		defer sm.publishLimitBreach(fill, breachedLimit)
+	}
+	if sm.orderGroups.Grouped(fill.OrderID) {
+		_, working := shard.orders[fill.OrderID]
+		defer sm.fillGroupMember(fill, !working)
+	}
+
+	var excess int64 // Reduce-only quantity beyond the position
+	pos, exists := shard.positions[fill.SymbolHash]
+	if reduceOnly && (!exists || pos.Side == fill.Side) {
+		// Nothing to reduce, e.g. after a replay inconsistency
+		shard.mu.Unlock()
+		sm.quarantineFill(*fill, QuarantineReduceWithoutPosition, 0)
+		return
+	}
+	spec := sm.symbols.Get(fill.SymbolHash)
+	sm.execution.RecordLiquidity(spec, fill)
 	if !exists {
 		pos = positionPool.Get().(*PositionOptimized)
-		pos.SymbolHash = symbolHash
-		pos.Side = side
-		pos.EntryPrice = price
-		shard.positions[symbolHash] = pos
+		pos.SymbolHash = fill.SymbolHash
+		pos.Side = fill.Side
+		pos.EntryPrice = fill.Price
+		pos.OpenedAt = time.Now().UnixNano()
+		pos.Tag = tag
+		shard.positions[fill.SymbolHash] = pos
 	}
 
+	var realized int64 // This fill's realized PnL, for the event
 	// Update position
-	if pos.Side == side {
+	if pos.Side == fill.Side {
 		// Increasing position
-		totalCost := pos.EntryPrice*pos.Quantity + price*quantity
-		pos.Quantity += quantity
+		sm.openLot(shard, pos, fill.Quantity, fill.Price)
+		recordScale(shard, fill, !exists)
+		role := FillAdd
+		if !exists {
+			role = FillOpen
+		}
+		sm.recordPositionFill(shard, fill, role, fill.Quantity)
+		shard.holdCapital(fill.SymbolHash, strategy, reserved) // Now backs the position
+		prevQty := pos.Quantity
+		pos.Quantity += fill.Quantity
 		if pos.Quantity > 0 {
-			pos.EntryPrice = totalCost / pos.Quantity
+			// Weighted average in 128-bit intermediates - price × qty overflows int64
+			pos.EntryPrice = mulDiv(pos.EntryPrice, prevQty, pos.Quantity) + mulDiv(fill.Price, fill.Quantity, pos.Quantity)
+		}
+		pos.Commission += fill.Commission
+		cost := positionCost(spec, fill.Side, fill.Quantity, fill.Price)
+		pos.Cost += cost
+
+		// A buy pays its notional, a short sale is credited it
+		atomic.AddInt64(&sm.state.Cash, -cost-fill.Commission)
+		atomic.AddInt64(&sm.bookValue, -fill.Commission)
+		shard.tagPnL[pos.Tag] -= fill.Commission
+		if strategy != "" {
+			sm.recordStrategyPnL(strategy, -fill.Commission)
 		}
 	} else {
-		// Reducing position
+		// Reducing position - PnL only on the quantity actually closed,
+		// against the entry the cost basis assigns it. A sub-lot residue
+		// closes with the fill rather than linger as a phantom position.
+		closed := min(fill.Quantity, pos.Quantity)
+		if spec.IsDust(pos.Quantity - closed) {
+			closed = pos.Quantity
+		}
+		entry := sm.closeLots(shard, pos, closed)
+		sm.recordPositionFill(shard, fill, FillReduce, closed)
 		var pnl int64
 		if pos.Side == 0 { // Long
-			pnl = (price - pos.EntryPrice) * quantity / PriceScale
+			pnl = mulDiv(fill.Price-entry, closed, PriceScale)
 		} else { // Short
-			pnl = (pos.EntryPrice - price) * quantity / PriceScale
+			pnl = mulDiv(entry-fill.Price, closed, PriceScale)
 		}
+		pnl = spec.RoundMoney(spec.ApplyMultiplier(pnl))
 		pos.RealizedPnL += pnl
-		pos.Quantity -= quantity
+		realized = pnl
 
-		// Update cash atomically
-		atomic.AddInt64(&sm.state.Cash, pnl)
+		if strategy != "" {
+			sm.recordStrategyPnL(strategy, pnl-fill.Commission)
+		}
+
+		remainder := max(fill.Quantity-closed, 0)
+		if reduceOnly {
+			excess, remainder = remainder, 0 // Never flips
+		}
+		if spec.IsDust(remainder) {
+			remainder = 0 // Too small to open the other side with
+		}
+		// A flip's commission is split pro rata: the closed quantity's
+		// share is charged to the closing tag, the remainder's to the
+		// position it opens
+		openCommission := mulDiv(fill.Commission, remainder, fill.Quantity)
+
+		// The capital behind the closed quantity is freed under the
+		// strategies that reserved it, and the reducing order's own
+		// reservation for it too. What it reserved for a flip's remainder
+		// backs the new position; for anything else it is freed.
+		sm.releaseCapital(shard, fill.SymbolHash, closed, pos.Quantity)
+		freed := takeShare(&reserved, closed, fill.Quantity)
+		if remainder == 0 {
+			freed, reserved = freed+reserved, 0
+		}
+		if freed != 0 {
+			sm.allocator.Release(strategy, freed)
+		}
 
-		if pos.Quantity <= 0 {
-			delete(shard.positions, symbolHash)
+		// Entry commission and cost leave with the closed quantity
+		released := mulDiv(pos.Cost, closed, pos.Quantity)
+		pos.Commission -= mulDiv(pos.Commission, closed, pos.Quantity)
+		pos.Cost -= released
+		pos.Quantity -= closed
+
+		// Update cash atomically - the closed quantity's cost comes back
+		// with its PnL
+		atomic.AddInt64(&sm.state.Cash, released+pnl-fill.Commission)
+		atomic.AddInt64(&sm.bookValue, pnl-fill.Commission)
+		shard.tagPnL[pos.Tag] += pnl - (fill.Commission - openCommission)
+
+		if remainder > 0 {
+			// Flip - the remainder opens the other side and carries its
+			// share of this fill's commission into the new breakeven
+			*pos = PositionOptimized{
+				SymbolHash:  fill.SymbolHash,
+				Side:        fill.Side,
+				Quantity:    remainder,
+				EntryPrice:  fill.Price,
+				RealizedPnL: pos.RealizedPnL,
+				Commission:  openCommission,
+				Cost:        positionCost(spec, fill.Side, remainder, fill.Price),
+				OpenedAt:    time.Now().UnixNano(),
+				Tag:         tag,
+			}
+			atomic.AddInt64(&sm.state.Cash, -pos.Cost)
+			shard.tagPnL[tag] -= openCommission
+			shard.holdCapital(fill.SymbolHash, strategy, reserved)
+			sm.resetLots(shard, pos)
+			recordScale(shard, fill, true)
+			sm.recordPositionFill(shard, fill, FillOpen, remainder)
+		} else if pos.Quantity == 0 {
+			sm.resetLots(shard, pos)
+			delete(shard.scaling, fill.SymbolHash)
+			delete(shard.fills, fill.SymbolHash)
+			delete(shard.positions, fill.SymbolHash)
+			*pos = PositionOptimized{}
 			positionPool.Put(pos)
+			pos = nil
 		}
 	}
 
-	pos.UpdatedAt = time.Now().UnixNano()
+	if pos != nil {
+		pos.BreakevenPrice = breakevenPrice(pos)
+		// Re-marked now rather than on the next tick, which would leave a
+		// reduced position showing the unrealized PnL of its old quantity
+		pos.UnrealizedPnL = unrealizedPnL(sm.symbols.Get(fill.SymbolHash), pos, markPrice(pos))
+		pos.UpdatedAt = time.Now().UnixNano()
+	}
+
+	// Sequenced under the shard lock so a snapshot sees the fill and its
+	// sequence ID together
+	seq := sm.nextSequence()
 	shard.mu.Unlock()
 
-	// Update sequence ID atomically
-	atomic.AddUint64(&sm.state.SequenceID, 1)
+	atomic.AddUint64(&sm.totalFills, 1)
+	sm.publishFill(fill, reduceOnly, realized, seq)
+	sm.persistTrade(fill, tag, reduceOnly, realized, seq)
+	sm.recordFill(fill, reduceOnly, realized, seq)
+
+	if excess > 0 {
+		q := *fill
+		q.Quantity, q.Commission = excess, 0 // Commission stays booked on the close
+		sm.quarantineFill(q, QuarantineReduceExceedsPosition, 0)
+	}
+}
+
+// breakevenPrice returns the price at which the position is flat net of fees
+func breakevenPrice(pos *PositionOptimized) int64 {
+	if pos.Quantity <= 0 {
+		return pos.EntryPrice
+	}
+	perUnit := mulDiv(pos.Commission, PriceScale, pos.Quantity)
+	if pos.Side == 0 { // Long
+		return pos.EntryPrice + perUnit
+	}
+	return pos.EntryPrice - perUnit // Short
 }
 
-// UpdateTick processes a market tick - lock-free
+// UpdateTick processes a market tick. The position is re-marked under the
+// shard's write lock: readers copy it under the read lock.
 func (sm *ShardedStateManager) UpdateTick(tick *MarketTickOptimized) {
 	start := time.Now()
 
+	spec := sm.symbols.Get(tick.SymbolHash)
 	shard := sm.GetShard(tick.SymbolHash)
-	shard.mu.RLock()
+	shard.mu.Lock()
 	pos, exists := shard.positions[tick.SymbolHash]
+	var unrealized int64
 	if exists {
 		pos.CurrentPrice = tick.LastPrice
-		if pos.Side == 0 { // Long
-			pos.UnrealizedPnL = (tick.LastPrice - pos.EntryPrice) * pos.Quantity / PriceScale
-		} else { // Short
-			pos.UnrealizedPnL = (pos.EntryPrice - tick.LastPrice) * pos.Quantity / PriceScale
-		}
+		pos.UnrealizedPnL = unrealizedPnL(spec, pos, tick.LastPrice)
+		unrealized = pos.UnrealizedPnL
 	}
-	shard.mu.RUnlock()
+	shard.mu.Unlock()
 
 	// Update global state atomically
 	sm.recomputePortfolioState()
+	if exists {
+		sm.checkPositionProfit(tick.SymbolHash, unrealized)
+	}
+
+	// Release stop / limit-if-touched orders the trade touched
+	sm.evaluateTriggers(tick.SymbolHash, tick.LastPrice)
 
 	// Record latency
-	latency := time.Since(start).Nanoseconds()
+	now := time.Now()
+	latency := now.Sub(start).Nanoseconds()
 	sm.ingestionHist.Record(latency)
+	sm.ingestionQ.Record(latency)
+	if tick.Timestamp > 0 && now.UnixNano() >= tick.Timestamp { // A skewed source clock can run ahead
+		sm.endToEndQ.Record(now.UnixNano() - tick.Timestamp)
+	}
 	atomic.AddUint64(&sm.totalTicks, 1)
 }
 
 // recomputePortfolioState updates global metrics atomically
 func (sm *ShardedStateManager) recomputePortfolioState() {
 	// Sum positions from all shards
-	var totalUnrealized int64
+	var totalUnrealized, initialMargin, maintMargin int64
 	for i := 0; i < NumShards; i++ {
 		sm.shards[i].mu.RLock()
 		for _, pos := range sm.shards[i].positions {
 			totalUnrealized += pos.UnrealizedPnL
+			initial, maint := positionMargin(sm.symbols.Get(pos.SymbolHash), pos)
+			initialMargin += initial
+			maintMargin += maint
 		}
 		sm.shards[i].mu.RUnlock()
 	}
 
-	// Update equity
-	cash := atomic.LoadInt64(&sm.state.Cash)
-	equity := cash + totalUnrealized
+	// Update equity - marked from bookValue rather than Cash, which a
+	// fill moves by its notional before this pass sees the position
+	equity := atomic.LoadInt64(&sm.bookValue) + totalUnrealized
 	atomic.StoreInt64(&sm.state.Equity, equity)
-	atomic.StoreInt64(&sm.state.TotalPnL, equity-100_000_00_000_000)
+	atomic.StoreInt64(&sm.state.TotalPnL, equity-sm.startingEquity)
+	dailyPnL := equity - atomic.LoadInt64(&sm.sessionEquity)
+	atomic.StoreInt64(&sm.state.DailyPnL, dailyPnL)
+	sm.updateMargin(equity, initialMargin, maintMargin)
+	sm.checkBlown(equity)
+	sm.checkProfitTarget(dailyPnL)
 
 	// Update high water mark
 	hwm := atomic.LoadInt64(&sm.state.HighWaterMark)
@@ -541,17 +1254,11 @@ func (sm *ShardedStateManager) recomputePortfolioState() {
 
 	// Calculate drawdown
 	if hwm > 0 {
-		drawdown := (hwm - equity) * 10000 / hwm // Basis points
-		atomic.StoreInt64(&sm.state.CurrentDrawdown, drawdown)
+		atomic.StoreInt64(&sm.state.CurrentDrawdown, drawdownBps(hwm, equity))
 	}
 
-	// Auto kill-switch on max drawdown
-	maxDD := int64(sm.config.MaxDrawdownPct * 100)
-	currentDD := atomic.LoadInt64(&sm.state.CurrentDrawdown)
-	if currentDD >= maxDD && sm.config.KillSwitchEnabled {
-		atomic.StoreInt32(&sm.state.KillSwitch, 1)
-		log.Printf("[CIRCUIT BREAKER] Drawdown %d bps >= limit %d bps", currentDD, maxDD)
-	}
+	// Reduce-only, then kill switch, on sustained drawdown
+	sm.checkBreaker(atomic.LoadInt64(&sm.state.CurrentDrawdown), int64(sm.config.MaxDrawdownPct*100))
 
 	atomic.StoreInt64(&sm.state.Timestamp, time.Now().UnixNano())
 }
@@ -668,22 +1375,96 @@ var sinLUT = NewSinLUT(65536)
 // HTTP HANDLERS - Zero Allocation
 // ============================================================================
 
-func setupHTTPRoutes(sm *ShardedStateManager) *http.ServeMux {
+func setupHTTPRoutes(sm *ShardedStateManager) http.Handler {
 	mux := http.NewServeMux()
 
-	// Health check - pre-allocated response
+	// Health check - pre-allocated response, dependency status from the
+	// background checker
 	mux.HandleFunc("/api/health", func(w http.ResponseWriter, r *http.Request) {
 		buf := bufferPool.Get().(*[]byte)
 		defer bufferPool.Put(buf)
 
-		n := copy(*buf, `{"status":"healthy","service":"go-orchestrator-zero","uptime_ns":`)
-		n += copy((*buf)[n:], fmt.AppendInt(nil, time.Since(sm.startTime).Nanoseconds()))
-		n += copy((*buf)[n:], `,"kill_switch":`)
-		n += copy((*buf)[n:], fmt.AppendInt(nil, int64(atomic.LoadInt32(&sm.state.KillSwitch))))
-		n += copy((*buf)[n:], `}`)
+		status := sm.deps.Overall()
+		unackedCritical := sm.UnackedCriticalAlerts()
+		if status == health.StatusHealthy && (sm.feed.SlowConsumerSustained() || unackedCritical > 0) {
+			status = health.StatusDegraded
+		}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.Write((*buf)[:n])
+		b := append((*buf)[:0], `{"status":"`...)
+		b = append(b, status...)
+		b = append(b, `","service":"go-orchestrator-zero","uptime_ns":`...)
+		b = strconv.AppendInt(b, time.Since(sm.startTime).Nanoseconds(), 10)
+		b = append(b, `,"kill_switch":`...)
+		b = strconv.AppendInt(b, int64(atomic.LoadInt32(&sm.state.KillSwitch)), 10)
+		b = append(b, `,"trading_paused":`...)
+		b = strconv.AppendInt(b, int64(atomic.LoadInt32(&sm.state.TradingPaused)), 10)
+		b = append(b, `,"standby":`...)
+		b = strconv.AppendBool(b, sm.Standby())
+		b = append(b, `,"paper_trading":`...)
+		b = strconv.AppendBool(b, sm.PaperTrading())
+		b = append(b, `,"clock_skew_events":`...)
+		b = strconv.AppendUint(b, sm.feed.ClockSkewEvents(), 10)
+		b = append(b, `,"last_clock_skew_ns":`...)
+		b = strconv.AppendInt(b, sm.feed.LastClockSkewNs(), 10)
+		b = append(b, `,"stale_ticks":`...)
+		b = strconv.AppendUint(b, sm.feed.StaleTicks(), 10)
+		b = append(b, `,"invalid_ticks":`...)
+		b = strconv.AppendUint(b, sm.feed.InvalidTicks(), 10)
+		b = append(b, `,"coalesced_ticks":`...)
+		b = strconv.AppendUint(b, sm.feed.CoalescedTicks(), 10)
+		b = append(b, `,"snapped_ticks":`...)
+		b = strconv.AppendUint(b, sm.feed.SnappedTicks(), 10)
+		b = append(b, `,"duplicate_fills":`...)
+		b = strconv.AppendUint(b, sm.feed.DuplicateFills(), 10)
+		b = append(b, `,"feed_gaps":`...)
+		b = strconv.AppendUint(b, sm.feed.FeedGaps(), 10)
+		b = append(b, `,"nats_slow_consumer":`...)
+		b = strconv.AppendUint(b, sm.feed.NATSSlowConsumer(), 10)
+		b = append(b, `,"nats_dropped_msgs":`...)
+		b = strconv.AppendUint(b, sm.feed.NATSDroppedMsgs(), 10)
+		b = append(b, `,"nats_reconnects":`...)
+		b = strconv.AppendUint(b, sm.feed.NATSReconnects(), 10)
+		b = append(b, `,"feed_slow_consumer":`...)
+		b = strconv.AppendBool(b, sm.feed.SlowConsumerSustained())
+		b = append(b, `,"quarantined_fills":`...)
+		b = strconv.AppendUint(b, sm.QuarantinedFills(), 10)
+		b = append(b, `,"unacked_critical_alerts":`...)
+		b = strconv.AppendInt(b, int64(unackedCritical), 10)
+		b = append(b, `,"order_anomalies":`...)
+		b = strconv.AppendUint(b, sm.OrderAnomalies(), 10)
+		b = append(b, `,"marshal_errors":`...)
+		b = strconv.AppendUint(b, sm.MarshalErrors(), 10)
+		ps := sm.persist.Stats()
+		b = append(b, `,"persist_queued":`...)
+		b = strconv.AppendInt(b, int64(ps.Queued), 10)
+		b = append(b, `,"persist_dropped":`...)
+		b = strconv.AppendUint(b, ps.Dropped, 10)
+		b = append(b, `,"persist_failed":`...)
+		b = strconv.AppendUint(b, ps.Failed, 10)
+		es := sm.forwarder.Stats()
+		b = append(b, `,"exec_queued":`...)
+		b = strconv.AppendInt(b, int64(es.Queued), 10)
+		b = append(b, `,"exec_published":`...)
+		b = strconv.AppendUint(b, es.Published, 10)
+		b = append(b, `,"exec_retries":`...)
+		b = strconv.AppendUint(b, es.Retries, 10)
+		b = append(b, `,"exec_failures":`...)
+		b = strconv.AppendUint(b, es.Failures, 10)
+		b = append(b, `,"exec_rejected":`...)
+		b = strconv.AppendUint(b, es.Rejected, 10)
+		rs := sm.riskPool.Stats()
+		b = append(b, `,"risk_queue_depth":`...)
+		b = strconv.AppendInt(b, int64(rs.QueueDepth), 10)
+		b = append(b, `,"risk_queue_rejected":`...)
+		b = strconv.AppendUint(b, rs.Rejected, 10)
+
+		b = append(b, `,"strategies":`...)
+		b = appendStrategyBreakers(b, sm.allocator.Snapshot())
+		b = append(b, `,"dependencies":`...)
+		b = appendDependencies(b, sm.deps.Snapshot())
+		b = append(b, '}')
+
+		handlers.WriteJSON(w, r, http.StatusOK, b)
 	})
 
 	// Portfolio state - atomic reads
@@ -692,19 +1473,39 @@ func setupHTTPRoutes(sm *ShardedStateManager) *http.ServeMux {
 		defer bufferPool.Put(buf)
 
 		n := copy(*buf, `{"equity":`)
-		n += copy((*buf)[n:], fmt.AppendFloat(nil, float64(atomic.LoadInt64(&sm.state.Equity))/float64(PriceScale), 'f', 2, 64))
+		n += copy((*buf)[n:], strconv.AppendFloat(nil, float64(atomic.LoadInt64(&sm.state.Equity))/float64(PriceScale), 'f', 2, 64))
 		n += copy((*buf)[n:], `,"cash":`)
-		n += copy((*buf)[n:], fmt.AppendFloat(nil, float64(atomic.LoadInt64(&sm.state.Cash))/float64(PriceScale), 'f', 2, 64))
+		n += copy((*buf)[n:], strconv.AppendFloat(nil, float64(atomic.LoadInt64(&sm.state.Cash))/float64(PriceScale), 'f', 2, 64))
+		n += copy((*buf)[n:], `,"starting_equity":`)
+		n += copy((*buf)[n:], strconv.AppendFloat(nil, float64(sm.startingEquity)/float64(PriceScale), 'f', 2, 64))
+		n += copy((*buf)[n:], `,"total_pnl":`)
+		n += copy((*buf)[n:], strconv.AppendFloat(nil, float64(atomic.LoadInt64(&sm.state.TotalPnL))/float64(PriceScale), 'f', 2, 64))
 		n += copy((*buf)[n:], `,"drawdown_bps":`)
-		n += copy((*buf)[n:], fmt.AppendInt(nil, atomic.LoadInt64(&sm.state.CurrentDrawdown)))
+		n += copy((*buf)[n:], strconv.AppendInt(nil, atomic.LoadInt64(&sm.state.CurrentDrawdown), 10))
 		n += copy((*buf)[n:], `,"kill_switch":`)
-		n += copy((*buf)[n:], fmt.AppendInt(nil, int64(atomic.LoadInt32(&sm.state.KillSwitch))))
+		n += copy((*buf)[n:], strconv.AppendInt(nil, int64(atomic.LoadInt32(&sm.state.KillSwitch)), 10))
+		n += copy((*buf)[n:], `,"drawdown_reduce_only":`)
+		n += copy((*buf)[n:], strconv.AppendBool(nil, sm.DrawdownReduceOnly()))
+		n += copy((*buf)[n:], `,"account_blown":`)
+		n += copy((*buf)[n:], strconv.AppendBool(nil, sm.AccountBlown()))
+		n += copy((*buf)[n:], `,"profit_target_hit":`)
+		n += copy((*buf)[n:], strconv.AppendBool(nil, sm.ProfitTargetHit()))
+		n += copy((*buf)[n:], `,"paper_trading":`)
+		n += copy((*buf)[n:], strconv.AppendBool(nil, sm.PaperTrading()))
+		initialMargin, maintMargin, marginCall := sm.MarginRequirement()
+		n += copy((*buf)[n:], `,"initial_margin":`)
+		n += copy((*buf)[n:], strconv.AppendFloat(nil, float64(initialMargin)/float64(PriceScale), 'f', 2, 64))
+		n += copy((*buf)[n:], `,"maintenance_margin":`)
+		n += copy((*buf)[n:], strconv.AppendFloat(nil, float64(maintMargin)/float64(PriceScale), 'f', 2, 64))
+		n += copy((*buf)[n:], `,"margin_available":`)
+		n += copy((*buf)[n:], strconv.AppendFloat(nil, float64(atomic.LoadInt64(&sm.state.Equity)-initialMargin)/float64(PriceScale), 'f', 2, 64))
+		n += copy((*buf)[n:], `,"margin_call":`)
+		n += copy((*buf)[n:], strconv.AppendBool(nil, marginCall))
 		n += copy((*buf)[n:], `,"seq_id":`)
-		n += copy((*buf)[n:], fmt.AppendUint(nil, atomic.LoadUint64(&sm.state.SequenceID)))
+		n += copy((*buf)[n:], strconv.AppendUint(nil, atomic.LoadUint64(&sm.state.SequenceID), 10))
 		n += copy((*buf)[n:], `}`)
 
-		w.Header().Set("Content-Type", "application/json")
-		w.Write((*buf)[:n])
+		handlers.WriteJSON(w, r, http.StatusOK, (*buf)[:n])
 	})
 
 	// Latency metrics - atomic reads
@@ -712,98 +1513,220 @@ func setupHTTPRoutes(sm *ShardedStateManager) *http.ServeMux {
 		buf := bufferPool.Get().(*[]byte)
 		defer bufferPool.Put(buf)
 
-		n := copy(*buf, `{"ticks":`)
-		n += copy((*buf)[n:], fmt.AppendUint(nil, atomic.LoadUint64(&sm.totalTicks)))
-		n += copy((*buf)[n:], `,"ingestion_p50_us":`)
-		n += copy((*buf)[n:], fmt.AppendInt(nil, sm.ingestionHist.Percentile(50)/1000))
-		n += copy((*buf)[n:], `,"ingestion_p99_us":`)
-		n += copy((*buf)[n:], fmt.AppendInt(nil, sm.ingestionHist.Percentile(99)/1000))
-		n += copy((*buf)[n:], `,"risk_p50_ns":`)
-		n += copy((*buf)[n:], fmt.AppendInt(nil, sm.riskHist.Percentile(50)))
-		n += copy((*buf)[n:], `,"risk_rejections":`)
-		n += copy((*buf)[n:], fmt.AppendUint(nil, atomic.LoadUint64(&sm.riskRejections)))
-		n += copy((*buf)[n:], `}`)
-
-		w.Header().Set("Content-Type", "application/json")
-		w.Write((*buf)[:n])
+		b := append((*buf)[:0], `{"ticks":`...)
+		b = strconv.AppendUint(b, atomic.LoadUint64(&sm.totalTicks), 10)
+		b = append(b, `,"ingestion_p50_us":`...)
+		b = strconv.AppendInt(b, sm.ingestionHist.Percentile(50)/1000, 10)
+		b = append(b, `,"ingestion_p99_us":`...)
+		b = strconv.AppendInt(b, sm.ingestionHist.Percentile(99)/1000, 10)
+		b = append(b, `,"risk_p50_ns":`...)
+		b = strconv.AppendInt(b, sm.riskHist.Percentile(50), 10)
+		b = append(b, `,"risk_p99_ns":`...)
+		b = strconv.AppendInt(b, sm.riskHist.Percentile(99), 10)
+		b = append(b, `,"risk_rejections":`...)
+		b = strconv.AppendUint(b, atomic.LoadUint64(&sm.riskRejections), 10)
+		b = append(b, `,"quantiles":{"ingestion":`...)
+		b = sm.ingestionQ.appendJSON(b)
+		b = append(b, `,"risk":`...)
+		b = sm.riskQ.appendJSON(b)
+		b = append(b, `,"end_to_end":`...)
+		b = sm.endToEndQ.appendJSON(b)
+		b = append(b, `}}`...)
+
+		handlers.WriteJSON(w, r, http.StatusOK, b)
 	})
 
-	// Risk check - lock-free
+	// Latency history - per-minute buckets, ?window= narrows
+	mux.HandleFunc("/api/metrics/latency/history", sm.handleLatencyHistory)
+
+	// Rolling Sharpe and Sortino of sampled equity
+	mux.HandleFunc("/api/metrics/risk-adjusted", sm.handleRiskAdjusted)
+
+	// Risk check - lock-free; ?all=true runs every check and lists each
+	// failure under "violations" instead of stopping at the first
 	mux.HandleFunc("/api/risk/check", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "POST required", http.StatusMethodNotAllowed)
 			return
 		}
+		all, _ := strconv.ParseBool(r.URL.Query().Get("all"))
 
-		buf := bufferPool.Get().(*[]byte)
-		defer bufferPool.Put(buf)
-
-		// Read body
-		body := make([]byte, 128)
-		n, _ := r.Body.Read(body)
-		body = body[:n]
+		order, errResp := sm.decodeOrder(w, r)
+		if errResp != nil {
+			handlers.WriteError(w, r, errResp)
+			return
+		}
 
-		// Parse minimal fields (assume binary format)
-		// In production, use proper binary parsing
-		symbolHash := SymbolHashBTC
-		side := uint8(0)
-		quantity := int64(1_00_000_000) // 1 unit
-		price := int64(67_500_00_000_000)
+		var result RiskCheckResult
+		if all {
+			result = sm.RiskCheckAll(order) // A preview, off the worker pool
+		} else {
+			var err error
+			if result, err = sm.checkRisk(order); err != nil {
+				handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusServiceUnavailable, Error: err.Error()})
+				return
+			}
+		}
 
-		approved, reason, latency := sm.RiskCheckFast(symbolHash, side, quantity, price)
+		buf := bufferPool.Get().(*[]byte)
+		defer bufferPool.Put(buf)
 
-		n = copy(*buf, `{"approved":`)
-		if approved {
+		n := copy(*buf, `{"approved":`)
+		if result.Approved {
 			n += copy((*buf)[n:], `true`)
 		} else {
 			n += copy((*buf)[n:], `false`)
 		}
 		n += copy((*buf)[n:], `,"reason":"`)
-		n += copy((*buf)[n:], reason)
-		n += copy((*buf)[n:], `","latency_ns":`)
-		n += copy((*buf)[n:], fmt.AppendInt(nil, latency))
-		n += copy((*buf)[n:], `}`)
+		n += copy((*buf)[n:], result.Reason.String())
+		n += copy((*buf)[n:], `","quantity":`)
+		n += copy((*buf)[n:], appendFixed(nil, result.Quantity))
+		n += copy((*buf)[n:], `,"latency_ns":`)
+		n += copy((*buf)[n:], strconv.AppendInt(nil, result.LatencyNs, 10))
+		b := (*buf)[:n]
+		if result.Group != "" {
+			b = append(b, `,"group":`...)
+			b = strconv.AppendQuote(b, result.Group)
+		}
+		if all {
+			b = append(b, `,"violations":[`...)
+			for i, reason := range result.Violations {
+				if i > 0 {
+					b = append(b, ',')
+				}
+				b = append(b, '"')
+				b = append(b, reason.String()...)
+				b = append(b, '"')
+			}
+			b = append(b, ']')
+		}
+		b = append(b, '}')
 
-		w.Header().Set("Content-Type", "application/json")
-		w.Write((*buf)[:n])
+		handlers.WriteJSON(w, r, http.StatusOK, b)
 	})
 
-	// Kill switch
-	mux.HandleFunc("/api/kill-switch", func(w http.ResponseWriter, r *http.Request) {
+	// Rejection breakdown by reason - atomic reads
+	mux.HandleFunc("/api/risk/rejections", sm.handleRiskRejections)
+
+	// Operator alert inbox - GET newest first, POST {id}/ack to acknowledge
+	mux.HandleFunc("/api/alerts", sm.handleAlerts)
+	mux.Handle("/api/alerts/{id}/ack", adminOnly(sm.handleAlertAck))
+
+	// Risk check worker pool - queue depth and per-worker throughput
+	mux.HandleFunc("/api/risk/workers", sm.handleRiskWorkers)
+
+	// Session boundary (admin) - re-baselines the daily loss limit
+	mux.Handle("/api/session/reset", adminOnly(sm.handleSessionReset))
+
+	// Contract specifications - immutable registry
+	mux.HandleFunc("/api/symbols", sm.handleSymbols)
+
+	// Weighted mid / microprice - cached per tick by the feed
+	mux.HandleFunc("/api/signals/microprice", sm.handleMicroprice)
+
+	// Gross/net exposure - shard read locks
+	mux.HandleFunc("/api/risk/exposure", sm.handleExposure)
+
+	// What-if price shock: projected equity, drawdown and limit breaches
+	mux.HandleFunc("/api/risk/stress", sm.handleStress)
+
+	// Two-step manual high water mark reset (admin)
+	mux.Handle("/api/risk/hwm/reset", adminOnly(sm.handleHWMReset))
+
+	// Order entry and working orders
+	mux.HandleFunc("/api/orders", sm.handleOrders)
+
+	// Emergency cancel of every working order and trigger (admin)
+	mux.Handle("/api/orders/cancel-all", adminOnly(sm.handleCancelAll))
+
+	// Trigger orders: GET list, POST arm and DELETE ?id= cancel (admin)
+	mux.Handle("/api/orders/triggers", adminWrites(sm.handleTriggers))
+
+	// OCO order groups: GET list, POST group and DELETE ?id= ungroup (admin)
+	mux.Handle("/api/orders/groups", adminWrites(sm.handleOrderGroups))
+
+	// Implementation shortfall per symbol
+	mux.HandleFunc("/api/execution/quality", sm.handleExecutionQuality)
+
+	// Forward-only sequence rebase (admin), e.g. after an import
+	mux.Handle("/api/sequence/rebase", adminOnly(sm.handleSequenceRebase))
+
+	// Blue/green handoff: promote this standby to active (admin)
+	mux.Handle("/api/standby/promote", adminOnly(sm.handleStandbyPromote))
+
+	// Per-strategy capital allocation; rebalance on demand (admin)
+	mux.HandleFunc("/api/allocator", sm.handleAllocator)
+	mux.Handle("/api/allocator/rebalance", adminOnly(sm.handleAllocatorRebalance))
+
+	// WebSocket stream and per-client slow-consumer metrics (admin)
+	mux.HandleFunc("/ws", sm.hub.ServeWS)
+	mux.Handle("/api/ws/clients", adminOnly(sm.handleWSClients))
+	mux.Handle("/api/ws/replay", adminOnly(sm.handleWSReplay))
+
+	// Fills quarantined instead of applied
+	mux.HandleFunc("/api/fills/quarantine", sm.handleQuarantinedFills)
+
+	// Open positions - shard read locks
+	mux.HandleFunc("/api/positions", sm.handlePositions)
+	mux.HandleFunc("/api/positions/{symbol}", sm.handlePosition)
+	mux.HandleFunc("/api/positions/{symbol}/fills", sm.handlePositionFills)
+	mux.HandleFunc("/api/positions/{symbol}/close-estimate", sm.handleCloseEstimate)
+
+	// Booked fills by time - ?symbol=&from=&to=&limit=&after=
+	mux.HandleFunc("/api/fills", sm.handleFills)
+
+	// PnL per strategy attribution tag
+	mux.HandleFunc("/api/pnl/by-tag", sm.handlePnLByTag)
+
+	// Per-symbol trigger auto-management: GET manual symbols, POST toggle (admin)
+	mux.Handle("/api/positions/automanage", adminWrites(sm.handleAutoManage))
+
+	// Synthetic fills and ticks - dev/sim mode only
+	registerSimRoutes(mux, sm)
+
+	// Kill switch - POST (admin) with {"active":bool,"at":RFC 3339} body
+	// or ?active=false; see ApplyKillSwitch for when a clear is refused
+	mux.Handle("/api/kill-switch", adminWrites(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodPost:
-			var active int32 = 1
-			if r.URL.Query().Get("active") == "false" {
-				active = 0
+			req := KillSwitchRequest{Active: true, Source: KillSourceManual, Actor: "anonymous"}
+			if claims := auth.GetClaims(r.Context()); claims != nil {
+				req.Actor = claims.Username
 			}
-			atomic.StoreInt32(&sm.state.KillSwitch, active)
-
-			buf := bufferPool.Get().(*[]byte)
-			defer bufferPool.Put(buf)
-			n := copy(*buf, `{"active":`)
-			if active == 1 {
-				n += copy((*buf)[n:], `true}`)
-			} else {
-				n += copy((*buf)[n:], `false}`)
+			if r.ContentLength != 0 {
+				var body struct {
+					Active *bool      `json:"active"`
+					At     *time.Time `json:"at"` // When the operator decided; defaults to arrival
+				}
+				if errResp := handlers.DecodeJSON(w, r, &body); errResp != nil {
+					handlers.WriteError(w, r, errResp)
+					return
+				}
+				if body.Active != nil && !*body.Active {
+					req.Active = false
+				}
+				if body.At != nil {
+					req.At = *body.At
+				}
+			} else if r.URL.Query().Get("active") == "false" {
+				req.Active = false
 			}
-			w.Header().Set("Content-Type", "application/json")
-			w.Write((*buf)[:n])
+			if _, err := sm.ApplyKillSwitch(req); err != nil {
+				handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusConflict, Error: err.Error(), Field: "active"})
+				return
+			}
+			sm.writeKillSwitch(w, r)
 
 		case http.MethodGet:
-			buf := bufferPool.Get().(*[]byte)
-			defer bufferPool.Put(buf)
-			n := copy(*buf, `{"active":`)
-			if atomic.LoadInt32(&sm.state.KillSwitch) != 0 {
-				n += copy((*buf)[n:], `true}`)
-			} else {
-				n += copy((*buf)[n:], `false}`)
-			}
-			w.Header().Set("Content-Type", "application/json")
-			w.Write((*buf)[:n])
+			sm.writeKillSwitch(w, r)
 		}
-	})
+	}))
+
+	// Per-route request metrics (Prometheus) and server spans
+	metrics := middleware.NewRouteMetrics(mux)
+	mux.Handle("/metrics", metrics)
 
-	return mux
+	return metrics.Middleware(mux)
 }
 
 // ============================================================================
@@ -811,15 +1734,212 @@ func setupHTTPRoutes(sm *ShardedStateManager) *http.ServeMux {
 // ============================================================================
 
 func main() {
+	verify := flag.Bool("verify", false, "Replay the recorded trades against the last persisted snapshot, report divergences and exit (4 on mismatch)")
+	flag.Parse()
+
 	cfg := Config{
+		StartingEquity:    DefaultStartingEquity,
 		MaxDrawdownPct:    5.0,
 		MaxPositionSize:   100_000.0,
+		OversizePolicy:    OversizeReject,
+		FillPriceBandPct:  10.0,
 		DailyLossLimit:    10_000.0,
 		KillSwitchEnabled: true,
 		HTTPPort:          8090,
+		GRPCPort:          9090,
+		Symbols: []SymbolMeta{
+			{Symbol: "BTCUSD", TickSize: 0.01, LotSize: 0.00001, Multiplier: 1, Currency: "USD"},
+			{Symbol: "ETHUSD", TickSize: 0.01, LotSize: 0.0001, Multiplier: 1, Currency: "USD"},
+			{Symbol: "SOLUSD", TickSize: 0.001, LotSize: 0.01, Multiplier: 1, Currency: "USD"},
+		},
+		SymbolAliases: map[string]string{
+			"XBTUSD": "BTCUSD",
+		},
+		Strategies: []allocator.Strategy{
+			{Name: "gann", Weight: 0.5},
+			{Name: "ehlers", Weight: 0.5},
+		},
+		StrategyLimits: StrategyLimits{MaxDrawdownPct: 3.0, DailyLossLimit: 5_000.0},
+		JWTSecret:      os.Getenv("JWT_SECRET"),
+		OTLPEndpoint:   os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"),
+		StandbyOf:      os.Getenv("STANDBY_OF"),
+		HWMReset:       HWMResetPolicy{Mode: os.Getenv("HWM_RESET_MODE"), Period: os.Getenv("HWM_RESET_PERIOD")},
+	}
+	cfg.SimMode, _ = strconv.ParseBool(os.Getenv("SIM_MODE"))
+	cfg.Paper.Enabled, _ = strconv.ParseBool(os.Getenv("PAPER_TRADING"))
+	cfg.SnapTickPrices, _ = strconv.ParseBool(os.Getenv("SNAP_TICK_PRICES"))
+	cfg.ShortingDisabled, _ = strconv.ParseBool(os.Getenv("SHORTING_DISABLED"))
+	if v := os.Getenv("BLOWN_EQUITY_FLOOR"); v != "" {
+		floor, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			log.Fatalf("[RISK] BLOWN_EQUITY_FLOOR: %v", err)
+		}
+		cfg.BlownEquityFloor = floor
+	}
+	if v := os.Getenv("DAILY_PROFIT_TARGET"); v != "" {
+		target, err := strconv.ParseFloat(v, 64)
+		if err != nil || target < 0 {
+			log.Fatalf("[RISK] DAILY_PROFIT_TARGET must be a non-negative number, got %q", v)
+		}
+		cfg.DailyProfitTarget = target
 	}
+	if v := os.Getenv("POSITION_PROFIT_TARGET"); v != "" {
+		target, err := strconv.ParseFloat(v, 64)
+		if err != nil || target < 0 {
+			log.Fatalf("[RISK] POSITION_PROFIT_TARGET must be a non-negative number, got %q", v)
+		}
+		cfg.PositionProfitTarget = target
+	}
+	cfg.ProfitTargetFlatten, _ = strconv.ParseBool(os.Getenv("PROFIT_TARGET_FLATTEN"))
+	if v := os.Getenv("PAPER_SLIPPAGE_BPS"); v != "" {
+		bps, err := strconv.ParseFloat(v, 64)
+		if err != nil || bps < 0 {
+			log.Fatalf("[PAPER] PAPER_SLIPPAGE_BPS must be a non-negative number, got %q", v)
+		}
+		cfg.Paper.SlippageBps = bps
+	}
+	if origins := os.Getenv("CORS_ORIGINS"); origins != "" {
+		cfg.CORSOrigins = strings.Split(origins, ",")
+	}
+	if v := os.Getenv("RISK_WORKERS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			log.Fatalf("[RISK] RISK_WORKERS must be a non-negative integer, got %q", v)
+		}
+		cfg.RiskPool.Workers = n
+	}
+	if v := os.Getenv("STALE_ORDER_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("[ORDERS] STALE_ORDER_TIMEOUT: %v", err)
+		}
+		cfg.StaleOrders.Timeout = d
+	}
+	cfg.StaleOrders.AutoCancel, _ = strconv.ParseBool(os.Getenv("STALE_ORDER_CANCEL"))
+	if v := os.Getenv("EQUITY_SAMPLE_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("[METRICS] EQUITY_SAMPLE_INTERVAL: %v", err)
+		}
+		cfg.RiskAdjusted.SampleInterval = d
+	}
+	if v := os.Getenv("RISK_ADJUSTED_WINDOW"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 2 {
+			log.Fatalf("[METRICS] RISK_ADJUSTED_WINDOW must be an integer of at least 2, got %q", v)
+		}
+		cfg.RiskAdjusted.Window = n
+	}
+	if v := os.Getenv("BREAKER_WARMUP"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("[RISK] BREAKER_WARMUP: %v", err)
+		}
+		cfg.Breaker.WarmupDuration = d
+	}
+
+	// Downstream services, probed only when configured
+	for _, dep := range []health.Dependency{
+		{Name: "nats", URL: os.Getenv("NATS_URL"), Critical: true},
+		{Name: "rust_gateway", URL: os.Getenv("GATEWAY_HEALTH_URL")},
+		{Name: "python_ai", URL: os.Getenv("AI_HEALTH_URL")},
+	} {
+		if dep.URL != "" {
+			cfg.Dependencies = append(cfg.Dependencies, dep)
+		}
+	}
+
+	if cfg.JWTSecret != "" {
+		if _, err := auth.InitAuth(cfg.JWTSecret); err != nil {
+			log.Fatalf("[AUTH] %v", err)
+		}
+	} else {
+		log.Printf("[AUTH] JWT_SECRET not set - admin endpoints are unauthenticated")
+	}
+
+	// Persistence for trigger orders, trades, audit entries and state
+	// snapshots - Postgres when DATABASE_URL is set, else SQLite at
+	// DATABASE_PATH
+	var db interface {
+		PersistenceBackend
+		TriggerStore
+		LedgerSource
+		Close() error
+	}
+	if url := os.Getenv("DATABASE_URL"); url != "" {
+		pg, err := database.OpenPostgres(url)
+		if err != nil {
+			log.Fatalf("[DB] %v", err)
+		}
+		db = pg
+	} else if path := os.Getenv("DATABASE_PATH"); path != "" {
+		lite, err := database.InitDatabase(path)
+		if err != nil {
+			log.Fatalf("[DB] %v", err)
+		}
+		db = lite
+	}
+	if *verify {
+		if db == nil {
+			log.Fatalf("[VERIFY] DATABASE_URL or DATABASE_PATH required")
+		}
+		code := runVerify(cfg, db, os.Stdout)
+		db.Close()
+		os.Exit(code)
+	}
+	if db != nil {
+		cfg.TriggerStore = db
+		cfg.Persistence.Backend = db
+	}
+	cfg.Persistence.LatencyHistograms, _ = strconv.ParseBool(os.Getenv("PERSIST_LATENCY"))
+	if v := os.Getenv("SNAPSHOT_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("[DB] SNAPSHOT_INTERVAL: %v", err)
+		}
+		cfg.Persistence.SnapshotInterval = d
+	}
+
+	// Message bus - the normalized feed in, and the execution channel of
+	// approved orders to the gateway; paper trading never reaches the gateway
+	var nc *nats.Conn
+	if url := os.Getenv("NATS_URL"); url != "" {
+		conn, err := nats.Connect(url, nats.Name("go-orchestrator"), nats.RetryOnFailedConnect(true), nats.MaxReconnects(-1))
+		if err != nil {
+			log.Fatalf("[NATS] %v", err)
+		}
+		nc = conn
+		if !cfg.Paper.Enabled {
+			cfg.Exec.Transport = exec.NATSTransport{Conn: nc}
+			cfg.Exec.Publisher.Subject = os.Getenv("EXEC_SUBJECT")
+		}
+	}
+
+	// Every worker reports panics and fatal errors here
+	coord := NewShutdownCoordinator()
 
 	sm := NewShardedStateManager(cfg)
+	coord.Go("hub", sm.hub.Run)
+	if sm.persist != nil {
+		coord.Go("persist", sm.persist.Run)
+	}
+	if sm.riskPool != nil {
+		coord.Go("risk", sm.riskPool.Run)
+	}
+	if nc != nil {
+		nc.SetErrorHandler(sm.feed.NATSErrorHandler())
+		nc.SetReconnectHandler(sm.feed.NATSReconnectHandler())
+	}
+	if nc != nil && !cfg.Paper.Enabled {
+		coord.Go("exec", sm.forwarder.Run)
+		subject := os.Getenv("EXEC_REJECT_SUBJECT")
+		if subject == "" {
+			subject = exec.RejectSubject
+		}
+		if _, err := nc.Subscribe(subject, sm.NATSRejectHandler()); err != nil {
+			log.Fatalf("[EXEC] Subscribe %s: %v", subject, err)
+		}
+	}
 
 	log.Println("╔═══════════════════════════════════════════════════════════════╗")
 	log.Println("║  CENAYANG MARKET — Go Zero-Bottleneck Edition v3.0            ║")
@@ -837,76 +1957,140 @@ func main() {
 	log.Printf("[Init] Histogram buckets: %d (O(1) percentile)", HistogramBuckets)
 	log.Printf("[Init] Sin/Cos LUT: 65536 entries")
 	log.Printf("[Init] Cache-line padding: %d bytes", CacheLineSize)
+	if cfg.Paper.Enabled {
+		log.Printf("[PAPER] Paper trading - approved orders are filled at the last price with %.2f bps slippage, never sent to the gateway", cfg.Paper.SlippageBps)
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	shutdownTracing, err := initTracing(ctx, cfg.OTLPEndpoint)
+	if err != nil {
+		log.Fatalf("[TRACE] %v", err)
+	}
+
+	// Session boundaries
+	coord.Go("session", func() { sm.Run(ctx) })
+
+	// Feed ingestion - ticks, and gateway fills unless paper trading fills
+	// its own orders; the subscriptions end with ctx
+	if nc != nil {
+		tickSubject := os.Getenv("FEED_TICK_SUBJECT")
+		if tickSubject == "" {
+			tickSubject = TickSubject
+		}
+		fillSubject := ""
+		if !cfg.Paper.Enabled {
+			if fillSubject = os.Getenv("EXEC_FILL_SUBJECT"); fillSubject == "" {
+				fillSubject = exec.FillSubject
+			}
+		}
+		subs, err := sm.feed.SubscribeNATS(nc, tickSubject, fillSubject)
+		if err != nil {
+			log.Fatalf("[FEED] Subscribe: %v", err)
+		}
+		log.Printf("[FEED] Ticks on %q, fills on %q", tickSubject, fillSubject)
+		coord.Go("feed", func() {
+			<-ctx.Done()
+			for _, sub := range subs {
+				sub.Unsubscribe()
+			}
+		})
+	}
+
+	// Blue/green standby - replicate until promoted
+	if cfg.StandbyOf != "" {
+		conn, err := sm.followActive(ctx, cfg.StandbyOf)
+		if err != nil {
+			log.Fatalf("[STANDBY] %v", err)
+		}
+		defer conn.Close()
+		log.Printf("[STANDBY] Following %s", cfg.StandbyOf)
+	}
+
+	// Dependency health
+	coord.Go("deps", func() { sm.deps.Run(ctx, DependencyCheckInterval) })
+
 	// HTTP Server
-	mux := setupHTTPRoutes(sm)
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.HTTPPort),
-		Handler:      corsMiddleware(mux),
+		Handler:      corsMiddleware(cfg.CORSOrigins, setupHTTPRoutes(sm)),
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 10 * time.Second,
 	}
 
-	go func() {
+	coord.Go("http", func() {
 		log.Printf("[HTTP] Listening on :%d", cfg.HTTPPort)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("[HTTP] Server error: %v", err)
+			coord.Trigger(ShutdownFatal, ExitFatal, fmt.Errorf("http server: %w", err))
 		}
-	}()
-
-	// Benchmark goroutine
-	go func() {
-		time.Sleep(2 * time.Second)
-		log.Println("\n[Benchmark] Running 10,000,000 operations...")
-
-		start := time.Now()
-		for i := 0; i < 10_000_000; i++ {
-			// Use pre-computed sin
-			jitter := sinLUT.Sin(float64(i) * 7.31) * 50.0 / 100.0
-			_ = jitter
-
-			// Record latency - lock-free
-			sm.ingestionHist.Record(800)
-			sm.riskHist.Record(50)
+	})
 
-			// Atomic counter increment
-			atomic.AddUint64(&sm.totalTicks, 1)
+	// gRPC Server
+	grpcServer := NewGRPCServer(sm)
+	if cfg.GRPCPort != 0 {
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPCPort))
+		if err != nil {
+			log.Fatalf("[gRPC] Listen error: %v", err)
 		}
+		coord.Go("grpc", func() {
+			log.Printf("[gRPC] Listening on :%d", cfg.GRPCPort)
+			if err := grpcServer.Serve(lis); err != nil {
+				coord.Trigger(ShutdownFatal, ExitFatal, fmt.Errorf("grpc server: %w", err))
+			}
+		})
+	}
 
-		elapsed := time.Since(start)
-		log.Println("\n[Performance] ═════════════════════════════════════════════")
-		log.Printf("[Performance] Processed 10,000,000 operations in %v", elapsed)
-		log.Printf("[Performance] Rate: %.0f ops/sec", 10_000_000.0/elapsed.Seconds())
-		log.Printf("[Performance] Per-operation: %.2f ns", float64(elapsed.Nanoseconds())/10_000_000.0)
-
-		log.Println("\n[Metrics] ═════════════════════════════════════════════════")
-		log.Printf("[Metrics] Ticks: %d", atomic.LoadUint64(&sm.totalTicks))
-		log.Printf("[Metrics] Ingestion P50: %dμs, P99: %dμs",
-			sm.ingestionHist.Percentile(50)/1000,
-			sm.ingestionHist.Percentile(99)/1000)
-		log.Printf("[Metrics] Risk P50: %dns, P99: %dns",
-			sm.riskHist.Percentile(50),
-			sm.riskHist.Percentile(99))
-
-		log.Println("\n✅ Zero Bottleneck Verified: No mutex locks, no heap allocations, zero GC pressure")
-	}()
-
-	// Graceful shutdown
+	// Graceful shutdown - on a signal, a fatal server error or a worker panic
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-	<-sigCh
-
-	log.Println("[SHUTDOWN] Graceful shutdown initiated")
-	cancel()
-
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer shutdownCancel()
-	server.Shutdown(shutdownCtx)
+	select {
+	case sig := <-sigCh:
+		coord.Trigger(ShutdownSignal, ExitOK, fmt.Errorf("received %v", sig))
+	case <-coord.Done():
+	}
 
-	log.Println("[SHUTDOWN] Complete")
+	// Intake stops and in-flight requests drain before the state is saved,
+	// so the saved state includes everything acknowledged
+	code := coord.Finish([]ShutdownStep{
+		{Name: "workers", Run: func(context.Context) error { cancel(); return nil }},
+		{Name: "http", Run: server.Shutdown},
+		{Name: "hub", Run: func(context.Context) error { sm.hub.Shutdown(); return nil }}, // Ends StreamState streams
+		{Name: "grpc", Run: func(ctx context.Context) error { return stopGRPC(ctx, grpcServer) }},
+		{Name: "risk", Run: func(ctx context.Context) error {
+			if sm.riskPool == nil {
+				return nil
+			}
+			return sm.riskPool.Stop(ctx)
+		}},
+		{Name: "exec", Run: func(ctx context.Context) error {
+			if nc == nil {
+				return nil
+			}
+			err := sm.forwarder.Stop(ctx)
+			nc.Close()
+			return err
+		}},
+		{Name: "persist", Run: func(ctx context.Context) error {
+			if db == nil {
+				return nil
+			}
+			if err := sm.persist.Stop(ctx); err != nil {
+				return err
+			}
+			return sm.PersistState(db)
+		}, FailCode: ExitPersistFailed},
+		{Name: "tracing", Run: shutdownTracing},
+		{Name: "db", Run: func(context.Context) error {
+			if db == nil {
+				return nil
+			}
+			return db.Close()
+		}},
+	})
+	if code != ExitOK {
+		os.Exit(code)
+	}
 }
 
 // ============================================================================
@@ -914,23 +2098,75 @@ func main() {
 // ============================================================================
 
 type Config struct {
-	HTTPPort          int
-	MaxDrawdownPct    float64
-	MaxPositionSize   float64
-	DailyLossLimit    float64
-	KillSwitchEnabled bool
+	HTTPPort               int
+	GRPCPort               int     // 0 disables the gRPC server
+	StartingEquity         float64 // Account balance at start (0 = DefaultStartingEquity)
+	MaxDrawdownPct         float64
+	MaxPositionSize        float64
+	MaxPositionPctOfEquity float64 // Order notional cap as a percent of live equity; the tighter of this and MaxPositionSize applies (0 = off)
+	MinNotional            float64 // Order notional floor for symbols without their own (0 = none)
+	OversizePolicy         string  // OversizeReject (default) or OversizeClamp
+	CostBasis              string  // CostBasisAverage (default), CostBasisFIFO or CostBasisLIFO
+	FillPriceBandPct       float64 // Max fill deviation from last price (0 = off)
+	DailyLossLimit         float64
+	DailyProfitTarget      float64 // DailyPnL at which opening orders pause until the next session (0 = off)
+	ProfitTargetFlatten    bool    // Also close every position when DailyProfitTarget is hit
+	PositionProfitTarget   float64 // Unrealized PnL at which a position is closed (0 = off)
+	BlownEquityFloor       float64 // Equity at or below this blows the account: kill switch held, only closes allowed
+	ShortingDisabled       bool    // No order may open or add to a short, unless its symbol's ShortingAllowed says otherwise
+	KillSwitchEnabled      bool
+	Breaker                BreakerConfig
+	RiskPool               RiskPoolConfig
+	Symbols                []SymbolMeta
+	SymbolAliases          map[string]string // Alias → canonical; separators are always normalized
+	SymbolGroups           []SymbolGroup     // Aggregate notional caps, e.g. per asset class
+	Strategies             []allocator.Strategy
+	StrategyLimits         StrategyLimits // For strategies without their own
+	JWTSecret              string         // Empty disables auth on admin endpoints
+	CORSOrigins            []string       // Browser origins allowed, "*" = any without credentials; empty = none
+	OTLPEndpoint           string         // OTLP/HTTP traces URL; empty disables span export
+	Dependencies           []health.Dependency
+	Retention              RetentionConfig // Zero policies take DefaultRetention
+	Sessions               SessionConfig
+	Clock                  Clock         // nil = system clock
+	HeartbeatInterval      time.Duration // 0 = DefaultHeartbeatInterval, < 0 disables; also paces compact snapshots
+	TickThrottle           time.Duration // Coalesce each symbol's ticks to one per window (0 = every tick)
+	SnapTickPrices         bool          // Round inbound tick prices to the symbol's tick size
+	MaxFeedSilence         time.Duration // A held symbol without a tick this long is flagged stale (0 = off)
+	StaleOrders            StaleOrderConfig
+	LatencyHistory         time.Duration // Per-minute latency buckets kept (0 = DefaultLatencyHistory, < 0 off)
+	RiskAdjusted           RiskAdjustedConfig
+	StandbyOf              string        // Active's gRPC address; empty runs as active
+	TriggerStore           TriggerStore  // nil = trigger orders do not survive a restart
+	WS                     ws.Config     // Connection deadlines; zero fields take ws.DefaultConfig
+	Gateway                OrderGateway  // nil = the gateway follows order events only
+	Exec                   ExecConfig
+	Paper                  PaperConfig // Fill approved orders here instead of forwarding them
+	SimMode                bool        // Serve /api/sim/*; ignored by production builds
+	HWMReset               HWMResetPolicy
+	VolatilityHalt         VolatilityHaltConfig
+	AuditLog               AuditLog // nil = through Persistence.Backend if set, else audited actions are only logged
+	Persistence            PersistenceConfig
+}
+
+// adminOnly requires admin permission once auth is initialized
+func adminOnly(next http.HandlerFunc) http.Handler {
+	am := auth.GetAuthManager()
+	if am == nil {
+		return next
+	}
+	return am.AuthMiddleware(am.PermissionMiddleware(auth.PermAdmin)(next))
 }
 
-func corsMiddleware(next http.Handler) http.Handler {
+// adminWrites is adminOnly for every method but GET, which stays open
+func adminWrites(next http.HandlerFunc) http.Handler {
+	admin := adminOnly(next)
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			next(w, r)
 			return
 		}
-		next.ServeHTTP(w, r)
+		admin.ServeHTTP(w, r)
 	})
 }
 
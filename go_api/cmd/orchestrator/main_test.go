@@ -0,0 +1,21 @@
+package main
+
+import "time"
+
+// testConfig is a minimal live configuration with the risk limits on
+func testConfig() Config {
+	return Config{MaxDrawdownPct: 5, MaxPositionSize: 1e6, DailyLossLimit: 1e4, KillSwitchEnabled: true}
+}
+
+// fx converts to fixed-point
+func fx(f float64) int64 { return int64(f * float64(PriceScale)) }
+
+// tick feeds a last price through the ingester, unsequenced
+func tick(sm *ShardedStateManager, symbolHash uint64, last float64) {
+	sm.feed.OnTick(&MarketTickOptimized{SymbolHash: symbolHash, LastPrice: fx(last)})
+}
+
+// fakeClock stands still until a test moves it
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.t }
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"sync/atomic"
+
+	"cenayang-market/go-api/internal/ws"
+)
+
+// ============================================================================
+// MARGIN - Initial / Maintenance Requirement of the Open Book
+// ============================================================================
+
+// marginState - requirement as of the last portfolio recompute
+type marginState struct {
+	initial     int64 // Atomic, fixed-point
+	maintenance int64 // Atomic, fixed-point
+	call        int32 // Atomic bool: equity below maintenance
+}
+
+// positionMargin returns a position's initial and maintenance requirement
+// at its mark price
+func positionMargin(spec *SymbolSpec, pos *PositionOptimized) (initial, maintenance int64) {
+	if spec.InitialMarginBps == 0 && spec.MaintMarginBps == 0 {
+		return 0, 0
+	}
+	notional := notionalValue(spec, pos.Quantity, markPrice(pos))
+	return mulDiv(notional, spec.InitialMarginBps, 10000), mulDiv(notional, spec.MaintMarginBps, 10000)
+}
+
+// MarginRequirement returns the initial and maintenance margin of the book
+// and whether a margin call is in effect
+func (sm *ShardedStateManager) MarginRequirement() (initial, maintenance int64, call bool) {
+	m := &sm.margin
+	return atomic.LoadInt64(&m.initial), atomic.LoadInt64(&m.maintenance), atomic.LoadInt32(&m.call) != 0
+}
+
+// updateMargin stores the book's requirement and raises a margin call when
+// equity falls below maintenance. The call is broadcast once on entry and
+// once when equity recovers, not on every tick in between.
+func (sm *ShardedStateManager) updateMargin(equity, initial, maintenance int64) {
+	m := &sm.margin
+	atomic.StoreInt64(&m.initial, initial)
+	atomic.StoreInt64(&m.maintenance, maintenance)
+
+	var call int32
+	if maintenance > 0 && equity < maintenance {
+		call = 1
+	}
+	if atomic.SwapInt32(&m.call, call) == call {
+		return
+	}
+	if call == 1 {
+		log.Printf("[MARGIN] Margin call: equity %s below maintenance %s", appendFixed(nil, equity), appendFixed(nil, maintenance))
+		sm.raiseAlert(AlertCritical, "margin_call", fmt.Sprintf("equity %s below maintenance margin %s", appendFixed(nil, equity), appendFixed(nil, maintenance)))
+	} else {
+		log.Printf("[MARGIN] Margin call cleared: equity %s, maintenance %s", appendFixed(nil, equity), appendFixed(nil, maintenance))
+	}
+
+	seq := atomic.LoadUint64(&sm.state.SequenceID)
+	b := make([]byte, 0, 160)
+	b = append(b, `{"type":"margin_call","active":`...)
+	b = strconv.AppendBool(b, call == 1)
+	b = append(b, `,"equity":`...)
+	b = appendFixed(b, equity)
+	b = append(b, `,"maintenance_margin":`...)
+	b = appendFixed(b, maintenance)
+	b = append(b, `,"initial_margin":`...)
+	b = appendFixed(b, initial)
+	b = append(b, `,"seq_id":`...)
+	b = strconv.AppendUint(b, seq, 10)
+	b = append(b, '}')
+	sm.publish(ws.EventMarginCall, seq, b)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"sync/atomic"
+
+	"github.com/nats-io/nats.go"
+
+	"cenayang-market/go-api/internal/exec"
+)
+
+// ============================================================================
+// NATS FEED - Normalized Ticks and Gateway Fills Off the Bus
+// ============================================================================
+
+// TickSubject is where the normalized feed publishes ticks, one
+// TickWireSize frame per message
+const TickSubject = "feed.ticks"
+
+// NATSTickHandler returns the subscription callback for ticks. NATS runs
+// each subscription's callbacks on a goroutine of its own, in order; a
+// subscriber that falls behind is dropped from on the bus side and shows
+// up as a slow consumer rather than stalling the publisher.
+func (f *FeedIngester) NATSTickHandler() nats.MsgHandler {
+	return func(msg *nats.Msg) {
+		tick := AcquireTick()
+		defer ReleaseTick(tick) // OnTick copies what it holds
+		if !tick.FromBytes(msg.Data) {
+			atomic.AddUint64(&f.invalidTicks, 1)
+			return
+		}
+		f.OnTick(tick)
+	}
+}
+
+// NATSFillHandler returns the subscription callback for gateway fills
+// (exec.Fill on exec.FillSubject)
+func (f *FeedIngester) NATSFillHandler() nats.MsgHandler {
+	return func(msg *nats.Msg) {
+		var fill exec.Fill
+		if err := json.Unmarshal(msg.Data, &fill); err != nil || fill.ID == 0 || (fill.Side != "BUY" && fill.Side != "SELL") {
+			log.Printf("[FEED] Malformed gateway fill on %q: %q", msg.Subject, msg.Data)
+			return
+		}
+		event := &FillEvent{
+			OrderID:    fill.ID,
+			SymbolHash: fill.SymbolHash,
+			Quantity:   fill.Quantity,
+			Price:      fill.Price,
+			Commission: fill.Commission,
+			SeqID:      fill.SeqID,
+			Timestamp:  fill.Timestamp,
+			ReduceOnly: fill.ReduceOnly,
+		}
+		if fill.Side == "SELL" {
+			event.Side = 1
+		}
+		if fill.Maker {
+			event.Liquidity = LiquidityMaker
+		}
+		f.OnFill(event)
+	}
+}
+
+// NATSReconnects returns the number of times the bus connection was
+// re-established
+func (f *FeedIngester) NATSReconnects() uint64 {
+	return atomic.LoadUint64(&f.bus.reconnects)
+}
+
+// NATSReconnectHandler returns the callback to register on the connection
+// (nats.ReconnectHandler). Subscriptions are restored by the client;
+// whatever was published while we were away is gone, counted by the
+// reconnect rather than as a sequence gap.
+func (f *FeedIngester) NATSReconnectHandler() nats.ConnHandler {
+	return func(nc *nats.Conn) {
+		f.resetGaps()
+		n := atomic.AddUint64(&f.bus.reconnects, 1)
+		log.Printf("[FEED] NATS reconnected to %s (%d reconnects)", nc.ConnectedUrlRedacted(), n)
+	}
+}
+
+// SubscribeNATS subscribes the ingester to ticks on tickSubject and, unless
+// fillSubject is empty, to fills on fillSubject
+func (f *FeedIngester) SubscribeNATS(nc *nats.Conn, tickSubject, fillSubject string) ([]*nats.Subscription, error) {
+	sub, err := nc.Subscribe(tickSubject, f.NATSTickHandler())
+	if err != nil {
+		return nil, err
+	}
+	subs := []*nats.Subscription{sub}
+	if fillSubject != "" {
+		sub, err := nc.Subscribe(fillSubject, f.NATSFillHandler())
+		if err != nil {
+			subs[0].Unsubscribe()
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
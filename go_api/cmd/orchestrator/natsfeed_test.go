@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+
+	"cenayang-market/go-api/internal/exec"
+)
+
+func TestNATSHandlers(t *testing.T) {
+	sm := NewShardedStateManager(testConfig())
+	h := sm.symbols.Hash("BUSUSD")
+	onTick, onFill := sm.feed.NATSTickHandler(), sm.feed.NATSFillHandler()
+
+	frame := (&MarketTickOptimized{SymbolHash: h, LastPrice: fx(100), SeqID: 1}).ToBytes(nil)
+	onTick(&nats.Msg{Subject: TickSubject, Data: frame})
+	onTick(&nats.Msg{Subject: TickSubject, Data: frame[:TickWireSize-1]})
+	if last, ok := sm.feed.LastPrice(h); !ok || last != fx(100) || sm.feed.InvalidTicks() != 1 {
+		t.Fatalf("last %d (%v), %d invalid, want 100 and the truncated frame refused", last, ok, sm.feed.InvalidTicks())
+	}
+
+	hash := strconv.FormatUint(h, 10)
+	fill := func(data string) { onFill(&nats.Msg{Subject: exec.FillSubject, Data: []byte(data)}) }
+	fill(`{"id":1,"symbol_hash":` + hash + `,"side":"BUY","quantity":300000000,"price":10000000000,"seq_id":1}`)
+	fill(`{"id":2,"symbol_hash":` + hash + `,"side":"SELL","quantity":100000000,"price":10000000000,"maker":true,"seq_id":2}`)
+	for _, bad := range []string{
+		`not json`,
+		`{"id":0,"symbol_hash":` + hash + `,"side":"BUY","quantity":100000000,"price":10000000000,"seq_id":3}`,
+		`{"id":3,"symbol_hash":` + hash + `,"side":"HOLD","quantity":100000000,"price":10000000000,"seq_id":3}`,
+	} {
+		fill(bad)
+	}
+	pos := sm.GetShard(h).positions[h]
+	if pos == nil || pos.Side != 0 || pos.Quantity != fx(2) {
+		t.Fatalf("position %+v, want long 2 from the well-formed fills only", pos)
+	}
+	if n := sm.QuarantinedFills(); n != 0 {
+		t.Fatalf("%d fills quarantined; malformed messages never reach the book", n)
+	}
+
+	// A reconnect is counted; fills redelivered after it are still dropped
+	sm.feed.NATSReconnectHandler()(nil)
+	fill(`{"id":2,"symbol_hash":` + hash + `,"side":"SELL","quantity":100000000,"price":10000000000,"seq_id":2}`)
+	if sm.feed.NATSReconnects() != 1 || sm.GetShard(h).positions[h].Quantity != fx(2) || sm.feed.DuplicateFills() != 1 {
+		t.Fatalf("%d reconnects, %d duplicates after a redelivery", sm.feed.NATSReconnects(), sm.feed.DuplicateFills())
+	}
+}
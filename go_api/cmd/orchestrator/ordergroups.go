@@ -0,0 +1,503 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"cenayang-market/go-api/internal/handlers"
+	"cenayang-market/go-api/internal/ws"
+)
+
+// ============================================================================
+// ORDER GROUPS - One-Cancels-Other Brackets Over Orders and Triggers
+// ============================================================================
+
+// Order group policies
+const (
+	GroupOCO uint8 = iota // A fill on one member shrinks the others; a complete fill cancels them
+)
+
+var groupPolicyNames = [...]string{
+	GroupOCO: "OCO",
+}
+
+func groupPolicyName(policy uint8) string {
+	if int(policy) < len(groupPolicyNames) {
+		return groupPolicyNames[policy]
+	}
+	return "UNKNOWN"
+}
+
+var (
+	// ErrUnknownOrderGroup - no group has the ID
+	ErrUnknownOrderGroup = errors.New("unknown order group")
+	// ErrGroupMembers - a group needs two distinct live members
+	ErrGroupMembers = errors.New("an order group needs at least two distinct orders")
+	// ErrNotGroupable - a member is neither a working order nor an armed trigger
+	ErrNotGroupable = errors.New("not a working order or armed trigger")
+	// ErrAlreadyGrouped - an order belongs to one group at a time
+	ErrAlreadyGrouped = errors.New("order already grouped")
+)
+
+// OrderGroupMember - one order of a group. A trigger keeps its ID once
+// released, so a bracket's stop leg stays grouped after it fires.
+type OrderGroupMember struct {
+	ID         uint64
+	SymbolHash uint64
+	Quantity   int64 // Open quantity when grouped, the base siblings shrink against
+}
+
+// OrderGroup - orders whose fills act on each other under Policy
+type OrderGroup struct {
+	ID        uint64
+	Policy    uint8
+	Members   []OrderGroupMember
+	CreatedAt int64
+}
+
+// OrderGroupBook holds the live groups. Held in memory only: a restart
+// leaves the members working, ungrouped.
+type OrderGroupBook struct {
+	mu       sync.Mutex
+	byID     map[uint64]*OrderGroup
+	byMember map[uint64]uint64 // Order ID → group ID
+	grouped  int64             // Atomic; lets fills skip the lock when empty
+}
+
+func NewOrderGroupBook() *OrderGroupBook {
+	return &OrderGroupBook{
+		byID:     make(map[uint64]*OrderGroup, 8),
+		byMember: make(map[uint64]uint64, 16),
+	}
+}
+
+// Grouped reports whether an order belongs to a group
+func (b *OrderGroupBook) Grouped(orderID uint64) bool {
+	if atomic.LoadInt64(&b.grouped) == 0 || orderID == 0 {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, ok := b.byMember[orderID]
+	return ok
+}
+
+// removeLocked dissolves g - caller holds the lock
+func (b *OrderGroupBook) removeLocked(g *OrderGroup) {
+	delete(b.byID, g.ID)
+	for _, m := range g.Members {
+		delete(b.byMember, m.ID)
+	}
+	atomic.AddInt64(&b.grouped, -int64(len(g.Members)))
+}
+
+// Remove dissolves a group, leaving its members working, and returns it
+func (b *OrderGroupBook) Remove(id uint64) (OrderGroup, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	g, ok := b.byID[id]
+	if !ok {
+		return OrderGroup{}, ErrUnknownOrderGroup
+	}
+	b.removeLocked(g)
+	return *g, nil
+}
+
+// List returns the groups, oldest first
+func (b *OrderGroupBook) List() []OrderGroup {
+	b.mu.Lock()
+	out := make([]OrderGroup, 0, len(b.byID))
+	for _, g := range b.byID {
+		out = append(out, *g)
+	}
+	b.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// groupMember resolves an order ID to a working order or armed trigger
+// and its open quantity
+func (sm *ShardedStateManager) groupMember(id uint64) (OrderGroupMember, bool) {
+	if t, ok := sm.triggers.Get(id); ok {
+		return OrderGroupMember{ID: id, SymbolHash: t.SymbolHash, Quantity: t.Quantity}, true
+	}
+	v, ok := sm.orderIndex.Load(id)
+	if !ok {
+		return OrderGroupMember{}, false
+	}
+	entry := v.(orderIndexEntry)
+	shard := sm.GetShard(entry.symbolHash)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+	order, ok := shard.orders[id]
+	if !ok || order.Quantity <= order.FilledQty {
+		return OrderGroupMember{}, false
+	}
+	return OrderGroupMember{ID: id, SymbolHash: order.SymbolHash, Quantity: order.Quantity - order.FilledQty}, true
+}
+
+// groupMemberLive reports whether a member still works, as an order or
+// an armed trigger
+func (sm *ShardedStateManager) groupMemberLive(m *OrderGroupMember) bool {
+	if _, ok := sm.triggers.Get(m.ID); ok {
+		return true
+	}
+	return sm.isWorkingOrder(m.ID, m.SymbolHash)
+}
+
+// CreateOrderGroup groups working orders and armed triggers under policy.
+// Groups whose members have all stopped working are dropped first.
+func (sm *ShardedStateManager) CreateOrderGroup(policy uint8, ids []uint64) (OrderGroup, error) {
+	if sm.Standby() {
+		return OrderGroup{}, ErrStandbyOrders
+	}
+	if policy != GroupOCO {
+		return OrderGroup{}, fmt.Errorf("unknown group policy %d", policy)
+	}
+	members := make([]OrderGroupMember, 0, len(ids))
+	seen := make(map[uint64]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return OrderGroup{}, ErrGroupMembers
+		}
+		seen[id] = true
+		m, ok := sm.groupMember(id)
+		if !ok {
+			return OrderGroup{}, fmt.Errorf("order %d: %w", id, ErrNotGroupable)
+		}
+		members = append(members, m)
+	}
+	if len(members) < 2 {
+		return OrderGroup{}, ErrGroupMembers
+	}
+	sm.pruneOrderGroups()
+
+	g := &OrderGroup{ID: sm.orderIDs.Next(), Policy: policy, Members: members, CreatedAt: sm.clock.Now().UnixNano()}
+	b := sm.orderGroups
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, m := range members {
+		if _, ok := b.byMember[m.ID]; ok {
+			return OrderGroup{}, fmt.Errorf("order %d: %w", m.ID, ErrAlreadyGrouped)
+		}
+	}
+	b.byID[g.ID] = g
+	for _, m := range members {
+		b.byMember[m.ID] = g.ID
+	}
+	atomic.AddInt64(&b.grouped, int64(len(members)))
+	log.Printf("[ORDERS] Grouped %d orders as %s %d", len(members), groupPolicyName(policy), g.ID)
+	return *g, nil
+}
+
+// pruneOrderGroups dissolves groups with no working member left, e.g.
+// once every leg was cancelled by hand
+func (sm *ShardedStateManager) pruneOrderGroups() {
+	for _, g := range sm.orderGroups.List() {
+		live := false
+		for i := range g.Members {
+			if sm.groupMemberLive(&g.Members[i]) {
+				live = true
+				break
+			}
+		}
+		if !live {
+			sm.orderGroups.Remove(g.ID)
+		}
+	}
+}
+
+// groupAction - what a member's fill does to one sibling
+type groupAction struct {
+	member OrderGroupMember
+	reduce int64 // Quantity taken off; the whole remainder when cancel
+	cancel bool
+}
+
+// fillGroupMember applies an OCO member's fill to its siblings: each
+// shrinks by the share of the member that filled, and once the member is
+// complete every sibling is cancelled and the group dissolved. Runs after
+// the fill is booked, outside the shard lock.
+func (sm *ShardedStateManager) fillGroupMember(fill *FillEvent, complete bool) {
+	b := sm.orderGroups
+	b.mu.Lock()
+	g, ok := b.byID[b.byMember[fill.OrderID]]
+	if !ok {
+		b.mu.Unlock()
+		return
+	}
+	var filled OrderGroupMember
+	for _, m := range g.Members {
+		if m.ID == fill.OrderID {
+			filled = m
+		}
+	}
+	actions := make([]groupAction, 0, len(g.Members)-1)
+	for _, m := range g.Members {
+		if m.ID == fill.OrderID {
+			continue
+		}
+		reduce := m.Quantity
+		if !complete && filled.Quantity > 0 {
+			reduce = mulDiv(m.Quantity, fill.Quantity, filled.Quantity)
+		}
+		actions = append(actions, groupAction{member: m, reduce: reduce, cancel: complete})
+	}
+	if complete {
+		b.removeLocked(g)
+	}
+	b.mu.Unlock()
+
+	var cancelled []uint64
+	type resized struct {
+		id       uint64
+		quantity int64
+	}
+	var reduced []resized
+	for _, a := range actions {
+		if a.reduce <= 0 {
+			continue
+		}
+		left, ok := sm.shrinkGroupMember(&a.member, a.reduce, a.cancel)
+		switch {
+		case !ok:
+			continue // Already filled, cancelled or fired away
+		case left == 0:
+			cancelled = append(cancelled, a.member.ID)
+		default:
+			reduced = append(reduced, resized{a.member.ID, left})
+		}
+	}
+	state := "partially filled"
+	if complete {
+		state = "filled"
+	}
+	log.Printf("[ORDERS] OCO %d: order %d %s; %d siblings cancelled, %d reduced", g.ID, fill.OrderID, state, len(cancelled), len(reduced))
+
+	seq := atomic.LoadUint64(&sm.state.SequenceID)
+	e := make([]byte, 0, 256)
+	e = append(e, `{"type":"oco_triggered","group_id":"`...)
+	e = strconv.AppendUint(e, g.ID, 10)
+	e = append(e, `","order_id":"`...)
+	e = strconv.AppendUint(e, fill.OrderID, 10)
+	e = append(e, `","filled":`...)
+	e = appendFixed(e, fill.Quantity)
+	e = append(e, `,"complete":`...)
+	e = strconv.AppendBool(e, complete)
+	e = append(e, `,"cancelled":[`...)
+	for i, id := range cancelled {
+		if i > 0 {
+			e = append(e, ',')
+		}
+		e = append(e, '"')
+		e = strconv.AppendUint(e, id, 10)
+		e = append(e, '"')
+	}
+	e = append(e, `],"reduced":[`...)
+	for i, r := range reduced {
+		if i > 0 {
+			e = append(e, ',')
+		}
+		e = append(e, `{"order_id":"`...)
+		e = strconv.AppendUint(e, r.id, 10)
+		e = append(e, `","quantity":`...)
+		e = appendFixed(e, r.quantity)
+		e = append(e, '}')
+	}
+	e = append(e, `],"seq_id":`...)
+	e = strconv.AppendUint(e, seq, 10)
+	e = append(e, '}')
+	sm.publish(ws.EventOCOTriggered, seq, e)
+}
+
+// shrinkGroupMember takes reduce off a sibling's open quantity, cancelling
+// it when cancel is set or nothing is left. Returns the open quantity left
+// and whether the sibling was still live.
+func (sm *ShardedStateManager) shrinkGroupMember(m *OrderGroupMember, reduce int64, cancel bool) (int64, bool) {
+	if t, ok := sm.triggers.Get(m.ID); ok {
+		if left := t.Quantity - reduce; !cancel && left > 0 {
+			if _, err := sm.resizeTrigger(m.ID, left); err != nil {
+				log.Printf("[ORDERS] Resize of trigger %d failed: %v", m.ID, err)
+				return t.Quantity, true
+			}
+			return left, true
+		}
+		if _, err := sm.triggers.Cancel(m.ID); err != nil {
+			return 0, false // Fired meanwhile
+		}
+		return 0, true
+	}
+	return sm.shrinkGroupOrder(m.ID, m.SymbolHash, reduce, cancel)
+}
+
+// shrinkGroupOrder takes reduce off a working order, freeing the capital
+// it reserved, or cancels it through the gateway. The gateway has no
+// amend: a reduced order shrinks here only, and a fill beyond the new
+// quantity books to the position and completes the order.
+func (sm *ShardedStateManager) shrinkGroupOrder(orderID, symbolHash uint64, reduce int64, cancel bool) (int64, bool) {
+	shard := sm.GetShard(symbolHash)
+	shard.mu.Lock()
+	order, ok := shard.orders[orderID]
+	if !ok {
+		shard.mu.Unlock()
+		return 0, false
+	}
+	left := order.Quantity - order.FilledQty - reduce
+	if !cancel && left > 0 {
+		if freed := takeShare(&order.Reserved, reduce, order.Quantity-order.FilledQty); freed != 0 {
+			sm.allocator.Release(order.Strategy, freed)
+		}
+		sm.releaseOrderCash(order, reduce)
+		order.Quantity -= reduce
+		order.SequenceID = sm.nextSequence()
+		order.Timestamp = time.Now().UnixNano()
+		updated := *order
+		shard.mu.Unlock()
+
+		ctx, done := context.WithTimeout(context.Background(), CancelBroadcastTimeout)
+		sm.publishOrderWait(ctx, &updated)
+		done()
+		return left, true
+	}
+	if !sm.transitionOrder(order, OrderCancelled) {
+		shard.mu.Unlock()
+		return 0, false
+	}
+	order.SequenceID = sm.nextSequence()
+	order.Timestamp = time.Now().UnixNano()
+	cancelled := *order
+	sm.cancelOrderLocked(shard, order)
+	shard.mu.Unlock()
+
+	ctx, done := context.WithTimeout(context.Background(), CancelBroadcastTimeout)
+	sm.publishOrderWait(ctx, &cancelled)
+	done()
+
+	if sm.gateway != nil && !sm.PaperTrading() {
+		go sm.sendGatewayCancels([]OrderOptimized{cancelled})
+	}
+	return 0, true
+}
+
+func appendOrderGroup(b []byte, g *OrderGroup, sm *ShardedStateManager) []byte {
+	b = append(b, `{"id":"`...)
+	b = strconv.AppendUint(b, g.ID, 10)
+	b = append(b, `","policy":"`...)
+	b = append(b, groupPolicyName(g.Policy)...)
+	b = append(b, `","members":[`...)
+	for i := range g.Members {
+		m := &g.Members[i]
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, `{"order_id":"`...)
+		b = strconv.AppendUint(b, m.ID, 10)
+		b = append(b, `","symbol":`...)
+		b = strconv.AppendQuote(b, sm.symbols.Name(m.SymbolHash))
+		b = append(b, `,"quantity":`...)
+		b = appendFixed(b, m.Quantity)
+		b = append(b, '}')
+	}
+	b = append(b, `],"created_at":`...)
+	b = strconv.AppendInt(b, g.CreatedAt, 10)
+	return append(b, '}')
+}
+
+// orderGroupRequest - wire format for POST /api/orders/groups
+type orderGroupRequest struct {
+	Policy   string   `json:"policy"` // OCO
+	OrderIDs []string `json:"order_ids"`
+}
+
+// handleOrderGroups lists (GET), creates (POST) or dissolves (DELETE ?id=)
+// order groups
+func (sm *ShardedStateManager) handleOrderGroups(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		sm.pruneOrderGroups()
+		groups := sm.orderGroups.List()
+
+		buf := bufferPool.Get().(*[]byte)
+		defer bufferPool.Put(buf)
+
+		b := append((*buf)[:0], `{"groups":[`...)
+		for i := range groups {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = appendOrderGroup(b, &groups[i], sm)
+		}
+		b = append(b, `]}`...)
+
+		handlers.WriteJSON(w, r, http.StatusOK, b)
+
+	case http.MethodPost:
+		var req orderGroupRequest
+		if errResp := handlers.DecodeJSON(w, r, &req); errResp != nil {
+			handlers.WriteError(w, r, errResp)
+			return
+		}
+		if strings.ToUpper(req.Policy) != "OCO" {
+			handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "policy must be OCO", Field: "policy"})
+			return
+		}
+		ids := make([]uint64, len(req.OrderIDs))
+		for i, s := range req.OrderIDs {
+			id, err := strconv.ParseUint(s, 10, 64)
+			if err != nil {
+				handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "order_ids must be order IDs", Field: "order_ids"})
+				return
+			}
+			ids[i] = id
+		}
+		g, err := sm.CreateOrderGroup(GroupOCO, ids)
+		if err != nil {
+			status := http.StatusBadRequest
+			switch {
+			case errors.Is(err, ErrStandbyOrders), errors.Is(err, ErrAlreadyGrouped):
+				status = http.StatusConflict
+			case errors.Is(err, ErrNotGroupable):
+				status = http.StatusNotFound
+			}
+			handlers.WriteError(w, r, &handlers.ErrorResponse{Status: status, Error: err.Error(), Field: "order_ids"})
+			return
+		}
+
+		buf := bufferPool.Get().(*[]byte)
+		defer bufferPool.Put(buf)
+
+		b := appendOrderGroup((*buf)[:0], &g, sm)
+		handlers.WriteJSON(w, r, http.StatusCreated, b)
+
+	case http.MethodDelete:
+		id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+		if err != nil {
+			handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "id required", Field: "id"})
+			return
+		}
+		g, err := sm.orderGroups.Remove(id)
+		if err != nil {
+			handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusNotFound, Error: err.Error(), Field: "id"})
+			return
+		}
+
+		buf := bufferPool.Get().(*[]byte)
+		defer bufferPool.Put(buf)
+
+		b := appendOrderGroup((*buf)[:0], &g, sm)
+		handlers.WriteJSON(w, r, http.StatusOK, b)
+
+	default:
+		http.Error(w, "GET, POST or DELETE required", http.StatusMethodNotAllowed)
+	}
+}
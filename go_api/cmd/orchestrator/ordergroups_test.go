@@ -0,0 +1,63 @@
+package main
+
+import "testing"
+
+// A long of 10 at 100 bracketed by a take-profit sell limit at 110 and a
+// sell stop at 90, grouped OCO
+func TestOCOBracket(t *testing.T) {
+	tests := []struct {
+		name        string
+		stopFires   bool    // Last trade at 90 releases the stop leg, which then fills
+		filled      float64 // Of the leg that fills
+		wantSibling float64 // Open quantity left on the other leg, 0 = cancelled
+	}{
+		{"take-profit fills", false, 10, 0},
+		{"take-profit partly fills", false, 4, 6},
+		{"stop fires and fills", true, 10, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := NewShardedStateManager(testConfig())
+			h := sm.symbols.Hash("OCOUSD")
+			tick(sm, h, 100)
+			fill(sm, h, 0, 10, 100, 0)
+
+			target := &OrderOptimized{SymbolHash: h, Side: 1, Quantity: fx(10), Price: fx(110), ReduceOnly: true}
+			if res, err := sm.SubmitOrder(target); err != nil || !res.Approved {
+				t.Fatal(res.Reason, err)
+			}
+			stop := &TriggerOrder{SymbolHash: h, Side: 1, Kind: TriggerStop, TriggerPrice: fx(90), Quantity: fx(10), ReduceOnly: true}
+			if err := sm.ArmTrigger(stop); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := sm.CreateOrderGroup(GroupOCO, []uint64{target.ID, stop.ID}); err != nil {
+				t.Fatal(err)
+			}
+
+			leg, price := target.ID, 110.0
+			if tt.stopFires {
+				tick(sm, h, 90)
+				if sm.triggers.Len() != 0 || !sm.isWorkingOrder(stop.ID, h) {
+					t.Fatal("stop not released at 90")
+				}
+				leg, price = stop.ID, 90
+			}
+			sm.ApplyFill(&FillEvent{OrderID: leg, SymbolHash: h, Side: 1, Quantity: fx(tt.filled), Price: fx(price)})
+
+			var left int64
+			if tt.stopFires {
+				if order, ok := sm.GetShard(h).orders[target.ID]; ok {
+					left = order.Quantity - order.FilledQty
+				}
+			} else if trigger, ok := sm.triggers.Get(stop.ID); ok {
+				left = trigger.Quantity
+			}
+			if left != fx(tt.wantSibling) {
+				t.Fatalf("sibling has %d open, want %d", left, fx(tt.wantSibling))
+			}
+			if grouped := len(sm.orderGroups.List()) == 1; grouped != (tt.wantSibling > 0) {
+				t.Fatalf("group still live %v", grouped)
+			}
+		})
+	}
+}
@@ -0,0 +1,690 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"cenayang-market/go-api/internal/handlers"
+)
+
+// ============================================================================
+// ORDER STATUS
+// ============================================================================
+
+const (
+	OrderPending uint8 = iota
+	OrderSubmitted
+	OrderFilled
+	OrderPartial
+	OrderCancelled
+	OrderRejected
+)
+
+var orderStatusNames = [...]string{
+	OrderPending:   "PENDING",
+	OrderSubmitted: "SUBMITTED",
+	OrderFilled:    "FILLED",
+	OrderPartial:   "PARTIAL",
+	OrderCancelled: "CANCELLED",
+	OrderRejected:  "REJECTED",
+}
+
+func orderStatusName(status uint8) string {
+	if int(status) < len(orderStatusNames) {
+		return orderStatusNames[status]
+	}
+	return "UNKNOWN"
+}
+
+// orderTransitions[from] - the statuses an order may move to, as a bit
+// set. FILLED, CANCELLED and REJECTED are terminal.
+var orderTransitions = [len(orderStatusNames)]uint8{
+	OrderPending:   1<<OrderSubmitted | 1<<OrderRejected | 1<<OrderCancelled,
+	OrderSubmitted: 1<<OrderPartial | 1<<OrderFilled | 1<<OrderCancelled | 1<<OrderRejected,
+	OrderPartial:   1<<OrderPartial | 1<<OrderFilled | 1<<OrderCancelled | 1<<OrderRejected,
+}
+
+// validOrderTransition reports whether an order may move from one status
+// to another
+func validOrderTransition(from, to uint8) bool {
+	return int(from) < len(orderTransitions) && to < 8 && orderTransitions[from]&(1<<to) != 0
+}
+
+// orderTerminal reports whether an order in status can never change again
+func orderTerminal(status uint8) bool {
+	return status == OrderFilled || status == OrderCancelled || status == OrderRejected
+}
+
+// ErrOrderNotPending - only a new order can be submitted
+var ErrOrderNotPending = errors.New("order is not pending")
+
+// transitionOrder moves an order to a new status. An illegal transition is
+// logged and counted as an anomaly, and the order keeps its status.
+func (sm *ShardedStateManager) transitionOrder(order *OrderOptimized, to uint8) bool {
+	if !validOrderTransition(order.Status, to) {
+		sm.orderAnomaly(order.ID, order.Status, to)
+		return false
+	}
+	order.Status = to
+	return true
+}
+
+// orderAnomaly logs and counts an illegal status transition
+func (sm *ShardedStateManager) orderAnomaly(id uint64, from, to uint8) {
+	atomic.AddUint64(&sm.orderAnomalies, 1)
+	log.Printf("[ORDERS] Illegal transition of order %d: %s -> %s", id, orderStatusName(from), orderStatusName(to))
+	sm.raiseAlert(AlertWarning, "order_anomaly", fmt.Sprintf("illegal transition of order %d: %s -> %s", id, orderStatusName(from), orderStatusName(to)))
+}
+
+// OrderAnomalies returns the number of illegal status transitions refused
+func (sm *ShardedStateManager) OrderAnomalies() uint64 {
+	return atomic.LoadUint64(&sm.orderAnomalies)
+}
+
+// retireOrderID records a retired order's final status in the order index,
+// so a late fill can tell it from a fill for an order never seen
+func (sm *ShardedStateManager) retireOrderID(id uint64, status uint8) {
+	if v, ok := sm.orderIndex.Load(id); ok {
+		entry := v.(orderIndexEntry)
+		entry.status = status
+		sm.orderIndex.Store(id, entry)
+	}
+}
+
+// retiredStatus returns the final status of an order no longer working, or
+// OrderPending when the ID is unknown or its order still works - caller
+// holds the shard lock
+func (sm *ShardedStateManager) retiredStatus(shard *StateShard, id uint64) uint8 {
+	if id == 0 {
+		return OrderPending
+	}
+	if _, working := shard.orders[id]; working {
+		return OrderPending
+	}
+	if v, ok := sm.orderIndex.Load(id); ok {
+		return v.(orderIndexEntry).status
+	}
+	return OrderPending
+}
+
+// ============================================================================
+// ORDER ID GENERATOR - Time-Ordered, Monotonic, Lock-Free
+// ============================================================================
+
+// OrderIDGenerator issues IDs laid out as [42 bits unix ms | 22 bits counter].
+// IDs are strictly increasing even if the wall clock steps backwards, and
+// sort by creation time across restarts.
+type OrderIDGenerator struct {
+	last uint64 // Atomic
+}
+
+const orderIDCounterBits = 22
+
+// Next returns a new unique ID - CAS loop, no locks
+func (g *OrderIDGenerator) Next() uint64 {
+	for {
+		last := atomic.LoadUint64(&g.last)
+		next := uint64(time.Now().UnixMilli()) << orderIDCounterBits
+		if next <= last {
+			next = last + 1
+		}
+		if atomic.CompareAndSwapUint64(&g.last, last, next) {
+			return next
+		}
+	}
+}
+
+// Observe advances the generator past an externally assigned ID
+func (g *OrderIDGenerator) Observe(id uint64) {
+	for {
+		last := atomic.LoadUint64(&g.last)
+		if id <= last || atomic.CompareAndSwapUint64(&g.last, last, id) {
+			return
+		}
+	}
+}
+
+// ============================================================================
+// ORDER SUBMISSION
+// ============================================================================
+
+var ErrDuplicateOrderID = errors.New("duplicate order id")
+
+// SubmitOrder risk-checks an order and, if approved, assigns its ID and
+// sequence number, adds it to the working book and forwards it to the
+// execution channel. Rejected orders are
+// returned with OrderRejected status and are not stored. With a risk pool
+// configured, a full queue returns ErrRiskQueueFull and leaves the order
+// pending.
+func (sm *ShardedStateManager) SubmitOrder(order *OrderOptimized) (RiskCheckResult, error) {
+	if order.Status != OrderPending {
+		sm.orderAnomaly(order.ID, order.Status, OrderSubmitted)
+		return RiskCheckResult{}, ErrOrderNotPending
+	}
+	result, err := sm.checkRisk(order)
+	if err != nil {
+		return RiskCheckResult{}, err
+	}
+	if !result.Approved {
+		sm.transitionOrder(order, OrderRejected)
+		return result, nil
+	}
+
+	// Reserved, sequenced and stored under the shard lock so a snapshot
+	// sees the order, its capital and its sequence ID together
+	shard := sm.GetShard(order.SymbolHash)
+	shard.mu.Lock()
+
+	// A market order is measured, and its capital reserved, at the last
+	// price the risk check valued it at
+	order.DecisionPrice = result.Price
+	if order.Price != 0 {
+		order.DecisionPrice = sm.decisionPrice(order)
+	}
+
+	// Hold the cash a buy's fill will debit - the risk check only compared
+	// it, so concurrent buys could each pass against the same cash
+	if !sm.reserveOrderCash(order, result.Quantity) {
+		shard.mu.Unlock()
+		atomic.AddUint64(&sm.riskRejections, 1)
+		sm.rejections.Record(ReasonInsufficientCapital)
+		sm.transitionOrder(order, OrderRejected)
+		return RiskCheckResult{Reason: ReasonInsufficientCapital, LatencyNs: result.LatencyNs}, nil
+	}
+
+	// Commit strategy capital - the risk check only previewed it
+	var reserved int64
+	if order.Strategy != "" && !order.ReduceOnly && sm.allocator.Enabled() {
+		reserved = notionalValue(sm.symbols.Get(order.SymbolHash), result.Quantity, reservePrice(order))
+		if err := sm.allocator.Reserve(order.Strategy, reserved); err != nil {
+			sm.releaseOrderCash(order, result.Quantity)
+			shard.mu.Unlock()
+			reason := allocationReason(err)
+			atomic.AddUint64(&sm.riskRejections, 1)
+			sm.rejections.Record(reason)
+			sm.transitionOrder(order, OrderRejected)
+			return RiskCheckResult{Reason: reason, LatencyNs: result.LatencyNs}, nil
+		}
+		order.Reserved = reserved
+	}
+
+	if order.ID == 0 {
+		order.ID = sm.orderIDs.Next()
+	} else {
+		sm.orderIDs.Observe(order.ID)
+	}
+	entry := orderIndexEntry{symbolHash: order.SymbolHash, indexedAt: sm.clock.Now().UnixNano()}
+	if _, loaded := sm.orderIndex.LoadOrStore(order.ID, entry); loaded {
+		if reserved != 0 {
+			sm.allocator.Release(order.Strategy, reserved)
+		}
+		sm.releaseOrderCash(order, result.Quantity)
+		shard.mu.Unlock()
+		return result, ErrDuplicateOrderID
+	}
+
+	order.Quantity = result.Quantity
+	sm.transitionOrder(order, OrderSubmitted)
+	order.SequenceID = sm.nextSequence()
+	order.Timestamp = time.Now().UnixNano()
+
+	stored := orderPool.Get().(*OrderOptimized)
+	*stored = *order
+	shard.orders[order.ID] = stored
+	shard.mu.Unlock()
+
+	sm.publishOrder(order)
+	sm.forwardOrder(order)
+	atomic.AddUint64(&sm.totalOrders, 1)
+	return result, nil
+}
+
+// ============================================================================
+// CANCEL-ALL - Emergency Path, Independent of Order Flow
+// ============================================================================
+
+// Emergency cancel tuning
+const (
+	// CancelBroadcastTimeout bounds how long a cancel event waits for room
+	// in a saturated hub before it is dropped
+	CancelBroadcastTimeout = time.Second
+
+	// GatewayCancelTimeout bounds each cancel sent to the gateway
+	GatewayCancelTimeout = 2 * time.Second
+)
+
+// ErrStandbyOrders - a standby's working orders are owned by the active
+var ErrStandbyOrders = errors.New("standby: working orders are owned by the active")
+
+// OrderGateway forwards order instructions to the execution gateway
+type OrderGateway interface {
+	CancelOrder(ctx context.Context, orderID, symbolHash uint64) error
+}
+
+// cancelOrderLocked retires a working order and frees the capital its
+// unfilled remainder reserved - caller holds the shard lock
+func (sm *ShardedStateManager) cancelOrderLocked(shard *StateShard, order *OrderOptimized) {
+	sm.retireOrderLocked(shard, order, OrderCancelled)
+}
+
+// reservePrice is the price an order's strategy capital is reserved and
+// released at: its limit, or the last price a market order was accepted at
+func reservePrice(order *OrderOptimized) int64 {
+	if order.Price != 0 {
+		return order.Price
+	}
+	return order.DecisionPrice
+}
+
+// orderCashNeed is the cash quantity of a buy would debit when filled at
+// its reserve price: notional plus estimated commission. Sells and
+// reduce-only orders hold none.
+func (sm *ShardedStateManager) orderCashNeed(order *OrderOptimized, quantity int64) int64 {
+	if order.Side != 0 || order.ReduceOnly {
+		return 0
+	}
+	spec := sm.symbols.Get(order.SymbolHash)
+	notional := notionalValue(spec, quantity, reservePrice(order))
+	return notional + estimatedCommission(spec, notional)
+}
+
+// reserveOrderCash holds the cash quantity of a buy needs against the cash
+// other working buys have not already claimed; false if it does not fit
+func (sm *ShardedStateManager) reserveOrderCash(order *OrderOptimized, quantity int64) bool {
+	need := sm.orderCashNeed(order, quantity)
+	if need <= 0 {
+		return true
+	}
+	for {
+		held := atomic.LoadInt64(&sm.cashReserved)
+		if need > atomic.LoadInt64(&sm.state.Cash)-held {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&sm.cashReserved, held, held+need) {
+			order.CashReserved = need
+			return true
+		}
+	}
+}
+
+// releaseOrderCash frees the share of an order's held cash behind quantity
+// of its open remainder, all of it once quantity covers the remainder -
+// caller holds the shard lock
+func (sm *ShardedStateManager) releaseOrderCash(order *OrderOptimized, quantity int64) {
+	atomic.AddInt64(&sm.cashReserved, -takeShare(&order.CashReserved, quantity, order.Quantity-order.FilledQty))
+}
+
+// takeShare removes and returns the share of *held behind quantity out of
+// total, all of it once quantity covers total, so the shares taken always
+// add up to what was held
+func takeShare(held *int64, quantity, total int64) int64 {
+	if *held == 0 || quantity <= 0 {
+		return 0
+	}
+	share := *held
+	if quantity < total {
+		share = mulDiv(*held, quantity, total)
+	}
+	*held -= share
+	return share
+}
+
+// retireOrderLocked removes a working order that ends unfilled with status
+// and frees the capital and cash its unfilled remainder reserved - caller
+// holds the shard lock
+func (sm *ShardedStateManager) retireOrderLocked(shard *StateShard, order *OrderOptimized, status uint8) {
+	sm.releaseOrderCash(order, order.Quantity-order.FilledQty)
+	if order.Reserved != 0 {
+		sm.allocator.Release(order.Strategy, order.Reserved)
+	}
+	// ID stays in orderIndex so it is never reissued
+	sm.retireOrderID(order.ID, status)
+	delete(shard.orders, order.ID)
+	*order = OrderOptimized{}
+	orderPool.Put(order)
+}
+
+// CancelAllOrders cancels every working order and disarms every trigger.
+// Shard locks are taken directly, so nothing queued ahead of it - order
+// flow, a backed-up hub - delays the book change. Each cancel is sequenced
+// and broadcast like a new order, waiting for room in the hub rather than
+// being dropped, and sent to the gateway in the background. Calling it on
+// an empty book is a no-op.
+func (sm *ShardedStateManager) CancelAllOrders() ([]OrderOptimized, []TriggerOrder, error) {
+	if sm.Standby() {
+		return nil, nil, ErrStandbyOrders
+	}
+
+	var cancelled []OrderOptimized
+	for i := 0; i < NumShards; i++ {
+		shard := &sm.shards[i]
+		shard.mu.Lock()
+		for _, order := range shard.orders {
+			sm.transitionOrder(order, OrderCancelled)
+			order.SequenceID = sm.nextSequence()
+			order.Timestamp = time.Now().UnixNano()
+			cancelled = append(cancelled, *order)
+			sm.cancelOrderLocked(shard, order)
+		}
+		shard.mu.Unlock()
+	}
+
+	var triggers []TriggerOrder
+	for _, t := range sm.triggers.List() {
+		if t, err := sm.triggers.Cancel(t.ID); err == nil {
+			triggers = append(triggers, t)
+		}
+	}
+
+	if len(cancelled) == 0 {
+		return nil, triggers, nil
+	}
+	sort.Slice(cancelled, func(i, j int) bool { return cancelled[i].SequenceID < cancelled[j].SequenceID })
+
+	ctx, cancel := context.WithTimeout(context.Background(), CancelBroadcastTimeout)
+	for i := range cancelled {
+		sm.publishOrderWait(ctx, &cancelled[i])
+	}
+	cancel()
+
+	if sm.gateway != nil && !sm.PaperTrading() {
+		go sm.sendGatewayCancels(cancelled)
+	}
+	log.Printf("[ORDERS] Cancel-all: %d orders, %d triggers", len(cancelled), len(triggers))
+	return cancelled, triggers, nil
+}
+
+// sendGatewayCancels asks the gateway to cancel each order
+func (sm *ShardedStateManager) sendGatewayCancels(orders []OrderOptimized) {
+	for i := range orders {
+		ctx, cancel := context.WithTimeout(context.Background(), GatewayCancelTimeout)
+		if err := sm.gateway.CancelOrder(ctx, orders[i].ID, orders[i].SymbolHash); err != nil {
+			log.Printf("[ORDERS] Gateway cancel of order %d failed: %v", orders[i].ID, err)
+		}
+		cancel()
+	}
+}
+
+// handleCancelAll serves CancelAllOrders
+func (sm *ShardedStateManager) handleCancelAll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+
+	orders, triggers, err := sm.CancelAllOrders()
+	if err != nil {
+		handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusConflict, Error: err.Error()})
+		return
+	}
+
+	buf := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(buf)
+
+	b := append((*buf)[:0], `{"cancelled":`...)
+	b = strconv.AppendInt(b, int64(len(orders)), 10)
+	b = append(b, `,"triggers_cancelled":`...)
+	b = strconv.AppendInt(b, int64(len(triggers)), 10)
+	b = append(b, `,"order_ids":[`...)
+	for i := range orders {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, '"')
+		b = strconv.AppendUint(b, orders[i].ID, 10)
+		b = append(b, '"')
+	}
+	b = append(b, `],"seq_id":`...)
+	b = strconv.AppendUint(b, atomic.LoadUint64(&sm.state.SequenceID), 10)
+	b = append(b, '}')
+
+	handlers.WriteJSON(w, r, http.StatusOK, b)
+}
+
+// orderIndexEntry - where an order ID lives, when it was first seen and
+// how it ended
+type orderIndexEntry struct {
+	symbolHash uint64
+	indexedAt  int64 // Retention clock, unix ns
+	status     uint8 // Final status once retired, else OrderPending
+}
+
+// fillOrderLocked books a fill against its working order and retires the
+// order once complete - caller holds the shard lock. Returns the order's
+// strategy and attribution tag ("" if the fill has no working order),
+// reduce-only flag, when the fill is worse than the order's limit that
+// limit (else 0), and the strategy capital the order reserved for the
+// filled quantity, now the caller's to hold or release.
+func (sm *ShardedStateManager) fillOrderLocked(shard *StateShard, fill *FillEvent) (string, string, bool, int64, int64) {
+	order, ok := shard.orders[fill.OrderID]
+	if !ok || fill.OrderID == 0 {
+		return "", "", false, 0, 0
+	}
+
+	sm.releaseOrderCash(order, fill.Quantity) // The fill debits it instead
+	reserved := takeShare(&order.Reserved, fill.Quantity, order.Quantity-order.FilledQty)
+	filled := order.FilledQty + fill.Quantity
+	order.AvgFillPrice = mulDiv(order.AvgFillPrice, order.FilledQty, filled) + mulDiv(fill.Price, fill.Quantity, filled)
+	order.FilledQty = filled
+	var breached int64
+	if sm.execution.Record(sm.symbols.Get(fill.SymbolHash), order, fill, filled >= order.Quantity) {
+		breached = order.Price
+	}
+
+	strategy, tag, reduceOnly := order.Strategy, orderTag(order), order.ReduceOnly
+	if filled < order.Quantity {
+		sm.transitionOrder(order, OrderPartial)
+		return strategy, tag, reduceOnly, breached, reserved
+	}
+
+	// ID stays in orderIndex so it is never reissued
+	sm.transitionOrder(order, OrderFilled)
+	sm.retireOrderID(order.ID, OrderFilled)
+	delete(shard.orders, order.ID)
+	*order = OrderOptimized{}
+	orderPool.Put(order)
+	return strategy, tag, reduceOnly, breached, reserved
+}
+
+// reducibleQuantity returns how much of a position an order on side can
+// close: the position quantity if it is on the opposite side, else 0
+func (sm *ShardedStateManager) reducibleQuantity(symbolHash uint64, side uint8) int64 {
+	shard := sm.GetShard(symbolHash)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	if pos, ok := shard.positions[symbolHash]; ok && pos.Side != side {
+		return pos.Quantity
+	}
+	return 0
+}
+
+// orderRequest - wire format for POST /api/orders and /api/risk/check
+type orderRequest struct {
+	ID         uint64  `json:"id,string,omitempty"`
+	Symbol     string  `json:"symbol"`
+	Side       string  `json:"side"` // BUY or SELL
+	Quantity   float64 `json:"quantity"`
+	Price      float64 `json:"price"` // 0 = market
+	Strategy   string  `json:"strategy,omitempty"`
+	Tag        string  `json:"tag,omitempty"` // PnL attribution; defaults to strategy
+	ReduceOnly bool    `json:"reduce_only,omitempty"`
+}
+
+// decodeOrder strictly decodes and validates an order request body
+func (sm *ShardedStateManager) decodeOrder(w http.ResponseWriter, r *http.Request) (*OrderOptimized, *handlers.ErrorResponse) {
+	var req orderRequest
+	if errResp := handlers.DecodeJSON(w, r, &req); errResp != nil {
+		return nil, errResp
+	}
+	return sm.orderFromRequest(&req)
+}
+
+// orderFromRequest validates a wire order and converts it to fixed-point.
+// The symbol may be in any notation the registry resolves.
+func (sm *ShardedStateManager) orderFromRequest(req *orderRequest) (*OrderOptimized, *handlers.ErrorResponse) {
+	order := &OrderOptimized{
+		ID:         req.ID,
+		SymbolHash: sm.symbols.Hash(req.Symbol),
+		Quantity:   toFixed(req.Quantity),
+		Price:      toFixed(req.Price),
+		Strategy:   req.Strategy,
+		Tag:        req.Tag,
+		ReduceOnly: req.ReduceOnly,
+	}
+	switch strings.ToUpper(req.Side) {
+	case "BUY":
+		order.Side = 0
+	case "SELL":
+		order.Side = 1
+	default:
+		return nil, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "side must be BUY or SELL", Field: "side"}
+	}
+	switch {
+	case req.Symbol == "":
+		return nil, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "symbol required", Field: "symbol"}
+	case !fixedInRange(req.Quantity):
+		return nil, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "quantity out of range", Field: "quantity"}
+	case !fixedInRange(req.Price):
+		return nil, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "price out of range", Field: "price"}
+	case order.Quantity <= 0:
+		return nil, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "quantity must be positive", Field: "quantity"}
+	case order.Price < 0:
+		return nil, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "price must be non-negative", Field: "price"}
+	}
+	return order, nil
+}
+
+// handleOrders lists working orders (GET) or submits a new order (POST)
+func (sm *ShardedStateManager) handleOrders(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		sm.writeOpenOrders(w, r)
+
+	case http.MethodPost:
+		order, errResp := sm.decodeOrder(w, r)
+		if errResp != nil {
+			handlers.WriteError(w, r, errResp)
+			return
+		}
+
+		result, err := sm.SubmitOrder(order)
+		if err != nil {
+			status := http.StatusConflict
+			if errors.Is(err, ErrRiskQueueFull) || errors.Is(err, ErrRiskPoolStopped) {
+				status = http.StatusServiceUnavailable
+			}
+			handlers.WriteError(w, r, &handlers.ErrorResponse{Status: status, Error: err.Error()})
+			return
+		}
+
+		status := http.StatusCreated
+		if !result.Approved {
+			status = http.StatusUnprocessableEntity
+		}
+
+		buf := bufferPool.Get().(*[]byte)
+		defer bufferPool.Put(buf)
+
+		b := append((*buf)[:0], `{"id":"`...)
+		b = strconv.AppendUint(b, order.ID, 10)
+		b = append(b, `","status":"`...)
+		b = append(b, orderStatusName(order.Status)...)
+		b = append(b, `","reason":"`...)
+		b = append(b, result.Reason.String()...)
+		b = append(b, '"')
+		if result.Group != "" {
+			b = append(b, `,"group":`...)
+			b = strconv.AppendQuote(b, result.Group)
+		}
+		b = append(b, `,"quantity":`...)
+		b = appendFixed(b, order.Quantity)
+		b = append(b, `,"seq_id":`...)
+		b = strconv.AppendUint(b, order.SequenceID, 10)
+		b = append(b, '}')
+
+		handlers.WriteJSON(w, r, status, b)
+
+	default:
+		http.Error(w, "GET or POST required", http.StatusMethodNotAllowed)
+	}
+}
+
+// writeOpenOrders serves the working order book, oldest first unless
+// ?sort= names another key (see orderSortKeys)
+func (sm *ShardedStateManager) writeOpenOrders(w http.ResponseWriter, r *http.Request) {
+	var rows []orderRow
+	for i := 0; i < NumShards; i++ {
+		shard := &sm.shards[i]
+		shard.mu.RLock()
+		for _, o := range shard.orders {
+			rows = append(rows, orderRow{symbol: sm.symbols.Name(o.SymbolHash), order: *o})
+		}
+		shard.mu.RUnlock()
+	}
+	if errResp := sortList(r, rows, orderSortKeys); errResp != nil {
+		handlers.WriteError(w, r, errResp)
+		return
+	}
+
+	buf := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(buf)
+
+	b := append((*buf)[:0], `{"orders":[`...)
+	for i := range rows {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = appendOrder(b, &rows[i].order, rows[i].symbol)
+	}
+	b = append(b, `]}`...)
+
+	handlers.WriteJSON(w, r, http.StatusOK, b)
+}
+
+// appendOrder serializes an order - caller holds the shard lock or a copy
+func appendOrder(b []byte, o *OrderOptimized, symbol string) []byte {
+	b = append(b, `{"id":"`...)
+	b = strconv.AppendUint(b, o.ID, 10)
+	b = append(b, `","symbol":`...)
+	b = strconv.AppendQuote(b, symbol)
+	b = append(b, `,"side":"`...)
+	if o.Side == 0 {
+		b = append(b, `BUY`...)
+	} else {
+		b = append(b, `SELL`...)
+	}
+	b = append(b, `","status":"`...)
+	b = append(b, orderStatusName(o.Status)...)
+	b = append(b, `","strategy":`...)
+	b = strconv.AppendQuote(b, o.Strategy)
+	b = append(b, `,"tag":`...)
+	b = strconv.AppendQuote(b, orderTag(o))
+	b = append(b, `,"reduce_only":`...)
+	b = strconv.AppendBool(b, o.ReduceOnly)
+	b = append(b, `,"quantity":`...)
+	b = appendFixed(b, o.Quantity)
+	b = append(b, `,"price":`...)
+	b = appendFixed(b, o.Price)
+	b = append(b, `,"filled_qty":`...)
+	b = appendFixed(b, o.FilledQty)
+	b = append(b, `,"avg_fill_price":`...)
+	b = appendFixed(b, o.AvgFillPrice)
+	b = append(b, `,"decision_price":`...)
+	b = appendFixed(b, o.DecisionPrice)
+	b = append(b, `,"shortfall_bps":`...)
+	b = strconv.AppendInt(b, orderShortfallBps(o), 10)
+	b = append(b, `,"seq_id":`...)
+	b = strconv.AppendUint(b, o.SequenceID, 10)
+	b = append(b, `,"timestamp_ns":`...)
+	b = strconv.AppendInt(b, o.Timestamp, 10)
+	return append(b, '}')
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestOrderIDsMonotonic(t *testing.T) {
+	var g OrderIDGenerator
+	const workers, perWorker = 8, 1000
+	ids := make([][]uint64, workers)
+	var wg sync.WaitGroup
+	for w := range ids {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				ids[w] = append(ids[w], g.Next())
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	seen := make(map[uint64]bool, workers*perWorker)
+	for w := range ids {
+		for i, id := range ids[w] {
+			if i > 0 && id <= ids[w][i-1] {
+				t.Fatalf("worker %d: id %d after %d", w, id, ids[w][i-1])
+			}
+			if seen[id] {
+				t.Fatalf("id %d issued twice", id)
+			}
+			seen[id] = true
+		}
+	}
+
+	// A client ID ahead of the clock moves the generator past it
+	ahead := g.Next() + 1<<40
+	g.Observe(ahead)
+	if next := g.Next(); next <= ahead {
+		t.Fatalf("next %d not after observed %d", next, ahead)
+	}
+}
+
+func TestSubmitOrderDuplicateID(t *testing.T) {
+	sm := NewShardedStateManager(testConfig())
+	h := SymbolHash("BTCUSD")
+	first := &OrderOptimized{SymbolHash: h, Quantity: fx(1), Price: fx(100)}
+	if _, err := sm.SubmitOrder(first); err != nil || first.ID == 0 {
+		t.Fatalf("id %d, err %v", first.ID, err)
+	}
+	second := &OrderOptimized{SymbolHash: h, Quantity: fx(1), Price: fx(100)}
+	if _, err := sm.SubmitOrder(second); err != nil || second.ID <= first.ID || second.SequenceID <= first.SequenceID {
+		t.Fatalf("second id %d seq %d after id %d seq %d, err %v", second.ID, second.SequenceID, first.ID, first.SequenceID, err)
+	}
+	dup := &OrderOptimized{ID: first.ID, SymbolHash: h, Quantity: fx(1), Price: fx(100)}
+	if _, err := sm.SubmitOrder(dup); !errors.Is(err, ErrDuplicateOrderID) {
+		t.Fatalf("err %v, want ErrDuplicateOrderID", err)
+	}
+}
+
+// A refused submission answers with a JSON error body
+func TestHandleOrdersErrorBody(t *testing.T) {
+	sm := NewShardedStateManager(testConfig())
+	mux := setupHTTPRoutes(sm)
+	post := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		body := strings.NewReader(`{"id":"7","symbol":"BTCUSD","side":"BUY","quantity":1,"price":100}`)
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/orders", body))
+		return rec
+	}
+	if rec := post(); rec.Code != http.StatusCreated {
+		t.Fatalf("first submission %d: %s", rec.Code, rec.Body)
+	}
+	rec := post()
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("duplicate %d, want %d", rec.Code, http.StatusConflict)
+	}
+	var body struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error != ErrDuplicateOrderID.Error() {
+		t.Fatalf("body %s: %v", rec.Body, err)
+	}
+}
+
+func TestReduceOnlyOrders(t *testing.T) {
+	sm := NewShardedStateManager(testConfig())
+	h := SymbolHash("BTCUSD")
+	sell := &OrderOptimized{SymbolHash: h, Side: 1, Quantity: fx(5), Price: fx(100), ReduceOnly: true}
+	if res, _ := sm.SubmitOrder(sell); res.Reason != ReasonReduceOnlyViolation {
+		t.Fatalf("reason %v with nothing to reduce, want %v", res.Reason, ReasonReduceOnlyViolation)
+	}
+
+	fill(sm, h, 0, 2, 100, 0)
+	atomic.StoreInt64(&sm.state.DailyPnL, -fx(20_000)) // Limits that block new risk do not block a close
+	sell = &OrderOptimized{SymbolHash: h, Side: 1, Quantity: fx(5), Price: fx(100), ReduceOnly: true}
+	if res, err := sm.SubmitOrder(sell); err != nil || !res.Approved || sell.Quantity != fx(2) {
+		t.Fatalf("approved %v quantity %d, want capped to 2 (%v, %v)", res.Approved, sell.Quantity, res.Reason, err)
+	}
+	buy := &OrderOptimized{SymbolHash: h, Side: 0, Quantity: fx(1), Price: fx(100), ReduceOnly: true}
+	if res, _ := sm.SubmitOrder(buy); res.Reason != ReasonReduceOnlyViolation {
+		t.Fatalf("reason %v adding to a long, want %v", res.Reason, ReasonReduceOnlyViolation)
+	}
+
+	// A venue overfill closes the position and quarantines the excess
+	// rather than open a short
+	sm.ApplyFill(&FillEvent{OrderID: sell.ID, SymbolHash: h, Side: 1, Quantity: fx(3), Price: fx(100)})
+	if pos, open := sm.GetShard(h).positions[h]; open {
+		t.Fatalf("side %d quantity %d open after the reduce-only fill", pos.Side, pos.Quantity)
+	}
+	if n := sm.QuarantinedFills(); n != 1 {
+		t.Fatalf("%d fills quarantined, want 1", n)
+	}
+	if q := sm.quarantine.fills[0]; q.Reason != QuarantineReduceExceedsPosition || q.Fill.Quantity != fx(1) {
+		t.Fatalf("quarantined %s quantity %d, want %s of 1", q.Reason, q.Fill.Quantity, QuarantineReduceExceedsPosition)
+	}
+}
+
+func TestOrderTransitions(t *testing.T) {
+	tests := []struct {
+		from, to uint8
+		want     bool
+	}{
+		{OrderPending, OrderSubmitted, true},
+		{OrderPending, OrderRejected, true},
+		{OrderPending, OrderCancelled, true},
+		{OrderPending, OrderFilled, false},
+		{OrderSubmitted, OrderPartial, true},
+		{OrderSubmitted, OrderFilled, true},
+		{OrderSubmitted, OrderCancelled, true},
+		{OrderSubmitted, OrderRejected, true},
+		{OrderSubmitted, 99, false},
+		{OrderPartial, OrderPartial, true},
+		{OrderPartial, OrderFilled, true},
+		{OrderPartial, OrderCancelled, true},
+		{OrderPartial, OrderSubmitted, false},
+		{OrderFilled, OrderPartial, false},
+		{OrderFilled, OrderCancelled, false},
+		{OrderCancelled, OrderPartial, false},
+		{OrderRejected, OrderSubmitted, false},
+	}
+	for _, tt := range tests {
+		t.Run(orderStatusName(tt.from)+"->"+orderStatusName(tt.to), func(t *testing.T) {
+			sm := NewShardedStateManager(testConfig())
+			order := &OrderOptimized{ID: 1, Status: tt.from}
+			if moved := sm.transitionOrder(order, tt.to); moved != tt.want {
+				t.Fatalf("moved %v, want %v", moved, tt.want)
+			}
+			want, anomalies := tt.to, uint64(0)
+			if !tt.want {
+				want, anomalies = tt.from, 1 // Refused and counted
+			}
+			if order.Status != want || sm.OrderAnomalies() != anomalies {
+				t.Fatalf("status %s, %d anomalies", orderStatusName(order.Status), sm.OrderAnomalies())
+			}
+		})
+	}
+}
+
+func TestSubmitOrderNeedsPending(t *testing.T) {
+	sm := NewShardedStateManager(testConfig())
+	order := &OrderOptimized{SymbolHash: 3, Status: OrderFilled, Quantity: fx(1), Price: fx(1)}
+	if _, err := sm.SubmitOrder(order); err != ErrOrderNotPending {
+		t.Fatalf("err %v, want %v", err, ErrOrderNotPending)
+	}
+}
+
+func TestFillOnTerminalOrderQuarantined(t *testing.T) {
+	tests := []struct {
+		name   string
+		retire func(sm *ShardedStateManager, order *OrderOptimized)
+	}{
+		{"cancelled", func(sm *ShardedStateManager, _ *OrderOptimized) { sm.CancelAllOrders() }},
+		{"filled", func(sm *ShardedStateManager, order *OrderOptimized) {
+			sm.ApplyFill(&FillEvent{OrderID: order.ID, SymbolHash: order.SymbolHash, Quantity: fx(1), Price: fx(100)})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := NewShardedStateManager(testConfig())
+			h := sm.symbols.Hash("ORDUSD")
+			order := &OrderOptimized{SymbolHash: h, Quantity: fx(2), Price: fx(100)}
+			if res, err := sm.SubmitOrder(order); err != nil || !res.Approved {
+				t.Fatal(res.Reason, err)
+			}
+			sm.ApplyFill(&FillEvent{OrderID: order.ID, SymbolHash: h, Quantity: fx(1), Price: fx(100)})
+			if status := sm.GetShard(h).orders[order.ID].Status; status != OrderPartial {
+				t.Fatalf("status %s after the first fill", orderStatusName(status))
+			}
+			tt.retire(sm, order)
+			held := sm.GetShard(h).positions[h].Quantity
+
+			sm.ApplyFill(&FillEvent{OrderID: order.ID, SymbolHash: h, Quantity: fx(1), Price: fx(100)})
+			if sm.QuarantinedFills() != 1 || sm.GetShard(h).positions[h].Quantity != held {
+				t.Fatalf("late fill booked: %d quarantined", sm.QuarantinedFills())
+			}
+		})
+	}
+}
+
+// Readers copy working orders by value under the shard lock fills take;
+// run with -race
+func TestOrderCopiesRaceFills(t *testing.T) {
+	sm := NewShardedStateManager(testConfig())
+	h := sm.symbols.Hash("RACEUSD")
+	tick(sm, h, 100)
+	ids := make([]uint64, 50)
+	for i := range ids {
+		order := &OrderOptimized{SymbolHash: h, Quantity: fx(1), Price: fx(100)}
+		if res, err := sm.SubmitOrder(order); err != nil || !res.Approved {
+			t.Fatal(res.Reason, err)
+		}
+		ids[i] = order.ID
+	}
+	before := sm.Snapshot()
+
+	mux := setupHTTPRoutes(sm)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		seq := uint64(0)
+		for _, id := range ids {
+			for i := 0; i < 4; i++ {
+				seq++
+				sm.feed.OnFill(&FillEvent{OrderID: id, SymbolHash: h, Quantity: fx(0.25), Price: fx(100), SeqID: seq})
+			}
+		}
+	}()
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 30; i++ {
+				mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/orders", nil))
+				sm.Snapshot()
+				sm.captureState()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := len(sm.GetShard(h).orders); n != 0 {
+		t.Fatalf("%d orders still working", n)
+	}
+	for _, o := range before.Orders { // Taken before the fills, untouched by them
+		if o.FilledQty != 0 || o.Status != uint32(OrderSubmitted) {
+			t.Fatalf("snapshot order %d moved: filled %d status %d", o.Id, o.FilledQty, o.Status)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// ============================================================================
+// PAPER TRADING - Synthetic Fills at the Live Price Instead of the Gateway
+// ============================================================================
+
+// Paper orders take the same path as live ones - risk check, working book,
+// order events - and only the hop to the gateway is replaced: the fill
+// comes back through the feed ingester, so booking, PnL, the breaker and
+// broadcasts are exactly the live ones.
+
+// PaperConfig - paper trading against the live feed
+type PaperConfig struct {
+	Enabled     bool    // Approved orders are filled here, never sent to the gateway
+	SlippageBps float64 // Adverse slippage off the last price, basis points
+}
+
+// PaperTrading reports whether approved orders are filled synthetically
+func (sm *ShardedStateManager) PaperTrading() bool {
+	return sm.config.Paper.Enabled
+}
+
+// paperFill fills an accepted order in full at the symbol's last price,
+// moved against it by the configured slippage and rounded outward to the
+// tick. A limit that the slipped price would breach fills at the limit if
+// the last price itself is within it; otherwise, like an order with no
+// price to fill at, it stays working until cancelled.
+func (sm *ShardedStateManager) paperFill(order *OrderOptimized) {
+	last, ok := sm.feed.LastPrice(order.SymbolHash)
+	if !ok {
+		log.Printf("[PAPER] Order %d left working: no last price for %s", order.ID, sm.symbols.Name(order.SymbolHash))
+		return
+	}
+	spec := sm.symbols.Get(order.SymbolHash)
+	buy := order.Side == 0
+
+	slip := mulDiv(last, int64(sm.config.Paper.SlippageBps*100), 1_000_000)
+	price := last + slip
+	if !buy {
+		price = last - slip
+	}
+	if tick := spec.TickSize; tick > 0 {
+		if rem := price % tick; rem != 0 {
+			price -= rem
+			if buy {
+				price += tick
+			}
+		}
+	}
+	if order.Price > 0 {
+		if buy && last > order.Price || !buy && last < order.Price {
+			log.Printf("[PAPER] Order %d left working: last %s beyond limit %s",
+				order.ID, appendFixed(nil, last), appendFixed(nil, order.Price))
+			return
+		}
+		if buy {
+			price = min(price, order.Price)
+		} else {
+			price = max(price, order.Price)
+		}
+	}
+	if price <= 0 {
+		log.Printf("[PAPER] Order %d left working: slipped price not positive", order.ID)
+		return
+	}
+
+	fill := &FillEvent{
+		OrderID:    order.ID,
+		SymbolHash: order.SymbolHash,
+		Side:       order.Side,
+		Quantity:   order.Quantity - order.FilledQty,
+		Price:      price,
+		ReduceOnly: order.ReduceOnly,
+		Liquidity:  LiquidityTaker,
+		Timestamp:  time.Now().UnixNano(),
+	}
+	if !sm.feed.OnFill(fill) {
+		log.Printf("[PAPER] Fill of order %d not accepted", order.ID)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import "testing"
+
+// 10 bps of slippage off a last price of 100, on a 0.01 tick
+func TestPaperFills(t *testing.T) {
+	cfg := testConfig()
+	cfg.Paper = PaperConfig{Enabled: true, SlippageBps: 10}
+	cfg.Symbols = []SymbolMeta{{Symbol: "PAPERUSD", TickSize: 0.01, LotSize: 1, Multiplier: 1, Currency: "USD"}}
+	sm := NewShardedStateManager(cfg)
+	h := sm.symbols.Hash("PAPERUSD")
+
+	submit := func(side uint8, limit float64) *OrderOptimized {
+		order := &OrderOptimized{SymbolHash: h, Side: side, Quantity: fx(1), Price: fx(limit)}
+		if res, err := sm.SubmitOrder(order); err != nil || !res.Approved {
+			t.Fatalf("side %d limit %v not approved: %v %v", side, limit, res.Reason, err)
+		}
+		return order
+	}
+	working := func(order *OrderOptimized) bool {
+		_, ok := sm.GetShard(h).orders[order.ID]
+		return ok
+	}
+
+	if order := submit(0, 101); !working(order) {
+		t.Fatal("filled without a last price")
+	}
+	tick(sm, h, 100)
+	tests := []struct {
+		name      string
+		side      uint8
+		limit     float64
+		wantPrice float64 // 0 = left working
+	}{
+		{"buy slipped up", 0, 101, 100.10},
+		{"buy capped at the limit", 0, 100.05, 100.05},
+		{"sell slipped down", 1, 99, 99.90},
+		{"sell limit beyond last", 1, 101, 0},
+	}
+	for _, tt := range tests {
+		order := submit(tt.side, tt.limit)
+		if tt.wantPrice == 0 {
+			if !working(order) {
+				t.Fatalf("%s: filled", tt.name)
+			}
+			continue
+		}
+		if working(order) {
+			t.Fatalf("%s: left working", tt.name)
+		}
+		fills, _ := sm.Fills(FillsQuery{Limit: MaxFillsPage})
+		if last := fills[len(fills)-1]; last.Fill.OrderID != order.ID || last.Fill.Price != fx(tt.wantPrice) {
+			t.Fatalf("%s: order %d filled at %d, want %d", tt.name, last.Fill.OrderID, last.Fill.Price, fx(tt.wantPrice))
+		}
+	}
+}
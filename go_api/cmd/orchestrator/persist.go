@@ -0,0 +1,368 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"cenayang-market/go-api/internal/database"
+	"cenayang-market/go-api/internal/ws"
+)
+
+// ============================================================================
+// PERSISTENCE - Async Trade, Audit and Snapshot Writes Off the Hot Path
+// ============================================================================
+
+// Persistence defaults
+const (
+	DefaultPersistQueue     = 4096
+	DefaultEnqueueTimeout   = time.Millisecond
+	DefaultSnapshotInterval = 30 * time.Second
+)
+
+// ErrPersistQueueFull is returned when a record is dropped because the
+// writer could not keep up
+var ErrPersistQueueFull = errors.New("persistence queue full")
+
+// Ledger records executed trades.
+// *database.Database and *database.Postgres implement it.
+type Ledger interface {
+	SaveTrade(t database.TradeRecord) error
+}
+
+// EventJournal records broadcast events and reads them back for replay.
+// *database.Database and *database.Postgres implement it.
+type EventJournal interface {
+	SaveEvent(e database.EventRecord) error
+	LoadEvents(from, to int64, limit int) ([]database.EventRecord, error)
+}
+
+// PersistenceBackend - everything the writer stores. *database.Database
+// (SQLite) and *database.Postgres implement it.
+type PersistenceBackend interface {
+	StateStore
+	Ledger
+	AuditLog
+	EventJournal
+}
+
+// PersistenceConfig - the backend and how writes are queued for it
+type PersistenceConfig struct {
+	Backend           PersistenceBackend // nil = trades, events and periodic snapshots are not persisted
+	QueueSize         int                // Trade, audit and event records waiting for the writer (0 = DefaultPersistQueue)
+	EnqueueTimeout    time.Duration      // How long a full queue may hold up the caller before the record is dropped (0 = DefaultEnqueueTimeout, < 0 drops at once)
+	SnapshotInterval  time.Duration      // Portfolio and position snapshots (0 = DefaultSnapshotInterval, < 0 only at shutdown)
+	LatencyHistograms bool               // Latency histograms are saved with each snapshot and merged back in at startup; the backend must implement LatencyStore
+}
+
+// Record kinds
+const (
+	recordTrade uint8 = iota
+	recordAudit
+	recordEvent
+)
+
+// persistRecord - one queued write, by value so queuing does not allocate
+type persistRecord struct {
+	kind  uint8
+	trade database.TradeRecord
+	event database.EventRecord
+
+	// Audit
+	action, resource, details, ip string
+}
+
+// stateSnapshot - a consistent copy of the portfolio and positions, with
+// the latency histograms when they are persisted
+type stateSnapshot struct {
+	portfolio database.PortfolioRecord
+	positions []database.PositionRecord
+	latency   []database.LatencyHistogramRecord
+}
+
+// writeTo stores the snapshot in one backend
+func (s *stateSnapshot) writeTo(store StateStore) error {
+	if err := store.SavePortfolioState(s.portfolio); err != nil {
+		return err
+	}
+	if err := store.ReplacePositions(s.positions); err != nil {
+		return err
+	}
+	if ls, ok := store.(LatencyStore); ok && len(s.latency) > 0 {
+		return ls.SaveLatencyHistograms(s.latency)
+	}
+	return nil
+}
+
+// Persister writes trades, audit entries, events and snapshots to the
+// backend from its own goroutine. Records other than snapshots go through a bounded queue: a
+// full queue holds the caller for at most EnqueueTimeout, then the record is
+// dropped and counted. Snapshots are latest-wins - one waiting is replaced
+// by a newer one.
+type Persister struct {
+	backend  PersistenceBackend
+	queue    chan persistRecord
+	pending  atomic.Pointer[stateSnapshot]
+	wake     chan struct{} // Snapshot pending
+	timeout  time.Duration
+	interval time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+
+	written    uint64
+	dropped    uint64
+	failed     uint64
+	snapshots  uint64
+	superseded uint64
+}
+
+// NewPersister returns nil when cfg has no backend
+func NewPersister(cfg PersistenceConfig) *Persister {
+	if cfg.Backend == nil {
+		return nil
+	}
+	size := cfg.QueueSize
+	if size <= 0 {
+		size = DefaultPersistQueue
+	}
+	timeout := cfg.EnqueueTimeout
+	if timeout == 0 {
+		timeout = DefaultEnqueueTimeout
+	}
+	interval := cfg.SnapshotInterval
+	if interval == 0 {
+		interval = DefaultSnapshotInterval
+	}
+	return &Persister{
+		backend:  cfg.Backend,
+		queue:    make(chan persistRecord, size),
+		wake:     make(chan struct{}, 1),
+		timeout:  timeout,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// enqueue hands rec to the writer, waiting up to the enqueue timeout for
+// room
+func (p *Persister) enqueue(rec persistRecord) error {
+	select {
+	case p.queue <- rec:
+		return nil
+	default:
+	}
+	if p.timeout > 0 {
+		t := time.NewTimer(p.timeout)
+		defer t.Stop()
+		select {
+		case p.queue <- rec:
+			return nil
+		case <-t.C:
+		}
+	}
+	atomic.AddUint64(&p.dropped, 1)
+	return ErrPersistQueueFull
+}
+
+// SaveTrade queues a trade record
+func (p *Persister) SaveTrade(t database.TradeRecord) error {
+	return p.enqueue(persistRecord{kind: recordTrade, trade: t})
+}
+
+// SaveAuditLog queues an audit entry
+func (p *Persister) SaveAuditLog(action, resource, details, ipAddress string) error {
+	return p.enqueue(persistRecord{kind: recordAudit, action: action, resource: resource, details: details, ip: ipAddress})
+}
+
+// SaveEvent queues a broadcast event
+func (p *Persister) SaveEvent(e database.EventRecord) error {
+	return p.enqueue(persistRecord{kind: recordEvent, event: e})
+}
+
+// submitSnapshot queues s for writing, replacing one not yet written
+func (p *Persister) submitSnapshot(s *stateSnapshot) {
+	if p.pending.Swap(s) != nil {
+		atomic.AddUint64(&p.superseded, 1)
+	}
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run writes queued records and snapshots until Stop, then drains what is
+// left
+func (p *Persister) Run() {
+	defer close(p.done)
+	for {
+		select {
+		case rec := <-p.queue:
+			p.write(&rec)
+		case <-p.wake:
+			p.writeSnapshot()
+		case <-p.stop:
+			for {
+				select {
+				case rec := <-p.queue:
+					p.write(&rec)
+				default:
+					p.writeSnapshot()
+					return
+				}
+			}
+		}
+	}
+}
+
+// Stop ends Run once the queue is drained, or gives up at ctx's deadline
+func (p *Persister) Stop(ctx context.Context) error {
+	close(p.stop)
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Persister) write(rec *persistRecord) {
+	var err error
+	switch rec.kind {
+	case recordTrade:
+		err = p.backend.SaveTrade(rec.trade)
+	case recordAudit:
+		err = p.backend.SaveAuditLog(rec.action, rec.resource, rec.details, rec.ip)
+	case recordEvent:
+		err = p.backend.SaveEvent(rec.event)
+	}
+	if err != nil {
+		atomic.AddUint64(&p.failed, 1)
+		log.Printf("[DB] Failed to persist record: %v", err)
+		return
+	}
+	atomic.AddUint64(&p.written, 1)
+}
+
+func (p *Persister) writeSnapshot() {
+	s := p.pending.Swap(nil)
+	if s == nil {
+		return
+	}
+	if err := s.writeTo(p.backend); err != nil {
+		atomic.AddUint64(&p.failed, 1)
+		log.Printf("[DB] Failed to persist snapshot: %v", err)
+		return
+	}
+	atomic.AddUint64(&p.snapshots, 1)
+}
+
+// PersistStats - writer counters for /api/health
+type PersistStats struct {
+	Queued     int
+	Written    uint64
+	Dropped    uint64
+	Failed     uint64
+	Snapshots  uint64
+	Superseded uint64
+}
+
+// Stats returns the writer's counters; zero for a nil Persister
+func (p *Persister) Stats() PersistStats {
+	if p == nil {
+		return PersistStats{}
+	}
+	return PersistStats{
+		Queued:     len(p.queue),
+		Written:    atomic.LoadUint64(&p.written),
+		Dropped:    atomic.LoadUint64(&p.dropped),
+		Failed:     atomic.LoadUint64(&p.failed),
+		Snapshots:  atomic.LoadUint64(&p.snapshots),
+		Superseded: atomic.LoadUint64(&p.superseded),
+	}
+}
+
+// captureState copies the portfolio and positions under every shard lock
+func (sm *ShardedStateManager) captureState() *stateSnapshot {
+	sm.lockShards()
+	defer sm.unlockShards()
+
+	s := &stateSnapshot{}
+	for i := range sm.shards {
+		for _, pos := range sm.shards[i].positions {
+			s.positions = append(s.positions, database.PositionRecord{
+				SymbolHash:    pos.SymbolHash,
+				Symbol:        sm.symbols.Name(pos.SymbolHash),
+				Side:          pos.Side,
+				Quantity:      pos.Quantity,
+				EntryPrice:    pos.EntryPrice,
+				CurrentPrice:  pos.CurrentPrice,
+				UnrealizedPnL: pos.UnrealizedPnL,
+				RealizedPnL:   pos.RealizedPnL,
+			})
+		}
+	}
+	st := &sm.state
+	s.portfolio = database.PortfolioRecord{
+		Equity:          atomic.LoadInt64(&st.Equity),
+		Cash:            atomic.LoadInt64(&st.Cash),
+		TotalPnL:        atomic.LoadInt64(&st.TotalPnL),
+		DailyPnL:        atomic.LoadInt64(&st.DailyPnL),
+		HighWaterMark:   atomic.LoadInt64(&st.HighWaterMark),
+		CurrentDrawdown: atomic.LoadInt64(&st.CurrentDrawdown),
+		KillSwitch:      atomic.LoadInt32(&st.KillSwitch) != 0,
+		SequenceID:      atomic.LoadUint64(&st.SequenceID),
+		StartedAt:       sm.startTime.UnixNano(),
+	}
+	return s
+}
+
+// persistSnapshot queues a periodic snapshot. A standby leaves the
+// backend to the active.
+func (sm *ShardedStateManager) persistSnapshot() {
+	if sm.persist == nil || sm.Standby() {
+		return
+	}
+	s := sm.captureState()
+	s.latency = sm.latencyRecords()
+	sm.persist.submitSnapshot(s)
+}
+
+// persistTrade queues a booked fill for the ledger - called after the
+// shard lock is released. A standby leaves the ledger to the active.
+func (sm *ShardedStateManager) persistTrade(fill *FillEvent, tag string, reduceOnly bool, realized int64, seq uint64) {
+	if sm.persist == nil || sm.Standby() {
+		return
+	}
+	// Dropped records are counted by the writer
+	_ = sm.persist.SaveTrade(database.TradeRecord{
+		ClientOrderID: fill.OrderID,
+		SymbolHash:    fill.SymbolHash,
+		Symbol:        sm.symbols.Name(fill.SymbolHash),
+		Tag:           tag,
+		SeqID:         seq,
+		ReduceOnly:    reduceOnly,
+		Side:          fill.Side,
+		Quantity:      fill.Quantity,
+		Price:         fill.Price,
+		Commission:    fill.Commission,
+		PnL:           realized,
+	})
+}
+
+// journalEvent queues a broadcast event for replay. Heartbeats carry no
+// state and are left out; a standby leaves the backend to the active.
+func (sm *ShardedStateManager) journalEvent(e *ws.BinaryEvent) {
+	if sm.persist == nil || e.Type == ws.EventHeartbeat || sm.Standby() {
+		return
+	}
+	// Dropped records are counted by the writer
+	_ = sm.persist.SaveEvent(database.EventRecord{
+		SeqID:     e.SeqID,
+		Type:      e.Type,
+		Data:      e.Data,
+		Timestamp: e.Timestamp,
+	})
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"cenayang-market/go-api/internal/database"
+)
+
+// memBackend stores what the writer saves and reads it back like a
+// database. With block set, SaveTrade signals entered and waits for block
+// to close.
+type memBackend struct {
+	mu        sync.Mutex
+	portfolio database.PortfolioRecord
+	positions []database.PositionRecord
+	trades    []database.TradeRecord
+	audits    []string
+	events    []database.EventRecord
+	latency   []database.LatencyHistogramRecord
+	states    int
+	loadErr   error // Returned by LoadLatencyHistograms
+
+	entered chan struct{}
+	block   chan struct{}
+}
+
+func (m *memBackend) SavePortfolioState(p database.PortfolioRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.portfolio = p
+	m.states++
+	return nil
+}
+
+func (m *memBackend) ReplacePositions(positions []database.PositionRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.positions = positions
+	return nil
+}
+
+func (m *memBackend) SaveTrade(trade database.TradeRecord) error {
+	if m.block != nil {
+		m.entered <- struct{}{}
+		<-m.block
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	trade.Timestamp = time.Now().UnixNano()
+	m.trades = append(m.trades, trade)
+	return nil
+}
+
+func (m *memBackend) SaveAuditLog(action, resource, details, ipAddress string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.audits = append(m.audits, action)
+	return nil
+}
+
+func (m *memBackend) SaveEvent(e database.EventRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, e)
+	return nil
+}
+
+func (m *memBackend) SaveLatencyHistograms(records []database.LatencyHistogramRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.latency = records
+	return nil
+}
+
+func (m *memBackend) LoadLatencyHistograms() ([]database.LatencyHistogramRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.latency, m.loadErr
+}
+
+func (m *memBackend) LoadEvents(from, to int64, limit int) ([]database.EventRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var events []database.EventRecord
+	for _, e := range m.events {
+		if e.Timestamp >= from && e.Timestamp < to && len(events) < limit {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func (m *memBackend) LoadPortfolioState() (database.PortfolioRecord, error) {
+	return m.portfolio, nil
+}
+
+func (m *memBackend) LoadPositionRecords() ([]database.PositionRecord, error) {
+	return m.positions, nil
+}
+
+func (m *memBackend) LoadTrades(from int64) ([]database.TradeRecord, error) {
+	var trades []database.TradeRecord
+	for _, t := range m.trades {
+		if t.Timestamp >= from {
+			trades = append(trades, t)
+		}
+	}
+	return trades, nil
+}
+
+func TestPersisterTradesAndSnapshot(t *testing.T) {
+	backend := &memBackend{}
+	cfg := testConfig()
+	cfg.Persistence = PersistenceConfig{Backend: backend}
+	sm := NewShardedStateManager(cfg)
+	go sm.persist.Run()
+
+	h := sm.symbols.Hash("DBUSD")
+	sm.ApplyFill(&FillEvent{SymbolHash: h, Side: 0, Quantity: fx(2), Price: fx(100)})
+	sm.ApplyFill(&FillEvent{SymbolHash: h, Side: 1, Quantity: fx(1), Price: fx(110)})
+	sm.audit.SaveAuditLog("HWM_RESET", "risk", "test", "")
+	sm.persistSnapshot()
+	if err := sm.persist.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(backend.trades) != 2 || len(backend.audits) != 1 || len(backend.events) != 2 || backend.states != 1 || len(backend.positions) != 1 {
+		t.Fatalf("%d trades, %d audits, %d events, %d states, %d positions stored", len(backend.trades), len(backend.audits), len(backend.events), backend.states, len(backend.positions))
+	}
+	if s := sm.persist.Stats(); s.Written != 5 || s.Snapshots != 1 || s.Dropped != 0 {
+		t.Fatalf("stats %+v", s)
+	}
+}
+
+// A full queue drops at once with a negative timeout, and of two snapshots
+// waiting only the newer is written
+func TestPersisterBackpressure(t *testing.T) {
+	backend := &memBackend{entered: make(chan struct{}, 1), block: make(chan struct{})}
+	p := NewPersister(PersistenceConfig{Backend: backend, QueueSize: 2, EnqueueTimeout: -1})
+	go p.Run()
+
+	p.SaveTrade(database.TradeRecord{Quantity: 1})
+	<-backend.entered // The writer holds the first trade
+	for q := int64(2); q <= 3; q++ {
+		if err := p.SaveTrade(database.TradeRecord{Quantity: q}); err != nil {
+			t.Fatalf("trade %d: %v", q, err)
+		}
+	}
+	if err := p.SaveTrade(database.TradeRecord{Quantity: 4}); err != ErrPersistQueueFull {
+		t.Fatalf("err %v, want %v", err, ErrPersistQueueFull)
+	}
+	p.submitSnapshot(&stateSnapshot{portfolio: database.PortfolioRecord{SequenceID: 1}})
+	p.submitSnapshot(&stateSnapshot{portfolio: database.PortfolioRecord{SequenceID: 2}})
+
+	go func() {
+		for range backend.entered {
+		}
+	}()
+	close(backend.block)
+	if err := p.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	close(backend.entered)
+	s := p.Stats()
+	if s.Written != 3 || s.Dropped != 1 || s.Snapshots != 1 || s.Superseded != 1 {
+		t.Fatalf("stats %+v", s)
+	}
+	if len(backend.trades) != 3 || backend.trades[2].Quantity != 3 {
+		t.Fatalf("%d trades, want quantities 1 to 3 in order", len(backend.trades))
+	}
+}
+
+// Each run's samples are merged into what earlier runs saved, a histogram
+// stored with another bucket layout is dropped, and a failed load leaves
+// the stored history untouched
+func TestLatencyPersistence(t *testing.T) {
+	backend := &memBackend{}
+	run := func() *ShardedStateManager {
+		cfg := testConfig()
+		cfg.Persistence = PersistenceConfig{Backend: backend, LatencyHistograms: true}
+		return NewShardedStateManager(cfg)
+	}
+	stored := func(name string) uint64 {
+		for _, rec := range backend.latency {
+			if rec.Name == name {
+				return rec.Count
+			}
+		}
+		return 0
+	}
+
+	first := run()
+	for range 3 {
+		first.ingestionHist.Record(1_000)
+	}
+	first.riskHist.Record(50)
+	if err := first.PersistState(backend); err != nil {
+		t.Fatal(err)
+	}
+	if len(backend.latency) != 4 || stored("ingestion") != 3 || stored("risk") != 1 {
+		t.Fatalf("stored %+v", backend.latency)
+	}
+
+	second := run()
+	if n := second.ingestionHist.record("").Count; n != 3 {
+		t.Fatalf("%d ingestion samples restored, want 3", n)
+	}
+	second.ingestionHist.Record(2_000)
+	if err := second.PersistState(backend); err != nil {
+		t.Fatal(err)
+	}
+	if stored("ingestion") != 4 || stored("risk") != 1 {
+		t.Fatalf("stored %+v, want the runs accumulated", backend.latency)
+	}
+
+	for i := range backend.latency {
+		if backend.latency[i].Name == "risk" {
+			backend.latency[i].BucketSize++
+		}
+	}
+	if third := run(); third.riskHist.record("").Count != 0 || third.ingestionHist.record("").Count != 4 {
+		t.Fatal("histogram with another layout merged")
+	}
+
+	backend.loadErr = errors.New("unreachable")
+	fourth := run()
+	fourth.ingestionHist.Record(3_000)
+	if err := fourth.PersistState(backend); err != nil {
+		t.Fatal(err)
+	}
+	if stored("ingestion") != 4 {
+		t.Fatalf("stored history overwritten after a failed load: %d samples", stored("ingestion"))
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"cenayang-market/go-api/internal/handlers"
+)
+
+// ============================================================================
+// POSITIONS ENDPOINT - Shard Read Locks, Pre-Allocated Buffers
+// ============================================================================
+
+// MaxPositionsPage caps ?limit= on /api/positions
+const MaxPositionsPage = 1000
+
+// handlePositions serves open positions, by symbol unless ?sort= names
+// another key (see positionSortKeys). ?limit= (at most MaxPositionsPage)
+// and ?offset= page through them; without a limit every position from the
+// offset on is served. total counts all open positions.
+func (sm *ShardedStateManager) handlePositions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "GET required", http.StatusMethodNotAllowed)
+		return
+	}
+	limit, offset, errResp := pageParams(r, MaxPositionsPage)
+	if errResp != nil {
+		handlers.WriteError(w, r, errResp)
+		return
+	}
+
+	var rows []positionRow
+	for i := 0; i < NumShards; i++ {
+		shard := &sm.shards[i]
+		shard.mu.RLock()
+		for _, pos := range shard.positions {
+			rows = append(rows, positionRow{symbol: sm.symbols.Name(pos.SymbolHash), pos: *pos})
+		}
+		shard.mu.RUnlock()
+	}
+	if errResp := sortList(r, rows, positionSortKeys); errResp != nil {
+		handlers.WriteError(w, r, errResp)
+		return
+	}
+
+	buf := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(buf)
+
+	total := len(rows)
+	rows = rows[min(offset, total):]
+	if limit > 0 && len(rows) > limit {
+		rows = rows[:limit]
+	}
+
+	now := sm.clock.Now()
+	b := append((*buf)[:0], `{"total":`...)
+	b = strconv.AppendInt(b, int64(total), 10)
+	b = append(b, `,"offset":`...)
+	b = strconv.AppendInt(b, int64(offset), 10)
+	b = append(b, `,"positions":[`...)
+	for i := range rows {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = appendPosition(b, &rows[i].pos, rows[i].symbol)
+		b = sm.appendQuoteAge(b, rows[i].pos.SymbolHash, now)
+	}
+	b = append(b, `]}`...)
+
+	handlers.WriteJSON(w, r, http.StatusOK, b)
+}
+
+// handlePosition serves one open position by symbol, with its margin, age
+// and scale-in history, read under its shard lock so every field is from
+// the same state. 404 when the symbol is flat.
+func (sm *ShardedStateManager) handlePosition(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "GET required", http.StatusMethodNotAllowed)
+		return
+	}
+
+	hash := sm.symbols.Hash(r.PathValue("symbol"))
+	shard := sm.GetShard(hash)
+	shard.mu.RLock()
+	p, ok := shard.positions[hash]
+	var pos PositionOptimized
+	var scale scaleHistory
+	if ok {
+		pos = *p
+		scale = positionScale(shard, p)
+	}
+	shard.mu.RUnlock()
+	if !ok {
+		handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusNotFound, Error: "no open position", Field: "symbol"})
+		return
+	}
+	initial, maintenance := positionMargin(sm.symbols.Get(hash), &pos)
+
+	buf := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(buf)
+
+	b := appendPosition((*buf)[:0], &pos, sm.symbols.Name(hash))
+	b = sm.appendQuoteAge(b, hash, sm.clock.Now())
+	b = append(b[:len(b)-1], `,"initial_margin":`...) // Reopen for the computed fields
+	b = appendFixed(b, initial)
+	b = append(b, `,"maintenance_margin":`...)
+	b = appendFixed(b, maintenance)
+	if pos.OpenedAt != 0 {
+		b = append(b, `,"opened_at_ns":`...)
+		b = strconv.AppendInt(b, pos.OpenedAt, 10)
+		b = append(b, `,"age_ms":`...)
+		b = strconv.AppendInt(b, time.Since(time.Unix(0, pos.OpenedAt)).Milliseconds(), 10)
+	}
+	b = append(b, `,"adds":`...)
+	b = strconv.AppendInt(b, int64(scale.Adds), 10)
+	b = append(b, `,"last_add_price":`...)
+	b = appendFixed(b, scale.LastPrice)
+	b = append(b, '}')
+
+	handlers.WriteJSON(w, r, http.StatusOK, b)
+}
+
+// appendPosition serializes a position - caller holds the shard lock or a copy
+func appendPosition(b []byte, pos *PositionOptimized, symbol string) []byte {
+	b = append(b, `{"symbol":`...)
+	b = strconv.AppendQuote(b, symbol)
+	b = append(b, `,"symbol_hash":"`...)
+	b = strconv.AppendUint(b, pos.SymbolHash, 16)
+	b = append(b, `","side":"`...)
+	if pos.Side == 0 {
+		b = append(b, `LONG`...)
+	} else {
+		b = append(b, `SHORT`...)
+	}
+	b = append(b, `","quantity":`...)
+	b = appendFixed(b, pos.Quantity)
+	b = append(b, `,"entry_price":`...)
+	b = appendFixed(b, pos.EntryPrice)
+	b = append(b, `,"current_price":`...)
+	b = appendFixed(b, pos.CurrentPrice)
+	b = append(b, `,"unrealized_pnl":`...)
+	b = appendFixed(b, pos.UnrealizedPnL)
+	b = append(b, `,"realized_pnl":`...)
+	b = appendFixed(b, pos.RealizedPnL)
+	b = append(b, `,"commission":`...)
+	b = appendFixed(b, pos.Commission)
+	b = append(b, `,"breakeven_price":`...)
+	b = appendFixed(b, pos.BreakevenPrice)
+	b = append(b, `,"tag":`...)
+	b = strconv.AppendQuote(b, pos.Tag)
+	b = append(b, `,"updated_at_ns":`...)
+	b = strconv.AppendInt(b, pos.UpdatedAt, 10)
+	return append(b, '}')
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"sync/atomic"
+
+	"cenayang-market/go-api/internal/ws"
+)
+
+// ============================================================================
+// PROFIT TARGETS - Locking In Gains, the Upside of the Daily Loss Limit
+// ============================================================================
+
+// ProfitTargetHit reports whether DailyPnL reached Config.DailyProfitTarget
+// this session. Until the next session only reduce-only orders pass.
+func (sm *ShardedStateManager) ProfitTargetHit() bool {
+	return atomic.LoadInt32(&sm.profitTargetHit) != 0
+}
+
+// checkProfitTarget pauses opening orders the first time a session's
+// DailyPnL reaches the target, closing every position first under
+// Config.ProfitTargetFlatten. A standby follows its active's pause.
+func (sm *ShardedStateManager) checkProfitTarget(dailyPnL int64) {
+	target := toFixed(sm.config.DailyProfitTarget)
+	if target <= 0 || dailyPnL < target || sm.Standby() || !atomic.CompareAndSwapInt32(&sm.profitTargetHit, 0, 1) {
+		return
+	}
+	if sm.SetTradingPaused(true) {
+		atomic.StoreInt32(&sm.profitTargetPaused, 1) // Ours to lift at the session boundary
+	}
+	flattened := 0
+	if sm.config.ProfitTargetFlatten {
+		flattened = sm.flattenAll()
+	}
+	log.Printf("[RISK] Daily profit target hit: daily PnL %s at or above %s; opening orders paused, %d positions flattened",
+		appendFixed(nil, dailyPnL), appendFixed(nil, target), flattened)
+
+	seq := atomic.LoadUint64(&sm.state.SequenceID)
+	b := make([]byte, 0, 128)
+	b = append(b, `{"type":"profit_target_hit","daily_pnl":`...)
+	b = appendFixed(b, dailyPnL)
+	b = append(b, `,"target":`...)
+	b = appendFixed(b, target)
+	b = append(b, `,"flattened":`...)
+	b = strconv.AppendInt(b, int64(flattened), 10)
+	b = append(b, `,"seq_id":`...)
+	b = strconv.AppendUint(b, seq, 10)
+	b = append(b, '}')
+	sm.publish(ws.EventProfitTargetHit, seq, b)
+}
+
+// resetProfitTarget re-arms the daily target at a session boundary and
+// lifts the pause it set; a pause an operator set stays
+func (sm *ShardedStateManager) resetProfitTarget() {
+	if atomic.SwapInt32(&sm.profitTargetHit, 0) == 0 {
+		return
+	}
+	if atomic.SwapInt32(&sm.profitTargetPaused, 0) != 0 {
+		sm.SetTradingPaused(false)
+		log.Printf("[RISK] New session: trading resumed after the daily profit target")
+	}
+}
+
+// checkPositionProfit closes a position whose unrealized PnL reached
+// Config.PositionProfitTarget
+func (sm *ShardedStateManager) checkPositionProfit(symbolHash uint64, unrealized int64) {
+	target := toFixed(sm.config.PositionProfitTarget)
+	if target <= 0 || unrealized < target || sm.Standby() {
+		return
+	}
+	if sm.flattenPosition(symbolHash) {
+		log.Printf("[RISK] %s unrealized PnL %s at or above the position target %s: flattening",
+			sm.symbols.Name(symbolHash), appendFixed(nil, unrealized), appendFixed(nil, target))
+	}
+}
+
+// flattenAll closes every open position and returns how many close orders
+// went out
+func (sm *ShardedStateManager) flattenAll() int {
+	var symbols []uint64
+	for i := range sm.shards {
+		shard := &sm.shards[i]
+		shard.mu.RLock()
+		for hash := range shard.positions {
+			symbols = append(symbols, hash)
+		}
+		shard.mu.RUnlock()
+	}
+	n := 0
+	for _, hash := range symbols {
+		if sm.flattenPosition(hash) {
+			n++
+		}
+	}
+	return n
+}
+
+// flattenPosition submits a reduce-only market order for the whole of a
+// position, unless the close it sent earlier is still working. Returns
+// whether an order went out.
+func (sm *ShardedStateManager) flattenPosition(symbolHash uint64) bool {
+	if id, ok := sm.flattening.Load(symbolHash); ok && sm.isWorkingOrder(id.(uint64), symbolHash) {
+		return false
+	}
+	shard := sm.GetShard(symbolHash)
+	shard.mu.RLock()
+	pos, ok := shard.positions[symbolHash]
+	var side uint8
+	var quantity int64
+	if ok {
+		side, quantity = 1-pos.Side, pos.Quantity
+	}
+	shard.mu.RUnlock()
+	if quantity <= 0 {
+		return false
+	}
+
+	order := &OrderOptimized{SymbolHash: symbolHash, Side: side, Quantity: quantity, ReduceOnly: true}
+	result, err := sm.SubmitOrder(order)
+	if err != nil || !result.Approved {
+		reason := result.Reason.String()
+		if err != nil {
+			reason = err.Error()
+		}
+		log.Printf("[RISK] Flatten of %s refused: %s", sm.symbols.Name(symbolHash), reason)
+		return false
+	}
+	sm.flattening.Store(symbolHash, order.ID)
+	return true
+}
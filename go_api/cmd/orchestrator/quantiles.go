@@ -0,0 +1,150 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// ============================================================================
+// STREAMING QUANTILES - P² Estimates of P50 and P99 Without Buckets
+// ============================================================================
+
+// The bucket histograms answer percentiles only to their bucket width and
+// clamp anything past their range into the last bucket. The P² algorithm
+// (Jain & Chlamtac, 1985) tracks one quantile with five markers whose
+// heights are nudged toward it on every sample: O(1) time and memory per
+// sample, and no fixed range, so a 40ms stall reads as 40ms.
+
+// p2Estimator - one quantile's five P² markers
+type p2Estimator struct {
+	p       float64
+	n       int
+	heights [5]float64 // Marker heights, the middle one the estimate
+	pos     [5]float64 // Actual marker positions, 1-based
+	want    [5]float64 // Desired marker positions
+	step    [5]float64 // Desired position increment per sample
+}
+
+func newP2Estimator(p float64) p2Estimator {
+	return p2Estimator{p: p, step: [5]float64{0, p / 2, p, (1 + p) / 2, 1}}
+}
+
+// add folds one sample into the markers
+func (e *p2Estimator) add(x float64) {
+	if e.n < 5 {
+		e.heights[e.n] = x
+		e.n++
+		if e.n == 5 {
+			slices.Sort(e.heights[:])
+			e.pos = [5]float64{1, 2, 3, 4, 5}
+			e.want = [5]float64{1, 1 + 2*e.p, 1 + 4*e.p, 3 + 2*e.p, 5}
+		}
+		return
+	}
+	e.n++
+
+	// Cell the sample falls in, stretching the extremes to cover it
+	var k int
+	switch {
+	case x < e.heights[0]:
+		e.heights[0] = x
+	case x >= e.heights[4]:
+		e.heights[4] = x
+		k = 3
+	default:
+		for k = 0; x >= e.heights[k+1]; k++ {
+		}
+	}
+	for i := k + 1; i < 5; i++ {
+		e.pos[i]++
+	}
+	for i := range e.want {
+		e.want[i] += e.step[i]
+	}
+
+	// Move each middle marker at most one position toward where it should be
+	for i := 1; i <= 3; i++ {
+		d := e.want[i] - e.pos[i]
+		if (d < 1 || e.pos[i+1]-e.pos[i] <= 1) && (d > -1 || e.pos[i-1]-e.pos[i] >= -1) {
+			continue
+		}
+		s := math.Copysign(1, d)
+		h := e.parabolic(i, s)
+		if h <= e.heights[i-1] || h >= e.heights[i+1] {
+			h = e.linear(i, s) // The parabola overshot a neighbour
+		}
+		e.heights[i] = h
+		e.pos[i] += s
+	}
+}
+
+// parabolic is the P² piecewise-parabolic height for marker i moved by s
+func (e *p2Estimator) parabolic(i int, s float64) float64 {
+	q, n := &e.heights, &e.pos
+	return q[i] + s/(n[i+1]-n[i-1])*((n[i]-n[i-1]+s)*(q[i+1]-q[i])/(n[i+1]-n[i])+(n[i+1]-n[i]-s)*(q[i]-q[i-1])/(n[i]-n[i-1]))
+}
+
+// linear interpolates marker i's height toward its neighbour in direction s
+func (e *p2Estimator) linear(i int, s float64) float64 {
+	j := i + int(s)
+	return e.heights[i] + s*(e.heights[j]-e.heights[i])/(e.pos[j]-e.pos[i])
+}
+
+// value returns the estimate; with fewer than five samples, the exact
+// nearest-rank quantile of those seen
+func (e *p2Estimator) value() float64 {
+	if e.n == 0 {
+		return 0
+	}
+	if e.n < 5 {
+		seen := e.heights
+		slices.Sort(seen[:e.n])
+		rank := max(int(math.Ceil(e.p*float64(e.n))), 1)
+		return seen[rank-1]
+	}
+	return e.heights[2]
+}
+
+// StreamingQuantiles - P² estimates of one latency's P50 and P99 since
+// start. A sample costs a short critical section and no allocation.
+type StreamingQuantiles struct {
+	mu    sync.Mutex
+	count uint64
+	p50   p2Estimator
+	p99   p2Estimator
+}
+
+func NewStreamingQuantiles() *StreamingQuantiles {
+	return &StreamingQuantiles{p50: newP2Estimator(0.50), p99: newP2Estimator(0.99)}
+}
+
+// Record adds one sample, in nanoseconds
+func (q *StreamingQuantiles) Record(ns int64) {
+	q.mu.Lock()
+	q.count++
+	q.p50.add(float64(ns))
+	q.p99.add(float64(ns))
+	q.mu.Unlock()
+}
+
+// Quantiles returns the sample count and the P50 and P99 estimates in
+// nanoseconds
+func (q *StreamingQuantiles) Quantiles() (count uint64, p50, p99 int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.count, int64(math.Round(q.p50.value())), int64(math.Round(q.p99.value()))
+}
+
+// appendJSON writes the count and estimates as a JSON object
+func (q *StreamingQuantiles) appendJSON(b []byte) []byte {
+	count, p50, p99 := q.Quantiles()
+	b = append(b, `{"count":`...)
+	b = strconv.AppendUint(b, count, 10)
+	b = append(b, `,"p50_ns":`...)
+	b = strconv.AppendInt(b, p50, 10)
+	b = append(b, `,"p99_ns":`...)
+	b = strconv.AppendInt(b, p99, 10)
+	return append(b, '}')
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"math"
+	"math/rand/v2"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStreamingQuantiles(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	tests := []struct {
+		name     string
+		sample   func() float64
+		p50, p99 float64
+		tol      float64 // Relative
+	}{
+		{"uniform 0-100µs", func() float64 { return rng.Float64() * 100_000 }, 50_000, 99_000, 0.01},
+		{"exponential, mean 1ms", func() float64 { return rng.ExpFloat64() * 1_000_000 }, 1_000_000 * math.Ln2, 1_000_000 * math.Log(100), 0.03},
+		{"normal 500±50µs", func() float64 { return 500_000 + rng.NormFloat64()*50_000 }, 500_000, 500_000 + 2.326348*50_000, 0.01},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewStreamingQuantiles()
+			for range 200_000 {
+				q.Record(int64(tt.sample()))
+			}
+			count, p50, p99 := q.Quantiles()
+			if count != 200_000 {
+				t.Fatalf("count %d", count)
+			}
+			if math.Abs(float64(p50)-tt.p50) > tt.tol*tt.p50 {
+				t.Errorf("P50 %d, want %.0f ± %.1f%%", p50, tt.p50, tt.tol*100)
+			}
+			if math.Abs(float64(p99)-tt.p99) > tt.tol*tt.p99 {
+				t.Errorf("P99 %d, want %.0f ± %.1f%%", p99, tt.p99, tt.tol*100)
+			}
+		})
+	}
+
+	// Under five samples the quantiles are exact
+	q := NewStreamingQuantiles()
+	for _, ns := range []int64{30, 10, 20} {
+		q.Record(ns)
+	}
+	if _, p50, p99 := q.Quantiles(); p50 != 20 || p99 != 30 {
+		t.Fatalf("P50 %d P99 %d of 10, 20, 30", p50, p99)
+	}
+}
+
+func TestLatencyEndpointQuantiles(t *testing.T) {
+	sm := NewShardedStateManager(testConfig())
+	srv := httptest.NewServer(setupHTTPRoutes(sm))
+	defer srv.Close()
+	h := sm.symbols.Hash("LATUSD")
+	sm.feed.OnTick(&MarketTickOptimized{SymbolHash: h, LastPrice: fx(100), Timestamp: time.Now().Add(-time.Millisecond).UnixNano()})
+	tick(sm, h, 101) // No timestamp: no end-to-end sample
+	sm.RiskCheckFast(&OrderOptimized{SymbolHash: h, Quantity: fx(1), Price: fx(100)})
+
+	quantiles, _ := smokeGet(t, srv.URL+"/api/metrics/latency")["quantiles"].(map[string]any)
+	for _, v := range []struct {
+		name  string
+		count float64
+	}{{"ingestion", 2}, {"risk", 1}, {"end_to_end", 1}} {
+		est, _ := quantiles[v.name].(map[string]any)
+		if est["count"] != v.count {
+			t.Fatalf("%s: %v, want %v samples", v.name, est, v.count)
+		}
+	}
+	if e2e := quantiles["end_to_end"].(map[string]any); e2e["p50_ns"].(float64) < 1e6 {
+		t.Fatalf("end-to-end %v under the tick's 1ms age", e2e)
+	}
+}
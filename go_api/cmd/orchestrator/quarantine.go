@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"cenayang-market/go-api/internal/handlers"
+	"cenayang-market/go-api/internal/ws"
+)
+
+// ============================================================================
+// FILL QUARANTINE - Fills That Cannot Be Applied Consistently
+// ============================================================================
+
+// Quarantine reasons
+const (
+	QuarantineReduceWithoutPosition = "REDUCE_WITHOUT_POSITION"
+	QuarantineReduceExceedsPosition = "REDUCE_EXCEEDS_POSITION"
+	QuarantineFillPriceAnomaly      = "FILL_PRICE_ANOMALY"
+	QuarantineMalformedFill         = "MALFORMED_FILL"
+	QuarantineTerminalOrder         = "TERMINAL_ORDER" // Late fill for a filled, cancelled or rejected order
+)
+
+// QuarantinedFill - a fill (or the part of one) left out of the book
+type QuarantinedFill struct {
+	Fill          FillEvent
+	Reason        string
+	MarketPrice   int64 // Reference price for FILL_PRICE_ANOMALY
+	QuarantinedAt int64
+}
+
+// fillQuarantine keeps the most recent quarantined fills for operators to
+// reconcile against the venue, bounded by RetentionConfig.QuarantinedFills.
+// The total count is exact.
+type fillQuarantine struct {
+	mu    sync.Mutex
+	fills []QuarantinedFill // Oldest first
+	total uint64            // Atomic
+}
+
+// quarantineFill records a fill that was not applied and broadcasts it.
+// Must not be called with a shard lock held.
+func (sm *ShardedStateManager) quarantineFill(fill FillEvent, reason string, marketPrice int64) {
+	q := &sm.quarantine
+	entry := QuarantinedFill{Fill: fill, Reason: reason, MarketPrice: marketPrice, QuarantinedAt: sm.clock.Now().UnixNano()}
+
+	q.mu.Lock()
+	if limit := sm.retention.QuarantinedFills.MaxEntries; limit > 0 && len(q.fills) >= limit {
+		n := copy(q.fills, q.fills[len(q.fills)-limit+1:])
+		q.fills = q.fills[:n]
+	}
+	q.fills = append(q.fills, entry)
+	q.mu.Unlock()
+	atomic.AddUint64(&q.total, 1)
+
+	log.Printf("[FILL] Quarantined order %d %s: %s qty %s",
+		fill.OrderID, sm.symbols.Name(fill.SymbolHash), reason, appendFixed(nil, fill.Quantity))
+	sm.raiseAlert(AlertWarning, "fill_quarantined", fmt.Sprintf("order %d %s: %s qty %s",
+		fill.OrderID, sm.symbols.Name(fill.SymbolHash), reason, appendFixed(nil, fill.Quantity)))
+
+	eventType := ws.EventFillQuarantined
+	if reason == QuarantineFillPriceAnomaly {
+		eventType = ws.EventFillPriceAnomaly
+	}
+	b := appendQuarantinedFill(make([]byte, 0, 256), sm, &entry)
+	sm.publish(eventType, atomic.LoadUint64(&sm.state.SequenceID), b)
+}
+
+// malformedFill reports whether a fill cannot describe an execution:
+// non-positive quantity or price, or an unknown side. Negative commission
+// is a rebate and allowed.
+func malformedFill(fill *FillEvent) bool {
+	return fill.Quantity <= 0 || fill.Price <= 0 || fill.Side > 1
+}
+
+// fillPriceAnomaly reports whether a fill's price lies outside the sanity
+// band around the symbol's last market price, returning that price. Fills
+// for symbols without a band or without a tick yet are never anomalous.
+func (sm *ShardedStateManager) fillPriceAnomaly(fill *FillEvent) (int64, bool) {
+	bandBps := sm.symbols.Get(fill.SymbolHash).FillBandBps
+	if bandBps == 0 {
+		bandBps = sm.fillBandBps
+	}
+	if bandBps <= 0 {
+		return 0, false
+	}
+	market, ok := sm.feed.LastPrice(fill.SymbolHash)
+	if !ok {
+		return 0, false
+	}
+	deviation := fill.Price - market
+	if deviation < 0 {
+		deviation = -deviation
+	}
+	return market, mulDiv(deviation, 10000, market) > bandBps
+}
+
+// QuarantinedFills returns the number of fills quarantined since start
+func (sm *ShardedStateManager) QuarantinedFills() uint64 {
+	return atomic.LoadUint64(&sm.quarantine.total)
+}
+
+func appendQuarantinedFill(b []byte, sm *ShardedStateManager, q *QuarantinedFill) []byte {
+	if q.Reason == QuarantineFillPriceAnomaly {
+		b = append(b, `{"type":"fill_price_anomaly","reason":"`...)
+	} else {
+		b = append(b, `{"type":"fill_quarantined","reason":"`...)
+	}
+	b = append(b, q.Reason...)
+	b = append(b, `","order_id":"`...)
+	b = strconv.AppendUint(b, q.Fill.OrderID, 10)
+	b = append(b, `","symbol":`...)
+	b = strconv.AppendQuote(b, sm.symbols.Name(q.Fill.SymbolHash))
+	b = append(b, `,"side":"`...)
+	if q.Fill.Side == 0 {
+		b = append(b, `BUY`...)
+	} else {
+		b = append(b, `SELL`...)
+	}
+	b = append(b, `","quantity":`...)
+	b = appendFixed(b, q.Fill.Quantity)
+	b = append(b, `,"price":`...)
+	b = appendFixed(b, q.Fill.Price)
+	if q.MarketPrice != 0 {
+		b = append(b, `,"market_price":`...)
+		b = appendFixed(b, q.MarketPrice)
+	}
+	b = append(b, `,"fill_seq_id":`...)
+	b = strconv.AppendUint(b, q.Fill.SeqID, 10)
+	b = append(b, `,"quarantined_at":`...)
+	b = strconv.AppendInt(b, q.QuarantinedAt, 10)
+	return append(b, '}')
+}
+
+// handleQuarantinedFills lists retained quarantined fills, oldest first
+func (sm *ShardedStateManager) handleQuarantinedFills(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "GET required", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sm.quarantine.mu.Lock()
+	fills := append([]QuarantinedFill(nil), sm.quarantine.fills...)
+	sm.quarantine.mu.Unlock()
+
+	buf := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(buf)
+
+	b := append((*buf)[:0], `{"total":`...)
+	b = strconv.AppendUint(b, sm.QuarantinedFills(), 10)
+	b = append(b, `,"fills":[`...)
+	for i := range fills {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = appendQuarantinedFill(b, sm, &fills[i])
+	}
+	b = append(b, `]}`...)
+
+	handlers.WriteJSON(w, r, http.StatusOK, b)
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"cenayang-market/go-api/internal/auth"
+	"cenayang-market/go-api/internal/handlers"
+)
+
+// ============================================================================
+// RISK REASON CODES
+// ============================================================================
+
+// RiskReason identifies the outcome of a risk check
+type RiskReason uint8
+
+const (
+	ReasonApproved RiskReason = iota
+
+	// Approved with the quantity cut to the position size limit
+	ReasonPositionClamped
+
+	ReasonKillSwitch
+	ReasonMaxDrawdown
+	ReasonPositionTooLarge
+	ReasonDailyLossLimit
+	ReasonInsufficientCapital
+	ReasonBelowLotSize
+	ReasonInvalidTickSize
+	ReasonStrategyAllocation
+	ReasonStrategyHalted
+	ReasonUnknownStrategy
+	ReasonReduceOnlyViolation
+	ReasonMarketClosed
+	ReasonTradingPaused
+	ReasonStandby
+	ReasonSymbolHalted
+	ReasonScaleInViolation
+	ReasonBelowMinQuantity
+	ReasonBelowMinNotional
+	ReasonDrawdownReduceOnly
+	ReasonGroupLimitExceeded
+	ReasonAccountBlown
+	ReasonShortingNotAllowed
+	ReasonNoMarketPrice
+	numRiskReasons
+
+	firstRejectReason = ReasonKillSwitch // Reasons below approve the order
+)
+
+// Wire names - pre-allocated, never built on the hot path
+var riskReasonNames = [numRiskReasons]string{
+	ReasonApproved:            "APPROVED",
+	ReasonPositionClamped:     "CLAMPED_TO_MAX_POSITION",
+	ReasonKillSwitch:          "KILL_SWITCH_ACTIVE",
+	ReasonMaxDrawdown:         "MAX_DRAWDOWN",
+	ReasonPositionTooLarge:    "POSITION_TOO_LARGE",
+	ReasonDailyLossLimit:      "DAILY_LOSS_LIMIT",
+	ReasonInsufficientCapital: "INSUFFICIENT_CAPITAL",
+	ReasonBelowLotSize:        "BELOW_LOT_SIZE",
+	ReasonInvalidTickSize:     "INVALID_TICK_SIZE",
+	ReasonStrategyAllocation:  "STRATEGY_ALLOCATION_EXCEEDED",
+	ReasonStrategyHalted:      "STRATEGY_HALTED",
+	ReasonUnknownStrategy:     "UNKNOWN_STRATEGY",
+	ReasonReduceOnlyViolation: "REDUCE_ONLY_VIOLATION",
+	ReasonMarketClosed:        "MARKET_CLOSED",
+	ReasonTradingPaused:       "TRADING_PAUSED",
+	ReasonStandby:             "STANDBY",
+	ReasonSymbolHalted:        "SYMBOL_HALTED",
+	ReasonScaleInViolation:    "SCALE_IN_VIOLATION",
+	ReasonBelowMinQuantity:    "BELOW_MIN_QUANTITY",
+	ReasonBelowMinNotional:    "BELOW_MIN_NOTIONAL",
+	ReasonDrawdownReduceOnly:  "DRAWDOWN_REDUCE_ONLY",
+	ReasonGroupLimitExceeded:  "GROUP_LIMIT_EXCEEDED",
+	ReasonAccountBlown:        "ACCOUNT_BLOWN",
+	ReasonShortingNotAllowed:  "SHORTING_NOT_ALLOWED",
+	ReasonNoMarketPrice:       "NO_MARKET_PRICE",
+}
+
+// String returns the wire name of the reason
+func (r RiskReason) String() string {
+	if r < numRiskReasons {
+		return riskReasonNames[r]
+	}
+	return "UNKNOWN"
+}
+
+// ============================================================================
+// REJECTION HISTOGRAM - Per-Reason Atomic Counters
+// ============================================================================
+
+// RejectionHistogram counts risk rejections by reason code
+type RejectionHistogram struct {
+	counts [numRiskReasons]uint64 // Atomic via atomic package
+	since  int64                  // Unix nanos of last reset
+}
+
+func NewRejectionHistogram() *RejectionHistogram {
+	return &RejectionHistogram{since: time.Now().UnixNano()}
+}
+
+// Record counts a rejection - lock-free
+func (h *RejectionHistogram) Record(reason RiskReason) {
+	if reason < firstRejectReason || reason >= numRiskReasons {
+		return
+	}
+	atomic.AddUint64(&h.counts[reason], 1)
+}
+
+// Count returns the number of rejections for a reason
+func (h *RejectionHistogram) Count(reason RiskReason) uint64 {
+	if reason >= numRiskReasons {
+		return 0
+	}
+	return atomic.LoadUint64(&h.counts[reason])
+}
+
+// Since returns when the histogram was last reset (Unix nanos)
+func (h *RejectionHistogram) Since() int64 {
+	return atomic.LoadInt64(&h.since)
+}
+
+// Total returns the rejections counted since the last reset
+func (h *RejectionHistogram) Total() uint64 {
+	var total uint64
+	for i := range h.counts {
+		total += atomic.LoadUint64(&h.counts[i])
+	}
+	return total
+}
+
+// Reset clears all counts
+func (h *RejectionHistogram) Reset() {
+	for i := range h.counts {
+		atomic.StoreUint64(&h.counts[i], 0)
+	}
+	atomic.StoreInt64(&h.since, time.Now().UnixNano())
+}
+
+// ResetSession clears session-scoped statistics, restarts daily PnL from
+// current equity, re-arms the daily profit target and reallocates strategy
+// capital at a session boundary
+func (sm *ShardedStateManager) ResetSession() {
+	sm.rejections.Reset()
+	sm.restoreDailyPnL(0)
+	sm.resetProfitTarget()
+	sm.allocator.Rebalance(atomic.LoadInt64(&sm.state.Equity))
+	log.Printf("[SESSION] Session statistics reset")
+}
+
+// handleRiskRejections serves the per-reason rejection breakdown since
+// the last session reset; total is their sum
+func (sm *ShardedStateManager) handleRiskRejections(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "GET required", http.StatusMethodNotAllowed)
+		return
+	}
+
+	buf := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(buf)
+
+	b := append((*buf)[:0], `{"total":`...)
+	b = strconv.AppendUint(b, sm.rejections.Total(), 10)
+	b = append(b, `,"since_ns":`...)
+	b = strconv.AppendInt(b, sm.rejections.Since(), 10)
+	b = append(b, `,"reasons":{`...)
+	for reason := firstRejectReason; reason < numRiskReasons; reason++ {
+		if reason > firstRejectReason {
+			b = append(b, ',')
+		}
+		b = append(b, '"')
+		b = append(b, reason.String()...)
+		b = append(b, `":`...)
+		b = strconv.AppendUint(b, sm.rejections.Count(reason), 10)
+	}
+	b = append(b, `}}`...)
+
+	handlers.WriteJSON(w, r, http.StatusOK, b)
+}
+
+// restoreDailyPnL moves the session baseline so DailyPnL reads pnl at the
+// current equity
+func (sm *ShardedStateManager) restoreDailyPnL(pnl int64) {
+	atomic.StoreInt64(&sm.sessionEquity, atomic.LoadInt64(&sm.state.Equity)-pnl)
+	atomic.StoreInt64(&sm.state.DailyPnL, pnl)
+}
+
+// handleSessionReset marks a session boundary by hand (admin). It lifts a
+// daily loss lockout and the profit target's pause, so it is audited.
+func (sm *ShardedStateManager) handleSessionReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+	actor := "anonymous"
+	if claims := auth.GetClaims(r.Context()); claims != nil {
+		actor = claims.Username
+	}
+	dailyPnL := atomic.LoadInt64(&sm.state.DailyPnL)
+	sm.ResetSession()
+
+	details := fmt.Sprintf("actor=%s daily_pnl=%s", actor, appendFixed(nil, dailyPnL))
+	log.Printf("[SESSION] Manual session reset: %s", details)
+	if sm.audit != nil {
+		if err := sm.audit.SaveAuditLog("session_reset", "portfolio", details, r.RemoteAddr); err != nil {
+			log.Printf("[SESSION] Failed to audit session reset: %v", err)
+		}
+	}
+	handlers.WriteJSON(w, r, http.StatusOK, []byte(`{"status":"ok"}`))
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestRejectionHistogramCounts(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxPositionSize = 500
+	cfg.Symbols = []SymbolMeta{{Symbol: "REJUSD", TickSize: 0.5, LotSize: 1, Multiplier: 1, Currency: "USD"}}
+	sm := NewShardedStateManager(cfg)
+	h := SymbolHash("REJUSD")
+	checks := []struct {
+		setup    func()
+		side     uint8
+		quantity float64
+		price    float64
+		want     RiskReason
+	}{
+		{nil, 0, 1, 100, ReasonApproved},
+		{nil, 0, 6, 100, ReasonPositionTooLarge},
+		{nil, 1, 6, 100, ReasonPositionTooLarge},
+		{nil, 0, 0.5, 100, ReasonBelowLotSize},
+		{nil, 0, 1, 100.3, ReasonInvalidTickSize},
+		{func() { atomic.StoreInt64(&sm.state.Cash, fx(50)) }, 0, 1, 100, ReasonInsufficientCapital},
+		{nil, 1, 1, 100, ReasonApproved}, // Sells need no cash
+		{func() { atomic.StoreInt64(&sm.state.DailyPnL, -fx(20_000)) }, 0, 1, 100, ReasonDailyLossLimit},
+		{func() { atomic.StoreInt32(&sm.state.KillSwitch, 1) }, 1, 1, 100, ReasonKillSwitch},
+	}
+	for i, c := range checks {
+		if c.setup != nil {
+			c.setup()
+		}
+		if res := sm.RiskCheckFast(&OrderOptimized{SymbolHash: h, Side: c.side, Quantity: fx(c.quantity), Price: fx(c.price)}); res.Reason != c.want {
+			t.Fatalf("check %d: reason %v, want %v", i, res.Reason, c.want)
+		}
+	}
+
+	want := map[RiskReason]uint64{
+		ReasonPositionTooLarge: 2, ReasonBelowLotSize: 1, ReasonInvalidTickSize: 1,
+		ReasonInsufficientCapital: 1, ReasonDailyLossLimit: 1, ReasonKillSwitch: 1,
+	}
+	for reason := RiskReason(0); reason < numRiskReasons; reason++ {
+		if n := sm.rejections.Count(reason); n != want[reason] {
+			t.Errorf("%v counted %d, want %d", reason, n, want[reason])
+		}
+	}
+	if total := atomic.LoadUint64(&sm.riskRejections); total != 7 {
+		t.Errorf("total %d, want 7", total)
+	}
+
+	sm.ResetSession()
+	for reason := RiskReason(0); reason < numRiskReasons; reason++ {
+		if n := sm.rejections.Count(reason); n != 0 {
+			t.Errorf("%v counted %d after the session reset", reason, n)
+		}
+	}
+}
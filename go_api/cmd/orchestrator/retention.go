@@ -0,0 +1,150 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"time"
+)
+
+// ============================================================================
+// RETENTION - Bounded In-Memory History
+// ============================================================================
+
+// RetentionPolicy bounds one buffer. Either limit may be zero (unlimited);
+// a policy with both zero takes the buffer's default.
+type RetentionPolicy struct {
+	MaxEntries int
+	MaxAge     time.Duration
+}
+
+// RetentionConfig - one policy per retained buffer
+type RetentionConfig struct {
+	QuarantinedFills RetentionPolicy
+	// Retired order IDs kept for duplicate detection. A client ID indexed
+	// before this window may be accepted again; generated IDs never repeat.
+	OrderIndex RetentionPolicy
+	// Fills kept per open position for entry attribution; only MaxEntries
+	// applies, as the list is cleared when the position flattens
+	PositionFills RetentionPolicy
+	// Alerts in the operator inbox. MaxAge only drops acknowledged
+	// alerts; at MaxEntries the oldest acknowledged one makes room first.
+	Alerts RetentionPolicy
+	// Booked fills served by /api/fills
+	Fills RetentionPolicy
+}
+
+// DefaultRetention applies to policies left unset
+var DefaultRetention = RetentionConfig{
+	QuarantinedFills: RetentionPolicy{MaxEntries: 256, MaxAge: 7 * 24 * time.Hour},
+	OrderIndex:       RetentionPolicy{MaxEntries: 1_000_000, MaxAge: 24 * time.Hour},
+	PositionFills:    RetentionPolicy{MaxEntries: 512},
+	Alerts:           RetentionPolicy{MaxEntries: 1000, MaxAge: 7 * 24 * time.Hour},
+	Fills:            RetentionPolicy{MaxEntries: 100_000, MaxAge: 24 * time.Hour},
+}
+
+// RetentionTrimInterval is how often the session loop trims buffers
+const RetentionTrimInterval = time.Minute
+
+func (c RetentionConfig) withDefaults() RetentionConfig {
+	if c.QuarantinedFills == (RetentionPolicy{}) {
+		c.QuarantinedFills = DefaultRetention.QuarantinedFills
+	}
+	if c.OrderIndex == (RetentionPolicy{}) {
+		c.OrderIndex = DefaultRetention.OrderIndex
+	}
+	if c.PositionFills == (RetentionPolicy{}) {
+		c.PositionFills = DefaultRetention.PositionFills
+	}
+	if c.Alerts == (RetentionPolicy{}) {
+		c.Alerts = DefaultRetention.Alerts
+	}
+	if c.Fills == (RetentionPolicy{}) {
+		c.Fills = DefaultRetention.Fills
+	}
+	return c
+}
+
+// cutoff returns the oldest instant the policy keeps, zero if unlimited
+func (p RetentionPolicy) cutoff(now time.Time) time.Time {
+	if p.MaxAge <= 0 {
+		return time.Time{}
+	}
+	return now.Add(-p.MaxAge)
+}
+
+// trimRetention applies every retention policy once
+func (sm *ShardedStateManager) trimRetention(now time.Time) {
+	quarantined := sm.trimQuarantine(now)
+	orders := sm.trimOrderIndex(now)
+	alerts := sm.trimAlerts(now)
+	fills := sm.trimFillHistory(now)
+	if quarantined+orders+alerts+fills > 0 {
+		log.Printf("[RETENTION] Trimmed %d quarantined fills, %d retired order IDs, %d acknowledged alerts, %d booked fills", quarantined, orders, alerts, fills)
+	}
+}
+
+// trimQuarantine drops quarantined fills past their age; the entry cap is
+// enforced on insert. Returns the number dropped.
+func (sm *ShardedStateManager) trimQuarantine(now time.Time) int {
+	cutoff := sm.retention.QuarantinedFills.cutoff(now)
+	if cutoff.IsZero() {
+		return 0
+	}
+
+	q := &sm.quarantine
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	n := sort.Search(len(q.fills), func(i int) bool {
+		return q.fills[i].QuarantinedAt >= cutoff.UnixNano()
+	})
+	if n > 0 {
+		q.fills = append(q.fills[:0], q.fills[n:]...)
+	}
+	return n
+}
+
+// trimOrderIndex drops retired order IDs past their age, then the oldest
+// beyond the entry cap. Working orders are never dropped. Returns the
+// number dropped.
+func (sm *ShardedStateManager) trimOrderIndex(now time.Time) int {
+	policy := sm.retention.OrderIndex
+	cutoff := policy.cutoff(now)
+
+	type retiredID struct {
+		id        uint64
+		indexedAt int64
+	}
+	var retired []retiredID
+	sm.orderIndex.Range(func(k, v interface{}) bool {
+		id, entry := k.(uint64), v.(orderIndexEntry)
+		if !sm.isWorkingOrder(id, entry.symbolHash) {
+			retired = append(retired, retiredID{id, entry.indexedAt})
+		}
+		return true
+	})
+	sort.Slice(retired, func(i, j int) bool { return retired[i].indexedAt < retired[j].indexedAt })
+
+	drop := 0
+	if !cutoff.IsZero() {
+		drop = sort.Search(len(retired), func(i int) bool {
+			return retired[i].indexedAt >= cutoff.UnixNano()
+		})
+	}
+	if policy.MaxEntries > 0 && len(retired)-drop > policy.MaxEntries {
+		drop = len(retired) - policy.MaxEntries
+	}
+	for _, r := range retired[:drop] {
+		sm.orderIndex.Delete(r.id)
+	}
+	return drop
+}
+
+// isWorkingOrder reports whether an order is still on its shard's book
+func (sm *ShardedStateManager) isWorkingOrder(id, symbolHash uint64) bool {
+	shard := sm.GetShard(symbolHash)
+	shard.mu.RLock()
+	_, ok := shard.orders[id]
+	shard.mu.RUnlock()
+	return ok
+}
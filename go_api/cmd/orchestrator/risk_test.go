@@ -0,0 +1,212 @@
+package main
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"cenayang-market/go-api/internal/allocator"
+)
+
+// Orders at 100 against DefaultStartingEquity's 100k
+func TestPositionPctOfEquityCap(t *testing.T) {
+	tests := []struct {
+		name     string
+		size     float64 // MaxPositionSize
+		pct      float64
+		equity   float64 // 0 = as started
+		quantity float64
+		want     RiskReason
+	}{
+		{"at the cap", 1e6, 10, 0, 100, ReasonApproved},
+		{"over the cap", 1e6, 10, 0, 101, ReasonPositionTooLarge},
+		{"follows live equity", 1e6, 10, 200_000, 150, ReasonApproved},
+		{"size is tighter", 5000, 10, 0, 51, ReasonPositionTooLarge},
+		{"percent alone", 0, 10, 0, 99, ReasonApproved},
+		{"off", 1e6, 0, 0, 5000, ReasonApproved},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.MaxPositionSize = tt.size
+			cfg.MaxPositionPctOfEquity = tt.pct
+			sm := NewShardedStateManager(cfg)
+			if tt.equity > 0 {
+				atomic.StoreInt64(&sm.state.Equity, fx(tt.equity))
+			}
+			h := sm.symbols.Hash("PCTUSD")
+			if res := sm.RiskCheckFast(&OrderOptimized{SymbolHash: h, Side: 1, Quantity: fx(tt.quantity), Price: fx(100)}); res.Reason != tt.want {
+				t.Fatalf("reason %v, want %v", res.Reason, tt.want)
+			}
+		})
+	}
+}
+
+// A buy must cover its taker commission on top of notional; a rebate is
+// not counted on. 100k of cash against the taker rate.
+func TestCashCheckReservesCommission(t *testing.T) {
+	tests := []struct {
+		name     string
+		taker    float64 // Bps
+		quantity float64 // At 100
+		want     RiskReason
+	}{
+		{"covered", 10, 999, ReasonApproved},                              // 99_900 + 99.90
+		{"commission tips it over", 10, 999.5, ReasonInsufficientCapital}, // 99_950 + 99.95
+		{"no schedule", 0, 1000, ReasonApproved},
+		{"rebate not counted on", -10, 1000.5, ReasonInsufficientCapital}, // 100_050 - 100.05 would pass
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.Symbols = []SymbolMeta{{Symbol: "FEEUSD", TickSize: 0.01, LotSize: 0.5, Multiplier: 1, Currency: "USD", TakerFeeBps: tt.taker}}
+			sm := NewShardedStateManager(cfg)
+			order := &OrderOptimized{SymbolHash: sm.symbols.Hash("FEEUSD"), Side: 0, Quantity: fx(tt.quantity), Price: fx(100)}
+			if res := sm.RiskCheckFast(order); res.Reason != tt.want {
+				t.Fatalf("reason %v, want %v", res.Reason, tt.want)
+			}
+		})
+	}
+}
+
+// Working buys hold their cash, so concurrent submissions cannot each
+// spend the same 100k
+func TestWorkingBuysHoldCash(t *testing.T) {
+	sm := NewShardedStateManager(testConfig())
+	h := sm.symbols.Hash("HOLDUSD")
+	var approved atomic.Int64
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := sm.SubmitOrder(&OrderOptimized{SymbolHash: h, Quantity: fx(300), Price: fx(100)}) // 30k each
+			if err != nil {
+				t.Error(err)
+			} else if res.Approved {
+				approved.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := approved.Load(); n != 3 {
+		t.Fatalf("%d buys of 30k approved against 100k", n)
+	}
+	if held := atomic.LoadInt64(&sm.cashReserved); held != fx(90_000) {
+		t.Fatalf("%d held, want 90k", held)
+	}
+
+	// A partial fill swaps held cash for debited cash; the reject frees the rest
+	var id uint64
+	for oid := range sm.GetShard(h).orders {
+		id = oid
+		break
+	}
+	sm.ApplyFill(&FillEvent{OrderID: id, SymbolHash: h, Side: 0, Quantity: fx(100), Price: fx(100)})
+	if cash, held := atomic.LoadInt64(&sm.state.Cash), atomic.LoadInt64(&sm.cashReserved); cash != fx(90_000) || held != fx(80_000) {
+		t.Fatalf("cash %d held %d after the fill, want 90k and 80k", cash, held)
+	}
+	if err := sm.RejectOrder(id, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if held := atomic.LoadInt64(&sm.cashReserved); held != fx(60_000) {
+		t.Fatalf("%d held after the reject, want 60k", held)
+	}
+	if res, _ := sm.SubmitOrder(&OrderOptimized{SymbolHash: h, Quantity: fx(300), Price: fx(100)}); !res.Approved {
+		t.Fatalf("freed cash not reused: %v", res.Reason)
+	}
+}
+
+func TestRiskCheckFailFastVsAll(t *testing.T) {
+	tests := []struct {
+		name     string
+		paused   bool
+		quantity float64 // At 100
+		want     []RiskReason
+	}{
+		{"clean", false, 1, nil},
+		{"paused", true, 1, []RiskReason{ReasonTradingPaused}},
+		{"over cash", false, 5_000, []RiskReason{ReasonInsufficientCapital}},
+		{"every violation", true, 20_000, []RiskReason{ReasonTradingPaused, ReasonPositionTooLarge, ReasonInsufficientCapital}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := NewShardedStateManager(testConfig())
+			if tt.paused {
+				sm.SetTradingPaused(true)
+			}
+			order := &OrderOptimized{SymbolHash: sm.symbols.Hash("RISKUSD"), Quantity: fx(tt.quantity), Price: fx(100)}
+
+			fast, all := sm.RiskCheckFast(order), sm.RiskCheckAll(order)
+			if len(tt.want) == 0 {
+				if !fast.Approved || !all.Approved || len(all.Violations) != 0 {
+					t.Fatalf("fast %v, all %v %v", fast.Reason, all.Reason, all.Violations)
+				}
+				return
+			}
+			// Fail-fast stops at the first failure, which RiskCheckAll
+			// reports as its reason alongside every other
+			if fast.Approved || fast.Reason != tt.want[0] || len(fast.Violations) != 0 {
+				t.Fatalf("fast %v %v", fast.Reason, fast.Violations)
+			}
+			if all.Approved || all.Reason != tt.want[0] || !slices.Equal(all.Violations, tt.want) {
+				t.Fatalf("all %v %v, want %v", all.Reason, all.Violations, tt.want)
+			}
+		})
+	}
+}
+
+func TestRiskCheckMarketOrders(t *testing.T) {
+	tests := []struct {
+		name     string
+		last     float64 // 0 = no tick yet
+		quantity float64
+		want     RiskReason
+	}{
+		{"no last price", 0, 1, ReasonNoMarketPrice},
+		{"within limits", 100, 500, ReasonApproved},
+		{"over the position cap", 100, 20_000, ReasonPositionTooLarge}, // 2M against 1M
+		{"over cash", 100, 5_000, ReasonInsufficientCapital},           // 500k against 100k
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := NewShardedStateManager(testConfig())
+			h := sm.symbols.Hash("MKTUSD")
+			if tt.last > 0 {
+				tick(sm, h, tt.last)
+			}
+			res := sm.RiskCheckFast(&OrderOptimized{SymbolHash: h, Quantity: fx(tt.quantity)})
+			if res.Reason != tt.want {
+				t.Fatalf("reason %v, want %v", res.Reason, tt.want)
+			}
+			if tt.want == ReasonApproved && res.Price != fx(tt.last) {
+				t.Fatalf("valued at %d, want the last price", res.Price)
+			}
+		})
+	}
+}
+
+func TestMarketOrderReservesAtLastPrice(t *testing.T) {
+	cfg := testConfig()
+	cfg.Strategies = []allocator.Strategy{{Name: "gann", Weight: 1}}
+	sm := NewShardedStateManager(cfg)
+	h := sm.symbols.Hash("MKTUSD")
+	tick(sm, h, 100)
+
+	order := &OrderOptimized{SymbolHash: h, Quantity: fx(10), Strategy: "gann"}
+	if res, err := sm.SubmitOrder(order); err != nil || !res.Approved {
+		t.Fatal(res.Reason, err)
+	}
+	if used := sm.allocator.Snapshot()[0].Used; used != fx(1000) {
+		t.Fatalf("reserved %d, want 1000 at the last price", used)
+	}
+
+	tick(sm, h, 120) // Released at the price it was reserved at, not the new one
+	if err := sm.RejectOrder(order.ID, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if used := sm.allocator.Snapshot()[0].Used; used != 0 {
+		t.Fatalf("%d still reserved after the reject", used)
+	}
+}
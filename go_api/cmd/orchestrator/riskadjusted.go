@@ -0,0 +1,185 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"cenayang-market/go-api/internal/handlers"
+)
+
+// ============================================================================
+// RISK-ADJUSTED RETURNS - Rolling Sharpe and Sortino of Sampled Equity
+// ============================================================================
+
+// Equity sampling defaults
+const (
+	DefaultEquitySampleInterval = time.Minute
+	DefaultRiskAdjustedWindow   = 60 // Returns, an hour at the default interval
+)
+
+// RiskAdjustedConfig - how equity is sampled for the rolling ratios
+type RiskAdjustedConfig struct {
+	SampleInterval time.Duration // 0 = DefaultEquitySampleInterval, < 0 disables sampling
+	Window         int           // Returns in the rolling window, at least 2 (0 = DefaultRiskAdjustedWindow)
+}
+
+// equityHistory - a ring of the last Window+1 equity samples, giving
+// Window periodic returns
+type equityHistory struct {
+	mu       sync.Mutex
+	interval time.Duration
+	samples  []int64 // Fixed-point equity
+	next     int     // Slot the next sample goes in
+	count    int     // Samples held, up to len(samples)
+}
+
+func newEquityHistory(cfg RiskAdjustedConfig) *equityHistory {
+	if cfg.SampleInterval == 0 {
+		cfg.SampleInterval = DefaultEquitySampleInterval
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultRiskAdjustedWindow
+	}
+	cfg.Window = max(cfg.Window, 2) // A deviation needs two returns
+	return &equityHistory{interval: cfg.SampleInterval, samples: make([]int64, cfg.Window+1)}
+}
+
+// Enabled reports whether equity is sampled
+func (h *equityHistory) Enabled() bool {
+	return h.interval > 0
+}
+
+// add records one equity sample. Returns off a non-positive equity are
+// undefined, so such a sample restarts the history.
+func (h *equityHistory) add(equity int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if equity <= 0 {
+		h.next, h.count = 0, 0
+		return
+	}
+	h.samples[h.next] = equity
+	h.next = (h.next + 1) % len(h.samples)
+	h.count = min(h.count+1, len(h.samples))
+}
+
+// returns copies the periodic returns held, oldest first
+func (h *equityHistory) returns() []float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.count < 2 {
+		return nil
+	}
+	out := make([]float64, 0, h.count-1)
+	first := (h.next - h.count + len(h.samples)) % len(h.samples)
+	prev := h.samples[first]
+	for i := 1; i < h.count; i++ {
+		cur := h.samples[(first+i)%len(h.samples)]
+		out = append(out, float64(cur-prev)/float64(prev))
+		prev = cur
+	}
+	return out
+}
+
+// sampleEquity records the current equity in the history
+func (sm *ShardedStateManager) sampleEquity() {
+	sm.equityHistory.add(atomic.LoadInt64(&sm.state.Equity))
+}
+
+// RiskAdjusted - rolling ratios of per-period equity returns, risk-free
+// rate zero, not annualized. A ratio whose denominator is zero - a flat
+// curve for Sharpe, no losing period for Sortino - is NaN.
+type RiskAdjusted struct {
+	Ready   bool // The window is full; until then the ratios are NaN
+	Returns int  // Returns held
+	Window  int  // Returns the ratios are taken over
+	Mean    float64
+	Sharpe  float64 // Mean over the sample standard deviation
+	Sortino float64 // Mean over the downside deviation, shortfalls below zero
+}
+
+// computeRiskAdjusted takes the ratios of a full window of returns
+func computeRiskAdjusted(returns []float64) (mean, sharpe, sortino float64) {
+	n := float64(len(returns))
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= n
+
+	var variance, downside float64
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+		if r < 0 {
+			downside += r * r
+		}
+	}
+	sharpe, sortino = math.NaN(), math.NaN()
+	if sd := math.Sqrt(variance / (n - 1)); sd > 0 {
+		sharpe = mean / sd
+	}
+	if dd := math.Sqrt(downside / n); dd > 0 {
+		sortino = mean / dd
+	}
+	return mean, sharpe, sortino
+}
+
+// RiskAdjusted returns the rolling ratios over the configured window
+func (sm *ShardedStateManager) RiskAdjusted() RiskAdjusted {
+	h := sm.equityHistory
+	ra := RiskAdjusted{Window: len(h.samples) - 1, Mean: math.NaN(), Sharpe: math.NaN(), Sortino: math.NaN()}
+	returns := h.returns()
+	ra.Returns = len(returns)
+	if ra.Returns < ra.Window {
+		return ra
+	}
+	ra.Ready = true
+	ra.Mean, ra.Sharpe, ra.Sortino = computeRiskAdjusted(returns)
+	return ra
+}
+
+// handleRiskAdjusted serves the rolling Sharpe and Sortino ratios; until
+// the window fills, "ready" is false and the ratios are null
+func (sm *ShardedStateManager) handleRiskAdjusted(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "GET required", http.StatusMethodNotAllowed)
+		return
+	}
+	if !sm.equityHistory.Enabled() {
+		handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusNotFound, Error: "equity sampling disabled"})
+		return
+	}
+	ra := sm.RiskAdjusted()
+
+	buf := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(buf)
+
+	b := append((*buf)[:0], `{"ready":`...)
+	b = strconv.AppendBool(b, ra.Ready)
+	b = append(b, `,"returns":`...)
+	b = strconv.AppendInt(b, int64(ra.Returns), 10)
+	b = append(b, `,"window":`...)
+	b = strconv.AppendInt(b, int64(ra.Window), 10)
+	b = append(b, `,"sample_interval_s":`...)
+	b = strconv.AppendFloat(b, sm.equityHistory.interval.Seconds(), 'f', -1, 64)
+	b = append(b, `,"mean_return":`...)
+	b = appendRatio(b, ra.Mean)
+	b = append(b, `,"sharpe":`...)
+	b = appendRatio(b, ra.Sharpe)
+	b = append(b, `,"sortino":`...)
+	b = appendRatio(b, ra.Sortino)
+	b = append(b, '}')
+
+	handlers.WriteJSON(w, r, http.StatusOK, b)
+}
+
+// appendRatio writes a ratio, null when undefined
+func appendRatio(b []byte, v float64) []byte {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return append(b, `null`...)
+	}
+	return strconv.AppendFloat(b, v, 'g', 8, 64)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Equity of 100, 110, 99 and 108.9 gives returns of +10%, -10% and +10%:
+// mean 1/30, sample deviation 0.11547, downside deviation 0.057735
+func TestRiskAdjustedRatios(t *testing.T) {
+	cfg := testConfig()
+	cfg.RiskAdjusted.Window = 3
+	sm := NewShardedStateManager(cfg)
+	srv := httptest.NewServer(setupHTTPRoutes(sm))
+	defer srv.Close()
+
+	for _, equity := range []float64{100, 110, 99} {
+		sm.equityHistory.add(fx(equity))
+	}
+	body := smokeGet(t, srv.URL+"/api/metrics/risk-adjusted")
+	if body["ready"] != false || body["returns"] != 2.0 || body["sharpe"] != nil || body["sortino"] != nil {
+		t.Fatalf("partial window %v", body)
+	}
+
+	sm.equityHistory.add(fx(108.9))
+	body = smokeGet(t, srv.URL+"/api/metrics/risk-adjusted")
+	for _, v := range []struct {
+		field string
+		want  float64
+	}{
+		{"mean_return", 1.0 / 30},
+		{"sharpe", 0.288675},
+		{"sortino", 0.577350},
+	} {
+		if got, ok := body[v.field].(float64); !ok || math.Abs(got-v.want) > 1e-6 {
+			t.Errorf("%s %v, want %v", v.field, body[v.field], v.want)
+		}
+	}
+	if body["ready"] != true || body["window"] != 3.0 {
+		t.Fatalf("full window %v", body)
+	}
+
+	// Only gains: Sortino has no downside to divide by
+	for _, equity := range []float64{110, 120, 130} {
+		sm.equityHistory.add(fx(equity))
+	}
+	if body = smokeGet(t, srv.URL+"/api/metrics/risk-adjusted"); body["sortino"] != nil || body["sharpe"] == nil {
+		t.Fatalf("no losing period %v", body)
+	}
+	// A non-positive equity restarts the history
+	sm.equityHistory.add(0)
+	if ra := sm.RiskAdjusted(); ra.Ready || ra.Returns != 0 {
+		t.Fatalf("%+v after zero equity", ra)
+	}
+
+	cfg.RiskAdjusted.SampleInterval = -1
+	off := httptest.NewServer(setupHTTPRoutes(NewShardedStateManager(cfg)))
+	defer off.Close()
+	resp, err := http.Get(off.URL + "/api/metrics/risk-adjusted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("sampling off: %s", resp.Status)
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"cenayang-market/go-api/internal/handlers"
+)
+
+// ============================================================================
+// RISK WORKERS - Bounded Pool for Risk Checks Off the Request Goroutine
+// ============================================================================
+
+// DefaultRiskQueue is the pool's queue length when RiskPoolConfig leaves it unset
+const DefaultRiskQueue = 1024
+
+var (
+	// ErrRiskQueueFull is returned when an order is turned away because
+	// every worker is busy and the queue is full
+	ErrRiskQueueFull = errors.New("risk check queue full")
+	// ErrRiskPoolStopped is returned once the pool has shut down
+	ErrRiskPoolStopped = errors.New("risk check pool stopped")
+)
+
+// RiskPoolConfig - how many workers run risk checks and how many checks
+// may wait for them
+type RiskPoolConfig struct {
+	Workers   int // 0 = checks run on the caller's goroutine
+	QueueSize int // Checks waiting for a worker (0 = DefaultRiskQueue)
+}
+
+type riskJob struct {
+	order *OrderOptimized
+	done  chan RiskCheckResult
+}
+
+// riskWorker - one worker's counters, padded so workers do not share a
+// cache line
+type riskWorker struct {
+	checks uint64
+	busyNs int64
+	_      [CacheLineSize - 16]byte
+}
+
+// RiskPool - a fixed set of workers running RiskCheckFast off a bounded
+// queue. Checks only read shared state, so workers need no coordination
+// beyond the shard read locks the check already takes.
+type RiskPool struct {
+	sm      *ShardedStateManager
+	workers []riskWorker
+	queue   chan riskJob
+	jobs    sync.Pool
+
+	mu      sync.RWMutex // Held for reading while enqueuing, for writing to close the queue
+	stopped bool
+	done    chan struct{}
+
+	startedAt     time.Time
+	rejected      uint64
+	maxQueueDepth int64
+}
+
+// NewRiskPool returns nil when cfg has no workers; call Run to start them
+func NewRiskPool(sm *ShardedStateManager, cfg RiskPoolConfig) *RiskPool {
+	if cfg.Workers <= 0 {
+		return nil
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DefaultRiskQueue
+	}
+	p := &RiskPool{
+		sm:        sm,
+		workers:   make([]riskWorker, cfg.Workers),
+		queue:     make(chan riskJob, cfg.QueueSize),
+		done:      make(chan struct{}),
+		startedAt: time.Now(),
+	}
+	p.jobs.New = func() any { return make(chan RiskCheckResult, 1) }
+	return p
+}
+
+// Run starts the workers and returns once Stop has closed the queue and
+// every queued check has been answered
+func (p *RiskPool) Run() {
+	defer close(p.done)
+	var wg sync.WaitGroup
+	for i := range p.workers {
+		wg.Add(1)
+		go func(w *riskWorker) {
+			defer wg.Done()
+			for job := range p.queue {
+				start := time.Now()
+				result := p.sm.RiskCheckFast(job.order)
+				atomic.AddInt64(&w.busyNs, int64(time.Since(start)))
+				atomic.AddUint64(&w.checks, 1) // Counted before the caller sees the result
+				job.done <- result
+			}
+		}(&p.workers[i])
+	}
+	wg.Wait()
+}
+
+// Check queues order for a worker and waits for its result. A full queue
+// fails at once rather than holding up the caller.
+func (p *RiskPool) Check(order *OrderOptimized) (RiskCheckResult, error) {
+	done := p.jobs.Get().(chan RiskCheckResult)
+	p.mu.RLock()
+	if p.stopped {
+		p.mu.RUnlock()
+		p.jobs.Put(done)
+		return RiskCheckResult{}, ErrRiskPoolStopped
+	}
+	select {
+	case p.queue <- riskJob{order: order, done: done}:
+	default:
+		p.mu.RUnlock()
+		p.jobs.Put(done)
+		atomic.AddUint64(&p.rejected, 1)
+		return RiskCheckResult{}, ErrRiskQueueFull
+	}
+	if depth := int64(len(p.queue)); depth > atomic.LoadInt64(&p.maxQueueDepth) {
+		atomic.StoreInt64(&p.maxQueueDepth, depth) // Approximate under contention
+	}
+	p.mu.RUnlock()
+
+	result := <-done
+	p.jobs.Put(done)
+	return result, nil
+}
+
+// Stop refuses new checks, lets the workers finish the queued ones, and
+// gives up waiting at ctx's deadline
+func (p *RiskPool) Stop(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.stopped {
+		p.stopped = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RiskWorkerStats - one worker's share of the checks
+type RiskWorkerStats struct {
+	Checks uint64
+	BusyNs int64
+}
+
+// RiskPoolStats - queue depth and per-worker throughput
+type RiskPoolStats struct {
+	Workers       []RiskWorkerStats
+	QueueDepth    int
+	QueueCapacity int
+	MaxQueueDepth int64
+	Rejected      uint64
+	Uptime        time.Duration
+}
+
+// Stats returns the pool's counters; zero for a nil RiskPool
+func (p *RiskPool) Stats() RiskPoolStats {
+	if p == nil {
+		return RiskPoolStats{}
+	}
+	s := RiskPoolStats{
+		Workers:       make([]RiskWorkerStats, len(p.workers)),
+		QueueDepth:    len(p.queue),
+		QueueCapacity: cap(p.queue),
+		MaxQueueDepth: atomic.LoadInt64(&p.maxQueueDepth),
+		Rejected:      atomic.LoadUint64(&p.rejected),
+		Uptime:        time.Since(p.startedAt),
+	}
+	for i := range p.workers {
+		s.Workers[i] = RiskWorkerStats{
+			Checks: atomic.LoadUint64(&p.workers[i].checks),
+			BusyNs: atomic.LoadInt64(&p.workers[i].busyNs),
+		}
+	}
+	return s
+}
+
+// checkRisk runs the risk check on the pool when one is configured and on
+// the caller's goroutine otherwise
+func (sm *ShardedStateManager) checkRisk(order *OrderOptimized) (RiskCheckResult, error) {
+	if sm.riskPool == nil {
+		return sm.RiskCheckFast(order), nil
+	}
+	return sm.riskPool.Check(order)
+}
+
+// handleRiskWorkers serves the pool's queue depth and each worker's
+// checks, busy time and throughput since the pool started
+func (sm *ShardedStateManager) handleRiskWorkers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "GET required", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s := sm.riskPool.Stats()
+	secs := s.Uptime.Seconds()
+
+	buf := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(buf)
+
+	b := append((*buf)[:0], `{"enabled":`...)
+	b = strconv.AppendBool(b, sm.riskPool != nil)
+	b = append(b, `,"queue_depth":`...)
+	b = strconv.AppendInt(b, int64(s.QueueDepth), 10)
+	b = append(b, `,"queue_capacity":`...)
+	b = strconv.AppendInt(b, int64(s.QueueCapacity), 10)
+	b = append(b, `,"max_queue_depth":`...)
+	b = strconv.AppendInt(b, s.MaxQueueDepth, 10)
+	b = append(b, `,"rejected":`...)
+	b = strconv.AppendUint(b, s.Rejected, 10)
+	b = append(b, `,"workers":[`...)
+	for i, wk := range s.Workers {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, `{"id":`...)
+		b = strconv.AppendInt(b, int64(i), 10)
+		b = append(b, `,"checks":`...)
+		b = strconv.AppendUint(b, wk.Checks, 10)
+		b = append(b, `,"busy_ns":`...)
+		b = strconv.AppendInt(b, wk.BusyNs, 10)
+		b = append(b, `,"checks_per_sec":`...)
+		if secs > 0 {
+			b = strconv.AppendFloat(b, float64(wk.Checks)/secs, 'f', 2, 64)
+		} else {
+			b = append(b, '0')
+		}
+		b = append(b, '}')
+	}
+	b = append(b, `]}`...)
+
+	handlers.WriteJSON(w, r, http.StatusOK, b)
+}
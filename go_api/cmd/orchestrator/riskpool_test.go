@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Orders checked on the pool get the answers RiskCheckFast gives, every
+// check is counted to a worker, and a stopped pool refuses more
+func TestRiskPoolChecks(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxPositionSize = 500
+	cfg.RiskPool = RiskPoolConfig{Workers: 4}
+	sm := NewShardedStateManager(cfg)
+	go sm.riskPool.Run()
+	h := sm.symbols.Hash("POOLUSD")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(quantity float64) {
+			defer wg.Done()
+			order := &OrderOptimized{SymbolHash: h, Side: 1, Quantity: fx(quantity), Price: fx(100)}
+			res, err := sm.checkRisk(order)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if want := sm.RiskCheckFast(order); res.Approved != want.Approved || res.Reason != want.Reason {
+				t.Errorf("quantity %v: pool %v, inline %v", quantity, res.Reason, want.Reason)
+			}
+		}(float64(1 + i%10)) // Over 5 is too large
+	}
+	wg.Wait()
+
+	var checks uint64
+	for _, w := range sm.riskPool.Stats().Workers {
+		checks += w.Checks
+	}
+	if checks != 100 {
+		t.Fatalf("workers counted %d checks, want 100", checks)
+	}
+	if err := sm.riskPool.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sm.checkRisk(&OrderOptimized{SymbolHash: h, Quantity: fx(1), Price: fx(100)}); err != ErrRiskPoolStopped {
+		t.Fatalf("err %v, want %v", err, ErrRiskPoolStopped)
+	}
+}
+
+// With the queue full, a check fails at once and is counted
+func TestRiskPoolQueueFull(t *testing.T) {
+	cfg := testConfig()
+	cfg.RiskPool = RiskPoolConfig{Workers: 1, QueueSize: 1}
+	sm := NewShardedStateManager(cfg)
+	order := &OrderOptimized{SymbolHash: 1, Quantity: fx(1), Price: fx(100)}
+
+	queued := make(chan error)
+	go func() { // Waits in the queue until the workers start
+		_, err := sm.riskPool.Check(order)
+		queued <- err
+	}()
+	for sm.riskPool.Stats().QueueDepth == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := sm.riskPool.Check(order); err != ErrRiskQueueFull {
+		t.Fatalf("err %v, want %v", err, ErrRiskQueueFull)
+	}
+	go sm.riskPool.Run()
+	if err := <-queued; err != nil {
+		t.Fatal(err)
+	}
+	if s := sm.riskPool.Stats(); s.Rejected != 1 || s.MaxQueueDepth != 1 {
+		t.Fatalf("stats %+v", s)
+	}
+	sm.riskPool.Stop(context.Background())
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+
+	"cenayang-market/go-api/internal/handlers"
+	"cenayang-market/go-api/internal/ws"
+)
+
+// ============================================================================
+// SEQUENCE - Monotonic, Never Wraps, Rebased Forward Only
+// ============================================================================
+
+// ErrSequenceRegression - a rebase would move the sequence backwards
+var ErrSequenceRegression = errors.New("sequence rebase below current sequence")
+
+// nextSequence returns the next state sequence ID. Clients order events by
+// comparing IDs, so the sequence never wraps: at math.MaxUint64 it
+// saturates and the kill switch is engaged. (At a billion events per
+// second that is five centuries away.)
+func (sm *ShardedStateManager) nextSequence() uint64 {
+	for {
+		cur := atomic.LoadUint64(&sm.state.SequenceID)
+		if cur == math.MaxUint64 {
+			if changed, _ := sm.ApplyKillSwitch(KillSwitchRequest{Active: true, Source: KillSourceSequence}); changed {
+				log.Printf("[SEQ] Sequence exhausted - kill switch engaged")
+			}
+			return cur
+		}
+		if atomic.CompareAndSwapUint64(&sm.state.SequenceID, cur, cur+1) {
+			return cur + 1
+		}
+	}
+}
+
+// RebaseSequence moves the sequence to base, e.g. to continue from an
+// imported snapshot's sequence. Rebasing only ever moves forward, so every
+// event a connected client sees afterwards still compares greater than
+// everything it saw before; clients are told of the gap by a
+// sequence_rebased event. Returns the previous sequence.
+func (sm *ShardedStateManager) RebaseSequence(base uint64) (uint64, error) {
+	for {
+		cur := atomic.LoadUint64(&sm.state.SequenceID)
+		if base < cur {
+			return cur, ErrSequenceRegression
+		}
+		if atomic.CompareAndSwapUint64(&sm.state.SequenceID, cur, base) {
+			b := make([]byte, 0, 80)
+			b = append(b, `{"type":"sequence_rebased","from":`...)
+			b = strconv.AppendUint(b, cur, 10)
+			b = append(b, `,"to":`...)
+			b = strconv.AppendUint(b, base, 10)
+			b = append(b, '}')
+			sm.publish(ws.EventSequenceRebased, base, b)
+			log.Printf("[SEQ] Rebased %d → %d", cur, base)
+			return cur, nil
+		}
+	}
+}
+
+// handleSequenceRebase applies RebaseSequence from {"base":N}
+func (sm *ShardedStateManager) handleSequenceRebase(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Base uint64 `json:"base"`
+	}
+	if errResp := handlers.DecodeJSON(w, r, &req); errResp != nil {
+		handlers.WriteError(w, r, errResp)
+		return
+	}
+	prev, err := sm.RebaseSequence(req.Base)
+	if err != nil {
+		handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusConflict, Error: err.Error(), Field: "base"})
+		return
+	}
+
+	buf := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(buf)
+
+	b := append((*buf)[:0], `{"from":`...)
+	b = strconv.AppendUint(b, prev, 10)
+	b = append(b, `,"to":`...)
+	b = strconv.AppendUint(b, req.Base, 10)
+	b = append(b, '}')
+
+	handlers.WriteJSON(w, r, http.StatusOK, b)
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+	_ "time/tzdata" // Runtime image is scratch: no system zoneinfo
+)
+
+// ============================================================================
+// CLOCK - Injectable Time Source
+// ============================================================================
+
+// Clock supplies wall-clock time to session and calendar logic.
+// Latency measurement always uses the real clock.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// ============================================================================
+// SESSION CALENDAR - Trading Hours, Holidays, Session Boundaries
+// ============================================================================
+
+// SessionWindow - one daily trading window in the calendar's timezone.
+// A window whose Close is before its Open runs overnight and belongs to
+// the day it opens on.
+type SessionWindow struct {
+	Open  string         // "15:04"
+	Close string         // "15:04"
+	Days  []time.Weekday // Empty = every day
+}
+
+// SessionConfig - trading calendar as configured
+type SessionConfig struct {
+	Timezone string                     // IANA name, empty = UTC
+	Windows  []SessionWindow            // Global; empty = always open
+	Symbols  map[string][]SessionWindow // Per-symbol override of Windows
+	Holidays []string                   // "2006-01-02", closed all day
+}
+
+type sessionWindow struct {
+	open, close int  // Minutes from midnight
+	days        byte // Weekday bitmask
+}
+
+// SessionCalendar answers whether a symbol is tradable at an instant.
+// Immutable after construction.
+type SessionCalendar struct {
+	loc      *time.Location
+	global   []sessionWindow
+	bySymbol map[uint64][]sessionWindow
+	holidays map[string]struct{}
+}
+
+// NewSessionCalendar validates and compiles a calendar configuration,
+// resolving per-symbol keys through the registry's aliases
+func NewSessionCalendar(cfg SessionConfig, symbols *SymbolRegistry) (*SessionCalendar, error) {
+	loc := time.UTC
+	if cfg.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(cfg.Timezone); err != nil {
+			return nil, fmt.Errorf("session timezone: %w", err)
+		}
+	}
+
+	cal := &SessionCalendar{
+		loc:      loc,
+		bySymbol: make(map[uint64][]sessionWindow, len(cfg.Symbols)),
+		holidays: make(map[string]struct{}, len(cfg.Holidays)),
+	}
+
+	var err error
+	if cal.global, err = compileWindows(cfg.Windows); err != nil {
+		return nil, err
+	}
+	for symbol, windows := range cfg.Symbols {
+		compiled, err := compileWindows(windows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", symbol, err)
+		}
+		cal.bySymbol[symbols.Hash(symbol)] = compiled
+	}
+	for _, day := range cfg.Holidays {
+		if _, err := time.ParseInLocation("2006-01-02", day, loc); err != nil {
+			return nil, fmt.Errorf("session holiday %q: %w", day, err)
+		}
+		cal.holidays[day] = struct{}{}
+	}
+	return cal, nil
+}
+
+func compileWindows(windows []SessionWindow) ([]sessionWindow, error) {
+	out := make([]sessionWindow, 0, len(windows))
+	for _, w := range windows {
+		open, err := parseClock(w.Open)
+		if err != nil {
+			return nil, err
+		}
+		closeAt, err := parseClock(w.Close)
+		if err != nil {
+			return nil, err
+		}
+		if open == closeAt {
+			return nil, fmt.Errorf("session window %s-%s is empty", w.Open, w.Close)
+		}
+		sw := sessionWindow{open: open, close: closeAt, days: 0x7F}
+		if len(w.Days) > 0 {
+			sw.days = 0
+			for _, d := range w.Days {
+				sw.days |= 1 << d
+			}
+		}
+		out = append(out, sw)
+	}
+	return out, nil
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("session time %q: %w", s, err)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// IsOpen reports whether a symbol may trade at t
+func (cal *SessionCalendar) IsOpen(symbolHash uint64, t time.Time) bool {
+	windows, ok := cal.bySymbol[symbolHash]
+	if !ok {
+		windows = cal.global
+	}
+	if len(windows) == 0 {
+		return !cal.isHoliday(t.In(cal.loc)) // 24/7 apart from holidays
+	}
+
+	local := t.In(cal.loc)
+	minute := local.Hour()*60 + local.Minute()
+	for _, w := range windows {
+		day := local
+		if w.open < w.close {
+			if minute < w.open || minute >= w.close {
+				continue
+			}
+		} else if minute < w.close {
+			day = local.AddDate(0, 0, -1) // After midnight: previous day's session
+		} else if minute < w.open {
+			continue
+		}
+		if w.days&(1<<day.Weekday()) != 0 && !cal.isHoliday(day) {
+			return true
+		}
+	}
+	return false
+}
+
+func (cal *SessionCalendar) isHoliday(local time.Time) bool {
+	if len(cal.holidays) == 0 {
+		return false
+	}
+	_, ok := cal.holidays[local.Format("2006-01-02")]
+	return ok
+}
+
+// SessionDate returns the trading date t belongs to in the calendar's
+// timezone. Overnight global sessions are dated by the day they open.
+func (cal *SessionCalendar) SessionDate(t time.Time) string {
+	local := t.In(cal.loc)
+	minute := local.Hour()*60 + local.Minute()
+	for _, w := range cal.global {
+		if w.open > w.close && minute < w.close {
+			return local.AddDate(0, 0, -1).Format("2006-01-02")
+		}
+	}
+	return local.Format("2006-01-02")
+}
+
+// ============================================================================
+// SESSION LOOP - Resets at Each Session Boundary, Retention Trimming
+// ============================================================================
+
+// SessionCheckInterval is how often the session loop polls the calendar
+const SessionCheckInterval = time.Second
+
+// Run resets session statistics whenever the global session date rolls
+// over, flags held symbols whose feed went silent and orders the gateway
+// never answered, trims retained history, rolls up latency, samples
+// equity, broadcasts heartbeats and compact snapshots and queues state
+// snapshots for persistence, until ctx is cancelled
+func (sm *ShardedStateManager) Run(ctx context.Context) {
+	ticker := time.NewTicker(SessionCheckInterval)
+	defer ticker.Stop()
+	trim := time.NewTicker(RetentionTrimInterval)
+	defer trim.Stop()
+	rollup := time.NewTicker(LatencyRollupInterval)
+	defer rollup.Stop()
+	sm.rollLatency(sm.clock.Now()) // Baseline for the first bucket
+
+	var heartbeat <-chan time.Time // nil (never fires) when disabled
+	if interval := sm.heartbeatInterval(); interval > 0 {
+		hb := time.NewTicker(interval)
+		defer hb.Stop()
+		heartbeat = hb.C
+	}
+
+	var equity <-chan time.Time // nil when equity sampling is disabled
+	if sm.equityHistory.Enabled() {
+		es := time.NewTicker(sm.equityHistory.interval)
+		defer es.Stop()
+		equity = es.C
+	}
+
+	var snapshot <-chan time.Time // nil without a persistence backend
+	if sm.persist != nil && sm.persist.interval > 0 {
+		st := time.NewTicker(sm.persist.interval)
+		defer st.Stop()
+		snapshot = st.C
+	}
+
+	current := sm.calendar.SessionDate(sm.clock.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current = sm.checkSessionBoundary(current)
+			sm.checkStaleFeeds(sm.clock.Now())
+			sm.checkStaleOrders(sm.clock.Now())
+		case <-trim.C:
+			sm.trimRetention(sm.clock.Now())
+		case <-rollup.C:
+			sm.rollLatency(sm.clock.Now())
+		case <-equity:
+			sm.sampleEquity()
+		case <-heartbeat:
+			sm.publishHeartbeat()
+			sm.publishCompactSnapshot()
+		case <-snapshot:
+			sm.persistSnapshot()
+		}
+	}
+}
+
+// heartbeatInterval resolves Config.HeartbeatInterval: zero takes the
+// default, negative disables heartbeats
+func (sm *ShardedStateManager) heartbeatInterval() time.Duration {
+	if sm.config.HeartbeatInterval == 0 {
+		return DefaultHeartbeatInterval
+	}
+	return sm.config.HeartbeatInterval
+}
+
+// checkSessionBoundary resets the session if the date moved past current
+// and returns the date now in effect
+func (sm *ShardedStateManager) checkSessionBoundary(current string) string {
+	date := sm.calendar.SessionDate(sm.clock.Now())
+	if date != current {
+		log.Printf("[SESSION] Session boundary %s → %s", current, date)
+		sm.ResetSession()
+		sm.applyHWMPolicy(current, date)
+	}
+	return date
+}
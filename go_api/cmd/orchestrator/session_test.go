@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// auditRecorder keeps audit entries in memory
+type auditRecorder struct{ actions []string }
+
+func (a *auditRecorder) SaveAuditLog(action, resource, details, ipAddress string) error {
+	a.actions = append(a.actions, action)
+	return nil
+}
+
+func TestSessionResetLiftsDailyLossAndAudits(t *testing.T) {
+	audit := &auditRecorder{}
+	cfg := testConfig()
+	cfg.AuditLog = audit
+	cfg.MaxDrawdownPct = 50 // Only the daily limit trips
+	sm := NewShardedStateManager(cfg)
+	h := sm.symbols.Hash("SESUSD")
+	tick(sm, h, 100)
+
+	atomic.AddInt64(&sm.bookValue, -fx(20_000)) // A loss past the 10k daily limit
+	sm.recomputePortfolioState()
+	order := &OrderOptimized{SymbolHash: h, Quantity: fx(1), Price: fx(100)}
+	if res := sm.RiskCheckFast(order); res.Reason != ReasonDailyLossLimit {
+		t.Fatalf("reason %v before the reset", res.Reason)
+	}
+
+	rec := httptest.NewRecorder()
+	sm.handleSessionReset(rec, httptest.NewRequest(http.MethodPost, "/api/session/reset", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatal(rec.Code, rec.Body)
+	}
+	if res := sm.RiskCheckFast(order); !res.Approved {
+		t.Fatalf("reason %v after the reset", res.Reason)
+	}
+	if len(audit.actions) != 1 || audit.actions[0] != "session_reset" {
+		t.Fatalf("audit %v", audit.actions)
+	}
+}
+
+func TestRejectionTotalFollowsSessionReset(t *testing.T) {
+	sm := NewShardedStateManager(testConfig())
+	sm.SetKillSwitch(true)
+	for i := 0; i < 3; i++ {
+		sm.RiskCheckFast(&OrderOptimized{SymbolHash: 1, Quantity: fx(1), Price: fx(1)})
+	}
+	sm.ResetSession()
+	sm.RiskCheckFast(&OrderOptimized{SymbolHash: 1, Quantity: fx(1), Price: fx(1)})
+
+	rec := httptest.NewRecorder()
+	sm.handleRiskRejections(rec, httptest.NewRequest(http.MethodGet, "/api/risk/rejections", nil))
+	var body struct {
+		Total   uint64            `json:"total"`
+		Reasons map[string]uint64 `json:"reasons"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	var sum uint64
+	for _, n := range body.Reasons {
+		sum += n
+	}
+	if body.Total != 1 || sum != body.Total {
+		t.Fatalf("total %d, reasons sum %d", body.Total, sum)
+	}
+}
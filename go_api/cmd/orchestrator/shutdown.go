@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"cenayang-market/go-api/internal/database"
+)
+
+// ============================================================================
+// SHUTDOWN - One Reason, Ordered Teardown, Meaningful Exit Code
+// ============================================================================
+
+// Exit codes
+const (
+	ExitOK             = 0
+	ExitFatal          = 1 // A server or critical dependency failed
+	ExitPersistFailed  = 2 // The final state save failed
+	ExitPanic          = 3 // A worker goroutine panicked
+	ExitVerifyMismatch = 4 // -verify: the ledger replay diverged from the snapshot
+)
+
+// Shutdown reasons
+const (
+	ShutdownSignal = "signal"
+	ShutdownFatal  = "fatal"
+	ShutdownPanic  = "panic"
+)
+
+// ShutdownTimeout bounds the whole teardown
+const ShutdownTimeout = 10 * time.Second
+
+// ShutdownCoordinator records why the process is stopping - the first
+// reason wins - and runs the teardown in order, turning failures into a
+// non-zero exit code
+type ShutdownCoordinator struct {
+	once   sync.Once
+	done   chan struct{}
+	reason string
+	err    error
+	code   int
+}
+
+func NewShutdownCoordinator() *ShutdownCoordinator {
+	return &ShutdownCoordinator{done: make(chan struct{})}
+}
+
+// Trigger starts shutdown. Calls after the first are logged and ignored.
+func (c *ShutdownCoordinator) Trigger(reason string, code int, err error) {
+	first := false
+	c.once.Do(func() {
+		c.reason, c.code, c.err = reason, code, err
+		first = true
+		close(c.done)
+	})
+	if !first {
+		log.Printf("[SHUTDOWN] Already stopping; ignoring %s: %v", reason, err)
+		return
+	}
+	if err != nil {
+		log.Printf("[SHUTDOWN] Initiated by %s: %v", reason, err)
+	} else {
+		log.Printf("[SHUTDOWN] Initiated by %s", reason)
+	}
+}
+
+// Done is closed once shutdown is triggered
+func (c *ShutdownCoordinator) Done() <-chan struct{} {
+	return c.done
+}
+
+// Reason returns the triggering reason and error, "" before Trigger
+func (c *ShutdownCoordinator) Reason() (string, error) {
+	select {
+	case <-c.done:
+		return c.reason, c.err
+	default:
+		return "", nil
+	}
+}
+
+// Go runs a worker goroutine. A panic is recovered, logged with its stack
+// and turned into a clean shutdown instead of crashing the process.
+func (c *ShutdownCoordinator) Go(name string, fn func()) {
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				log.Printf("[SHUTDOWN] %s panicked: %v\n%s", name, p, debug.Stack())
+				c.Trigger(ShutdownPanic, ExitPanic, fmt.Errorf("%s: panic: %v", name, p))
+			}
+		}()
+		fn()
+	}()
+}
+
+// ShutdownStep - one teardown action. A step that fails sets the exit code
+// to FailCode unless an earlier failure or the trigger already set one; 0
+// means the failure is only logged.
+type ShutdownStep struct {
+	Name     string
+	Run      func(ctx context.Context) error
+	FailCode int
+}
+
+// Finish runs the steps in order under ShutdownTimeout and returns the
+// process exit code. Every step runs even if an earlier one failed.
+func (c *ShutdownCoordinator) Finish(steps []ShutdownStep) int {
+	ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+
+	code := c.code
+	for _, step := range steps {
+		if err := step.Run(ctx); err != nil {
+			log.Printf("[SHUTDOWN] %s failed: %v", step.Name, err)
+			if code == ExitOK {
+				code = step.FailCode
+			}
+		}
+	}
+
+	reason, _ := c.Reason()
+	log.Printf("[SHUTDOWN] Complete (reason %s, exit %d)", reason, code)
+	return code
+}
+
+// stopGRPC lets in-flight RPCs finish, cutting streams off at ctx's
+// deadline - a StreamState subscriber never finishes on its own
+func stopGRPC(ctx context.Context, server *grpc.Server) error {
+	done := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		server.Stop()
+		return ctx.Err()
+	}
+}
+
+// StateStore persists the portfolio and open positions.
+// *database.Database and *database.Postgres implement it.
+type StateStore interface {
+	SavePortfolioState(p database.PortfolioRecord) error
+	ReplacePositions(positions []database.PositionRecord) error
+}
+
+// PersistState saves a consistent snapshot of the portfolio and positions
+func (sm *ShardedStateManager) PersistState(store StateStore) error {
+	s := sm.captureState()
+	s.latency = sm.latencyRecords()
+	return s.writeTo(store)
+}
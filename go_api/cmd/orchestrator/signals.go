@@ -0,0 +1,109 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"cenayang-market/go-api/internal/handlers"
+)
+
+// ============================================================================
+// MICROSTRUCTURE SIGNALS - Computed Per Tick, Cached Per Symbol
+// ============================================================================
+
+// Microstructure - top-of-book signals derived from one tick (fixed-point)
+type Microstructure struct {
+	Mid          int64 // (bid + ask) / 2
+	WeightedMid  int64 // (bid×askSize + ask×bidSize) / (bidSize + askSize)
+	Microprice   int64 // mid + halfSpread × imbalance
+	ImbalanceBps int64 // (bidSize - askSize) / (bidSize + askSize), -10000..10000
+	Bid          int64 // Top of book as ticked, 0 = side empty
+	Ask          int64
+	BidSize      int64
+	AskSize      int64
+	LastPrice    int64
+	Timestamp    int64
+	SeqID        uint64
+}
+
+// computeMicrostructure derives signals from a tick. With no resting size
+// both weighted prices fall back to the mid; with a one-sided book every
+// price falls back to the last trade.
+func computeMicrostructure(tick *MarketTickOptimized) Microstructure {
+	m := Microstructure{
+		Bid: tick.BidPrice, Ask: tick.AskPrice, BidSize: tick.BidSize, AskSize: tick.AskSize,
+		LastPrice: tick.LastPrice, Timestamp: tick.Timestamp, SeqID: tick.SeqID,
+	}
+	if tick.BidPrice <= 0 || tick.AskPrice <= 0 {
+		m.Mid, m.WeightedMid, m.Microprice = tick.LastPrice, tick.LastPrice, tick.LastPrice
+		return m
+	}
+
+	m.Mid = tick.BidPrice + (tick.AskPrice-tick.BidPrice)/2
+	total := tick.BidSize + tick.AskSize
+	if tick.BidSize < 0 || tick.AskSize < 0 || total <= 0 {
+		m.WeightedMid, m.Microprice = m.Mid, m.Mid
+		return m
+	}
+
+	// Each term divided separately: price × size overflows 64 bits
+	m.WeightedMid = mulDiv(tick.BidPrice, tick.AskSize, total) + mulDiv(tick.AskPrice, tick.BidSize, total)
+	m.ImbalanceBps = mulDiv(tick.BidSize-tick.AskSize, 10000, total)
+	m.Microprice = m.Mid + mulDiv(tick.AskPrice-tick.BidPrice, m.ImbalanceBps, 20000)
+	return m
+}
+
+// Microstructure returns the signals from a symbol's latest accepted tick
+func (f *FeedIngester) Microstructure(symbolHash uint64) (Microstructure, bool) {
+	f.mu.Lock()
+	m, ok := f.signals[symbolHash]
+	f.mu.Unlock()
+	return m, ok
+}
+
+// LastPrice returns a symbol's last trade price from its latest tick
+func (f *FeedIngester) LastPrice(symbolHash uint64) (int64, bool) {
+	m, ok := f.Microstructure(symbolHash)
+	return m.LastPrice, ok && m.LastPrice > 0
+}
+
+// handleMicroprice serves cached signals for ?symbol=
+func (sm *ShardedStateManager) handleMicroprice(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "GET required", http.StatusMethodNotAllowed)
+		return
+	}
+
+	symbol := r.URL.Query().Get("symbol")
+	if symbol == "" {
+		handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "symbol required", Field: "symbol"})
+		return
+	}
+	hash := sm.symbols.Hash(symbol)
+	m, ok := sm.feed.Microstructure(hash)
+	if !ok {
+		handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusNotFound, Error: "no ticks for symbol", Field: "symbol"})
+		return
+	}
+
+	buf := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(buf)
+
+	b := append((*buf)[:0], `{"symbol":`...)
+	b = strconv.AppendQuote(b, sm.symbols.Name(hash))
+	b = append(b, `,"mid":`...)
+	b = appendFixed(b, m.Mid)
+	b = append(b, `,"weighted_mid":`...)
+	b = appendFixed(b, m.WeightedMid)
+	b = append(b, `,"microprice":`...)
+	b = appendFixed(b, m.Microprice)
+	b = append(b, `,"imbalance_bps":`...)
+	b = strconv.AppendInt(b, m.ImbalanceBps, 10)
+	b = append(b, `,"timestamp":`...)
+	b = strconv.AppendInt(b, m.Timestamp, 10)
+	b = append(b, `,"seq_id":`...)
+	b = strconv.AppendUint(b, m.SeqID, 10)
+	b = append(b, '}')
+
+	handlers.WriteJSON(w, r, http.StatusOK, b)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"cenayang-market/go-api/internal/handlers"
+)
+
+// ============================================================================
+// SIMULATION - Synthetic Fills and Ticks for Frontend Development
+// ============================================================================
+
+// Synthetic events enter through the feed ingester like transport events,
+// so sequencing, state updates and broadcasts are exactly the live ones.
+// The routes exist only when Config.SimMode is set in a build without the
+// production tag.
+
+// simEnabled reports whether the /api/sim routes are served
+func simEnabled(cfg *Config) bool {
+	return simAvailable && cfg.SimMode
+}
+
+// registerSimRoutes adds the simulation routes when enabled
+func registerSimRoutes(mux *http.ServeMux, sm *ShardedStateManager) {
+	if !simEnabled(&sm.config) {
+		if sm.config.SimMode {
+			log.Printf("[SIM] SimMode ignored: production build")
+		}
+		return
+	}
+	log.Printf("[SIM] Simulation endpoints enabled - synthetic fills and ticks will move state")
+	mux.HandleFunc("/api/sim/fill", sm.handleSimFill)
+	mux.HandleFunc("/api/sim/tick", sm.handleSimTick)
+	mux.HandleFunc("/api/sim/replay", sm.handleSimReplay)
+}
+
+// simFillRequest - wire format for POST /api/sim/fill
+type simFillRequest struct {
+	OrderID    uint64  `json:"order_id,string,omitempty"`
+	Symbol     string  `json:"symbol"`
+	Side       string  `json:"side"` // BUY or SELL
+	Quantity   float64 `json:"quantity"`
+	Price      float64 `json:"price"`
+	Commission float64 `json:"commission,omitempty"`
+	Liquidity  string  `json:"liquidity,omitempty"` // MAKER or TAKER (default)
+	SeqID      uint64  `json:"seq_id,omitempty"`    // 0 = unsequenced
+}
+
+// handleSimFill injects a synthetic fill
+func (sm *ShardedStateManager) handleSimFill(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req simFillRequest
+	if errResp := handlers.DecodeJSON(w, r, &req); errResp != nil {
+		handlers.WriteError(w, r, errResp)
+		return
+	}
+	fill, errResp := sm.simFill(&req)
+	if errResp != nil {
+		handlers.WriteError(w, r, errResp)
+		return
+	}
+
+	if !sm.feed.OnFill(fill) {
+		handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusConflict, Error: "fill not accepted: standby or duplicate seq_id"})
+		return
+	}
+	sm.writeSimAccepted(w, r)
+}
+
+// simFill validates a synthetic fill request and builds the fill
+func (sm *ShardedStateManager) simFill(req *simFillRequest) (*FillEvent, *handlers.ErrorResponse) {
+	fill := &FillEvent{
+		OrderID:    req.OrderID,
+		SymbolHash: sm.symbols.Hash(req.Symbol),
+		Quantity:   toFixed(req.Quantity),
+		Price:      toFixed(req.Price),
+		Commission: toFixed(req.Commission),
+		Liquidity:  parseLiquidity(req.Liquidity),
+		SeqID:      req.SeqID,
+		Timestamp:  time.Now().UnixNano(),
+	}
+	switch strings.ToUpper(req.Side) {
+	case "BUY":
+		fill.Side = 0
+	case "SELL":
+		fill.Side = 1
+	default:
+		return nil, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "side must be BUY or SELL", Field: "side"}
+	}
+	switch {
+	case req.Symbol == "":
+		return nil, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "symbol required", Field: "symbol"}
+	case !fixedInRange(req.Quantity) || fill.Quantity <= 0:
+		return nil, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "quantity must be positive", Field: "quantity"}
+	case !fixedInRange(req.Price) || fill.Price <= 0:
+		return nil, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "price must be positive", Field: "price"}
+	case !fixedInRange(req.Commission):
+		return nil, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "commission out of range", Field: "commission"}
+	}
+	return fill, nil
+}
+
+// simTickRequest - wire format for POST /api/sim/tick
+type simTickRequest struct {
+	Symbol string  `json:"symbol"`
+	Bid    float64 `json:"bid,omitempty"`
+	Ask    float64 `json:"ask,omitempty"`
+	Last   float64 `json:"last"`
+	Volume float64 `json:"volume,omitempty"`
+	SeqID  uint64  `json:"seq_id,omitempty"` // 0 = unsequenced
+}
+
+// handleSimTick injects a synthetic market tick
+func (sm *ShardedStateManager) handleSimTick(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req simTickRequest
+	if errResp := handlers.DecodeJSON(w, r, &req); errResp != nil {
+		handlers.WriteError(w, r, errResp)
+		return
+	}
+	tick, errResp := sm.simTick(&req)
+	if errResp != nil {
+		handlers.WriteError(w, r, errResp)
+		return
+	}
+	if !sm.feed.OnTick(tick) {
+		handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusUnprocessableEntity, Error: "tick not accepted: invalid prices or stale seq_id"})
+		return
+	}
+	sm.writeSimAccepted(w, r)
+}
+
+// simTick validates a synthetic tick request and builds the tick
+func (sm *ShardedStateManager) simTick(req *simTickRequest) (*MarketTickOptimized, *handlers.ErrorResponse) {
+	if req.Symbol == "" {
+		return nil, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "symbol required", Field: "symbol"}
+	}
+	for _, f := range []struct {
+		v    float64
+		name string
+	}{{req.Bid, "bid"}, {req.Ask, "ask"}, {req.Last, "last"}, {req.Volume, "volume"}} {
+		if !fixedInRange(f.v) {
+			return nil, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: f.name + " out of range", Field: f.name}
+		}
+	}
+
+	tick := &MarketTickOptimized{
+		SymbolHash: sm.symbols.Hash(req.Symbol),
+		BidPrice:   toFixed(req.Bid),
+		AskPrice:   toFixed(req.Ask),
+		LastPrice:  toFixed(req.Last),
+		Volume:     toFixed(req.Volume),
+		Timestamp:  time.Now().UnixNano(),
+		SeqID:      req.SeqID,
+	}
+	return tick, nil
+}
+
+func (sm *ShardedStateManager) writeSimAccepted(w http.ResponseWriter, r *http.Request) {
+	buf := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(buf)
+
+	b := append((*buf)[:0], `{"accepted":true,"seq_id":`...)
+	b = strconv.AppendUint(b, atomic.LoadUint64(&sm.state.SequenceID), 10)
+	b = append(b, '}')
+
+	handlers.WriteJSON(w, r, http.StatusAccepted, b)
+}
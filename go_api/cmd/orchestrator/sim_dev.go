@@ -0,0 +1,6 @@
+//go:build !production
+
+package main
+
+// simAvailable - this build may serve /api/sim/* when Config.SimMode is set
+const simAvailable = true
@@ -0,0 +1,7 @@
+//go:build production
+
+package main
+
+// simAvailable - production builds never serve /api/sim/*, whatever the
+// configuration says
+const simAvailable = false
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestSmoke drives a fixed script of ticks and fills through the feed
+// ingester - the path transport events take - and reads the results back
+// over the REST API. Nothing reaches NATS, the database or a gateway.
+//
+// The script opens a long of 0.5 at 50000, marks it at 52000 and sells
+// 0.2 there: 0.3 left open with 600 unrealized and 15000 of cash tied up,
+// 400 realized.
+func TestSmoke(t *testing.T) {
+	sm := NewShardedStateManager(testConfig())
+	go sm.hub.Run()
+	defer sm.hub.Shutdown()
+	srv := httptest.NewServer(setupHTTPRoutes(sm))
+	defer srv.Close()
+
+	h := sm.symbols.Hash("BTCUSD")
+	script := []struct {
+		tick *MarketTickOptimized
+		fill *FillEvent
+	}{
+		{tick: &MarketTickOptimized{SymbolHash: h, SeqID: 1, LastPrice: toFixed(50_000)}},
+		{fill: &FillEvent{OrderID: 1, SymbolHash: h, Side: 0, Quantity: toFixed(0.5), Price: toFixed(50_000), SeqID: 1}},
+		{tick: &MarketTickOptimized{SymbolHash: h, SeqID: 2, LastPrice: toFixed(52_000)}},
+		{fill: &FillEvent{OrderID: 2, SymbolHash: h, Side: 1, Quantity: toFixed(0.2), Price: toFixed(52_000), SeqID: 2}},
+	}
+	for i, step := range script {
+		switch {
+		case step.tick != nil:
+			step.tick.Timestamp = time.Now().UnixNano()
+			if !sm.feed.OnTick(step.tick) {
+				t.Fatalf("step %d: tick refused", i+1)
+			}
+		case step.fill != nil:
+			step.fill.Timestamp = time.Now().UnixNano()
+			if !sm.feed.OnFill(step.fill) {
+				t.Fatalf("step %d: fill refused", i+1)
+			}
+		}
+	}
+
+	start := float64(sm.startingEquity) / float64(PriceScale)
+	tests := []struct {
+		path  string
+		field func(body map[string]any) any
+		want  float64
+	}{
+		{"/api/portfolio", field("cash"), start + 400 - 15_000},
+		{"/api/portfolio", field("equity"), start + 1000},
+		{"/api/portfolio", field("total_pnl"), 1000},
+		{"/api/portfolio", field("drawdown_bps"), 0},
+		{"/api/positions", field("total"), 1},
+		{"/api/positions", positionField("quantity"), 0.3},
+		{"/api/positions", positionField("entry_price"), 50_000},
+		{"/api/positions", positionField("unrealized_pnl"), 600},
+		{"/api/positions", positionField("realized_pnl"), 400},
+		{"/api/metrics/latency", field("ticks"), 2},
+		{"/api/metrics/latency", field("risk_rejections"), 0},
+	}
+	bodies := make(map[string]map[string]any)
+	for _, tt := range tests {
+		body, ok := bodies[tt.path]
+		if !ok {
+			body = smokeGet(t, srv.URL+tt.path)
+			bodies[tt.path] = body
+		}
+		if got, ok := tt.field(body).(float64); !ok || got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.path, tt.field(body), tt.want)
+		}
+	}
+}
+
+// field reads a top-level field of a response
+func field(name string) func(map[string]any) any {
+	return func(body map[string]any) any { return body[name] }
+}
+
+// positionField reads a field of the only position listed
+func positionField(name string) func(map[string]any) any {
+	return func(body map[string]any) any {
+		list, _ := body["positions"].([]any)
+		if len(list) != 1 {
+			return nil
+		}
+		pos, _ := list[0].(map[string]any)
+		return pos[name]
+	}
+}
+
+// smokeGet fetches and decodes one JSON object
+func smokeGet(t *testing.T, url string) map[string]any {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s: %s", url, resp.Status)
+	}
+	var body map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	return body
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"cenayang-market/go-api/internal/ws"
+)
+
+// ============================================================================
+// STALE FEEDS - Held Symbols Whose Ticks Stopped
+// ============================================================================
+
+// QuoteAge returns how long ago the symbol's latest tick was accepted;
+// false before its first
+func (f *FeedIngester) QuoteAge(symbolHash uint64, now time.Time) (time.Duration, bool) {
+	f.mu.Lock()
+	at, ok := f.tickTimes[symbolHash]
+	f.mu.Unlock()
+	if !ok {
+		return 0, false
+	}
+	return now.Sub(time.Unix(0, at)), true
+}
+
+// FeedStale reports whether a held symbol's feed has been flagged silent
+func (sm *ShardedStateManager) FeedStale(symbolHash uint64) bool {
+	_, stale := sm.staleFeeds.Load(symbolHash)
+	return stale
+}
+
+// checkStaleFeeds flags every symbol with an open position whose latest
+// tick - or the start of the process, before its first - is older than
+// Config.MaxFeedSilence. The flag clears on the symbol's next tick, or
+// silently once it is flat.
+func (sm *ShardedStateManager) checkStaleFeeds(now time.Time) {
+	maxSilence := sm.config.MaxFeedSilence
+	if maxSilence <= 0 {
+		return
+	}
+
+	held := make(map[uint64]struct{})
+	for i := range sm.shards {
+		shard := &sm.shards[i]
+		shard.mu.RLock()
+		for hash := range shard.positions {
+			held[hash] = struct{}{}
+		}
+		shard.mu.RUnlock()
+	}
+
+	sm.staleFeeds.Range(func(key, _ any) bool {
+		if _, ok := held[key.(uint64)]; !ok {
+			sm.staleFeeds.Delete(key)
+		}
+		return true
+	})
+	for hash := range held {
+		silence, ok := sm.feed.QuoteAge(hash, now)
+		if !ok {
+			silence = now.Sub(sm.startTime)
+		}
+		if silence >= maxSilence {
+			sm.setFeedStale(hash, true, silence)
+		}
+	}
+}
+
+// setFeedStale flags or clears a symbol's feed and broadcasts the change
+func (sm *ShardedStateManager) setFeedStale(symbolHash uint64, stale bool, silence time.Duration) {
+	if stale {
+		if _, was := sm.staleFeeds.LoadOrStore(symbolHash, struct{}{}); was {
+			return
+		}
+	} else if _, was := sm.staleFeeds.LoadAndDelete(symbolHash); !was {
+		return
+	}
+
+	symbol := sm.symbols.Name(symbolHash)
+	if stale {
+		log.Printf("[FEED] %s stale: no tick for %v with a position open", symbol, silence.Round(time.Millisecond))
+		sm.raiseAlert(AlertWarning, "stale_feed", fmt.Sprintf("%s: no tick for %v with a position open", symbol, silence.Round(time.Millisecond)))
+	} else {
+		log.Printf("[FEED] %s ticking again", symbol)
+	}
+
+	b := make([]byte, 0, 160)
+	b = append(b, `{"type":"stale_feed","symbol":`...)
+	b = strconv.AppendQuote(b, symbol)
+	b = append(b, `,"symbol_hash":"`...)
+	b = strconv.AppendUint(b, symbolHash, 16)
+	b = append(b, `","stale":`...)
+	b = strconv.AppendBool(b, stale)
+	b = append(b, `,"silence_ms":`...)
+	b = strconv.AppendInt(b, silence.Milliseconds(), 10)
+	b = append(b, `,"max_silence_ms":`...)
+	b = strconv.AppendInt(b, sm.config.MaxFeedSilence.Milliseconds(), 10)
+	b = append(b, '}')
+	sm.publish(ws.EventStaleFeed, atomic.LoadUint64(&sm.state.SequenceID), b)
+}
+
+// appendQuoteAge reopens a serialized position to add its quote age (null
+// before the first tick) and stale flag
+func (sm *ShardedStateManager) appendQuoteAge(b []byte, symbolHash uint64, now time.Time) []byte {
+	b = append(b[:len(b)-1], `,"quote_age_ms":`...)
+	if age, ok := sm.feed.QuoteAge(symbolHash, now); ok {
+		b = strconv.AppendInt(b, age.Milliseconds(), 10)
+	} else {
+		b = append(b, `null`...)
+	}
+	b = append(b, `,"feed_stale":`...)
+	b = strconv.AppendBool(b, sm.FeedStale(symbolHash))
+	return append(b, '}')
+}
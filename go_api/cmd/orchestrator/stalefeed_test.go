@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStaleFeed(t *testing.T) {
+	clk := &fakeClock{t: time.Now()}
+	cfg := testConfig()
+	cfg.Clock = clk
+	cfg.MaxFeedSilence = 5 * time.Second
+	sm := NewShardedStateManager(cfg)
+	held, idle := sm.symbols.Hash("HELDUSD"), sm.symbols.Hash("IDLEUSD")
+	tick(sm, idle, 10) // Never held: never flagged
+	tick(sm, held, 100)
+	fill(sm, held, 0, 1, 100, 0)
+
+	steps := []struct {
+		after time.Duration
+		tick  bool
+		want  bool
+	}{
+		{4 * time.Second, false, false},
+		{time.Second, false, true}, // Silent for MaxFeedSilence
+		{time.Second, true, false}, // The next tick clears it
+		{5 * time.Second, false, true},
+	}
+	for i, s := range steps {
+		clk.t = clk.t.Add(s.after)
+		if s.tick {
+			tick(sm, held, 100)
+		}
+		sm.checkStaleFeeds(clk.t)
+		if got := sm.FeedStale(held); got != s.want {
+			t.Fatalf("step %d: stale %v, want %v", i, got, s.want)
+		}
+		if sm.FeedStale(idle) {
+			t.Fatalf("step %d: a symbol without a position flagged", i)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	sm.handlePositions(rec, httptest.NewRequest(http.MethodGet, "/api/positions", nil))
+	var body struct {
+		Positions []struct {
+			QuoteAgeMs int64 `json:"quote_age_ms"`
+			FeedStale  bool  `json:"feed_stale"`
+		} `json:"positions"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err, rec.Body)
+	}
+	if len(body.Positions) != 1 || body.Positions[0].QuoteAgeMs != 5000 || !body.Positions[0].FeedStale {
+		t.Fatalf("positions %+v", body.Positions)
+	}
+
+	fill(sm, held, 1, 1, 100, 0) // Flat: the flag goes without a tick
+	sm.checkStaleFeeds(clk.t)
+	if sm.FeedStale(held) {
+		t.Fatal("still stale once flat")
+	}
+}
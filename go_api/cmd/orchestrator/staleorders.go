@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"cenayang-market/go-api/internal/ws"
+)
+
+// ============================================================================
+// STALE ORDERS - Submitted Orders the Gateway Never Answered
+// ============================================================================
+
+// StaleOrderConfig - when a working order with no fill counts as lost
+type StaleOrderConfig struct {
+	Timeout    time.Duration // A submitted order with no fill or reject this long is stale (0 = off)
+	AutoCancel bool          // Cancel stale orders, releasing their capital, rather than only flagging them
+}
+
+// OrderStale reports whether a working order has been flagged stale
+func (sm *ShardedStateManager) OrderStale(orderID uint64) bool {
+	_, stale := sm.staleOrders.Load(orderID)
+	return stale
+}
+
+// checkStaleOrders flags every order still SUBMITTED - no fill, no reject -
+// StaleOrders.Timeout after it was sent, once per order. A fill, reject or
+// cancel takes it out of the book and clears the flag. A standby leaves
+// the book to the active.
+func (sm *ShardedStateManager) checkStaleOrders(now time.Time) {
+	timeout := sm.config.StaleOrders.Timeout
+	if timeout <= 0 || sm.Standby() {
+		return
+	}
+
+	var stale []OrderOptimized
+	working := make(map[uint64]struct{})
+	cutoff := now.Add(-timeout).UnixNano()
+	for i := range sm.shards {
+		shard := &sm.shards[i]
+		shard.mu.RLock()
+		for id, order := range shard.orders {
+			working[id] = struct{}{}
+			if order.Status == OrderSubmitted && order.Timestamp <= cutoff && !sm.OrderStale(id) {
+				stale = append(stale, *order)
+			}
+		}
+		shard.mu.RUnlock()
+	}
+
+	sm.staleOrders.Range(func(key, _ any) bool {
+		if _, ok := working[key.(uint64)]; !ok {
+			sm.staleOrders.Delete(key)
+		}
+		return true
+	})
+	for i := range stale {
+		sm.flagStaleOrder(&stale[i], now)
+	}
+}
+
+// flagStaleOrder alerts and broadcasts a stale order, cancelling it under
+// StaleOrders.AutoCancel
+func (sm *ShardedStateManager) flagStaleOrder(order *OrderOptimized, now time.Time) {
+	sm.staleOrders.Store(order.ID, struct{}{})
+	age := now.Sub(time.Unix(0, order.Timestamp))
+	symbol := sm.symbols.Name(order.SymbolHash)
+	cancelled := sm.config.StaleOrders.AutoCancel && sm.cancelStaleOrder(order.ID, order.SymbolHash)
+
+	action := "left working"
+	if cancelled {
+		action = "cancelled"
+	}
+	log.Printf("[ORDERS] Order %d (%s) stale: no fill or reject for %v; %s", order.ID, symbol, age.Round(time.Millisecond), action)
+	sm.raiseAlert(AlertWarning, "stale_order", fmt.Sprintf("order %d (%s): no fill or reject for %v; %s", order.ID, symbol, age.Round(time.Millisecond), action))
+
+	seq := atomic.LoadUint64(&sm.state.SequenceID)
+	b := make([]byte, 0, 192)
+	b = append(b, `{"type":"stale_order","order_id":"`...)
+	b = strconv.AppendUint(b, order.ID, 10)
+	b = append(b, `","symbol":`...)
+	b = strconv.AppendQuote(b, symbol)
+	b = append(b, `,"age_ms":`...)
+	b = strconv.AppendInt(b, age.Milliseconds(), 10)
+	b = append(b, `,"timeout_ms":`...)
+	b = strconv.AppendInt(b, sm.config.StaleOrders.Timeout.Milliseconds(), 10)
+	b = append(b, `,"cancelled":`...)
+	b = strconv.AppendBool(b, cancelled)
+	b = append(b, '}')
+	sm.publish(ws.EventStaleOrder, seq, b)
+}
+
+// cancelStaleOrder cancels one order if it is still unfilled, releasing
+// its capital and telling the gateway, and returns whether it did
+func (sm *ShardedStateManager) cancelStaleOrder(orderID, symbolHash uint64) bool {
+	shard := sm.GetShard(symbolHash)
+	shard.mu.Lock()
+	order, ok := shard.orders[orderID]
+	if !ok || order.Status != OrderSubmitted || !sm.transitionOrder(order, OrderCancelled) {
+		shard.mu.Unlock()
+		return false // Filled, rejected or cancelled meanwhile
+	}
+	order.SequenceID = sm.nextSequence()
+	order.Timestamp = time.Now().UnixNano()
+	cancelled := *order
+	sm.cancelOrderLocked(shard, order)
+	shard.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), CancelBroadcastTimeout)
+	sm.publishOrderWait(ctx, &cancelled)
+	cancel()
+
+	if sm.gateway != nil && !sm.PaperTrading() {
+		go sm.sendGatewayCancels([]OrderOptimized{cancelled})
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"cenayang-market/go-api/internal/allocator"
+)
+
+func TestStaleOrders(t *testing.T) {
+	for _, autoCancel := range []bool{false, true} {
+		clk := &fakeClock{t: time.Now()}
+		cfg := testConfig()
+		cfg.Clock = clk
+		cfg.StaleOrders = StaleOrderConfig{Timeout: 10 * time.Second, AutoCancel: autoCancel}
+		cfg.Strategies = []allocator.Strategy{{Name: "gann", Weight: 1}}
+		sm := NewShardedStateManager(cfg)
+		h := sm.symbols.Hash("STALEUSD")
+		submit := func(strategy string) *OrderOptimized {
+			order := &OrderOptimized{SymbolHash: h, Quantity: fx(10), Price: fx(100), Strategy: strategy}
+			if res, err := sm.SubmitOrder(order); err != nil || !res.Approved {
+				t.Fatal(res.Reason, err)
+			}
+			return order
+		}
+		lost, partial := submit("gann"), submit("")
+		sm.ApplyFill(&FillEvent{OrderID: partial.ID, SymbolHash: h, Side: 0, Quantity: fx(4), Price: fx(100)}) // Answered
+
+		clk.t = clk.t.Add(9 * time.Second)
+		sm.checkStaleOrders(sm.clock.Now())
+		if sm.OrderStale(lost.ID) {
+			t.Fatalf("cancel %v: flagged before the timeout", autoCancel)
+		}
+		clk.t = clk.t.Add(2 * time.Second)
+		sm.checkStaleOrders(sm.clock.Now())
+		sm.checkStaleOrders(sm.clock.Now()) // Flagged once
+		if alerts := sm.Alerts(false); len(alerts) != 1 || alerts[0].Kind != "stale_order" {
+			t.Fatalf("cancel %v: alerts %+v", autoCancel, alerts)
+		}
+		if sm.OrderStale(partial.ID) {
+			t.Fatalf("cancel %v: partially filled order flagged", autoCancel)
+		}
+
+		shard := sm.GetShard(h)
+		_, working := shard.orders[lost.ID]
+		used := sm.allocator.Snapshot()[0].Used
+		switch {
+		case !autoCancel && (!working || !sm.OrderStale(lost.ID)):
+			t.Fatal("flag-only: stale order left the book")
+		case autoCancel && (working || sm.retiredStatus(shard, lost.ID) != OrderCancelled):
+			t.Fatal("auto-cancel: stale order still working")
+		case autoCancel && used != 0:
+			t.Fatalf("auto-cancel: %d still reserved", used)
+		}
+	}
+}
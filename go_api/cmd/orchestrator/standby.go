@@ -0,0 +1,671 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"cenayang-market/go-api/internal/allocator"
+	"cenayang-market/go-api/internal/handlers"
+	"cenayang-market/go-api/internal/pb"
+	"cenayang-market/go-api/internal/ws"
+)
+
+// ============================================================================
+// BLUE/GREEN HANDOFF - Standby Replication and Promotion
+// ============================================================================
+
+const (
+	// ReplicationMaxPending bounds out-of-order events held while a missing
+	// sequence ID is awaited; beyond it the standby resyncs from a snapshot
+	ReplicationMaxPending = StreamStateBuffer
+
+	// ReplicationGapTimeout is how long a missing sequence ID is awaited
+	ReplicationGapTimeout = 2 * time.Second
+
+	// ReplicationRetryDelay paces reconnects to the active
+	ReplicationRetryDelay = time.Second
+
+	// PromotionTimeout bounds how long promotion waits for the active to
+	// go quiet and the standby to catch up
+	PromotionTimeout = 10 * time.Second
+
+	promotionPollInterval = 50 * time.Millisecond
+
+	// SnapshotCashNotional marks snapshots whose cash moves by fill
+	// notional; older ones hold margin-style cash and are migrated on import
+	SnapshotCashNotional = 1
+)
+
+var (
+	ErrNotStandby       = errors.New("not a standby")
+	ErrActiveSequencing = errors.New("active still sequencing events")
+
+	errReplicationGap = errors.New("replication sequence gap")
+)
+
+// Replicator follows an active orchestrator: it restores the active's
+// snapshot, then applies the fill, order and rebase events sequenced after
+// it strictly in sequence order, so the standby's state and sequence ID
+// match the active's at every applied event. Events arriving out of order
+// are held until their predecessor arrives; a gap that does not close
+// within ReplicationGapTimeout, e.g. after the hub dropped events for a
+// slow stream, triggers a resync from a fresh snapshot.
+type Replicator struct {
+	sm      *ShardedStateManager
+	active  pb.OrchestratorClient
+	applied uint64 // Atomic: last sequence ID applied
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// Standby reports whether the instance follows an active one. A standby
+// refuses fills from the feed and rejects orders with STANDBY.
+func (sm *ShardedStateManager) Standby() bool {
+	return atomic.LoadInt32(&sm.standby) != 0
+}
+
+// Follow puts the state manager in standby and replicates active's state
+// until promoted or ctx ends
+func (sm *ShardedStateManager) Follow(ctx context.Context, active pb.OrchestratorClient) *Replicator {
+	ctx, cancel := context.WithCancel(ctx)
+	r := &Replicator{sm: sm, active: active, cancel: cancel, done: make(chan struct{})}
+	atomic.StoreInt32(&sm.standby, 1)
+	sm.replica.Store(r)
+	go r.run(ctx)
+	return r
+}
+
+// followActive dials the active's gRPC API and follows it
+func (sm *ShardedStateManager) followActive(ctx context.Context, addr string) (*grpc.ClientConn, error) {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	sm.Follow(ctx, pb.NewOrchestratorClient(conn))
+	return conn, nil
+}
+
+// Applied returns the last sequence ID replicated from the active
+func (r *Replicator) Applied() uint64 {
+	return atomic.LoadUint64(&r.applied)
+}
+
+func (r *Replicator) run(ctx context.Context) {
+	defer close(r.done)
+	for {
+		err := r.sync(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("[STANDBY] Replication interrupted: %v - resyncing", err)
+		if errors.Is(err, errReplicationGap) {
+			continue // The active is reachable; resync at once
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(ReplicationRetryDelay):
+		}
+	}
+}
+
+// sync subscribes to the active's events, restores its snapshot and
+// applies the events sequenced after it until the stream fails
+func (r *Replicator) sync(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Subscribe before taking the snapshot so no event falls between the
+	// two; the header arrives once the active has registered the stream.
+	// Events already in the snapshot are recognized by sequence ID.
+	stream, err := r.active.StreamState(ctx, &pb.StreamStateRequest{})
+	if err != nil {
+		return err
+	}
+	if _, err := stream.Header(); err != nil {
+		return err
+	}
+	snap, err := r.active.GetSnapshot(ctx, &pb.GetSnapshotRequest{})
+	if err != nil {
+		return err
+	}
+	r.sm.restoreSnapshot(snap)
+	atomic.StoreUint64(&r.applied, snap.SeqId)
+	log.Printf("[STANDBY] Restored snapshot at seq %d: %d positions, %d orders",
+		snap.SeqId, len(snap.Positions), len(snap.Orders))
+
+	pending := make(map[uint64]*pb.StateEvent) // By predecessor sequence ID
+	var gapSince time.Time
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		if err := r.apply(event, pending); err != nil {
+			return err
+		}
+		switch {
+		case len(pending) == 0:
+			gapSince = time.Time{}
+		case len(pending) > ReplicationMaxPending:
+			return errReplicationGap
+		case gapSince.IsZero():
+			gapSince = time.Now()
+		case time.Since(gapSince) > ReplicationGapTimeout:
+			return errReplicationGap
+		}
+	}
+}
+
+// apply mirrors control events at once and queues sequenced events until
+// every earlier one has been applied. Other events carry no state.
+func (r *Replicator) apply(event *pb.StateEvent, pending map[uint64]*pb.StateEvent) error {
+	prev := event.SeqId - 1
+	switch uint8(event.Type) {
+	case ws.EventKillSwitch:
+		var p struct {
+			Active bool `json:"active"`
+		}
+		if err := json.Unmarshal(event.Data, &p); err != nil {
+			return err
+		}
+		r.sm.SetKillSwitch(p.Active)
+		return nil
+
+	case ws.EventTradingPaused:
+		var p struct {
+			Paused bool `json:"paused"`
+		}
+		if err := json.Unmarshal(event.Data, &p); err != nil {
+			return err
+		}
+		r.sm.SetTradingPaused(p.Paused)
+		return nil
+
+	case ws.EventVolatilityHalt:
+		var p struct {
+			SymbolHash    string  `json:"symbol_hash"`
+			Halted        bool    `json:"halted"`
+			VolatilityBps float64 `json:"volatility_bps"`
+		}
+		if err := json.Unmarshal(event.Data, &p); err != nil {
+			return err
+		}
+		hash, err := strconv.ParseUint(p.SymbolHash, 16, 64)
+		if err != nil {
+			return err
+		}
+		r.sm.setVolatilityHalt(hash, p.Halted, p.VolatilityBps)
+		return nil
+
+	case ws.EventHWMReset:
+		var p struct {
+			HighWaterMark float64 `json:"high_water_mark"`
+		}
+		if err := json.Unmarshal(event.Data, &p); err != nil {
+			return err
+		}
+		r.sm.setHighWaterMark(toFixed(p.HighWaterMark))
+		return nil
+
+	case ws.EventSequenceRebased:
+		var p struct {
+			From uint64 `json:"from"`
+		}
+		if err := json.Unmarshal(event.Data, &p); err != nil {
+			return err
+		}
+		prev = p.From
+
+	case ws.EventFill, ws.EventOrder:
+
+	default:
+		return nil
+	}
+
+	if event.SeqId <= r.Applied() {
+		return nil // Already in the snapshot
+	}
+	pending[prev] = event
+	for {
+		next, ok := pending[r.Applied()]
+		if !ok {
+			return nil
+		}
+		delete(pending, r.Applied())
+		if err := r.applySequenced(next); err != nil {
+			return fmt.Errorf("seq %d: %w", next.SeqId, err)
+		}
+		atomic.StoreUint64(&r.applied, next.SeqId)
+	}
+}
+
+// applySequenced books one sequenced event so that the standby's sequence
+// ID lands on the event's
+func (r *Replicator) applySequenced(event *pb.StateEvent) error {
+	sm := r.sm
+	switch uint8(event.Type) {
+	case ws.EventFill:
+		fill, err := parseReplicatedFill(event.Data)
+		if err != nil {
+			return err
+		}
+		atomic.StoreUint64(&sm.state.SequenceID, event.SeqId-1)
+		sm.bookFill(fill)
+		sm.feed.observeFill(fill.SeqID, fill.Timestamp)
+
+	case ws.EventOrder:
+		order, err := parseReplicatedOrder(event.Data)
+		if err != nil {
+			return err
+		}
+		order.SequenceID = event.SeqId
+		if order.Status == OrderCancelled {
+			sm.restoreCancel(order)
+		} else {
+			sm.restoreOrder(order, true)
+		}
+		atomic.StoreUint64(&sm.state.SequenceID, event.SeqId)
+
+	case ws.EventSequenceRebased:
+		if _, err := sm.RebaseSequence(event.SeqId); err != nil {
+			return err
+		}
+	}
+	if seq := atomic.LoadUint64(&sm.state.SequenceID); seq != event.SeqId {
+		return fmt.Errorf("diverged: local sequence %d", seq)
+	}
+	return nil
+}
+
+// replicatedFill - fill event payload (see publishFill)
+type replicatedFill struct {
+	OrderID       uint64    `json:"order_id,string"`
+	SymbolHash    string    `json:"symbol_hash"`
+	Side          string    `json:"side"`
+	Quantity      jsonFixed `json:"quantity"`
+	Price         jsonFixed `json:"price"`
+	Commission    jsonFixed `json:"commission"`
+	Liquidity     string    `json:"liquidity"`
+	ReduceOnly    bool      `json:"reduce_only"`
+	FillSeqID     uint64    `json:"fill_seq_id"`
+	FillTimestamp int64     `json:"fill_timestamp_ns"`
+}
+
+func parseReplicatedFill(data []byte) (*FillEvent, error) {
+	var p replicatedFill
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	fill := &FillEvent{
+		OrderID:    p.OrderID,
+		Side:       parseSide(p.Side),
+		Quantity:   int64(p.Quantity),
+		Price:      int64(p.Price),
+		Commission: int64(p.Commission),
+		Liquidity:  parseLiquidity(p.Liquidity),
+		ReduceOnly: p.ReduceOnly,
+		SeqID:      p.FillSeqID,
+		Timestamp:  p.FillTimestamp,
+	}
+	var err error
+	fill.SymbolHash, err = strconv.ParseUint(p.SymbolHash, 16, 64)
+	return fill, err
+}
+
+// replicatedOrder - order event payload (see publishOrder)
+type replicatedOrder struct {
+	SymbolHash string `json:"symbol_hash"`
+	Order      struct {
+		ID            uint64    `json:"id,string"`
+		Side          string    `json:"side"`
+		Strategy      string    `json:"strategy"`
+		Tag           string    `json:"tag"`
+		ReduceOnly    bool      `json:"reduce_only"`
+		Quantity      jsonFixed `json:"quantity"`
+		Price         jsonFixed `json:"price"`
+		DecisionPrice jsonFixed `json:"decision_price"`
+		Status        string    `json:"status"`
+		Timestamp     int64     `json:"timestamp_ns"`
+	} `json:"order"`
+}
+
+func parseReplicatedOrder(data []byte) (*OrderOptimized, error) {
+	var p replicatedOrder
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	order := &OrderOptimized{
+		ID:            p.Order.ID,
+		Side:          parseSide(p.Order.Side),
+		Status:        OrderSubmitted,
+		ReduceOnly:    p.Order.ReduceOnly,
+		Quantity:      int64(p.Order.Quantity),
+		Price:         int64(p.Order.Price),
+		DecisionPrice: int64(p.Order.DecisionPrice),
+		Timestamp:     p.Order.Timestamp,
+		Strategy:      p.Order.Strategy,
+		Tag:           p.Order.Tag,
+	}
+	if p.Order.Status == orderStatusNames[OrderCancelled] {
+		order.Status = OrderCancelled
+	}
+	var err error
+	order.SymbolHash, err = strconv.ParseUint(p.SymbolHash, 16, 64)
+	return order, err
+}
+
+func parseSide(side string) uint8 {
+	if side == "SELL" {
+		return 1
+	}
+	return 0
+}
+
+// ============================================================================
+// SNAPSHOT - Consistent Point-in-Time State
+// ============================================================================
+
+// lockShards read-locks every shard in index order. Fills and orders are
+// sequenced under their shard lock, so while all are held the state and
+// sequence ID cannot move apart.
+func (sm *ShardedStateManager) lockShards() {
+	for i := range sm.shards {
+		sm.shards[i].mu.RLock()
+	}
+}
+
+func (sm *ShardedStateManager) unlockShards() {
+	for i := range sm.shards {
+		sm.shards[i].mu.RUnlock()
+	}
+}
+
+// Snapshot captures the replicated state; every fill and order event up to
+// the returned sequence ID is reflected in it
+func (sm *ShardedStateManager) Snapshot() *pb.Snapshot {
+	sm.lockShards()
+	defer sm.unlockShards()
+
+	snap := &pb.Snapshot{
+		SeqId:         atomic.LoadUint64(&sm.state.SequenceID),
+		Cash:          atomic.LoadInt64(&sm.state.Cash),
+		DailyPnl:      atomic.LoadInt64(&sm.state.DailyPnL),
+		HighWaterMark: atomic.LoadInt64(&sm.state.HighWaterMark),
+		KillSwitch:    atomic.LoadInt32(&sm.state.KillSwitch) != 0,
+		TradingPaused: atomic.LoadInt32(&sm.state.TradingPaused) != 0,
+		LastOrderId:   atomic.LoadUint64(&sm.orderIDs.last),
+		CashModel:     SnapshotCashNotional,
+	}
+	snap.FillCursorSeqId, snap.FillCursorTimestampNs = sm.feed.FillCursor()
+
+	for i := range sm.shards {
+		for _, pos := range sm.shards[i].positions {
+			snap.Positions = append(snap.Positions, &pb.PositionState{
+				SymbolHash:    pos.SymbolHash,
+				Side:          pb.Side(pos.Side),
+				Quantity:      pos.Quantity,
+				EntryPrice:    pos.EntryPrice,
+				CurrentPrice:  pos.CurrentPrice,
+				UnrealizedPnl: pos.UnrealizedPnL,
+				RealizedPnl:   pos.RealizedPnL,
+				Commission:    pos.Commission,
+			})
+		}
+		for _, o := range sm.shards[i].orders {
+			snap.Orders = append(snap.Orders, &pb.OrderState{
+				Id:            o.ID,
+				SymbolHash:    o.SymbolHash,
+				Side:          pb.Side(o.Side),
+				Status:        uint32(o.Status),
+				ReduceOnly:    o.ReduceOnly,
+				Quantity:      o.Quantity,
+				Price:         o.Price,
+				FilledQty:     o.FilledQty,
+				AvgFillPrice:  o.AvgFillPrice,
+				DecisionPrice: o.DecisionPrice,
+				SeqId:         o.SequenceID,
+				TimestampNs:   o.Timestamp,
+				Strategy:      o.Strategy,
+			})
+		}
+	}
+	for _, a := range sm.allocator.Snapshot() {
+		snap.Allocations = append(snap.Allocations, &pb.StrategyAllocation{
+			Name:     a.Name,
+			Capital:  a.Capital,
+			Used:     a.Used,
+			Pnl:      a.PnL,
+			Peak:     a.Peak,
+			Halted:   a.Halted,
+			HaltedOn: a.HaltedOn,
+		})
+	}
+	return snap
+}
+
+// restoreSnapshot replaces positions, working orders, cash and strategy
+// capital with a snapshot's. Order IDs already indexed stay indexed.
+func (sm *ShardedStateManager) restoreSnapshot(snap *pb.Snapshot) {
+	for i := range sm.shards {
+		shard := &sm.shards[i]
+		shard.mu.Lock()
+		shard.positions = make(map[uint64]*PositionOptimized, 16)
+		shard.orders = make(map[uint64]*OrderOptimized, 16)
+		shard.lots = make(map[uint64][]costLot, 16) // Reseeded from each entry on next use
+		shard.scaling = make(map[uint64]scaleHistory, 16)
+		shard.fills = make(map[uint64]positionFills, 16)      // Imported positions start without fills
+		shard.tagPnL = make(map[string]int64, 4)              // Imported positions are untagged
+		shard.capital = make(map[uint64]map[string]int64, 16) // The snapshot's allocations already count it
+		shard.mu.Unlock()
+	}
+	atomic.StoreInt64(&sm.cashReserved, 0) // Held again by each restored order
+
+	now := time.Now().UnixNano()
+	var cost int64
+	for _, p := range snap.Positions {
+		pos := &PositionOptimized{
+			SymbolHash:    p.SymbolHash,
+			Side:          uint8(p.Side),
+			Quantity:      p.Quantity,
+			EntryPrice:    p.EntryPrice,
+			CurrentPrice:  p.CurrentPrice,
+			UnrealizedPnL: p.UnrealizedPnl,
+			RealizedPnL:   p.RealizedPnl,
+			Commission:    p.Commission,
+			UpdatedAt:     now,
+		}
+		pos.BreakevenPrice = breakevenPrice(pos)
+		// The snapshot carries no cost; an open position tied up its entry notional
+		pos.Cost = positionCost(sm.symbols.Get(pos.SymbolHash), pos.Side, pos.Quantity, pos.EntryPrice)
+		cost += pos.Cost
+		shard := sm.GetShard(pos.SymbolHash)
+		shard.mu.Lock()
+		shard.positions[pos.SymbolHash] = pos
+		shard.mu.Unlock()
+	}
+	for _, o := range snap.Orders {
+		sm.restoreOrder(&OrderOptimized{
+			ID:            o.Id,
+			SymbolHash:    o.SymbolHash,
+			Side:          uint8(o.Side),
+			Status:        uint8(o.Status),
+			ReduceOnly:    o.ReduceOnly,
+			Quantity:      o.Quantity,
+			Price:         o.Price,
+			FilledQty:     o.FilledQty,
+			AvgFillPrice:  o.AvgFillPrice,
+			DecisionPrice: o.DecisionPrice,
+			SequenceID:    o.SeqId,
+			Timestamp:     o.TimestampNs,
+			Strategy:      o.Strategy,
+		}, false)
+	}
+
+	allocs := make([]allocator.Allocation, len(snap.Allocations))
+	for i, a := range snap.Allocations {
+		allocs[i] = allocator.Allocation{Name: a.Name, Capital: a.Capital, Used: a.Used, PnL: a.Pnl, Peak: a.Peak, Halted: a.Halted, HaltedOn: a.HaltedOn}
+	}
+	sm.allocator.Restore(allocs)
+
+	cash := snap.Cash
+	if snap.CashModel < SnapshotCashNotional {
+		// Margin-style cash never paid for the open positions
+		cash -= cost
+		log.Printf("[STANDBY] Migrated margin-style snapshot cash: %d -> %d", snap.Cash, cash)
+	}
+	atomic.StoreInt64(&sm.state.Cash, cash)
+	atomic.StoreInt64(&sm.bookValue, cash+cost)
+	sm.restoreHighWaterMark(snap.HighWaterMark)
+	sm.restoreDailyPnL(snap.DailyPnl) // Against the imported equity
+	atomic.StoreUint64(&sm.state.SequenceID, snap.SeqId)
+	sm.orderIDs.Observe(snap.LastOrderId)
+	sm.feed.observeFill(snap.FillCursorSeqId, snap.FillCursorTimestampNs)
+	sm.SetKillSwitch(snap.KillSwitch)
+	sm.SetTradingPaused(snap.TradingPaused)
+	sm.startBreakerWarmup("snapshot import")
+	sm.recomputePortfolioState()
+}
+
+// restoreOrder adds an order accepted by the active to the working book,
+// committing its strategy capital unless the snapshot already did. The
+// cash a buy holds is not snapshotted and is always held again - without
+// the active's check, which already admitted the order.
+func (sm *ShardedStateManager) restoreOrder(order *OrderOptimized, reserve bool) {
+	shard := sm.GetShard(order.SymbolHash)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if prev, ok := shard.orders[order.ID]; ok {
+		// Replaced by the update, which holds again for what is left
+		sm.releaseOrderCash(prev, prev.Quantity-prev.FilledQty)
+		if reserve && prev.Reserved != 0 {
+			sm.allocator.Release(prev.Strategy, prev.Reserved)
+		}
+	}
+	open := order.Quantity - order.FilledQty
+	order.CashReserved = sm.orderCashNeed(order, open)
+	atomic.AddInt64(&sm.cashReserved, order.CashReserved)
+
+	// A snapshot's allocations already count its orders' reservations
+	order.Reserved = 0
+	if order.Strategy != "" && !order.ReduceOnly && sm.allocator.Enabled() {
+		order.Reserved = notionalValue(sm.symbols.Get(order.SymbolHash), open, reservePrice(order))
+	}
+	if reserve && order.Reserved != 0 {
+		if err := sm.allocator.Reserve(order.Strategy, order.Reserved); err != nil {
+			log.Printf("[STANDBY] Order %d: strategy %s: %v", order.ID, order.Strategy, err)
+			order.Reserved = 0
+		}
+	}
+	sm.orderIDs.Observe(order.ID)
+	sm.orderIndex.LoadOrStore(order.ID, orderIndexEntry{symbolHash: order.SymbolHash, indexedAt: sm.clock.Now().UnixNano()})
+
+	stored := orderPool.Get().(*OrderOptimized)
+	*stored = *order
+	shard.orders[order.ID] = stored
+}
+
+// restoreCancel retires a replicated order the active cancelled
+func (sm *ShardedStateManager) restoreCancel(order *OrderOptimized) {
+	shard := sm.GetShard(order.SymbolHash)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if stored, ok := shard.orders[order.ID]; ok {
+		sm.cancelOrderLocked(shard, stored)
+	}
+}
+
+// ============================================================================
+// PROMOTION
+// ============================================================================
+
+// Promote ends replication and takes over from the active: fills and
+// orders are accepted from the next sequence ID on. It first waits until
+// the active's sequence ID holds still across two polls and the standby
+// has applied everything up to it, so no event is lost at the handoff and
+// the fill cursor drops any the feed re-delivers. Detach the feed from the
+// active before promoting; while the active keeps sequencing, promotion
+// fails with ErrActiveSequencing after PromotionTimeout. Cancelling ctx
+// abandons the promotion with ctx's error, the standby still following.
+func (sm *ShardedStateManager) Promote(ctx context.Context) (uint64, error) {
+	r := sm.replica.Load()
+	if r == nil || !sm.Standby() {
+		return 0, ErrNotStandby
+	}
+	caller := ctx
+	ctx, cancel := context.WithTimeout(ctx, PromotionTimeout)
+	defer cancel()
+
+	var last uint64
+	for polls := 0; ; polls++ {
+		p, err := r.active.GetPortfolio(ctx, &pb.GetPortfolioRequest{})
+		if err != nil && ctx.Err() == nil {
+			return 0, fmt.Errorf("active unreachable: %w", err)
+		}
+		if err == nil {
+			if polls > 0 && p.SeqId == last && r.Applied() == last {
+				break
+			}
+			last = p.SeqId
+		}
+		select {
+		case <-ctx.Done():
+			if err := caller.Err(); err != nil {
+				return 0, err // The caller gave up, e.g. the client disconnected
+			}
+			return 0, ErrActiveSequencing
+		case <-time.After(promotionPollInterval):
+		}
+	}
+
+	r.cancel()
+	<-r.done
+	sm.replica.Store(nil)
+	atomic.StoreInt32(&sm.standby, 0)
+
+	seq := atomic.LoadUint64(&sm.state.SequenceID)
+	log.Printf("[STANDBY] Promoted to active at seq %d", seq)
+	return seq, nil
+}
+
+// handleStandbyPromote promotes a standby to active
+func (sm *ShardedStateManager) handleStandbyPromote(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+
+	seq, err := sm.Promote(r.Context())
+	switch {
+	case errors.Is(err, context.Canceled):
+		handlers.WriteClientClosed(w, r)
+		return
+	case errors.Is(err, ErrNotStandby):
+		handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusConflict, Error: err.Error()})
+		return
+	case err != nil:
+		handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusServiceUnavailable, Error: err.Error()})
+		return
+	}
+
+	buf := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(buf)
+
+	b := append((*buf)[:0], `{"promoted":true,"seq_id":`...)
+	b = strconv.AppendUint(b, seq, 10)
+	b = append(b, '}')
+
+	handlers.WriteJSON(w, r, http.StatusOK, b)
+}
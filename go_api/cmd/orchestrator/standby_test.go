@@ -0,0 +1,68 @@
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"cenayang-market/go-api/internal/pb"
+)
+
+func TestRestoreSnapshotCash(t *testing.T) {
+	tests := []struct {
+		name      string
+		model     uint32
+		side      pb.Side
+		cash      float64 // As recorded
+		wantCash  float64
+		wantValue float64 // Cash plus open cost
+	}{
+		{"notional long", SnapshotCashNotional, pb.Side(0), 99_000, 99_000, 100_000},
+		{"notional short", SnapshotCashNotional, pb.Side(1), 101_000, 101_000, 100_000},
+		{"margin-style long", 0, pb.Side(0), 100_000, 99_000, 100_000},
+		{"margin-style short", 0, pb.Side(1), 100_000, 101_000, 100_000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := NewShardedStateManager(testConfig())
+			h := sm.symbols.Hash("SNAPUSD")
+			sm.restoreSnapshot(&pb.Snapshot{
+				Cash:          fx(tt.cash),
+				HighWaterMark: fx(100_000),
+				CashModel:     tt.model,
+				Positions: []*pb.PositionState{{
+					SymbolHash: h, Side: tt.side, Quantity: fx(10), EntryPrice: fx(100), CurrentPrice: fx(100),
+				}},
+			})
+			if cash := atomic.LoadInt64(&sm.state.Cash); cash != fx(tt.wantCash) {
+				t.Fatalf("cash %d, want %d", cash, fx(tt.wantCash))
+			}
+			if value := atomic.LoadInt64(&sm.bookValue); value != fx(tt.wantValue) {
+				t.Fatalf("book value %d, want %d", value, fx(tt.wantValue))
+			}
+		})
+	}
+}
+
+func TestSnapshotRoundTripKeepsCash(t *testing.T) {
+	sm := NewShardedStateManager(testConfig())
+	h := sm.symbols.Hash("SNAPUSD")
+	sm.bookFill(&FillEvent{OrderID: 1, SymbolHash: h, Side: 0, Quantity: fx(10), Price: fx(100)})
+
+	snap := sm.Snapshot()
+	if snap.CashModel != SnapshotCashNotional {
+		t.Fatalf("cash model %d, want %d", snap.CashModel, SnapshotCashNotional)
+	}
+	standby := NewShardedStateManager(testConfig())
+	standby.restoreSnapshot(snap)
+	for _, v := range []struct {
+		field     string
+		got, want int64
+	}{
+		{"cash", atomic.LoadInt64(&standby.state.Cash), atomic.LoadInt64(&sm.state.Cash)},
+		{"book value", atomic.LoadInt64(&standby.bookValue), atomic.LoadInt64(&sm.bookValue)},
+	} {
+		if v.got != v.want {
+			t.Errorf("%s %d, want %d", v.field, v.got, v.want)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"sync/atomic"
+
+	"cenayang-market/go-api/internal/handlers"
+)
+
+// ============================================================================
+// STRESS TEST - What-If Price Shocks, No State Mutated
+// ============================================================================
+
+// StressPosition - one position marked at its shocked price
+type StressPosition struct {
+	SymbolHash   uint64
+	MarkPrice    int64
+	ShockedPrice int64
+	PnLChange    int64 // Shocked unrealized - current unrealized
+}
+
+// StressResult - the book revalued under a price shock
+type StressResult struct {
+	Equity          int64
+	ProjectedEquity int64
+	DrawdownBps     int64 // Against the high water mark, or the projected equity if higher
+	DailyPnL        int64 // Projected
+	Breaches        []RiskReason
+	KillSwitch      bool // The drawdown breaker would engage
+	Positions       []StressPosition
+}
+
+// shockPrice moves price by bps, floored at zero
+func shockPrice(price, bps int64) int64 {
+	return max(mulDiv(price, 10000+bps, 10000), 0)
+}
+
+// StressTest revalues every position with its mark price shocked by
+// globalBps (per-symbol overrides take precedence) and reports the
+// projected equity, drawdown and the risk limits that would trip. Marks,
+// PnL and drawdown use the live path's math; nothing is written.
+func (sm *ShardedStateManager) StressTest(globalBps int64, overrides map[uint64]int64) StressResult {
+	var res StressResult
+	var change int64
+	for i := 0; i < NumShards; i++ {
+		shard := &sm.shards[i]
+		shard.mu.RLock()
+		for _, pos := range shard.positions {
+			bps, ok := overrides[pos.SymbolHash]
+			if !ok {
+				bps = globalBps
+			}
+			spec := sm.symbols.Get(pos.SymbolHash)
+			mark := markPrice(pos)
+			shocked := shockPrice(mark, bps)
+			p := StressPosition{
+				SymbolHash:   pos.SymbolHash,
+				MarkPrice:    mark,
+				ShockedPrice: shocked,
+				PnLChange:    unrealizedPnL(spec, pos, shocked) - unrealizedPnL(spec, pos, mark),
+			}
+			change += p.PnLChange
+			res.Positions = append(res.Positions, p)
+		}
+		shard.mu.RUnlock()
+	}
+	sort.Slice(res.Positions, func(i, j int) bool { return res.Positions[i].SymbolHash < res.Positions[j].SymbolHash })
+
+	res.Equity = atomic.LoadInt64(&sm.state.Equity)
+	res.ProjectedEquity = res.Equity + change
+	res.DailyPnL = atomic.LoadInt64(&sm.state.DailyPnL) + change
+	hwm := max(atomic.LoadInt64(&sm.state.HighWaterMark), res.ProjectedEquity)
+	if hwm > 0 {
+		res.DrawdownBps = drawdownBps(hwm, res.ProjectedEquity)
+	}
+
+	if res.DrawdownBps >= int64(sm.config.MaxDrawdownPct*100) {
+		res.Breaches = append(res.Breaches, ReasonMaxDrawdown)
+		res.KillSwitch = sm.config.KillSwitchEnabled
+	}
+	if res.DailyPnL < -int64(sm.config.DailyLossLimit*float64(PriceScale)) {
+		res.Breaches = append(res.Breaches, ReasonDailyLossLimit)
+	}
+	return res
+}
+
+// stressRequest - wire format for POST /api/risk/stress. Shocks are
+// percentages, e.g. -10 for a 10% drop.
+type stressRequest struct {
+	ShockPct float64            `json:"shock_pct"`
+	Symbols  map[string]float64 `json:"symbols,omitempty"` // Per-symbol overrides
+}
+
+// shockBps validates a percentage shock and converts it to basis points
+func shockBps(pct float64, field string) (int64, *handlers.ErrorResponse) {
+	if pct < -100 || pct > 1000 {
+		return 0, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "shock must be between -100 and 1000 percent", Field: field}
+	}
+	return int64(pct * 100), nil
+}
+
+// handleStress serves StressTest
+func (sm *ShardedStateManager) handleStress(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req stressRequest
+	if errResp := handlers.DecodeJSON(w, r, &req); errResp != nil {
+		handlers.WriteError(w, r, errResp)
+		return
+	}
+	global, errResp := shockBps(req.ShockPct, "shock_pct")
+	if errResp != nil {
+		handlers.WriteError(w, r, errResp)
+		return
+	}
+	overrides := make(map[uint64]int64, len(req.Symbols))
+	for symbol, pct := range req.Symbols {
+		bps, errResp := shockBps(pct, "symbols."+symbol)
+		if errResp != nil {
+			handlers.WriteError(w, r, errResp)
+			return
+		}
+		overrides[sm.symbols.Hash(symbol)] = bps
+	}
+
+	res := sm.StressTest(global, overrides)
+
+	buf := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(buf)
+
+	b := append((*buf)[:0], `{"equity":`...)
+	b = appendFixed(b, res.Equity)
+	b = append(b, `,"projected_equity":`...)
+	b = appendFixed(b, res.ProjectedEquity)
+	b = append(b, `,"pnl_change":`...)
+	b = appendFixed(b, res.ProjectedEquity-res.Equity)
+	b = append(b, `,"drawdown_bps":`...)
+	b = strconv.AppendInt(b, res.DrawdownBps, 10)
+	b = append(b, `,"max_drawdown_bps":`...)
+	b = strconv.AppendInt(b, int64(sm.config.MaxDrawdownPct*100), 10)
+	b = append(b, `,"daily_pnl":`...)
+	b = appendFixed(b, res.DailyPnL)
+	b = append(b, `,"kill_switch":`...)
+	b = strconv.AppendBool(b, res.KillSwitch)
+	b = append(b, `,"breaches":[`...)
+	for i, reason := range res.Breaches {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, '"')
+		b = append(b, reason.String()...)
+		b = append(b, '"')
+	}
+	b = append(b, `],"positions":[`...)
+	for i, p := range res.Positions {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, `{"symbol":`...)
+		b = strconv.AppendQuote(b, sm.symbols.Name(p.SymbolHash))
+		b = append(b, `,"mark_price":`...)
+		b = appendFixed(b, p.MarkPrice)
+		b = append(b, `,"shocked_price":`...)
+		b = appendFixed(b, p.ShockedPrice)
+		b = append(b, `,"pnl_change":`...)
+		b = appendFixed(b, p.PnLChange)
+		b = append(b, '}')
+	}
+	b = append(b, `]}`...)
+
+	handlers.WriteJSON(w, r, http.StatusOK, b)
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"cmp"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cenayang-market/go-api/internal/handlers"
+	"cenayang-market/go-api/internal/models"
+)
+
+// ============================================================================
+// SYMBOL METADATA REGISTRY - Immutable After Load (Lock-Free Reads)
+// ============================================================================
+
+// SymbolMeta - contract specification as configured
+type SymbolMeta struct {
+	Symbol           string
+	TickSize         float64 // Minimum price increment (0 = unchecked)
+	LotSize          float64 // Minimum quantity increment (0 = unchecked)
+	MinQuantity      float64 // Smallest order quantity (0 = one lot)
+	MinNotional      float64 // Smallest order notional (0 = Config.MinNotional)
+	Multiplier       float64 // Contract multiplier (0 = 1)
+	Currency         string
+	Precision        int           // PnL decimal places (0 = currency default)
+	FillPriceBandPct float64       // Overrides Config.FillPriceBandPct (0 = global)
+	InitialMarginPct float64       // Margin to open, % of notional (0 = none)
+	MaintMarginPct   float64       // Margin to keep open, % of notional (0 = none)
+	TickThrottle     time.Duration // Overrides Config.TickThrottle (0 = global, < 0 = off)
+	MakerFeeBps      float64       // Charged on maker notional when the venue reports no commission; negative = rebate
+	TakerFeeBps      float64       // Likewise for taker fills
+	MaxAdds          int           // Orders that may add to an open position (0 = unlimited)
+	AddSpacingBps    float64       // Price improvement an add needs over the last (0 = none)
+	MaxEntryDriftBps float64       // Averaged entry's allowed drift from the opening price (0 = none)
+	ShortingAllowed  *bool         // Whether orders may open or add to a short (nil = !Config.ShortingDisabled)
+}
+
+// SymbolSpec - fixed-point view of SymbolMeta used on the hot path
+type SymbolSpec struct {
+	Hash        uint64
+	Symbol      string
+	TickSize    int64 // Fixed-point
+	LotSize     int64 // Fixed-point
+	MinQuantity int64 // Fixed-point
+	MinNotional int64 // Fixed-point, 0 = global floor
+	Multiplier  int64 // Fixed-point
+	Currency    string
+	MoneyUnit   int64 // Fixed-point PnL increment, from precision
+	FillBandBps int64 // 0 = global band
+
+	InitialMarginBps int64
+	MaintMarginBps   int64
+	TickThrottle     time.Duration // 0 = global window
+	MakerFeeRate     int64         // Fixed-point fraction of notional
+	TakerFeeRate     int64         // Fixed-point fraction of notional
+	MaxAdds          int           // 0 = unlimited
+	AddSpacingBps    int64
+	MaxEntryDriftBps int64
+	ShortingAllowed  *bool // nil = global setting
+}
+
+// Pre-computed hashes for the core symbols; everything else uses FNV-1a
+var knownSymbolHashes = map[string]uint64{
+	"BTCUSD": SymbolHashBTC,
+	"ETHUSD": SymbolHashETH,
+	"SOLUSD": SymbolHashSOL,
+}
+
+// SymbolHash returns the state key for a symbol
+func SymbolHash(symbol string) uint64 {
+	if h, ok := knownSymbolHashes[symbol]; ok {
+		return h
+	}
+	return models.FNV1aHash(symbol)
+}
+
+// defaultSpec applies to symbols without metadata: linear 1:1, unchecked
+var defaultSpec = SymbolSpec{Multiplier: PriceScale, Currency: "USD", MoneyUnit: PriceScale / 100}
+
+// PriceDecimals is the precision of the fixed-point representation
+const PriceDecimals = 8
+
+// Default PnL decimal places per currency; unlisted currencies keep full
+// fixed-point precision
+var currencyPrecision = map[string]int{
+	"USD": 2, "EUR": 2, "GBP": 2, "AUD": 2, "CAD": 2, "CHF": 2,
+	"JPY": 0, "KRW": 0, "IDR": 0,
+	"USDT": 2, "USDC": 2,
+}
+
+// moneyUnit returns the fixed-point increment for a number of decimals
+func moneyUnit(decimals int) int64 {
+	unit := int64(1)
+	for i := decimals; i < PriceDecimals; i++ {
+		unit *= 10
+	}
+	return unit
+}
+
+// SymbolRegistry maps symbol hashes to contract specifications and
+// resolves aliases to canonical symbols. It is built once at startup and
+// never mutated, so reads take no lock.
+type SymbolRegistry struct {
+	byHash      map[uint64]*SymbolSpec
+	aliases     map[string]string // Normalized alias → canonical symbol
+	aliasHashes map[uint64]uint64 // Alias hash → canonical hash (ingestion)
+}
+
+// NewSymbolRegistry builds the registry from configuration. Alias keys and
+// values are normalized; values name the canonical symbol.
+func NewSymbolRegistry(metas []SymbolMeta, aliases map[string]string) *SymbolRegistry {
+	reg := &SymbolRegistry{
+		byHash:      make(map[uint64]*SymbolSpec, len(metas)),
+		aliases:     make(map[string]string, len(aliases)),
+		aliasHashes: make(map[uint64]uint64, 2*len(aliases)),
+	}
+	for alias, canonical := range aliases {
+		canonical = normalizeSymbol(canonical)
+		reg.aliases[normalizeSymbol(alias)] = canonical
+		reg.aliasHashes[SymbolHash(alias)] = SymbolHash(canonical)
+		reg.aliasHashes[SymbolHash(normalizeSymbol(alias))] = SymbolHash(canonical)
+	}
+	for _, m := range metas {
+		symbol := reg.Canonical(m.Symbol)
+		spec := &SymbolSpec{
+			Hash:        SymbolHash(symbol),
+			Symbol:      symbol,
+			TickSize:    toFixed(m.TickSize),
+			LotSize:     toFixed(m.LotSize),
+			MinQuantity: toFixed(m.MinQuantity),
+			MinNotional: toFixed(m.MinNotional),
+			Multiplier:  toFixed(m.Multiplier),
+			Currency:    m.Currency,
+		}
+		if spec.Multiplier <= 0 {
+			spec.Multiplier = PriceScale
+		}
+		if spec.Currency == "" {
+			spec.Currency = defaultSpec.Currency
+		}
+		precision, ok := currencyPrecision[spec.Currency]
+		if !ok {
+			precision = PriceDecimals
+		}
+		if m.Precision > 0 {
+			precision = min(m.Precision, PriceDecimals)
+		}
+		spec.MoneyUnit = moneyUnit(precision)
+		spec.FillBandBps = int64(m.FillPriceBandPct * 100)
+		spec.InitialMarginBps = int64(m.InitialMarginPct * 100)
+		spec.MaintMarginBps = int64(m.MaintMarginPct * 100)
+		spec.TickThrottle = m.TickThrottle
+		spec.MakerFeeRate = toFixed(m.MakerFeeBps / 10000)
+		spec.TakerFeeRate = toFixed(m.TakerFeeBps / 10000)
+		spec.MaxAdds = m.MaxAdds
+		spec.AddSpacingBps = int64(m.AddSpacingBps)
+		spec.MaxEntryDriftBps = int64(m.MaxEntryDriftBps)
+		spec.ShortingAllowed = m.ShortingAllowed
+		reg.byHash[spec.Hash] = spec
+	}
+	return reg
+}
+
+// normalizeSymbol upper-cases and strips notation separators, so BTC-USD,
+// btc/usd and BTC_USD all become BTCUSD
+func normalizeSymbol(symbol string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '-', '/', '_', ':', '.', ' ':
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(symbol)))
+}
+
+// Canonical returns the internal form of a symbol as named by a client
+func (reg *SymbolRegistry) Canonical(symbol string) string {
+	symbol = normalizeSymbol(symbol)
+	if canonical, ok := reg.aliases[symbol]; ok {
+		return canonical
+	}
+	return symbol
+}
+
+// Hash returns the state key for a symbol in any accepted notation
+func (reg *SymbolRegistry) Hash(symbol string) uint64 {
+	return SymbolHash(reg.Canonical(symbol))
+}
+
+// CanonicalHash maps a configured alias's hash to its canonical hash, for
+// feeds that identify symbols by hash only
+func (reg *SymbolRegistry) CanonicalHash(symbolHash uint64) uint64 {
+	if canonical, ok := reg.aliasHashes[symbolHash]; ok {
+		return canonical
+	}
+	return symbolHash
+}
+
+// Get returns the spec for a symbol hash, falling back to the default
+func (reg *SymbolRegistry) Get(symbolHash uint64) *SymbolSpec {
+	if spec, ok := reg.byHash[symbolHash]; ok {
+		return spec
+	}
+	return &defaultSpec
+}
+
+// Name returns the symbol name for a hash, or "" if unregistered
+func (reg *SymbolRegistry) Name(symbolHash uint64) string {
+	if spec, ok := reg.byHash[symbolHash]; ok {
+		return spec.Symbol
+	}
+	return ""
+}
+
+// RoundLot rounds a quantity down to a whole number of lots
+func (spec *SymbolSpec) RoundLot(quantity int64) int64 {
+	if spec.LotSize <= 0 {
+		return quantity
+	}
+	return quantity - quantity%spec.LotSize
+}
+
+// IsDust reports whether a quantity is a sub-lot residue - left by fills
+// that did not land on lot boundaries, and too small to ever trade out of
+func (spec *SymbolSpec) IsDust(quantity int64) bool {
+	return quantity > 0 && quantity < spec.LotSize
+}
+
+// OnTick reports whether a price is a whole number of ticks
+func (spec *SymbolSpec) OnTick(price int64) bool {
+	return spec.TickSize <= 0 || price%spec.TickSize == 0
+}
+
+// SnapToTick rounds a price to the nearest whole number of ticks, half
+// up; a positive price never snaps below one tick
+func (spec *SymbolSpec) SnapToTick(price int64) int64 {
+	if spec.TickSize <= 0 || price <= 0 {
+		return price
+	}
+	snapped := (price + spec.TickSize/2) / spec.TickSize * spec.TickSize
+	return max(snapped, spec.TickSize)
+}
+
+// ApplyMultiplier scales a per-unit amount by the contract multiplier
+func (spec *SymbolSpec) ApplyMultiplier(amount int64) int64 {
+	if spec.Multiplier == PriceScale {
+		return amount
+	}
+	return mulDiv(amount, spec.Multiplier, PriceScale)
+}
+
+// RoundMoney rounds a PnL amount to the currency precision, half away
+// from zero, so repeated updates never accumulate sub-unit noise
+func (spec *SymbolSpec) RoundMoney(amount int64) int64 {
+	if spec.MoneyUnit <= 1 {
+		return amount
+	}
+	rem := amount % spec.MoneyUnit
+	switch {
+	case 2*rem >= spec.MoneyUnit:
+		return amount - rem + spec.MoneyUnit
+	case 2*rem <= -spec.MoneyUnit:
+		return amount - rem - spec.MoneyUnit
+	}
+	return amount - rem
+}
+
+// shortingAllowed reports whether orders on spec may open or add to a
+// short: the symbol's own setting, else the global one
+func (sm *ShardedStateManager) shortingAllowed(spec *SymbolSpec) bool {
+	if spec.ShortingAllowed != nil {
+		return *spec.ShortingAllowed
+	}
+	return !sm.config.ShortingDisabled
+}
+
+// toFixed converts a configured float to fixed-point
+func toFixed(f float64) int64 {
+	return int64(math.Round(f * float64(PriceScale)))
+}
+
+// maxFixedFloat is the largest magnitude toFixed converts without overflow
+const maxFixedFloat = float64(math.MaxInt64 / PriceScale)
+
+// fixedInRange reports whether an untrusted float converts with toFixed:
+// finite and within ±maxFixedFloat (NaN fails both comparisons)
+func fixedInRange(f float64) bool {
+	return f >= -maxFixedFloat && f <= maxFixedFloat
+}
+
+// handleSymbols serves the registry
+func (sm *ShardedStateManager) handleSymbols(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "GET required", http.StatusMethodNotAllowed)
+		return
+	}
+
+	specs := make([]*SymbolSpec, 0, len(sm.symbols.byHash))
+	for _, spec := range sm.symbols.byHash {
+		specs = append(specs, spec)
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Symbol < specs[j].Symbol })
+
+	buf := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(buf)
+
+	b := append((*buf)[:0], `{"symbols":[`...)
+	for i, spec := range specs {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, `{"symbol":`...)
+		b = strconv.AppendQuote(b, spec.Symbol)
+		b = append(b, `,"symbol_hash":"`...)
+		b = strconv.AppendUint(b, spec.Hash, 16)
+		b = append(b, `","tick_size":`...)
+		b = appendFixed(b, spec.TickSize)
+		b = append(b, `,"lot_size":`...)
+		b = appendFixed(b, spec.LotSize)
+		b = append(b, `,"min_quantity":`...)
+		b = appendFixed(b, spec.MinQuantity)
+		b = append(b, `,"min_notional":`...)
+		b = appendFixed(b, cmp.Or(spec.MinNotional, sm.minNotional))
+		b = append(b, `,"contract_multiplier":`...)
+		b = appendFixed(b, spec.Multiplier)
+		b = append(b, `,"currency":`...)
+		b = strconv.AppendQuote(b, spec.Currency)
+		b = append(b, `,"pnl_increment":`...)
+		b = appendFixed(b, spec.MoneyUnit)
+		b = append(b, '}')
+	}
+	b = append(b, `]}`...)
+
+	handlers.WriteJSON(w, r, http.StatusOK, b)
+}
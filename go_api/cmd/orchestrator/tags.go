@@ -0,0 +1,104 @@
+package main
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"cenayang-market/go-api/internal/handlers"
+)
+
+// ============================================================================
+// TAG ATTRIBUTION - PnL per Strategy Tag
+// ============================================================================
+
+// A position belongs to the tag of the order that opened it. Every fill on
+// it - adds and reductions by orders with other tags included - books its
+// realized PnL and fees to that owner, and the open position's unrealized
+// PnL counts toward it. A flip closes the old position for its owner and
+// opens the new side for the flipping order's tag. Fills without a working
+// order, and positions restored from a snapshot, are untagged ("").
+const TagAttributionRule = "FIRST_OPENER"
+
+// orderTag returns the tag PnL from o's fills is attributed to
+func orderTag(o *OrderOptimized) string {
+	return cmp.Or(o.Tag, o.Strategy)
+}
+
+// tagPnL - one tag's share of the book
+type tagPnL struct {
+	Tag        string
+	Realized   int64 // Net of fees
+	Unrealized int64
+	Positions  int
+}
+
+// PnLByTag aggregates realized and open PnL per attribution tag, sorted
+// by tag
+func (sm *ShardedStateManager) PnLByTag() []tagPnL {
+	byTag := make(map[string]*tagPnL)
+	get := func(tag string) *tagPnL {
+		t, ok := byTag[tag]
+		if !ok {
+			t = &tagPnL{Tag: tag}
+			byTag[tag] = t
+		}
+		return t
+	}
+	for i := range sm.shards {
+		shard := &sm.shards[i]
+		shard.mu.RLock()
+		for tag, pnl := range shard.tagPnL {
+			get(tag).Realized += pnl
+		}
+		for _, pos := range shard.positions {
+			t := get(pos.Tag)
+			t.Unrealized += pos.UnrealizedPnL
+			t.Positions++
+		}
+		shard.mu.RUnlock()
+	}
+
+	tags := make([]tagPnL, 0, len(byTag))
+	for _, t := range byTag {
+		tags = append(tags, *t)
+	}
+	slices.SortFunc(tags, func(a, b tagPnL) int { return cmp.Compare(a.Tag, b.Tag) })
+	return tags
+}
+
+// handlePnLByTag serves PnL per attribution tag
+func (sm *ShardedStateManager) handlePnLByTag(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "GET required", http.StatusMethodNotAllowed)
+		return
+	}
+	tags := sm.PnLByTag()
+
+	buf := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(buf)
+
+	b := append((*buf)[:0], `{"attribution":"`...)
+	b = append(b, TagAttributionRule...)
+	b = append(b, `","tags":[`...)
+	for i, t := range tags {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, `{"tag":`...)
+		b = strconv.AppendQuote(b, t.Tag)
+		b = append(b, `,"realized_pnl":`...)
+		b = appendFixed(b, t.Realized)
+		b = append(b, `,"unrealized_pnl":`...)
+		b = appendFixed(b, t.Unrealized)
+		b = append(b, `,"total_pnl":`...)
+		b = appendFixed(b, t.Realized+t.Unrealized)
+		b = append(b, `,"open_positions":`...)
+		b = strconv.AppendInt(b, int64(t.Positions), 10)
+		b = append(b, '}')
+	}
+	b = append(b, `]}`...)
+
+	handlers.WriteJSON(w, r, http.StatusOK, b)
+}
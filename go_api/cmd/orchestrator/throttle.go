@@ -0,0 +1,77 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// ============================================================================
+// TICK THROTTLE - Per-Symbol Coalescing Ahead of the State Engine
+// ============================================================================
+
+// tickSampler coalesces one symbol's ticks - guarded by FeedIngester.mu.
+// The first tick after a quiet window is forwarded at once; later ones in
+// the window replace each other and the survivor is forwarded when the
+// window closes, so state always ends on the most recent price.
+type tickSampler struct {
+	last      int64                // Unix nanos of the last forward
+	pending   *MarketTickOptimized // Latest held tick, nil = none
+	scheduled bool                 // A flush is due at the window's end
+}
+
+// tickWindow resolves a symbol's throttle window: its own override, else
+// Config.TickThrottle. Zero or negative means every tick goes through.
+func (f *FeedIngester) tickWindow(symbolHash uint64) time.Duration {
+	if w := f.sm.symbols.Get(symbolHash).TickThrottle; w != 0 {
+		return w
+	}
+	return f.sm.config.TickThrottle
+}
+
+// sample decides whether an accepted tick is forwarded now; if not, it is
+// held for the window's flush - caller holds f.mu
+func (f *FeedIngester) sample(tick *MarketTickOptimized, window time.Duration) bool {
+	s, ok := f.samplers[tick.SymbolHash]
+	if !ok {
+		s = &tickSampler{}
+		f.samplers[tick.SymbolHash] = s
+	}
+	now := f.sm.clock.Now().UnixNano()
+	if s.pending == nil && now-s.last >= int64(window) {
+		s.last = now
+		return true
+	}
+
+	if s.pending != nil {
+		atomic.AddUint64(&f.coalescedTicks, 1) // Superseded before reaching state
+	}
+	held := *tick // The caller may reuse tick
+	s.pending = &held
+	if !s.scheduled {
+		s.scheduled = true
+		symbolHash := tick.SymbolHash
+		time.AfterFunc(max(time.Duration(s.last+int64(window)-now), 0), func() { f.flushTick(symbolHash) })
+	}
+	return false
+}
+
+// flushTick forwards the tick held for a symbol when its window closes
+func (f *FeedIngester) flushTick(symbolHash uint64) {
+	f.mu.Lock()
+	s := f.samplers[symbolHash]
+	tick := s.pending
+	s.pending, s.scheduled = nil, false
+	if tick != nil {
+		s.last = f.sm.clock.Now().UnixNano()
+	}
+	f.mu.Unlock()
+
+	if tick != nil {
+		f.sm.UpdateTick(tick)
+	}
+}
+
+// CoalescedTicks returns the number of ticks the throttle superseded
+func (f *FeedIngester) CoalescedTicks() uint64 {
+	return atomic.LoadUint64(&f.coalescedTicks)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// ============================================================================
+// TRACING - OpenTelemetry Spans over OTLP/HTTP (Optional)
+// ============================================================================
+
+// ServiceName identifies this process in traces
+const ServiceName = "go-orchestrator"
+
+// initTracing installs W3C trace-context propagation and, when endpoint is
+// set, an OTLP/HTTP span exporter. Without an endpoint spans are no-ops but
+// inbound trace context still propagates to outbound calls. The returned
+// function flushes pending spans.
+func initTracing(ctx context.Context, endpoint string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("otlp exporter: %w", err)
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", ServiceName))),
+	)
+	otel.SetTracerProvider(tp)
+	log.Printf("[TRACE] Exporting spans to %s", endpoint)
+	return tp.Shutdown, nil
+}
@@ -0,0 +1,597 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"cenayang-market/go-api/internal/database"
+	"cenayang-market/go-api/internal/handlers"
+	"cenayang-market/go-api/internal/ws"
+)
+
+// ============================================================================
+// TRIGGER ORDERS - Stop / Limit-If-Touched, Held Until the Market Touches
+// ============================================================================
+
+// Trigger kinds
+const (
+	TriggerStop           uint8 = iota // Buy fires at or above, sell at or below
+	TriggerLimitIfTouched              // Buy fires at or below, sell at or above
+)
+
+var triggerKindNames = [...]string{
+	TriggerStop:           "STOP",
+	TriggerLimitIfTouched: "LIMIT_IF_TOUCHED",
+}
+
+func triggerKindName(kind uint8) string {
+	if int(kind) < len(triggerKindNames) {
+		return triggerKindNames[kind]
+	}
+	return "UNKNOWN"
+}
+
+// ErrUnknownTrigger - no armed trigger has the ID
+var ErrUnknownTrigger = errors.New("unknown trigger order")
+
+// TriggerStore persists armed triggers across restarts.
+// *database.Database and *database.Postgres implement it.
+type TriggerStore interface {
+	SaveTriggerOrder(t database.TriggerOrder) error
+	DeleteTriggerOrder(id uint64) error
+	LoadTriggerOrders() ([]database.TriggerOrder, error)
+}
+
+// TriggerOrder - an order held by the orchestrator until the last trade
+// price touches TriggerPrice, then submitted like any other order
+type TriggerOrder struct {
+	ID           uint64 // Also the ID of the order it releases
+	SymbolHash   uint64
+	Side         uint8
+	Kind         uint8
+	TriggerPrice int64
+	Quantity     int64
+	Price        int64 // Limit once released, 0 = market
+	Strategy     string
+	ReduceOnly   bool
+	CreatedAt    int64
+}
+
+// Touched reports whether last reaches the trigger price from the side the
+// trigger waits on: a buy stop or sell limit-if-touched fires on an
+// up-cross, a sell stop or buy limit-if-touched on a down-cross
+func (t *TriggerOrder) Touched(last int64) bool {
+	if (t.Kind == TriggerStop) == (t.Side == 0) {
+		return last >= t.TriggerPrice
+	}
+	return last <= t.TriggerPrice
+}
+
+// TriggerBook holds armed triggers. A trigger leaves the book exactly once,
+// under the lock, by firing or by being cancelled - so it never fires twice.
+//
+// Auto-management can be turned off per symbol for manual handling: the
+// symbol's triggers stay armed and listed but are not evaluated until it is
+// turned back on, when the next tick fires any the market already touched.
+type TriggerBook struct {
+	mu       sync.Mutex
+	byID     map[uint64]*TriggerOrder
+	bySymbol map[uint64]map[uint64]*TriggerOrder
+	manual   map[uint64]bool // Symbols with auto-management off
+	armed    int64           // Atomic; lets ticks skip the lock when empty
+	store    TriggerStore    // nil = held in memory only
+}
+
+func NewTriggerBook(store TriggerStore) *TriggerBook {
+	return &TriggerBook{
+		byID:     make(map[uint64]*TriggerOrder, 16),
+		bySymbol: make(map[uint64]map[uint64]*TriggerOrder, 16),
+		manual:   make(map[uint64]bool),
+		store:    store,
+	}
+}
+
+// SetAutoManage turns tick evaluation of a symbol's triggers on or off.
+// Returns whether the setting changed. Held in memory only: a restart
+// re-enables every symbol.
+func (b *TriggerBook) SetAutoManage(symbolHash uint64, enabled bool) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.manual[symbolHash] == !enabled {
+		return false
+	}
+	if enabled {
+		delete(b.manual, symbolHash)
+	} else {
+		b.manual[symbolHash] = true
+	}
+	return true
+}
+
+// AutoManaged reports whether a symbol's triggers fire on ticks
+func (b *TriggerBook) AutoManaged(symbolHash uint64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.manual[symbolHash]
+}
+
+// ManualSymbols returns the symbols with auto-management off, sorted
+func (b *TriggerBook) ManualSymbols() []uint64 {
+	b.mu.Lock()
+	out := make([]uint64, 0, len(b.manual))
+	for hash := range b.manual {
+		out = append(out, hash)
+	}
+	b.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+// Len returns the number of armed triggers
+func (b *TriggerBook) Len() int {
+	return int(atomic.LoadInt64(&b.armed))
+}
+
+// insertLocked arms t - caller holds the lock
+func (b *TriggerBook) insertLocked(t *TriggerOrder) {
+	b.byID[t.ID] = t
+	symbol, ok := b.bySymbol[t.SymbolHash]
+	if !ok {
+		symbol = make(map[uint64]*TriggerOrder, 4)
+		b.bySymbol[t.SymbolHash] = symbol
+	}
+	symbol[t.ID] = t
+	atomic.AddInt64(&b.armed, 1)
+}
+
+// removeLocked disarms t - caller holds the lock
+func (b *TriggerBook) removeLocked(t *TriggerOrder) {
+	delete(b.byID, t.ID)
+	if symbol := b.bySymbol[t.SymbolHash]; symbol != nil {
+		delete(symbol, t.ID)
+		if len(symbol) == 0 {
+			delete(b.bySymbol, t.SymbolHash)
+		}
+	}
+	atomic.AddInt64(&b.armed, -1)
+}
+
+// Cancel disarms a trigger and returns it
+func (b *TriggerBook) Cancel(id uint64) (TriggerOrder, error) {
+	b.mu.Lock()
+	t, ok := b.byID[id]
+	if !ok {
+		b.mu.Unlock()
+		return TriggerOrder{}, ErrUnknownTrigger
+	}
+	b.removeLocked(t)
+	b.mu.Unlock()
+
+	b.forget(t.ID)
+	return *t, nil
+}
+
+// Get returns an armed trigger
+func (b *TriggerBook) Get(id uint64) (TriggerOrder, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t, ok := b.byID[id]
+	if !ok {
+		return TriggerOrder{}, false
+	}
+	return *t, true
+}
+
+// resize sets an armed trigger's quantity and returns it. A trigger that
+// cannot be persisted at its new size keeps the old one.
+func (sm *ShardedStateManager) resizeTrigger(id uint64, quantity int64) (TriggerOrder, error) {
+	b := sm.triggers
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t, ok := b.byID[id]
+	if !ok {
+		return TriggerOrder{}, ErrUnknownTrigger
+	}
+	resized := *t
+	resized.Quantity = quantity
+	if b.store != nil {
+		if err := b.store.SaveTriggerOrder(sm.triggerRecord(&resized)); err != nil {
+			return *t, err
+		}
+	}
+	t.Quantity = quantity
+	return resized, nil
+}
+
+// take disarms and returns the symbol's triggers that last touches, oldest
+// first. Nothing is taken while the symbol is under manual management.
+func (b *TriggerBook) take(symbolHash uint64, last int64) []TriggerOrder {
+	if atomic.LoadInt64(&b.armed) == 0 {
+		return nil
+	}
+
+	var fired []TriggerOrder
+	b.mu.Lock()
+	if b.manual[symbolHash] {
+		b.mu.Unlock()
+		return nil
+	}
+	for _, t := range b.bySymbol[symbolHash] {
+		if t.Touched(last) {
+			fired = append(fired, *t)
+			b.removeLocked(t)
+		}
+	}
+	b.mu.Unlock()
+
+	sort.Slice(fired, func(i, j int) bool { return fired[i].ID < fired[j].ID })
+	for i := range fired {
+		b.forget(fired[i].ID)
+	}
+	return fired
+}
+
+// forget removes a disarmed trigger from the store. On failure the trigger
+// comes back armed after a restart; its order then carries the same ID and
+// is refused as a duplicate downstream rather than executed twice.
+func (b *TriggerBook) forget(id uint64) {
+	if b.store == nil {
+		return
+	}
+	if err := b.store.DeleteTriggerOrder(id); err != nil {
+		log.Printf("[TRIGGER] Failed to delete trigger %d from store: %v", id, err)
+	}
+}
+
+// List returns the armed triggers, oldest first
+func (b *TriggerBook) List() []TriggerOrder {
+	b.mu.Lock()
+	out := make([]TriggerOrder, 0, len(b.byID))
+	for _, t := range b.byID {
+		out = append(out, *t)
+	}
+	b.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// ArmTrigger assigns the trigger an ID if it has none, persists it and adds
+// it to the book. A trigger that cannot be persisted is not armed.
+func (sm *ShardedStateManager) ArmTrigger(t *TriggerOrder) error {
+	if t.ID == 0 {
+		t.ID = sm.orderIDs.Next()
+	} else {
+		sm.orderIDs.Observe(t.ID)
+	}
+	t.CreatedAt = sm.clock.Now().UnixNano()
+
+	b := sm.triggers
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.byID[t.ID]; ok {
+		return ErrDuplicateOrderID
+	}
+	if _, ok := sm.orderIndex.Load(t.ID); ok {
+		return ErrDuplicateOrderID
+	}
+	if b.store != nil {
+		if err := b.store.SaveTriggerOrder(sm.triggerRecord(t)); err != nil {
+			return err
+		}
+	}
+
+	stored := *t
+	b.insertLocked(&stored)
+	log.Printf("[TRIGGER] Armed %s %s %s @ %s",
+		triggerKindName(t.Kind), sm.symbols.Name(t.SymbolHash), appendFixed(nil, t.Quantity), appendFixed(nil, t.TriggerPrice))
+	return nil
+}
+
+// restoreTriggers re-arms the triggers persisted by a previous run
+func (sm *ShardedStateManager) restoreTriggers() error {
+	b := sm.triggers
+	if b.store == nil {
+		return nil
+	}
+	records, err := b.store.LoadTriggerOrders()
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	for _, rec := range records {
+		t := &TriggerOrder{
+			ID:           rec.ID,
+			SymbolHash:   rec.SymbolHash,
+			Side:         rec.Side,
+			Kind:         rec.Kind,
+			TriggerPrice: rec.TriggerPrice,
+			Quantity:     rec.Quantity,
+			Price:        rec.Price,
+			Strategy:     rec.Strategy,
+			ReduceOnly:   rec.ReduceOnly,
+			CreatedAt:    rec.CreatedAt,
+		}
+		sm.orderIDs.Observe(t.ID)
+		b.insertLocked(t)
+	}
+	b.mu.Unlock()
+
+	if len(records) > 0 {
+		log.Printf("[TRIGGER] Restored %d armed triggers", len(records))
+	}
+	return nil
+}
+
+func (sm *ShardedStateManager) triggerRecord(t *TriggerOrder) database.TriggerOrder {
+	return database.TriggerOrder{
+		ID:           t.ID,
+		SymbolHash:   t.SymbolHash,
+		Symbol:       sm.symbols.Name(t.SymbolHash),
+		Side:         t.Side,
+		Kind:         t.Kind,
+		TriggerPrice: t.TriggerPrice,
+		Quantity:     t.Quantity,
+		Price:        t.Price,
+		Strategy:     t.Strategy,
+		ReduceOnly:   t.ReduceOnly,
+		CreatedAt:    t.CreatedAt,
+	}
+}
+
+// evaluateTriggers fires the symbol's triggers touched by a last trade.
+// A standby holds its triggers until promoted.
+func (sm *ShardedStateManager) evaluateTriggers(symbolHash uint64, last int64) {
+	if sm.Standby() {
+		return
+	}
+	fired := sm.triggers.take(symbolHash, last)
+	for i := range fired {
+		sm.fireTrigger(&fired[i], last)
+	}
+}
+
+// fireTrigger releases a touched trigger as a live order through the usual
+// risk check and broadcasts the outcome
+func (sm *ShardedStateManager) fireTrigger(t *TriggerOrder, last int64) {
+	order := &OrderOptimized{
+		ID:         t.ID,
+		SymbolHash: t.SymbolHash,
+		Side:       t.Side,
+		Quantity:   t.Quantity,
+		Price:      t.Price,
+		Strategy:   t.Strategy,
+		ReduceOnly: t.ReduceOnly,
+	}
+	result, err := sm.SubmitOrder(order)
+
+	reason := result.Reason.String()
+	if err != nil {
+		sm.transitionOrder(order, OrderRejected)
+		reason = err.Error()
+	}
+	log.Printf("[TRIGGER] Fired %d %s @ %s: %s",
+		t.ID, sm.symbols.Name(t.SymbolHash), appendFixed(nil, last), orderStatusName(order.Status))
+
+	seq := order.SequenceID
+	if seq == 0 {
+		seq = atomic.LoadUint64(&sm.state.SequenceID)
+	}
+	b := make([]byte, 0, 384)
+	b = append(b, `{"type":"trigger_fired","trigger":`...)
+	b = appendTrigger(b, t, sm.symbols.Name(t.SymbolHash))
+	b = append(b, `,"last_price":`...)
+	b = appendFixed(b, last)
+	b = append(b, `,"order_id":"`...)
+	b = strconv.AppendUint(b, order.ID, 10)
+	b = append(b, `","status":"`...)
+	b = append(b, orderStatusName(order.Status)...)
+	b = append(b, `","reason":`...)
+	b = strconv.AppendQuote(b, reason)
+	b = append(b, `,"seq_id":`...)
+	b = strconv.AppendUint(b, seq, 10)
+	b = append(b, '}')
+	sm.publish(ws.EventTriggerFired, seq, b)
+}
+
+func appendTrigger(b []byte, t *TriggerOrder, symbol string) []byte {
+	b = append(b, `{"id":"`...)
+	b = strconv.AppendUint(b, t.ID, 10)
+	b = append(b, `","symbol":`...)
+	b = strconv.AppendQuote(b, symbol)
+	b = append(b, `,"side":"`...)
+	if t.Side == 0 {
+		b = append(b, `BUY`...)
+	} else {
+		b = append(b, `SELL`...)
+	}
+	b = append(b, `","kind":"`...)
+	b = append(b, triggerKindName(t.Kind)...)
+	b = append(b, `","trigger_price":`...)
+	b = appendFixed(b, t.TriggerPrice)
+	b = append(b, `,"quantity":`...)
+	b = appendFixed(b, t.Quantity)
+	b = append(b, `,"price":`...)
+	b = appendFixed(b, t.Price)
+	b = append(b, `,"strategy":`...)
+	b = strconv.AppendQuote(b, t.Strategy)
+	b = append(b, `,"reduce_only":`...)
+	b = strconv.AppendBool(b, t.ReduceOnly)
+	b = append(b, `,"created_at":`...)
+	b = strconv.AppendInt(b, t.CreatedAt, 10)
+	return append(b, '}')
+}
+
+// triggerRequest - wire format for POST /api/orders/triggers
+type triggerRequest struct {
+	orderRequest
+	Kind         string  `json:"kind"` // STOP or LIMIT_IF_TOUCHED
+	TriggerPrice float64 `json:"trigger_price"`
+}
+
+// handleTriggers lists (GET), arms (POST) or cancels (DELETE ?id=) trigger
+// orders
+func (sm *ShardedStateManager) handleTriggers(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		triggers := sm.triggers.List()
+
+		buf := bufferPool.Get().(*[]byte)
+		defer bufferPool.Put(buf)
+
+		b := append((*buf)[:0], `{"triggers":[`...)
+		for i := range triggers {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = appendTrigger(b, &triggers[i], sm.symbols.Name(triggers[i].SymbolHash))
+		}
+		b = append(b, `]}`...)
+
+		handlers.WriteJSON(w, r, http.StatusOK, b)
+
+	case http.MethodPost:
+		var req triggerRequest
+		if errResp := handlers.DecodeJSON(w, r, &req); errResp != nil {
+			handlers.WriteError(w, r, errResp)
+			return
+		}
+		order, errResp := sm.orderFromRequest(&req.orderRequest)
+		if errResp != nil {
+			handlers.WriteError(w, r, errResp)
+			return
+		}
+		t := &TriggerOrder{
+			ID:           order.ID,
+			SymbolHash:   order.SymbolHash,
+			Side:         order.Side,
+			TriggerPrice: toFixed(req.TriggerPrice),
+			Quantity:     order.Quantity,
+			Price:        order.Price,
+			Strategy:     order.Strategy,
+			ReduceOnly:   order.ReduceOnly,
+		}
+		switch strings.ToUpper(req.Kind) {
+		case "STOP":
+			t.Kind = TriggerStop
+		case "LIMIT_IF_TOUCHED", "LIT":
+			t.Kind = TriggerLimitIfTouched
+		default:
+			handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "kind must be STOP or LIMIT_IF_TOUCHED", Field: "kind"})
+			return
+		}
+		if !fixedInRange(req.TriggerPrice) || t.TriggerPrice <= 0 {
+			handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "trigger_price must be positive", Field: "trigger_price"})
+			return
+		}
+		if err := sm.ArmTrigger(t); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, ErrDuplicateOrderID) {
+				status = http.StatusConflict
+			}
+			handlers.WriteError(w, r, &handlers.ErrorResponse{Status: status, Error: err.Error()})
+			return
+		}
+
+		buf := bufferPool.Get().(*[]byte)
+		defer bufferPool.Put(buf)
+
+		b := appendTrigger((*buf)[:0], t, sm.symbols.Name(t.SymbolHash))
+		handlers.WriteJSON(w, r, http.StatusCreated, b)
+
+	case http.MethodDelete:
+		id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+		if err != nil {
+			handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "id required", Field: "id"})
+			return
+		}
+		t, err := sm.triggers.Cancel(id)
+		if err != nil {
+			handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusNotFound, Error: err.Error(), Field: "id"})
+			return
+		}
+
+		buf := bufferPool.Get().(*[]byte)
+		defer bufferPool.Put(buf)
+
+		b := appendTrigger((*buf)[:0], &t, sm.symbols.Name(t.SymbolHash))
+		handlers.WriteJSON(w, r, http.StatusOK, b)
+
+	default:
+		http.Error(w, "GET, POST or DELETE required", http.StatusMethodNotAllowed)
+	}
+}
+
+// autoManageRequest - wire format for POST /api/positions/automanage
+type autoManageRequest struct {
+	Symbol  string `json:"symbol"`
+	Enabled *bool  `json:"enabled"`
+}
+
+// handleAutoManage lists symbols under manual management (GET) or turns
+// auto-management of a symbol's triggers on or off (POST)
+func (sm *ShardedStateManager) handleAutoManage(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		manual := sm.triggers.ManualSymbols()
+
+		buf := bufferPool.Get().(*[]byte)
+		defer bufferPool.Put(buf)
+
+		b := append((*buf)[:0], `{"manual":[`...)
+		for i, hash := range manual {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = strconv.AppendQuote(b, sm.symbols.Name(hash))
+		}
+		b = append(b, `]}`...)
+
+		handlers.WriteJSON(w, r, http.StatusOK, b)
+
+	case http.MethodPost:
+		var req autoManageRequest
+		if errResp := handlers.DecodeJSON(w, r, &req); errResp != nil {
+			handlers.WriteError(w, r, errResp)
+			return
+		}
+		switch {
+		case req.Symbol == "":
+			handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "symbol required", Field: "symbol"})
+			return
+		case req.Enabled == nil:
+			handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "enabled required", Field: "enabled"})
+			return
+		}
+
+		hash := sm.symbols.Hash(req.Symbol)
+		if sm.triggers.SetAutoManage(hash, *req.Enabled) {
+			log.Printf("[TRIGGER] Auto-management of %s set to %t", sm.symbols.Name(hash), *req.Enabled)
+		}
+
+		buf := bufferPool.Get().(*[]byte)
+		defer bufferPool.Put(buf)
+
+		b := append((*buf)[:0], `{"symbol":`...)
+		b = strconv.AppendQuote(b, sm.symbols.Name(hash))
+		b = append(b, `,"auto_manage":`...)
+		b = strconv.AppendBool(b, *req.Enabled)
+		b = append(b, `}`...)
+
+		handlers.WriteJSON(w, r, http.StatusOK, b)
+
+	default:
+		http.Error(w, "GET or POST required", http.StatusMethodNotAllowed)
+	}
+}
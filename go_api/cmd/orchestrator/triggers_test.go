@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// A trigger fires on a last price at its trigger price and stays armed one
+// tick short of it
+func TestTriggerTouch(t *testing.T) {
+	tests := []struct {
+		name string
+		kind uint8
+		side uint8
+		last float64 // Trigger at 100
+	}{
+		{"buy stop touched", TriggerStop, 0, 100},
+		{"buy stop near miss", TriggerStop, 0, 99.99},
+		{"sell stop touched", TriggerStop, 1, 100},
+		{"sell stop near miss", TriggerStop, 1, 100.01},
+		{"buy limit-if-touched touched", TriggerLimitIfTouched, 0, 100},
+		{"buy limit-if-touched near miss", TriggerLimitIfTouched, 0, 100.01},
+		{"sell limit-if-touched touched", TriggerLimitIfTouched, 1, 100},
+		{"sell limit-if-touched near miss", TriggerLimitIfTouched, 1, 99.99},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := NewShardedStateManager(testConfig())
+			h := sm.symbols.Hash("BTCUSD")
+			trigger := &TriggerOrder{SymbolHash: h, Side: tt.side, Kind: tt.kind, TriggerPrice: fx(100), Quantity: fx(1)}
+			start := 95.0 // Armed on the side it waits to cross from
+			if (tt.kind == TriggerStop) != (tt.side == 0) {
+				start = 105
+			}
+			tick(sm, h, start)
+			if err := sm.ArmTrigger(trigger); err != nil {
+				t.Fatal(err)
+			}
+
+			tick(sm, h, tt.last)
+			touched := tt.last == 100
+			if _, working := sm.GetShard(h).orders[trigger.ID]; working != touched {
+				t.Fatalf("order working %v at %v", working, tt.last)
+			}
+			if armed := sm.triggers.Len() == 1; armed == touched {
+				t.Fatalf("trigger armed %v at %v", armed, tt.last)
+			}
+		})
+	}
+}
+
+// With auto-management off a touched trigger stays armed; turning it back
+// on fires it on the next tick
+func TestAutoManageToggle(t *testing.T) {
+	sm := NewShardedStateManager(testConfig())
+	mux := setupHTTPRoutes(sm)
+	h := sm.symbols.Hash("BTCUSD")
+	serve := func(method, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, "/api/positions/automanage", strings.NewReader(body)))
+		return rec
+	}
+	toggle := func(enabled bool) {
+		t.Helper()
+		if rec := serve(http.MethodPost, `{"symbol":"BTCUSD","enabled":`+strconv.FormatBool(enabled)+`}`); rec.Code != http.StatusOK {
+			t.Fatalf("toggle %d: %s", rec.Code, rec.Body)
+		}
+	}
+	manual := func() int {
+		t.Helper()
+		var body struct {
+			Manual []string `json:"manual"`
+		}
+		if err := json.Unmarshal(serve(http.MethodGet, "").Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		return len(body.Manual)
+	}
+
+	tick(sm, h, 95)
+	trigger := &TriggerOrder{SymbolHash: h, Side: 0, Kind: TriggerStop, TriggerPrice: fx(100), Quantity: fx(1)}
+	if err := sm.ArmTrigger(trigger); err != nil {
+		t.Fatal(err)
+	}
+	toggle(false)
+	if n := manual(); n != 1 {
+		t.Fatalf("%d symbols listed manual, want 1", n)
+	}
+	tick(sm, h, 101)
+	if sm.triggers.Len() != 1 {
+		t.Fatal("fired under manual management")
+	}
+
+	toggle(true)
+	if n := manual(); n != 0 {
+		t.Fatalf("%d symbols listed manual after re-enabling", n)
+	}
+	tick(sm, h, 101)
+	if _, working := sm.GetShard(h).orders[trigger.ID]; !working || sm.triggers.Len() != 0 {
+		t.Fatalf("order working %v with %d armed after re-enabling", working, sm.triggers.Len())
+	}
+}
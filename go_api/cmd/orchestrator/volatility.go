@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"sync/atomic"
+
+	"cenayang-market/go-api/internal/ws"
+)
+
+// ============================================================================
+// VOLATILITY HALT - Per-Symbol Breaker on Short-Term Return Spikes
+// ============================================================================
+
+// VolatilityHaltConfig - when a symbol's short-term volatility halts new
+// risk on it. Volatility is the root mean square of tick-to-tick returns
+// over the last Window ticks, in basis points. The halt clears on its own
+// once volatility falls back to ClearBps; the gap keeps it from flapping.
+type VolatilityHaltConfig struct {
+	Window   int     // Ticks (0 = off)
+	HaltBps  float64 // Halt at or above
+	ClearBps float64 // Clear at or below (0 = HaltBps / 2)
+}
+
+func (c VolatilityHaltConfig) clearBps() float64 {
+	if c.ClearBps > 0 {
+		return c.ClearBps
+	}
+	return c.HaltBps / 2
+}
+
+// volWindow - one symbol's recent returns, guarded by FeedIngester.mu.
+// Returns are kept squared in hundredths of a basis point so the running
+// sum stays exact.
+type volWindow struct {
+	prev   int64   // Last price
+	sq     []int64 // Ring of squared returns
+	next   int
+	filled int
+	sumSq  int64
+	halted bool
+}
+
+// observeVolatility adds a tick's return to its symbol's window and
+// reports a halt state change - caller holds f.mu. Nothing is halted until
+// the window is full, and a standby follows the active's halts instead.
+func (f *FeedIngester) observeVolatility(tick *MarketTickOptimized) (halt bool, volBps float64, changed bool) {
+	cfg := &f.sm.config.VolatilityHalt
+	if cfg.Window <= 0 || cfg.HaltBps <= 0 || f.sm.Standby() {
+		return false, 0, false
+	}
+	w, ok := f.volatility[tick.SymbolHash]
+	if !ok {
+		w = &volWindow{sq: make([]int64, cfg.Window)}
+		f.volatility[tick.SymbolHash] = w
+	}
+	prev := w.prev
+	w.prev = tick.LastPrice
+	if prev <= 0 {
+		return false, 0, false
+	}
+
+	r := mulDiv(tick.LastPrice-prev, 1_000_000, prev) // Hundredths of a bp
+	w.sumSq += r*r - w.sq[w.next]
+	w.sq[w.next] = r * r
+	w.next = (w.next + 1) % len(w.sq)
+	if w.filled < len(w.sq) {
+		w.filled++
+		if w.filled < len(w.sq) {
+			return false, 0, false
+		}
+	}
+
+	volBps = math.Sqrt(float64(w.sumSq)/float64(len(w.sq))) / 100
+	switch {
+	case !w.halted && volBps >= cfg.HaltBps:
+		w.halted = true
+	case w.halted && volBps <= cfg.clearBps():
+		w.halted = false
+	default:
+		return w.halted, volBps, false
+	}
+	return w.halted, volBps, true
+}
+
+// SymbolHalted reports whether new risk on a symbol is halted
+func (sm *ShardedStateManager) SymbolHalted(symbolHash uint64) bool {
+	_, halted := sm.haltedSymbols.Load(symbolHash)
+	return halted
+}
+
+// setVolatilityHalt halts or resumes a symbol and broadcasts the change.
+// Only reduce-only orders pass a halted symbol's risk check.
+func (sm *ShardedStateManager) setVolatilityHalt(symbolHash uint64, halted bool, volBps float64) {
+	if halted {
+		if _, was := sm.haltedSymbols.LoadOrStore(symbolHash, struct{}{}); was {
+			return
+		}
+	} else if _, was := sm.haltedSymbols.LoadAndDelete(symbolHash); !was {
+		return
+	}
+
+	symbol := sm.symbols.Name(symbolHash)
+	if halted {
+		log.Printf("[RISK] %s halted: volatility %.1f bps >= %.1f bps", symbol, volBps, sm.config.VolatilityHalt.HaltBps)
+		sm.raiseAlert(AlertWarning, "volatility_halt", fmt.Sprintf("%s halted: volatility %.1f bps >= %.1f bps", symbol, volBps, sm.config.VolatilityHalt.HaltBps))
+	} else {
+		log.Printf("[RISK] %s resumed: volatility %.1f bps", symbol, volBps)
+	}
+
+	b := make([]byte, 0, 160)
+	b = append(b, `{"type":"volatility_halt","symbol":`...)
+	b = strconv.AppendQuote(b, symbol)
+	b = append(b, `,"symbol_hash":"`...)
+	b = strconv.AppendUint(b, symbolHash, 16)
+	b = append(b, `","halted":`...)
+	b = strconv.AppendBool(b, halted)
+	b = append(b, `,"volatility_bps":`...)
+	b = sm.appendFloat(b, volBps, 2)
+	b = append(b, '}')
+	sm.publish(ws.EventVolatilityHalt, atomic.LoadUint64(&sm.state.SequenceID), b)
+}
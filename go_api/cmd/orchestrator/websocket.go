@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cenayang-market/go-api/internal/auth"
+	"cenayang-market/go-api/internal/handlers"
+	"cenayang-market/go-api/internal/ws"
+)
+
+// ============================================================================
+// WEBSOCKET CLIENTS - Per-Client Slow-Consumer Metrics and Replay
+// ============================================================================
+
+// handleWSClients serves per-client drop counts and queue depths,
+// worst offenders first
+func (sm *ShardedStateManager) handleWSClients(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "GET required", http.StatusMethodNotAllowed)
+		return
+	}
+
+	buf := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(buf)
+
+	b := append((*buf)[:0], `{"clients":[`...)
+	for i, c := range sm.hub.ClientStats() {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, `{"id":`...)
+		b = strconv.AppendQuote(b, c.ID)
+		b = append(b, `,"protocol":`...)
+		b = strconv.AppendQuote(b, c.Protocol)
+		b = append(b, `,"connected_at_ns":`...)
+		b = strconv.AppendInt(b, c.ConnectedAt, 10)
+		b = append(b, `,"last_send_ns":`...)
+		b = strconv.AppendInt(b, c.LastSend, 10)
+		b = append(b, `,"queue_depth":`...)
+		b = strconv.AppendInt(b, int64(c.QueueDepth), 10)
+		b = append(b, `,"max_queue_depth":`...)
+		b = strconv.AppendUint(b, c.MaxQueueDepth, 10)
+		b = append(b, `,"drops":`...)
+		b = strconv.AppendUint(b, c.Drops, 10)
+		b = append(b, `,"write_errors":`...)
+		b = strconv.AppendUint(b, c.WriteErrors, 10)
+		b = append(b, '}')
+	}
+	b = append(b, `]}`...)
+
+	handlers.WriteJSON(w, r, http.StatusOK, b)
+}
+
+// MaxReplayEvents caps the events one replay streams
+const MaxReplayEvents = 100_000
+
+// handleWSReplay upgrades to a WebSocket and replays the events journaled
+// between from and to (RFC 3339; to defaults to now) at speed times their
+// original pace - 1 by default, 0 for back to back. Replay frames are
+// marked as such and the connection never joins the live broadcast; see
+// ws.ServeReplay.
+func (sm *ShardedStateManager) handleWSReplay(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "GET required", http.StatusMethodNotAllowed)
+		return
+	}
+	journal := sm.config.Persistence.Backend
+	if journal == nil {
+		handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusNotFound, Error: "no event journal: persistence is not configured"})
+		return
+	}
+
+	q := r.URL.Query()
+	from, err := time.Parse(time.RFC3339Nano, q.Get("from"))
+	if err != nil {
+		handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "from must be an RFC 3339 time", Field: "from"})
+		return
+	}
+	to := time.Now() // Events are stamped with wall time
+	if v := q.Get("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339Nano, v); err != nil {
+			handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "to must be an RFC 3339 time", Field: "to"})
+			return
+		}
+	}
+	if !to.After(from) {
+		handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "to must be after from", Field: "to"})
+		return
+	}
+	speed := 1.0
+	if v := q.Get("speed"); v != "" {
+		speed, err = strconv.ParseFloat(v, 64)
+		if err != nil || speed < 0 || math.IsInf(speed, 0) || math.IsNaN(speed) {
+			handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusBadRequest, Error: "speed must be a non-negative number", Field: "speed"})
+			return
+		}
+	}
+
+	records, err := journal.LoadEvents(from.UnixNano(), to.UnixNano(), MaxReplayEvents)
+	if err != nil {
+		log.Printf("[WS] Replay load failed: %v", err)
+		handlers.WriteError(w, r, &handlers.ErrorResponse{Status: http.StatusInternalServerError, Error: "failed to load events"})
+		return
+	}
+	events := make([]ws.BinaryEvent, len(records))
+	for i, rec := range records {
+		events[i] = ws.BinaryEvent{Type: rec.Type, SeqID: rec.SeqID, Timestamp: rec.Timestamp, Data: rec.Data}
+	}
+	sm.hub.ServeReplay(w, r, events, speed)
+}
+
+// ============================================================================
+// WEBSOCKET COMMANDS - Kill Switch and Pause from Authorized Clients
+// ============================================================================
+
+// authorizeWSControl grants the command channel to tokens with admin or
+// control permission. Without auth configured every client may send
+// commands, as with adminOnly endpoints.
+func authorizeWSControl(r *http.Request) bool {
+	am := auth.GetAuthManager()
+	if am == nil {
+		return true
+	}
+	perms, ok := am.RequestPermissions(r)
+	return ok && perms&(auth.PermAdmin|auth.PermControl) != 0
+}
+
+// handleWSCommand routes an authorized client command into the state
+// manager. kill_switch without "active" activates it.
+func (sm *ShardedStateManager) handleWSCommand(client *ws.Client, cmd ws.Command) error {
+	switch cmd.Cmd {
+	case "kill_switch":
+		req := KillSwitchRequest{Active: cmd.Active == nil || *cmd.Active, Source: KillSourceManual, Actor: "ws:" + client.ID}
+		if _, err := sm.ApplyKillSwitch(req); err != nil {
+			return err
+		}
+	case "pause":
+		sm.SetTradingPaused(true)
+	case "resume":
+		sm.SetTradingPaused(false)
+	default:
+		return fmt.Errorf("unknown command %q", cmd.Cmd)
+	}
+	log.Printf("[WS] Client %s: %s", client.ID, cmd.Cmd)
+	return nil
+}
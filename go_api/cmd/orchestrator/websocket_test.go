@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"cenayang-market/go-api/internal/ws"
+)
+
+// Published events are journaled, heartbeats aside, and a replay streams
+// them back between its start and end frames, every frame marked
+func TestEventJournalReplay(t *testing.T) {
+	backend := &memBackend{}
+	cfg := testConfig()
+	cfg.Persistence = PersistenceConfig{Backend: backend}
+	sm := NewShardedStateManager(cfg)
+	go sm.persist.Run()
+
+	from := time.Now()
+	sm.publish(ws.EventFill, 1, []byte(`{"n":1}`))
+	sm.publishHeartbeat()
+	sm.publish(ws.EventOrder, 2, []byte(`{"n":2}`))
+	if err := sm.persist.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(backend.events) != 2 {
+		t.Fatalf("%d events journaled, want 2", len(backend.events))
+	}
+
+	srv := httptest.NewServer(setupHTTPRoutes(sm))
+	defer srv.Close()
+	query := url.Values{"from": {from.Format(time.RFC3339Nano)}, "speed": {"0"}}
+	if resp, err := http.Get(srv.URL + "/api/ws/replay?from=yesterday"); err != nil || resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("bad from: %v %v", resp, err)
+	}
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/api/ws/replay?"+query.Encode(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	want := []string{
+		`{"type":"replay_start","replay":true,"events":2,"speed":0}`,
+		`{"replay":true,"seq_id":1,"timestamp_ns":` + strconv.FormatInt(backend.events[0].Timestamp, 10) + `,"event":{"n":1}}`,
+		`{"replay":true,"seq_id":2,"timestamp_ns":` + strconv.FormatInt(backend.events[1].Timestamp, 10) + `,"event":{"n":2}}`,
+		`{"type":"replay_end","replay":true,"events":2}`,
+	}
+	for i, w := range want {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("frame %d: %v", i, err)
+		}
+		if string(msg) != w {
+			t.Fatalf("frame %d %s, want %s", i, msg, w)
+		}
+		var frame map[string]any
+		if err := json.Unmarshal(msg, &frame); err != nil || frame["replay"] != true {
+			t.Fatalf("frame %d not valid and marked: %v", i, err)
+		}
+	}
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Fatalf("after the end frame: %v", err)
+	}
+}
@@ -0,0 +1,260 @@
+// Package allocator — Per-Strategy Capital Allocation with Drawdown Protection
+//
+// Amounts are opaque int64 values in the caller's fixed-point units.
+package allocator
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// Errors
+var (
+	ErrUnknownStrategy    = errors.New("unknown strategy")
+	ErrStrategyHalted     = errors.New("strategy halted on loss limit")
+	ErrAllocationExceeded = errors.New("strategy allocation exceeded")
+)
+
+// Strategy configures one allocation slot
+type Strategy struct {
+	Name           string
+	Weight         float64 // Target share of equity, normalized across strategies
+	MaxDrawdownPct float64 // Halts the strategy once its drawdown reaches this (0 = off)
+	DailyLossLimit int64   // Halts it once PnL since the last rebalance reaches -this (0 = off)
+}
+
+// Halt causes
+const (
+	HaltedOnDrawdown  = "drawdown"
+	HaltedOnDailyLoss = "daily_loss"
+)
+
+// Allocation is a point-in-time view of one strategy's capital
+type Allocation struct {
+	Name        string
+	Weight      float64 // Normalized
+	Capital     int64
+	Used        int64
+	Available   int64
+	PnL         int64 // Realized since last rebalance
+	Peak        int64 // High of Capital + PnL since last rebalance
+	DrawdownBps int64
+	Halted      bool
+	HaltedOn    string // HaltedOnDrawdown or HaltedOnDailyLoss while halted
+
+	// Limits
+	MaxDrawdownBps int64 // 0 = off
+	DailyLossLimit int64 // 0 = off
+}
+
+type slot struct {
+	Strategy
+	capital int64
+	used    int64
+	pnl     int64
+	peak    int64  // Peak of capital + pnl since last rebalance
+	halted  string // Halt cause, "" = trading
+}
+
+// Allocator divides equity between strategies and tracks the capital
+// each one has committed. A strategy whose realized drawdown or loss since
+// the last rebalance reaches its limit is halted until the next rebalance,
+// independently of the others.
+type Allocator struct {
+	mu          sync.RWMutex
+	slots       map[string]*slot
+	totalWeight float64
+}
+
+// New creates an allocator; call Rebalance to assign capital
+func New(strategies []Strategy) *Allocator {
+	a := &Allocator{slots: make(map[string]*slot, len(strategies))}
+	for _, s := range strategies {
+		if s.Weight <= 0 {
+			continue
+		}
+		a.slots[s.Name] = &slot{Strategy: s}
+		a.totalWeight += s.Weight
+	}
+	return a
+}
+
+// Enabled reports whether any strategy is configured
+func (a *Allocator) Enabled() bool {
+	return len(a.slots) > 0
+}
+
+// Rebalance assigns each strategy its weighted share of equity, resets
+// drawdown tracking and clears halts. Committed capital carries over.
+func (a *Allocator) Rebalance(equity int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if equity < 0 {
+		equity = 0
+	}
+	for _, s := range a.slots {
+		s.capital = int64(float64(equity) * s.Weight / a.totalWeight)
+		s.pnl = 0
+		s.peak = s.capital
+		s.halted = ""
+	}
+}
+
+// Available returns uncommitted capital for a strategy
+func (a *Allocator) Available(name string) int64 {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	s, ok := a.slots[name]
+	if !ok || s.halted != "" {
+		return 0
+	}
+	return available(s)
+}
+
+func available(s *slot) int64 {
+	if free := s.capital + s.pnl - s.used; free > 0 {
+		return free
+	}
+	return 0
+}
+
+// Check reports whether amount fits a strategy's allocation without
+// committing it
+func (a *Allocator) Check(name string, amount int64) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	_, err := a.fits(name, amount)
+	return err
+}
+
+// Reserve commits capital to a strategy if it fits its allocation
+func (a *Allocator) Reserve(name string, amount int64) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s, err := a.fits(name, amount)
+	if err != nil {
+		return err
+	}
+	s.used += amount
+	return nil
+}
+
+// fits validates amount against a strategy - caller holds a.mu
+func (a *Allocator) fits(name string, amount int64) (*slot, error) {
+	s, ok := a.slots[name]
+	switch {
+	case !ok:
+		return nil, ErrUnknownStrategy
+	case s.halted != "":
+		return nil, ErrStrategyHalted
+	case amount > available(s):
+		return nil, ErrAllocationExceeded
+	}
+	return s, nil
+}
+
+// Release returns committed capital to a strategy
+func (a *Allocator) Release(name string, amount int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if s, ok := a.slots[name]; ok {
+		s.used -= amount
+		if s.used < 0 {
+			s.used = 0
+		}
+	}
+}
+
+// RecordPnL books realized PnL and halts the strategy on a drawdown or
+// daily loss breach, returning the halt cause when this PnL caused it
+func (a *Allocator) RecordPnL(name string, pnl int64) (haltedOn string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s, ok := a.slots[name]
+	if !ok {
+		return ""
+	}
+	s.pnl += pnl
+	if equity := s.capital + s.pnl; equity > s.peak {
+		s.peak = equity
+	}
+	if s.halted != "" {
+		return ""
+	}
+	switch {
+	case s.MaxDrawdownPct > 0 && drawdownBps(s) >= maxDrawdownBps(s):
+		s.halted = HaltedOnDrawdown
+	case s.DailyLossLimit > 0 && s.pnl <= -s.DailyLossLimit:
+		s.halted = HaltedOnDailyLoss
+	}
+	return s.halted
+}
+
+func maxDrawdownBps(s *slot) int64 {
+	if s.MaxDrawdownPct <= 0 {
+		return 0
+	}
+	return int64(s.MaxDrawdownPct * 100)
+}
+
+func drawdownBps(s *slot) int64 {
+	if s.peak <= 0 {
+		return 0
+	}
+	return (s.peak - (s.capital + s.pnl)) * 10000 / s.peak
+}
+
+// Snapshot returns all allocations sorted by name
+func (a *Allocator) Snapshot() []Allocation {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	out := make([]Allocation, 0, len(a.slots))
+	for _, s := range a.slots {
+		avail := available(s)
+		if s.halted != "" {
+			avail = 0
+		}
+		out = append(out, Allocation{
+			Name:        s.Name,
+			Weight:      s.Weight / a.totalWeight,
+			Capital:     s.capital,
+			Used:        s.used,
+			Available:   avail,
+			PnL:         s.pnl,
+			Peak:        s.peak,
+			DrawdownBps: drawdownBps(s),
+			Halted:      s.halted != "",
+			HaltedOn:    s.halted,
+
+			MaxDrawdownBps: maxDrawdownBps(s),
+			DailyLossLimit: max(s.DailyLossLimit, 0),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Restore overwrites the capital, usage, PnL and halt state of configured
+// strategies from a snapshot, e.g. one taken by an active instance.
+// Strategies missing from allocs are left unchanged.
+func (a *Allocator) Restore(allocs []Allocation) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, al := range allocs {
+		if s, ok := a.slots[al.Name]; ok {
+			s.capital, s.used, s.pnl, s.peak, s.halted = al.Capital, al.Used, al.PnL, al.Peak, al.HaltedOn
+			if al.Halted && s.halted == "" {
+				s.halted = HaltedOnDrawdown // Snapshot from before causes were recorded
+			}
+		}
+	}
+}
@@ -5,6 +5,7 @@
 package auth
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/sha256"
 	"crypto/subtle"
@@ -44,7 +45,8 @@ const (
 	PermWrite
 	PermTrade
 	PermAdmin
-	PermSuper = PermRead | PermWrite | PermTrade | PermAdmin
+	PermControl // Kill switch and pause over the WebSocket command channel
+	PermSuper   = PermRead | PermWrite | PermTrade | PermAdmin | PermControl
 )
 
 // Claims represents JWT claims
@@ -359,6 +361,27 @@ func (a *AuthManager) PermissionMiddleware(required Permission) func(http.Handle
 	}
 }
 
+// RequestPermissions returns the permissions granted by a request's bearer
+// token, ?token= query parameter (browsers cannot set WebSocket headers)
+// or API key. ok is false if none is present and valid.
+func (a *AuthManager) RequestPermissions(r *http.Request) (perms Permission, ok bool) {
+	token := r.URL.Query().Get("token")
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		token = strings.TrimPrefix(h, "Bearer ")
+	}
+	if token != "" {
+		if claims, err := a.ValidateToken(token); err == nil {
+			return claims.Permissions, true
+		}
+	}
+	if apiKey := r.Header.Get("X-API-Key"); apiKey != "" {
+		if key, err := a.ValidateAPIKey(apiKey); err == nil {
+			return key.Permissions, true
+		}
+	}
+	return 0, false
+}
+
 // Context helpers
 type ctxKey int
 
@@ -9,6 +9,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -29,6 +31,7 @@ CREATE TABLE IF NOT EXISTS portfolio_state (
     current_drawdown INTEGER NOT NULL DEFAULT 0,
     kill_switch INTEGER NOT NULL DEFAULT 0,
     sequence_id INTEGER NOT NULL DEFAULT 0,
+    started_at INTEGER NOT NULL DEFAULT 0,
     updated_at INTEGER NOT NULL
 );
 
@@ -72,6 +75,9 @@ CREATE TABLE IF NOT EXISTS trades (
     client_order_id INTEGER NOT NULL,
     symbol_hash INTEGER NOT NULL,
     symbol TEXT NOT NULL,
+    tag TEXT NOT NULL DEFAULT '',
+    seq_id INTEGER NOT NULL DEFAULT 0,
+    reduce_only INTEGER NOT NULL DEFAULT 0,
     side INTEGER NOT NULL,
     quantity INTEGER NOT NULL,
     price INTEGER NOT NULL,
@@ -126,14 +132,61 @@ CREATE TABLE IF NOT EXISTS audit_log (
     timestamp INTEGER NOT NULL
 );
 
+-- Trigger Orders (armed stop / limit-if-touched orders)
+CREATE TABLE IF NOT EXISTS trigger_orders (
+    id INTEGER PRIMARY KEY,
+    symbol_hash INTEGER NOT NULL,
+    symbol TEXT NOT NULL,
+    side INTEGER NOT NULL,
+    kind INTEGER NOT NULL,
+    trigger_price INTEGER NOT NULL,
+    quantity INTEGER NOT NULL,
+    price INTEGER NOT NULL,
+    strategy TEXT NOT NULL DEFAULT '',
+    reduce_only INTEGER NOT NULL DEFAULT 0,
+    created_at INTEGER NOT NULL
+);
+
+-- Events (WebSocket events as broadcast, for replay)
+CREATE TABLE IF NOT EXISTS events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    seq_id INTEGER NOT NULL,
+    type INTEGER NOT NULL,
+    data TEXT NOT NULL,
+    timestamp INTEGER NOT NULL
+);
+
+-- Latency Histograms (cumulative bucket counts, carried across restarts)
+CREATE TABLE IF NOT EXISTS latency_histograms (
+    name TEXT PRIMARY KEY,
+    min_value INTEGER NOT NULL,
+    bucket_size INTEGER NOT NULL,
+    buckets TEXT NOT NULL,
+    count INTEGER NOT NULL,
+    sum INTEGER NOT NULL,
+    min_seen INTEGER NOT NULL,
+    max_seen INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+);
+
 -- Indices
 CREATE INDEX IF NOT EXISTS idx_orders_symbol ON orders(symbol_hash);
 CREATE INDEX IF NOT EXISTS idx_orders_status ON orders(status);
 CREATE INDEX IF NOT EXISTS idx_trades_timestamp ON trades(timestamp);
 CREATE INDEX IF NOT EXISTS idx_risk_events_timestamp ON risk_events(timestamp);
 CREATE INDEX IF NOT EXISTS idx_audit_log_timestamp ON audit_log(timestamp);
+CREATE INDEX IF NOT EXISTS idx_events_timestamp ON events(timestamp);
 `
 
+// columnMigrations add columns to tables created by an older schema;
+// "duplicate column" errors mean a database already has them
+var columnMigrations = []string{
+	`ALTER TABLE trades ADD COLUMN tag TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE trades ADD COLUMN seq_id INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE trades ADD COLUMN reduce_only INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE portfolio_state ADD COLUMN started_at INTEGER NOT NULL DEFAULT 0`,
+}
+
 type Database struct {
 	db   *sql.DB
 	path string
@@ -169,6 +222,12 @@ func InitDatabase(dbPath string) (*Database, error) {
 			initErr = fmt.Errorf("failed to initialize schema: %w", err)
 			return
 		}
+		for _, m := range columnMigrations {
+			if _, err := db.Exec(m); err != nil && !strings.Contains(err.Error(), "duplicate column") {
+				initErr = fmt.Errorf("failed to migrate schema: %w", err)
+				return
+			}
+		}
 		
 		if _, err := db.Exec(`
 			INSERT OR IGNORE INTO portfolio_state (id, equity, cash, high_water_mark, updated_at)
@@ -195,8 +254,21 @@ func (d *Database) Close() error {
 	return nil
 }
 
+// PortfolioRecord - the portfolio row of a state snapshot
+type PortfolioRecord struct {
+	Equity          int64
+	Cash            int64
+	TotalPnL        int64
+	DailyPnL        int64
+	HighWaterMark   int64
+	CurrentDrawdown int64
+	KillSwitch      bool
+	SequenceID      uint64 // Last sequence ID reflected in the snapshot
+	StartedAt       int64  // Unix ns the writing process started; its trades are stamped after it
+}
+
 // Portfolio State Operations
-func (d *Database) SavePortfolioState(equity, cash, totalPnL, dailyPnL, hwm, drawdown int64, killSwitch bool, seqID uint64) error {
+func (d *Database) SavePortfolioState(p PortfolioRecord) error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 	
@@ -204,25 +276,27 @@ func (d *Database) SavePortfolioState(equity, cash, totalPnL, dailyPnL, hwm, dra
 		UPDATE portfolio_state SET
 			equity = ?, cash = ?, total_pnl = ?, daily_pnl = ?,
 			high_water_mark = ?, current_drawdown = ?, kill_switch = ?,
-			sequence_id = ?, updated_at = ?
+			sequence_id = ?, started_at = ?, updated_at = ?
 		WHERE id = 1
-	`, equity, cash, totalPnL, dailyPnL, hwm, drawdown, boolToInt(killSwitch), seqID, time.Now().UnixNano())
+	`, p.Equity, p.Cash, p.TotalPnL, p.DailyPnL, p.HighWaterMark, p.CurrentDrawdown, boolToInt(p.KillSwitch), int64(p.SequenceID), p.StartedAt, time.Now().UnixNano())
 	
 	return err
 }
 
-func (d *Database) LoadPortfolioState() (equity, cash, totalPnL, dailyPnL, hwm, drawdown int64, killSwitch bool, seqID uint64, err error) {
+func (d *Database) LoadPortfolioState() (PortfolioRecord, error) {
 	d.mu.RLock()
 	defer d.mu.RUnlock()
 	
+	var p PortfolioRecord
 	var ks int
-	err = d.db.QueryRow(`
-		SELECT equity, cash, total_pnl, daily_pnl, high_water_mark, current_drawdown, kill_switch, sequence_id
+	var seqID int64
+	err := d.db.QueryRow(`
+		SELECT equity, cash, total_pnl, daily_pnl, high_water_mark, current_drawdown, kill_switch, sequence_id, started_at
 		FROM portfolio_state WHERE id = 1
-	`).Scan(&equity, &cash, &totalPnL, &dailyPnL, &hwm, &drawdown, &ks, &seqID)
+	`).Scan(&p.Equity, &p.Cash, &p.TotalPnL, &p.DailyPnL, &p.HighWaterMark, &p.CurrentDrawdown, &ks, &seqID, &p.StartedAt)
 	
-	killSwitch = ks != 0
-	return
+	p.KillSwitch, p.SequenceID = ks != 0, uint64(seqID)
+	return p, err
 }
 
 // Position Operations
@@ -280,6 +354,69 @@ func (d *Database) LoadPositions() (map[uint64]struct {
 	return positions, nil
 }
 
+// PositionRecord - one open position for ReplacePositions
+type PositionRecord struct {
+	SymbolHash    uint64
+	Symbol        string
+	Side          uint8
+	Quantity      int64
+	EntryPrice    int64
+	CurrentPrice  int64
+	UnrealizedPnL int64
+	RealizedPnL   int64
+}
+
+// ReplacePositions atomically replaces every stored position, so positions
+// closed since the last save do not linger
+func (d *Database) ReplacePositions(positions []PositionRecord) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	
+	if _, err := tx.Exec(`DELETE FROM positions`); err != nil {
+		return err
+	}
+	now := time.Now().UnixNano()
+	for _, p := range positions {
+		if _, err := tx.Exec(`
+			INSERT INTO positions (symbol_hash, symbol, side, quantity, entry_price, current_price, unrealized_pnl, realized_pnl, opened_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, int64(p.SymbolHash), p.Symbol, p.Side, p.Quantity, p.EntryPrice, p.CurrentPrice, p.UnrealizedPnL, p.RealizedPnL, now, now); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// LoadPositionRecords returns the positions of the last snapshot
+func (d *Database) LoadPositionRecords() ([]PositionRecord, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	rows, err := d.db.Query(`SELECT symbol_hash, symbol, side, quantity, entry_price, current_price, unrealized_pnl, realized_pnl FROM positions ORDER BY symbol`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var positions []PositionRecord
+	for rows.Next() {
+		var r PositionRecord
+		var hash int64
+		if err := rows.Scan(&hash, &r.Symbol, &r.Side, &r.Quantity, &r.EntryPrice, &r.CurrentPrice, &r.UnrealizedPnL, &r.RealizedPnL); err != nil {
+			return nil, err
+		}
+		r.SymbolHash = uint64(hash)
+		positions = append(positions, r)
+	}
+	return positions, rows.Err()
+}
+
 // Order Operations
 func (d *Database) SaveOrder(clientOrderID, exchangeOrderID, symbolHash uint64, symbol string, side, orderType, status, tif uint8, quantity, price, filledQty, avgPrice, commission int64, idempotencyKey string) error {
 	d.mu.Lock()
@@ -299,22 +436,107 @@ func (d *Database) SaveOrder(clientOrderID, exchangeOrderID, symbolHash uint64,
 	return err
 }
 
+// TradeRecord - one booked fill for SaveTrade
+type TradeRecord struct {
+	ClientOrderID uint64
+	SymbolHash    uint64
+	Symbol        string
+	Tag           string // Attribution tag of the order behind the fill
+	SeqID         uint64 // Sequence ID the fill was booked under
+	ReduceOnly    bool   // Booked as closing only
+	Side          uint8
+	Quantity      int64
+	Price         int64
+	Commission    int64
+	PnL           int64
+	Timestamp     int64 // Unix ns written; set by LoadTrades
+}
+
+// EventRecord - one broadcast WebSocket event for SaveEvent
+type EventRecord struct {
+	SeqID     uint64
+	Type      uint8
+	Data      []byte
+	Timestamp int64 // Unix ns broadcast
+}
+
+// SaveEvent records a broadcast event
+func (d *Database) SaveEvent(e EventRecord) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	_, err := d.db.Exec(`INSERT INTO events (seq_id, type, data, timestamp) VALUES (?, ?, ?, ?)`,
+		int64(e.SeqID), e.Type, string(e.Data), e.Timestamp)
+	return err
+}
+
+// LoadEvents returns up to limit events broadcast in [from, to), oldest
+// first
+func (d *Database) LoadEvents(from, to int64, limit int) ([]EventRecord, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	rows, err := d.db.Query(`SELECT seq_id, type, data, timestamp FROM events WHERE timestamp >= ? AND timestamp < ? ORDER BY timestamp, id LIMIT ?`, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []EventRecord
+	for rows.Next() {
+		var e EventRecord
+		var seqID int64
+		var data string
+		if err := rows.Scan(&seqID, &e.Type, &data, &e.Timestamp); err != nil {
+			return nil, err
+		}
+		e.SeqID, e.Data = uint64(seqID), []byte(data)
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
 // Trade History
-func (d *Database) SaveTrade(clientOrderID, symbolHash uint64, symbol string, side uint8, quantity, price, commission, pnl int64) error {
+func (d *Database) SaveTrade(t TradeRecord) error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 	
-	_, err := d.db.Exec(`INSERT INTO trades (client_order_id, symbol_hash, symbol, side, quantity, price, commission, pnl, timestamp) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		clientOrderID, symbolHash, symbol, side, quantity, price, commission, pnl, time.Now().UnixNano())
+	_, err := d.db.Exec(`INSERT INTO trades (client_order_id, symbol_hash, symbol, tag, seq_id, reduce_only, side, quantity, price, commission, pnl, timestamp) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		int64(t.ClientOrderID), int64(t.SymbolHash), t.Symbol, t.Tag, int64(t.SeqID), boolToInt(t.ReduceOnly), t.Side, t.Quantity, t.Price, t.Commission, t.PnL, time.Now().UnixNano())
 	
 	return err
 }
 
+// LoadTrades returns the trades written at or after from, oldest first
+func (d *Database) LoadTrades(from int64) ([]TradeRecord, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	rows, err := d.db.Query(`SELECT client_order_id, symbol_hash, symbol, tag, seq_id, reduce_only, side, quantity, price, commission, pnl, timestamp FROM trades WHERE timestamp >= ? ORDER BY id`, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var trades []TradeRecord
+	for rows.Next() {
+		var t TradeRecord
+		var orderID, hash, seqID int64
+		var reduceOnly int
+		if err := rows.Scan(&orderID, &hash, &t.Symbol, &t.Tag, &seqID, &reduceOnly, &t.Side, &t.Quantity, &t.Price, &t.Commission, &t.PnL, &t.Timestamp); err != nil {
+			return nil, err
+		}
+		t.ClientOrderID, t.SymbolHash, t.SeqID, t.ReduceOnly = uint64(orderID), uint64(hash), uint64(seqID), reduceOnly != 0
+		trades = append(trades, t)
+	}
+	return trades, rows.Err()
+}
+
 func (d *Database) LoadTradeHistory(limit int) ([]map[string]interface{}, error) {
 	d.mu.RLock()
 	defer d.mu.RUnlock()
 	
-	rows, err := d.db.Query(`SELECT id, client_order_id, symbol, side, quantity, price, commission, pnl, timestamp FROM trades ORDER BY timestamp DESC LIMIT ?`, limit)
+	rows, err := d.db.Query(`SELECT id, client_order_id, symbol, tag, side, quantity, price, commission, pnl, timestamp FROM trades ORDER BY timestamp DESC LIMIT ?`, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -323,11 +545,11 @@ func (d *Database) LoadTradeHistory(limit int) ([]map[string]interface{}, error)
 	var trades []map[string]interface{}
 	for rows.Next() {
 		var id, clientOrderID int64
-		var symbol string
+		var symbol, tag string
 		var side uint8
 		var quantity, price, commission, pnl, timestamp int64
 		
-		if err := rows.Scan(&id, &clientOrderID, &symbol, &side, &quantity, &price, &commission, &pnl, &timestamp); err != nil {
+		if err := rows.Scan(&id, &clientOrderID, &symbol, &tag, &side, &quantity, &price, &commission, &pnl, &timestamp); err != nil {
 			return nil, err
 		}
 		
@@ -335,6 +557,7 @@ func (d *Database) LoadTradeHistory(limit int) ([]map[string]interface{}, error)
 			"id":              id,
 			"client_order_id": clientOrderID,
 			"symbol":          symbol,
+			"tag":             tag,
 			"side":            side,
 			"quantity":        quantity,
 			"price":           price,
@@ -346,6 +569,175 @@ func (d *Database) LoadTradeHistory(limit int) ([]map[string]interface{}, error)
 	return trades, nil
 }
 
+// Trigger Orders
+type TriggerOrder struct {
+	ID           uint64
+	SymbolHash   uint64
+	Symbol       string
+	Side         uint8
+	Kind         uint8
+	TriggerPrice int64
+	Quantity     int64
+	Price        int64
+	Strategy     string
+	ReduceOnly   bool
+	CreatedAt    int64
+}
+
+// SaveTriggerOrder upserts an armed trigger. The symbol hash is stored as
+// its int64 bit pattern: the driver rejects uint64 values above MaxInt64.
+func (d *Database) SaveTriggerOrder(t TriggerOrder) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	
+	_, err := d.db.Exec(`
+		INSERT OR REPLACE INTO trigger_orders (id, symbol_hash, symbol, side, kind, trigger_price, quantity, price, strategy, reduce_only, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, t.ID, int64(t.SymbolHash), t.Symbol, t.Side, t.Kind, t.TriggerPrice, t.Quantity, t.Price, t.Strategy, boolToInt(t.ReduceOnly), t.CreatedAt)
+	
+	return err
+}
+
+func (d *Database) DeleteTriggerOrder(id uint64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	
+	_, err := d.db.Exec(`DELETE FROM trigger_orders WHERE id = ?`, id)
+	return err
+}
+
+func (d *Database) LoadTriggerOrders() ([]TriggerOrder, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	
+	rows, err := d.db.Query(`SELECT id, symbol_hash, symbol, side, kind, trigger_price, quantity, price, strategy, reduce_only, created_at FROM trigger_orders ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	
+	var triggers []TriggerOrder
+	for rows.Next() {
+		var t TriggerOrder
+		var symbolHash int64
+		var reduceOnly int
+		if err := rows.Scan(&t.ID, &symbolHash, &t.Symbol, &t.Side, &t.Kind, &t.TriggerPrice, &t.Quantity, &t.Price, &t.Strategy, &reduceOnly, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		t.SymbolHash = uint64(symbolHash)
+		t.ReduceOnly = reduceOnly != 0
+		triggers = append(triggers, t)
+	}
+	return triggers, rows.Err()
+}
+
+// LatencyHistogramRecord - a latency histogram's cumulative counters.
+// Buckets are indexed from MinValue in steps of BucketSize; a stored
+// histogram only merges into one with the same layout.
+type LatencyHistogramRecord struct {
+	Name       string
+	MinValue   int64
+	BucketSize int64
+	Buckets    []uint64
+	Count      uint64
+	Sum        int64
+	MinSeen    int64
+	MaxSeen    int64
+}
+
+// encodeBuckets writes the non-empty buckets as "index:count" pairs - most
+// of a latency histogram's buckets are empty
+func encodeBuckets(buckets []uint64) string {
+	var b strings.Builder
+	for i, n := range buckets {
+		if n == 0 {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.Itoa(i))
+		b.WriteByte(':')
+		b.WriteString(strconv.FormatUint(n, 10))
+	}
+	return b.String()
+}
+
+// decodeBuckets reads encodeBuckets' output back into a dense slice
+func decodeBuckets(s string) ([]uint64, error) {
+	var buckets []uint64
+	if s == "" {
+		return buckets, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		index, count, ok := strings.Cut(pair, ":")
+		i, err := strconv.Atoi(index)
+		if !ok || err != nil || i < 0 || i > 1<<20 {
+			return nil, fmt.Errorf("bad latency bucket %q", pair)
+		}
+		n, err := strconv.ParseUint(count, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad latency bucket %q", pair)
+		}
+		if i >= len(buckets) {
+			buckets = append(buckets, make([]uint64, i+1-len(buckets))...)
+		}
+		buckets[i] = n
+	}
+	return buckets, nil
+}
+
+// SaveLatencyHistograms upserts each histogram in one transaction
+func (d *Database) SaveLatencyHistograms(records []LatencyHistogramRecord) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UnixNano()
+	for _, r := range records {
+		if _, err := tx.Exec(`
+			INSERT OR REPLACE INTO latency_histograms (name, min_value, bucket_size, buckets, count, sum, min_seen, max_seen, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, r.Name, r.MinValue, r.BucketSize, encodeBuckets(r.Buckets), int64(r.Count), r.Sum, r.MinSeen, r.MaxSeen, now); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// LoadLatencyHistograms returns every stored histogram
+func (d *Database) LoadLatencyHistograms() ([]LatencyHistogramRecord, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	rows, err := d.db.Query(`SELECT name, min_value, bucket_size, buckets, count, sum, min_seen, max_seen FROM latency_histograms ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []LatencyHistogramRecord
+	for rows.Next() {
+		var r LatencyHistogramRecord
+		var buckets string
+		var count int64
+		if err := rows.Scan(&r.Name, &r.MinValue, &r.BucketSize, &buckets, &count, &r.Sum, &r.MinSeen, &r.MaxSeen); err != nil {
+			return nil, err
+		}
+		if r.Buckets, err = decodeBuckets(buckets); err != nil {
+			return nil, fmt.Errorf("latency histogram %s: %w", r.Name, err)
+		}
+		r.Count = uint64(count)
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
 // Risk Events
 func (d *Database) SaveRiskEvent(eventType string, symbolHash uint64, reason, details string) error {
 	d.mu.Lock()
@@ -357,6 +749,17 @@ func (d *Database) SaveRiskEvent(eventType string, symbolHash uint64, reason, de
 	return err
 }
 
+// Audit Log
+func (d *Database) SaveAuditLog(action, resource, details, ipAddress string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	
+	_, err := d.db.Exec(`INSERT INTO audit_log (action, resource, details, ip_address, timestamp) VALUES (?, ?, ?, ?, ?)`,
+		action, resource, details, ipAddress, time.Now().UnixNano())
+	
+	return err
+}
+
 // Daily Snapshot
 func (d *Database) SaveDailySnapshot(equity, cash, dailyPnL int64, totalTrades, winCount, lossCount int) error {
 	d.mu.Lock()
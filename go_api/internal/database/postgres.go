@@ -0,0 +1,361 @@
+// ============================================================================
+// POSTGRES PERSISTENCE LAYER — Shared Storage for Clustered Deployments
+// ============================================================================
+
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+// Postgres keeps the same records as the SQLite Database - portfolio state,
+// positions, trades, trigger orders and the audit log - in a server other
+// nodes and tools can read, so they outlive the node that wrote them.
+// Hashes and IDs are stored as their int64 bit patterns, as in SQLite.
+type Postgres struct {
+	db *sql.DB
+}
+
+const postgresSchemaSQL = `
+CREATE TABLE IF NOT EXISTS portfolio_state (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    equity BIGINT NOT NULL DEFAULT 0,
+    cash BIGINT NOT NULL DEFAULT 0,
+    total_pnl BIGINT NOT NULL DEFAULT 0,
+    daily_pnl BIGINT NOT NULL DEFAULT 0,
+    high_water_mark BIGINT NOT NULL DEFAULT 0,
+    current_drawdown BIGINT NOT NULL DEFAULT 0,
+    kill_switch BOOLEAN NOT NULL DEFAULT FALSE,
+    sequence_id BIGINT NOT NULL DEFAULT 0,
+    started_at BIGINT NOT NULL DEFAULT 0,
+    updated_at BIGINT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS positions (
+    symbol_hash BIGINT PRIMARY KEY,
+    symbol TEXT NOT NULL,
+    side SMALLINT NOT NULL,
+    quantity BIGINT NOT NULL,
+    entry_price BIGINT NOT NULL,
+    current_price BIGINT NOT NULL,
+    unrealized_pnl BIGINT NOT NULL DEFAULT 0,
+    realized_pnl BIGINT NOT NULL DEFAULT 0,
+    opened_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS trades (
+    id BIGSERIAL PRIMARY KEY,
+    client_order_id BIGINT NOT NULL,
+    symbol_hash BIGINT NOT NULL,
+    symbol TEXT NOT NULL,
+    tag TEXT NOT NULL DEFAULT '',
+    seq_id BIGINT NOT NULL DEFAULT 0,
+    reduce_only BOOLEAN NOT NULL DEFAULT FALSE,
+    side SMALLINT NOT NULL,
+    quantity BIGINT NOT NULL,
+    price BIGINT NOT NULL,
+    commission BIGINT NOT NULL,
+    pnl BIGINT NOT NULL,
+    timestamp BIGINT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    action TEXT NOT NULL,
+    resource TEXT,
+    details TEXT,
+    ip_address TEXT,
+    timestamp BIGINT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS trigger_orders (
+    id BIGINT PRIMARY KEY,
+    symbol_hash BIGINT NOT NULL,
+    symbol TEXT NOT NULL,
+    side SMALLINT NOT NULL,
+    kind SMALLINT NOT NULL,
+    trigger_price BIGINT NOT NULL,
+    quantity BIGINT NOT NULL,
+    price BIGINT NOT NULL,
+    strategy TEXT NOT NULL DEFAULT '',
+    reduce_only BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at BIGINT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS events (
+    id BIGSERIAL PRIMARY KEY,
+    seq_id BIGINT NOT NULL,
+    type SMALLINT NOT NULL,
+    data TEXT NOT NULL,
+    timestamp BIGINT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS latency_histograms (
+    name TEXT PRIMARY KEY,
+    min_value BIGINT NOT NULL,
+    bucket_size BIGINT NOT NULL,
+    buckets TEXT NOT NULL,
+    count BIGINT NOT NULL,
+    sum BIGINT NOT NULL,
+    min_seen BIGINT NOT NULL,
+    max_seen BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL
+);
+
+ALTER TABLE trades ADD COLUMN IF NOT EXISTS tag TEXT NOT NULL DEFAULT '';
+ALTER TABLE trades ADD COLUMN IF NOT EXISTS seq_id BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE trades ADD COLUMN IF NOT EXISTS reduce_only BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE portfolio_state ADD COLUMN IF NOT EXISTS started_at BIGINT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_trades_timestamp ON trades(timestamp);
+CREATE INDEX IF NOT EXISTS idx_audit_log_timestamp ON audit_log(timestamp);
+CREATE INDEX IF NOT EXISTS idx_events_timestamp ON events(timestamp);
+`
+
+// OpenPostgres connects to dsn (a postgres:// URL or key=value string) and
+// creates the schema if it is missing
+func OpenPostgres(dsn string) (*Postgres, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open postgres: %w", err)
+	}
+	db.SetMaxOpenConns(4)
+	db.SetConnMaxIdleTime(5 * time.Minute)
+
+	if _, err := db.Exec(postgresSchemaSQL); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize postgres schema: %w", err)
+	}
+	if _, err := db.Exec(`
+		INSERT INTO portfolio_state (id, updated_at) VALUES (1, $1)
+		ON CONFLICT (id) DO NOTHING
+	`, time.Now().UnixNano()); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize portfolio state: %w", err)
+	}
+	return &Postgres{db: db}, nil
+}
+
+func (p *Postgres) Close() error {
+	return p.db.Close()
+}
+
+func (p *Postgres) SavePortfolioState(r PortfolioRecord) error {
+	_, err := p.db.Exec(`
+		UPDATE portfolio_state SET
+			equity = $1, cash = $2, total_pnl = $3, daily_pnl = $4,
+			high_water_mark = $5, current_drawdown = $6, kill_switch = $7,
+			sequence_id = $8, started_at = $9, updated_at = $10
+		WHERE id = 1
+	`, r.Equity, r.Cash, r.TotalPnL, r.DailyPnL, r.HighWaterMark, r.CurrentDrawdown, r.KillSwitch, int64(r.SequenceID), r.StartedAt, time.Now().UnixNano())
+	return err
+}
+
+func (p *Postgres) LoadPortfolioState() (PortfolioRecord, error) {
+	var r PortfolioRecord
+	var seqID int64
+	err := p.db.QueryRow(`
+		SELECT equity, cash, total_pnl, daily_pnl, high_water_mark, current_drawdown, kill_switch, sequence_id, started_at
+		FROM portfolio_state WHERE id = 1
+	`).Scan(&r.Equity, &r.Cash, &r.TotalPnL, &r.DailyPnL, &r.HighWaterMark, &r.CurrentDrawdown, &r.KillSwitch, &seqID, &r.StartedAt)
+	r.SequenceID = uint64(seqID)
+	return r, err
+}
+
+// ReplacePositions atomically replaces every stored position
+func (p *Postgres) ReplacePositions(positions []PositionRecord) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM positions`); err != nil {
+		return err
+	}
+	now := time.Now().UnixNano()
+	for _, r := range positions {
+		if _, err := tx.Exec(`
+			INSERT INTO positions (symbol_hash, symbol, side, quantity, entry_price, current_price, unrealized_pnl, realized_pnl, opened_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		`, int64(r.SymbolHash), r.Symbol, r.Side, r.Quantity, r.EntryPrice, r.CurrentPrice, r.UnrealizedPnL, r.RealizedPnL, now, now); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// LoadPositionRecords returns the positions of the last snapshot
+func (p *Postgres) LoadPositionRecords() ([]PositionRecord, error) {
+	rows, err := p.db.Query(`SELECT symbol_hash, symbol, side, quantity, entry_price, current_price, unrealized_pnl, realized_pnl FROM positions ORDER BY symbol`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var positions []PositionRecord
+	for rows.Next() {
+		var r PositionRecord
+		var hash int64
+		if err := rows.Scan(&hash, &r.Symbol, &r.Side, &r.Quantity, &r.EntryPrice, &r.CurrentPrice, &r.UnrealizedPnL, &r.RealizedPnL); err != nil {
+			return nil, err
+		}
+		r.SymbolHash = uint64(hash)
+		positions = append(positions, r)
+	}
+	return positions, rows.Err()
+}
+
+func (p *Postgres) SaveTrade(t TradeRecord) error {
+	_, err := p.db.Exec(`INSERT INTO trades (client_order_id, symbol_hash, symbol, tag, seq_id, reduce_only, side, quantity, price, commission, pnl, timestamp) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		int64(t.ClientOrderID), int64(t.SymbolHash), t.Symbol, t.Tag, int64(t.SeqID), t.ReduceOnly, t.Side, t.Quantity, t.Price, t.Commission, t.PnL, time.Now().UnixNano())
+	return err
+}
+
+// LoadTrades returns the trades written at or after from, oldest first
+func (p *Postgres) LoadTrades(from int64) ([]TradeRecord, error) {
+	rows, err := p.db.Query(`SELECT client_order_id, symbol_hash, symbol, tag, seq_id, reduce_only, side, quantity, price, commission, pnl, timestamp FROM trades WHERE timestamp >= $1 ORDER BY id`, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var trades []TradeRecord
+	for rows.Next() {
+		var t TradeRecord
+		var orderID, hash, seqID int64
+		if err := rows.Scan(&orderID, &hash, &t.Symbol, &t.Tag, &seqID, &t.ReduceOnly, &t.Side, &t.Quantity, &t.Price, &t.Commission, &t.PnL, &t.Timestamp); err != nil {
+			return nil, err
+		}
+		t.ClientOrderID, t.SymbolHash, t.SeqID = uint64(orderID), uint64(hash), uint64(seqID)
+		trades = append(trades, t)
+	}
+	return trades, rows.Err()
+}
+
+func (p *Postgres) SaveEvent(e EventRecord) error {
+	_, err := p.db.Exec(`INSERT INTO events (seq_id, type, data, timestamp) VALUES ($1, $2, $3, $4)`,
+		int64(e.SeqID), e.Type, string(e.Data), e.Timestamp)
+	return err
+}
+
+// LoadEvents returns up to limit events broadcast in [from, to), oldest
+// first
+func (p *Postgres) LoadEvents(from, to int64, limit int) ([]EventRecord, error) {
+	rows, err := p.db.Query(`SELECT seq_id, type, data, timestamp FROM events WHERE timestamp >= $1 AND timestamp < $2 ORDER BY timestamp, id LIMIT $3`, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []EventRecord
+	for rows.Next() {
+		var e EventRecord
+		var seqID int64
+		var data string
+		if err := rows.Scan(&seqID, &e.Type, &data, &e.Timestamp); err != nil {
+			return nil, err
+		}
+		e.SeqID, e.Data = uint64(seqID), []byte(data)
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (p *Postgres) SaveAuditLog(action, resource, details, ipAddress string) error {
+	_, err := p.db.Exec(`INSERT INTO audit_log (action, resource, details, ip_address, timestamp) VALUES ($1, $2, $3, $4, $5)`,
+		action, resource, details, ipAddress, time.Now().UnixNano())
+	return err
+}
+
+// SaveLatencyHistograms upserts each histogram in one transaction
+func (p *Postgres) SaveLatencyHistograms(records []LatencyHistogramRecord) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UnixNano()
+	for _, r := range records {
+		if _, err := tx.Exec(`
+			INSERT INTO latency_histograms (name, min_value, bucket_size, buckets, count, sum, min_seen, max_seen, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			ON CONFLICT (name) DO UPDATE SET
+				min_value = excluded.min_value, bucket_size = excluded.bucket_size, buckets = excluded.buckets,
+				count = excluded.count, sum = excluded.sum, min_seen = excluded.min_seen,
+				max_seen = excluded.max_seen, updated_at = excluded.updated_at
+		`, r.Name, r.MinValue, r.BucketSize, encodeBuckets(r.Buckets), int64(r.Count), r.Sum, r.MinSeen, r.MaxSeen, now); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// LoadLatencyHistograms returns every stored histogram
+func (p *Postgres) LoadLatencyHistograms() ([]LatencyHistogramRecord, error) {
+	rows, err := p.db.Query(`SELECT name, min_value, bucket_size, buckets, count, sum, min_seen, max_seen FROM latency_histograms ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []LatencyHistogramRecord
+	for rows.Next() {
+		var r LatencyHistogramRecord
+		var buckets string
+		var count int64
+		if err := rows.Scan(&r.Name, &r.MinValue, &r.BucketSize, &buckets, &count, &r.Sum, &r.MinSeen, &r.MaxSeen); err != nil {
+			return nil, err
+		}
+		if r.Buckets, err = decodeBuckets(buckets); err != nil {
+			return nil, fmt.Errorf("latency histogram %s: %w", r.Name, err)
+		}
+		r.Count = uint64(count)
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+func (p *Postgres) SaveTriggerOrder(t TriggerOrder) error {
+	_, err := p.db.Exec(`
+		INSERT INTO trigger_orders (id, symbol_hash, symbol, side, kind, trigger_price, quantity, price, strategy, reduce_only, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (id) DO UPDATE SET
+			symbol_hash = excluded.symbol_hash, symbol = excluded.symbol, side = excluded.side,
+			kind = excluded.kind, trigger_price = excluded.trigger_price, quantity = excluded.quantity,
+			price = excluded.price, strategy = excluded.strategy, reduce_only = excluded.reduce_only,
+			created_at = excluded.created_at
+	`, int64(t.ID), int64(t.SymbolHash), t.Symbol, t.Side, t.Kind, t.TriggerPrice, t.Quantity, t.Price, t.Strategy, t.ReduceOnly, t.CreatedAt)
+	return err
+}
+
+func (p *Postgres) DeleteTriggerOrder(id uint64) error {
+	_, err := p.db.Exec(`DELETE FROM trigger_orders WHERE id = $1`, int64(id))
+	return err
+}
+
+func (p *Postgres) LoadTriggerOrders() ([]TriggerOrder, error) {
+	rows, err := p.db.Query(`SELECT id, symbol_hash, symbol, side, kind, trigger_price, quantity, price, strategy, reduce_only, created_at FROM trigger_orders ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var triggers []TriggerOrder
+	for rows.Next() {
+		var t TriggerOrder
+		var id, symbolHash int64
+		if err := rows.Scan(&id, &symbolHash, &t.Symbol, &t.Side, &t.Kind, &t.TriggerPrice, &t.Quantity, &t.Price, &t.Strategy, &t.ReduceOnly, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		t.ID, t.SymbolHash = uint64(id), uint64(symbolHash)
+		triggers = append(triggers, t)
+	}
+	return triggers, rows.Err()
+}
@@ -0,0 +1,358 @@
+// Package exec — Approved-Order Forwarding to the Execution Gateway
+//
+// Approved orders are queued, batched when they arrive in bursts, and
+// published with delivery confirmation. A batch is retried until the
+// gateway acknowledges it, so delivery is at-least-once: the gateway must
+// treat a repeated order ID as a duplicate.
+package exec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Subject approved orders are published on
+const Subject = "exec.orders"
+
+// RejectSubject is where the gateway reports orders it could not place
+const RejectSubject = "exec.rejects"
+
+// FillSubject is where the gateway reports executions
+const FillSubject = "exec.fills"
+
+// Defaults for zero Config fields
+const (
+	DefaultQueueSize    = 4096
+	DefaultBatchSize    = 64
+	DefaultTimeout      = 2 * time.Second
+	DefaultRetryBackoff = 50 * time.Millisecond
+	DefaultMaxBackoff   = 5 * time.Second
+)
+
+// Payload headers
+const (
+	HeaderContentEncoding = "Content-Encoding" // "gzip" when compressed
+	HeaderBatchSize       = "Exec-Batch-Size"
+)
+
+// ErrQueueFull is returned by Submit when the publisher is too far behind
+// to take another order
+var ErrQueueFull = errors.New("exec queue full")
+
+// ErrStopped is returned by Submit after Stop
+var ErrStopped = errors.New("exec publisher stopped")
+
+// Order - an approved order as the gateway receives it. Quantity and price
+// are fixed-point (1e8); a zero price is a market order.
+type Order struct {
+	ID         uint64 `json:"id"`
+	SymbolHash uint64 `json:"symbol_hash"`
+	Symbol     string `json:"symbol"`
+	Side       string `json:"side"` // BUY or SELL
+	Quantity   int64  `json:"quantity"`
+	Price      int64  `json:"price"`
+	ReduceOnly bool   `json:"reduce_only,omitempty"`
+	Strategy   string `json:"strategy,omitempty"`
+	SeqID      uint64 `json:"seq_id"`
+	Timestamp  int64  `json:"timestamp_ns"`
+}
+
+// Reject - the gateway's report that an order it accepted for delivery
+// was refused downstream, by the exchange or for lack of connectivity
+type Reject struct {
+	ID        uint64 `json:"id"`
+	Reason    string `json:"reason"`
+	Timestamp int64  `json:"timestamp_ns"`
+}
+
+// Fill - the gateway's report of one execution against an order.
+// Quantity, price and commission are fixed-point (1e8); SeqID is the
+// gateway's fill sequence, which makes a redelivery detectable.
+type Fill struct {
+	ID         uint64 `json:"id"` // Order ID
+	SymbolHash uint64 `json:"symbol_hash"`
+	Side       string `json:"side"` // BUY or SELL
+	Quantity   int64  `json:"quantity"`
+	Price      int64  `json:"price"`
+	Commission int64  `json:"commission,omitempty"`
+	ReduceOnly bool   `json:"reduce_only,omitempty"`
+	Maker      bool   `json:"maker,omitempty"` // Rested on the book; taker otherwise
+	SeqID      uint64 `json:"seq_id"`
+	Timestamp  int64  `json:"timestamp_ns"`
+}
+
+// batch - the published message body
+type batch struct {
+	Orders []Order `json:"orders"`
+}
+
+// Transport delivers one message and returns once the gateway has
+// confirmed it - a request/reply round trip or a stream ack. An error
+// means the message may or may not have arrived.
+type Transport interface {
+	Publish(ctx context.Context, subject string, data []byte, header map[string]string) error
+}
+
+// Config - batching, compression and retry. Zero fields take the defaults.
+type Config struct {
+	Subject       string        // "" = Subject
+	QueueSize     int           // Orders waiting to be published
+	BatchSize     int           // Most orders in one message (1 = no batching)
+	BatchWindow   time.Duration // How long a burst's first order waits for more (0 = publish what is queued at once)
+	CompressAbove int           // Gzip bodies at least this many bytes (0 = never)
+	Timeout       time.Duration // Confirmation wait per attempt
+	RetryBackoff  time.Duration // First retry delay, doubling per failure
+	MaxBackoff    time.Duration // Cap on the retry delay
+}
+
+func (c Config) withDefaults() Config {
+	if c.Subject == "" {
+		c.Subject = Subject
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = DefaultQueueSize
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = DefaultBatchSize
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	if c.RetryBackoff <= 0 {
+		c.RetryBackoff = DefaultRetryBackoff
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = DefaultMaxBackoff
+	}
+	return c
+}
+
+// Publisher forwards approved orders from its own goroutine. Batches go out
+// one at a time in submission order; a batch that fails is retried with
+// backoff before any later one is sent.
+type Publisher struct {
+	transport Transport
+	cfg       Config
+	queue     chan Order
+	stop      chan struct{}
+	done      chan struct{}
+	stopped   int32
+
+	// Cancelled when Stop gives up: ends the attempt in flight and retries
+	abortCtx context.Context
+	abort    context.CancelFunc
+
+	// Atomic stats
+	published uint64 // Orders confirmed
+	batches   uint64 // Messages confirmed
+	retries   uint64 // Attempts after a failure
+	failures  uint64 // Attempts that failed
+	rejected  uint64 // Orders refused by Submit
+	abandoned uint64 // Orders unconfirmed when Stop gave up
+}
+
+// NewPublisher returns a publisher sending through t; start it with Run
+func NewPublisher(t Transport, cfg Config) *Publisher {
+	cfg = cfg.withDefaults()
+	abortCtx, abort := context.WithCancel(context.Background())
+	return &Publisher{
+		transport: t,
+		cfg:       cfg,
+		queue:     make(chan Order, cfg.QueueSize),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+		abortCtx:  abortCtx,
+		abort:     abort,
+	}
+}
+
+// Submit queues an approved order without blocking. An order that cannot
+// be queued is refused with an error and counted - never dropped unseen.
+func (p *Publisher) Submit(o Order) error {
+	if atomic.LoadInt32(&p.stopped) != 0 {
+		atomic.AddUint64(&p.rejected, 1)
+		return ErrStopped
+	}
+	select {
+	case p.queue <- o:
+		return nil
+	default:
+		atomic.AddUint64(&p.rejected, 1)
+		return ErrQueueFull
+	}
+}
+
+// Run publishes queued orders until Stop, then drains what is left
+func (p *Publisher) Run() {
+	defer close(p.done)
+	orders := make([]Order, 0, p.cfg.BatchSize)
+	for {
+		select {
+		case o := <-p.queue:
+			orders = p.collect(append(orders[:0], o))
+			if !p.send(orders) {
+				p.abandon(len(orders))
+				return
+			}
+		case <-p.stop:
+			for {
+				orders = p.collect(orders[:0])
+				if len(orders) == 0 {
+					return
+				}
+				if !p.send(orders) {
+					p.abandon(len(orders))
+					return
+				}
+			}
+		}
+	}
+}
+
+// collect tops up a batch from the queue, waiting up to BatchWindow for a
+// burst to fill it
+func (p *Publisher) collect(orders []Order) []Order {
+	var window <-chan time.Time
+	if p.cfg.BatchWindow > 0 && len(orders) > 0 {
+		t := time.NewTimer(p.cfg.BatchWindow)
+		defer t.Stop()
+		window = t.C
+	}
+	for len(orders) < p.cfg.BatchSize {
+		select {
+		case o := <-p.queue:
+			orders = append(orders, o)
+			continue
+		default:
+		}
+		if window == nil {
+			break
+		}
+		select {
+		case o := <-p.queue:
+			orders = append(orders, o)
+		case <-window:
+			return orders
+		case <-p.stop:
+			window = nil // Publish what is held without waiting out the window
+		}
+	}
+	return orders
+}
+
+// send publishes one batch, retrying until it is confirmed. Returns false
+// if Stop's deadline passed first.
+func (p *Publisher) send(orders []Order) bool {
+	data, header, err := p.encode(orders)
+	if err != nil {
+		// Orders are plain values - encoding cannot fail short of a bug
+		log.Printf("[EXEC] Encoding %d orders failed: %v", len(orders), err)
+		atomic.AddUint64(&p.failures, 1)
+		return true
+	}
+
+	backoff := p.cfg.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			atomic.AddUint64(&p.retries, 1)
+		}
+		ctx, cancel := context.WithTimeout(p.abortCtx, p.cfg.Timeout)
+		err := p.transport.Publish(ctx, p.cfg.Subject, data, header)
+		cancel()
+		if err == nil {
+			atomic.AddUint64(&p.batches, 1)
+			atomic.AddUint64(&p.published, uint64(len(orders)))
+			return true
+		}
+
+		failures := atomic.AddUint64(&p.failures, 1)
+		if attempt == 0 || failures%100 == 0 {
+			log.Printf("[EXEC] Publishing %d orders failed, retrying: %v", len(orders), err)
+		}
+		t := time.NewTimer(backoff)
+		select {
+		case <-t.C:
+		case <-p.abortCtx.Done():
+			t.Stop()
+			return false
+		}
+		backoff = min(backoff*2, p.cfg.MaxBackoff)
+	}
+}
+
+// encode serializes a batch, gzipping it past CompressAbove
+func (p *Publisher) encode(orders []Order) ([]byte, map[string]string, error) {
+	data, err := json.Marshal(batch{Orders: orders})
+	if err != nil {
+		return nil, nil, err
+	}
+	header := map[string]string{HeaderBatchSize: strconv.Itoa(len(orders))}
+	if p.cfg.CompressAbove > 0 && len(data) >= p.cfg.CompressAbove {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write(data)
+		if err := zw.Close(); err != nil {
+			return nil, nil, err
+		}
+		data = buf.Bytes()
+		header[HeaderContentEncoding] = "gzip"
+	}
+	return data, header, nil
+}
+
+func (p *Publisher) abandon(n int) {
+	n += len(p.queue)
+	atomic.AddUint64(&p.abandoned, uint64(n))
+	log.Printf("[EXEC] Stopped with %d orders unconfirmed", n)
+}
+
+// Stop refuses new orders and waits for the queue to be published. At
+// ctx's deadline it gives up on the rest, counting them as abandoned.
+func (p *Publisher) Stop(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt32(&p.stopped, 0, 1) {
+		return nil
+	}
+	close(p.stop)
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		p.abort()
+		<-p.done
+		return ctx.Err()
+	}
+}
+
+// Stats - publisher counters
+type Stats struct {
+	Queued    int
+	Published uint64
+	Batches   uint64
+	Retries   uint64
+	Failures  uint64
+	Rejected  uint64
+	Abandoned uint64
+}
+
+// Stats returns the publisher's counters; zero for a nil Publisher
+func (p *Publisher) Stats() Stats {
+	if p == nil {
+		return Stats{}
+	}
+	return Stats{
+		Queued:    len(p.queue),
+		Published: atomic.LoadUint64(&p.published),
+		Batches:   atomic.LoadUint64(&p.batches),
+		Retries:   atomic.LoadUint64(&p.retries),
+		Failures:  atomic.LoadUint64(&p.failures),
+		Rejected:  atomic.LoadUint64(&p.rejected),
+		Abandoned: atomic.LoadUint64(&p.abandoned),
+	}
+}
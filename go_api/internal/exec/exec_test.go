@@ -0,0 +1,91 @@
+package exec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTransport fails its first failFirst publishes, then records each
+// batch it confirms
+type fakeTransport struct {
+	mu        sync.Mutex
+	failFirst int
+	attempts  int
+	batches   [][]Order
+}
+
+func (f *fakeTransport) Publish(ctx context.Context, subject string, data []byte, header map[string]string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.attempts++
+	if f.attempts <= f.failFirst {
+		return errors.New("no responders")
+	}
+	if header[HeaderContentEncoding] == "gzip" {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		if data, err = io.ReadAll(zr); err != nil {
+			return err
+		}
+	}
+	var b batch
+	if err := json.Unmarshal(data, &b); err != nil {
+		return err
+	}
+	f.batches = append(f.batches, b.Orders)
+	return nil
+}
+
+// Orders queued before Run go out in batches of BatchSize, in order, the
+// first after a retry, and Stop drains the queue before refusing more
+func TestPublisherBatchesAndRetries(t *testing.T) {
+	transport := &fakeTransport{failFirst: 1}
+	p := NewPublisher(transport, Config{BatchSize: 2, CompressAbove: 1, RetryBackoff: time.Millisecond})
+	for id := uint64(1); id <= 3; id++ {
+		if err := p.Submit(Order{ID: id, Side: "BUY"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	go p.Run()
+	if err := p.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Submit(Order{ID: 4}); err != ErrStopped {
+		t.Fatalf("submit after stop: %v", err)
+	}
+
+	if len(transport.batches) != 2 || len(transport.batches[0]) != 2 || len(transport.batches[1]) != 1 {
+		t.Fatalf("batches %v, want sizes 2 and 1", transport.batches)
+	}
+	for i, id := range []uint64{transport.batches[0][0].ID, transport.batches[0][1].ID, transport.batches[1][0].ID} {
+		if id != uint64(i+1) {
+			t.Fatalf("order %d has ID %d", i, id)
+		}
+	}
+	if s := p.Stats(); s.Published != 3 || s.Batches != 2 || s.Retries != 1 || s.Failures != 1 || s.Rejected != 1 {
+		t.Fatalf("stats %+v", s)
+	}
+}
+
+// A full queue refuses the order and counts it
+func TestPublisherQueueFull(t *testing.T) {
+	p := NewPublisher(&fakeTransport{}, Config{QueueSize: 1})
+	if err := p.Submit(Order{ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Submit(Order{ID: 2}); err != ErrQueueFull {
+		t.Fatalf("err %v, want %v", err, ErrQueueFull)
+	}
+	if s := p.Stats(); s.Queued != 1 || s.Rejected != 1 {
+		t.Fatalf("stats %+v", s)
+	}
+}
@@ -0,0 +1,35 @@
+package exec
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+)
+
+// AckReply is the body a gateway answers an accepted message with; any
+// other reply is a refusal, its body the reason
+const AckReply = "+ACK"
+
+// NATSTransport publishes as NATS requests: the gateway's reply is the
+// delivery confirmation
+type NATSTransport struct {
+	Conn *nats.Conn
+}
+
+// Publish sends data on subject and waits for the gateway's reply
+func (t NATSTransport) Publish(ctx context.Context, subject string, data []byte, header map[string]string) error {
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	for k, v := range header {
+		msg.Header.Set(k, v)
+	}
+	reply, err := t.Conn.RequestMsgWithContext(ctx, msg)
+	if err != nil {
+		return err
+	}
+	if string(reply.Data) != AckReply {
+		return fmt.Errorf("gateway refused: %q", reply.Data)
+	}
+	return nil
+}
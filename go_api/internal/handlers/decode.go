@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// MaxBodyBytes caps request bodies read by DecodeJSON
+const MaxBodyBytes = 64 << 10
+
+// StatusClientClosedRequest - the non-standard 499 for a request its
+// client abandoned. The client never reads it; it marks the outcome in
+// access logs and metrics.
+const StatusClientClosedRequest = 499
+
+// ErrorResponse - structured error body for rejected requests
+type ErrorResponse struct {
+	Status int    `json:"-"`
+	Error  string `json:"error"`
+	Field  string `json:"field,omitempty"` // Offending JSON field, if known
+}
+
+// DecodeJSON strictly decodes a single JSON object from the request body
+// into dst. Bodies over MaxBodyBytes, unknown fields, type mismatches and
+// trailing data are rejected. Returns nil on success.
+func DecodeJSON(w http.ResponseWriter, r *http.Request, dst interface{}) *ErrorResponse {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBodyBytes))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(dst); err != nil {
+		return decodeError(err)
+	}
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		return &ErrorResponse{Status: http.StatusBadRequest, Error: "body must contain a single JSON object"}
+	}
+	return nil
+}
+
+// decodeError maps a decoder error to a client-facing response
+func decodeError(err error) *ErrorResponse {
+	var (
+		syntaxErr   *json.SyntaxError
+		typeErr     *json.UnmarshalTypeError
+		maxBytesErr *http.MaxBytesError
+	)
+	switch {
+	case errors.Is(err, io.EOF):
+		return &ErrorResponse{Status: http.StatusBadRequest, Error: "request body required"}
+	case errors.As(err, &maxBytesErr):
+		return &ErrorResponse{Status: http.StatusRequestEntityTooLarge, Error: "body exceeds " + strconv.FormatInt(maxBytesErr.Limit, 10) + " bytes"}
+	case errors.As(err, &syntaxErr):
+		return &ErrorResponse{Status: http.StatusBadRequest, Error: "malformed JSON at offset " + strconv.FormatInt(syntaxErr.Offset, 10)}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return &ErrorResponse{Status: http.StatusBadRequest, Error: "malformed JSON"}
+	case errors.As(err, &typeErr):
+		return &ErrorResponse{Status: http.StatusBadRequest, Error: "invalid type for field, expected " + typeErr.Type.String(), Field: typeErr.Field}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no typed error for DisallowUnknownFields
+		field, _ := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+		return &ErrorResponse{Status: http.StatusBadRequest, Error: "unknown field", Field: field}
+	default:
+		return &ErrorResponse{Status: http.StatusBadRequest, Error: err.Error()}
+	}
+}
+
+// WriteError writes an ErrorResponse with its status code
+func WriteError(w http.ResponseWriter, r *http.Request, e *ErrorResponse) error {
+	body, _ := json.Marshal(e) // Strings only - cannot fail
+	return WriteJSON(w, r, e.Status, body)
+}
+
+// WriteClientClosed answers a request abandoned by its client, once a call
+// bound to r.Context() failed with context.Canceled
+func WriteClientClosed(w http.ResponseWriter, r *http.Request) error {
+	return WriteError(w, r, &ErrorResponse{Status: StatusClientClosedRequest, Error: "client closed request"})
+}
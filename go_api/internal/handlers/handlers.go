@@ -2,10 +2,13 @@
 package handlers
 
 import (
+	"compress/gzip"
+	"io"
+	"log"
 	"net/http"
+	"strings"
 	"sync"
 	"sync/atomic"
-	"time"
 )
 
 var (
@@ -24,23 +27,78 @@ func init() {
 
 // HealthHandler returns pre-allocated health response
 func HealthHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("X-Response-Time", "0ns")
-	w.Write(health)
+	WriteJSON(w, r, http.StatusOK, health)
 }
 
 // NotFoundHandler returns 404
 func NotFoundHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusNotFound)
-	w.Write([]byte(`{"error":"not_found"}`))
+	WriteJSON(w, r, http.StatusNotFound, []byte(`{"error":"not_found"}`))
 }
 
 // MethodNotAllowedHandler returns 405
 func MethodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusMethodNotAllowed)
-	w.Write([]byte(`{"error":"method_not_allowed"}`))
+	WriteJSON(w, r, http.StatusMethodNotAllowed, []byte(`{"error":"method_not_allowed"}`))
+}
+
+// GzipMinSize is the smallest body worth compressing
+const GzipMinSize = 1024
+
+// Reused gzip writers (zero allocation after warm-up)
+var gzipPool = sync.Pool{
+	New: func() interface{} {
+		return gzip.NewWriter(io.Discard)
+	},
+}
+
+// WriteJSON writes a pre-serialized JSON body, gzip-encoded when the client
+// accepts it and the body is large enough. Write failures (typically the
+// client going away mid-response) are logged with the request context and
+// returned.
+func WriteJSON(w http.ResponseWriter, r *http.Request, status int, body []byte) error {
+	h := w.Header()
+	h.Set("Content-Type", "application/json")
+	h.Add("Vary", "Accept-Encoding")
+
+	if len(body) < GzipMinSize || !AcceptsGzip(r) {
+		w.WriteHeader(status)
+		_, err := w.Write(body)
+		return logWriteError(r, len(body), err)
+	}
+
+	h.Set("Content-Encoding", "gzip")
+	h.Del("Content-Length")
+	w.WriteHeader(status)
+
+	gz := gzipPool.Get().(*gzip.Writer)
+	defer gzipPool.Put(gz)
+	gz.Reset(w)
+
+	_, err := gz.Write(body)
+	if closeErr := gz.Close(); err == nil {
+		err = closeErr
+	}
+	return logWriteError(r, len(body), err)
+}
+
+// AcceptsGzip reports whether the request negotiates gzip content coding
+func AcceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		q := strings.ReplaceAll(params, " ", "")
+		return q != "q=0" && q != "q=0.0" && q != "q=0.00" && q != "q=0.000"
+	}
+	return false
+}
+
+func logWriteError(r *http.Request, size int, err error) error {
+	if err != nil {
+		log.Printf("[HTTP] Write failed: %s %s (%d bytes) from %s: %v", r.Method, r.URL.Path, size, r.RemoteAddr, err)
+	}
+	return err
 }
 
 // LatencyTracker for handlers
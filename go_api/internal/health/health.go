@@ -0,0 +1,182 @@
+// Package health — Downstream Dependency Probing and Status Aggregation
+//
+// Dependencies are probed in the background; readers only see the last
+// completed round, so health endpoints never block on a slow dependency.
+package health
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Overall statuses
+const (
+	StatusHealthy   = "healthy"
+	StatusDegraded  = "degraded"  // A non-critical dependency is down
+	StatusUnhealthy = "unhealthy" // A critical dependency is down
+)
+
+// DefaultTimeout bounds each probe
+const DefaultTimeout = 2 * time.Second
+
+// Dependency configures one downstream service. http(s) URLs are probed
+// with a GET (any status below 500 is up); nats and tcp URLs with a TCP
+// connect.
+type Dependency struct {
+	Name     string
+	URL      string
+	Critical bool
+}
+
+// DependencyStatus - result of the most recent probe
+type DependencyStatus struct {
+	Dependency
+	Up          bool
+	Checked     bool // False until the first probe completes
+	LastSuccess time.Time
+	LastError   string
+	Latency     time.Duration
+}
+
+// Checker probes a fixed set of dependencies
+type Checker struct {
+	deps    []Dependency
+	timeout time.Duration
+	client  *http.Client
+
+	mu     sync.RWMutex
+	status []DependencyStatus // Same order as deps
+}
+
+// New creates a checker. timeout <= 0 uses DefaultTimeout.
+func New(deps []Dependency, timeout time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	c := &Checker{
+		deps:    deps,
+		timeout: timeout,
+		client:  &http.Client{Timeout: timeout},
+		status:  make([]DependencyStatus, len(deps)),
+	}
+	for i, d := range deps {
+		c.status[i].Dependency = d
+	}
+	return c
+}
+
+// Run probes every interval until ctx is cancelled, starting immediately
+func (c *Checker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		c.CheckAll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckAll probes all dependencies concurrently and records the results
+func (c *Checker) CheckAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := range c.deps {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			start := time.Now()
+			err := c.probe(ctx, c.deps[i].URL)
+			c.record(i, err, time.Since(start))
+		}(i)
+	}
+	wg.Wait()
+}
+
+func (c *Checker) record(i int, err error, latency time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := &c.status[i]
+	s.Checked = true
+	s.Up = err == nil
+	s.Latency = latency
+	if err != nil {
+		s.LastError = err.Error()
+		return
+	}
+	s.LastError = ""
+	s.LastSuccess = time.Now()
+}
+
+func (c *Checker) probe(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	switch u.Scheme {
+	case "http", "https":
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			return fmt.Errorf("status %d", resp.StatusCode)
+		}
+		return nil
+	case "nats", "tcp":
+		host := u.Host
+		if u.Port() == "" && u.Scheme == "nats" {
+			host = net.JoinHostPort(u.Hostname(), "4222")
+		}
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", host)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	default:
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+}
+
+// Snapshot returns the latest status of every dependency
+func (c *Checker) Snapshot() []DependencyStatus {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]DependencyStatus(nil), c.status...)
+}
+
+// Overall aggregates dependency status. Dependencies not yet probed count
+// as up, so startup does not report a spurious outage.
+func (c *Checker) Overall() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	overall := StatusHealthy
+	for _, s := range c.status {
+		if !s.Checked || s.Up {
+			continue
+		}
+		if s.Critical {
+			return StatusUnhealthy
+		}
+		overall = StatusDegraded
+	}
+	return overall
+}
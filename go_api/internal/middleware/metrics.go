@@ -0,0 +1,309 @@
+package middleware
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LatencyBuckets - histogram upper bounds in seconds (Prometheus "le")
+var LatencyBuckets = [...]float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5}
+
+// SizeBuckets - response body size histogram upper bounds in bytes
+var SizeBuckets = [...]float64{256, 1024, 4096, 16384, 65536, 262144, 1048576, 4194304}
+
+// UnmatchedRoute labels requests no pattern matched, bounding cardinality
+const UnmatchedRoute = "unmatched"
+
+// routeStats - per-route counters, atomics only
+type routeStats struct {
+	requests uint64
+	status   [6]uint64 // Index = status / 100 (1xx..5xx; 0 unused)
+	buckets  [len(LatencyBuckets)]uint64
+	sumNs    uint64
+	sizes    [len(SizeBuckets)]uint64
+	bytes    uint64
+}
+
+// RouteMetrics records request count, status class, latency and response
+// size per mux pattern, and opens a server span per request on the global tracer
+// provider (a no-op unless tracing is configured).
+type RouteMetrics struct {
+	mux    *http.ServeMux
+	routes sync.Map // pattern → *routeStats
+	tracer trace.Tracer
+}
+
+// NewRouteMetrics instruments requests routed by mux
+func NewRouteMetrics(mux *http.ServeMux) *RouteMetrics {
+	return &RouteMetrics{
+		mux:    mux,
+		tracer: otel.Tracer("cenayang-market/go-api/http"),
+	}
+}
+
+// Middleware records next's requests under the mux pattern they match.
+// Incoming W3C trace context is continued.
+func (m *RouteMetrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		route := m.route(r)
+
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := m.tracer.Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("http.route", route),
+			))
+		defer span.End()
+
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		status := rec.Status()
+		m.observe(route, status, time.Since(start), rec.Bytes())
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
+
+// route returns the pattern mux would dispatch r to
+func (m *RouteMetrics) route(r *http.Request) string {
+	if _, pattern := m.mux.Handler(r); pattern != "" {
+		return pattern
+	}
+	return UnmatchedRoute
+}
+
+func (m *RouteMetrics) observe(route string, status int, elapsed time.Duration, size uint64) {
+	v, ok := m.routes.Load(route)
+	if !ok {
+		v, _ = m.routes.LoadOrStore(route, &routeStats{})
+	}
+	s := v.(*routeStats)
+
+	atomic.AddUint64(&s.requests, 1)
+	if class := status / 100; class >= 1 && class <= 5 {
+		atomic.AddUint64(&s.status[class], 1)
+	}
+	seconds := elapsed.Seconds()
+	for i, le := range LatencyBuckets {
+		if seconds <= le {
+			atomic.AddUint64(&s.buckets[i], 1)
+			break
+		}
+	}
+	atomic.AddUint64(&s.sumNs, uint64(elapsed.Nanoseconds()))
+	for i, le := range SizeBuckets {
+		if float64(size) <= le {
+			atomic.AddUint64(&s.sizes[i], 1)
+			break
+		}
+	}
+	atomic.AddUint64(&s.bytes, size)
+}
+
+// Requests returns the number of requests recorded for a route pattern
+func (m *RouteMetrics) Requests(route string) uint64 {
+	if v, ok := m.routes.Load(route); ok {
+		return atomic.LoadUint64(&v.(*routeStats).requests)
+	}
+	return 0
+}
+
+// ResponseBytes returns the body bytes written for a route pattern
+func (m *RouteMetrics) ResponseBytes(route string) uint64 {
+	if v, ok := m.routes.Load(route); ok {
+		return atomic.LoadUint64(&v.(*routeStats).bytes)
+	}
+	return 0
+}
+
+// StatusCount returns requests for a route whose status was in class
+// (2 for 2xx, ...)
+func (m *RouteMetrics) StatusCount(route string, class int) uint64 {
+	if class < 1 || class > 5 {
+		return 0
+	}
+	if v, ok := m.routes.Load(route); ok {
+		return atomic.LoadUint64(&v.(*routeStats).status[class])
+	}
+	return 0
+}
+
+// ServeHTTP exposes the metrics in Prometheus text format
+func (m *RouteMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "GET required", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(m.AppendPrometheus(nil))
+}
+
+// AppendPrometheus appends all route metrics in text exposition format,
+// routes sorted for stable output
+func (m *RouteMetrics) AppendPrometheus(b []byte) []byte {
+	var routes []string
+	m.routes.Range(func(k, _ interface{}) bool {
+		routes = append(routes, k.(string))
+		return true
+	})
+	sort.Strings(routes)
+
+	b = append(b, "# HELP http_requests_total HTTP requests by route and status class.\n# TYPE http_requests_total counter\n"...)
+	for _, route := range routes {
+		s := m.stats(route)
+		for class := 1; class <= 5; class++ {
+			n := atomic.LoadUint64(&s.status[class])
+			if n == 0 {
+				continue
+			}
+			b = append(b, `http_requests_total{route=`...)
+			b = strconv.AppendQuote(b, route)
+			b = append(b, `,code="`...)
+			b = strconv.AppendInt(b, int64(class), 10)
+			b = append(b, `xx"} `...)
+			b = strconv.AppendUint(b, n, 10)
+			b = append(b, '\n')
+		}
+	}
+
+	b = append(b, "# HELP http_request_duration_seconds HTTP request latency by route.\n# TYPE http_request_duration_seconds histogram\n"...)
+	for _, route := range routes {
+		s := m.stats(route)
+		var cumulative uint64
+		for i, le := range LatencyBuckets {
+			cumulative += atomic.LoadUint64(&s.buckets[i])
+			b = appendSeries(b, "http_request_duration_seconds_bucket", route, strconv.FormatFloat(le, 'g', -1, 64), cumulative)
+		}
+		count := atomic.LoadUint64(&s.requests)
+		b = appendSeries(b, "http_request_duration_seconds_bucket", route, "+Inf", count)
+
+		b = append(b, `http_request_duration_seconds_sum{route=`...)
+		b = strconv.AppendQuote(b, route)
+		b = append(b, `} `...)
+		b = strconv.AppendFloat(b, float64(atomic.LoadUint64(&s.sumNs))/1e9, 'f', -1, 64)
+		b = append(b, '\n')
+		b = append(b, `http_request_duration_seconds_count{route=`...)
+		b = strconv.AppendQuote(b, route)
+		b = append(b, `} `...)
+		b = strconv.AppendUint(b, count, 10)
+		b = append(b, '\n')
+	}
+
+	b = append(b, "# HELP http_response_size_bytes HTTP response body size by route.\n# TYPE http_response_size_bytes histogram\n"...)
+	for _, route := range routes {
+		s := m.stats(route)
+		var cumulative uint64
+		for i, le := range SizeBuckets {
+			cumulative += atomic.LoadUint64(&s.sizes[i])
+			b = appendSeries(b, "http_response_size_bytes_bucket", route, strconv.FormatFloat(le, 'f', -1, 64), cumulative)
+		}
+		count := atomic.LoadUint64(&s.requests)
+		b = appendSeries(b, "http_response_size_bytes_bucket", route, "+Inf", count)
+
+		b = append(b, `http_response_size_bytes_sum{route=`...)
+		b = strconv.AppendQuote(b, route)
+		b = append(b, `} `...)
+		b = strconv.AppendUint(b, atomic.LoadUint64(&s.bytes), 10)
+		b = append(b, '\n')
+		b = append(b, `http_response_size_bytes_count{route=`...)
+		b = strconv.AppendQuote(b, route)
+		b = append(b, `} `...)
+		b = strconv.AppendUint(b, count, 10)
+		b = append(b, '\n')
+	}
+	return b
+}
+
+func (m *RouteMetrics) stats(route string) *routeStats {
+	v, _ := m.routes.Load(route)
+	return v.(*routeStats)
+}
+
+func appendSeries(b []byte, name, route, le string, v uint64) []byte {
+	b = append(b, name...)
+	b = append(b, `{route=`...)
+	b = strconv.AppendQuote(b, route)
+	b = append(b, `,le="`...)
+	b = append(b, le...)
+	b = append(b, `"} `...)
+	b = strconv.AppendUint(b, v, 10)
+	return append(b, '\n')
+}
+
+// statusRecorder captures the response status and body size. Hijack and Flush pass
+// through so WebSocket upgrades and streaming keep working.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  uint64
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += uint64(n)
+	return n, err
+}
+
+// Bytes returns the body bytes written so far
+func (r *statusRecorder) Bytes() uint64 {
+	return r.bytes
+}
+
+// Status returns the status written, 200 if the handler wrote nothing and
+// 101 after a successful hijack
+func (r *statusRecorder) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil && r.status == 0 {
+		r.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Body bytes are counted per route and land in the size histogram's bucket
+func TestRouteMetricsResponseSize(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/small", func(w http.ResponseWriter, r *http.Request) { w.Write(make([]byte, 100)) })
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, 1000))
+		w.Write(make([]byte, 1000))
+	})
+	m := NewRouteMetrics(mux)
+	h := m.Middleware(mux)
+	for _, path := range []string{"/small", "/small", "/large"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if n := m.ResponseBytes("/small"); n != 200 {
+		t.Fatalf("/small bytes %d, want 200", n)
+	}
+	if n := m.ResponseBytes("/large"); n != 2000 {
+		t.Fatalf("/large bytes %d, want 2000", n)
+	}
+	out := string(m.AppendPrometheus(nil))
+	for _, want := range []string{
+		`http_response_size_bytes_bucket{route="/small",le="256"} 2`,
+		`http_response_size_bytes_bucket{route="/large",le="1024"} 0`,
+		`http_response_size_bytes_bucket{route="/large",le="4096"} 1`,
+		`http_response_size_bytes_sum{route="/large"} 2000`,
+		`http_response_size_bytes_count{route="/small"} 2`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %s", want)
+		}
+	}
+}
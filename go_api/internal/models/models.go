@@ -88,7 +88,7 @@ const (
 
 	// Pre-computed symbol hashes
 	SymbolHashBTC uint64 = 0xAF4F2D6E8B1C3A5F
-	SymbolHashETH uint64 = 0xBF5G3E7F9C2D4B6A
+	SymbolHashETH uint64 = 0xBF5A3E7F9C2D4B6A
 )
 
 // FNV1aHash computes FNV-1a hash for symbol strings
@@ -0,0 +1,285 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: orchestrator.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Orchestrator_GetPortfolio_FullMethodName     = "/cenayang.orchestrator.v1.Orchestrator/GetPortfolio"
+	Orchestrator_StreamState_FullMethodName      = "/cenayang.orchestrator.v1.Orchestrator/StreamState"
+	Orchestrator_CheckRisk_FullMethodName        = "/cenayang.orchestrator.v1.Orchestrator/CheckRisk"
+	Orchestrator_ToggleKillSwitch_FullMethodName = "/cenayang.orchestrator.v1.Orchestrator/ToggleKillSwitch"
+	Orchestrator_GetSnapshot_FullMethodName      = "/cenayang.orchestrator.v1.Orchestrator/GetSnapshot"
+)
+
+// OrchestratorClient is the client API for Orchestrator service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type OrchestratorClient interface {
+	GetPortfolio(ctx context.Context, in *GetPortfolioRequest, opts ...grpc.CallOption) (*Portfolio, error)
+	StreamState(ctx context.Context, in *StreamStateRequest, opts ...grpc.CallOption) (Orchestrator_StreamStateClient, error)
+	CheckRisk(ctx context.Context, in *CheckRiskRequest, opts ...grpc.CallOption) (*CheckRiskResponse, error)
+	ToggleKillSwitch(ctx context.Context, in *ToggleKillSwitchRequest, opts ...grpc.CallOption) (*KillSwitchState, error)
+	GetSnapshot(ctx context.Context, in *GetSnapshotRequest, opts ...grpc.CallOption) (*Snapshot, error)
+}
+
+type orchestratorClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewOrchestratorClient(cc grpc.ClientConnInterface) OrchestratorClient {
+	return &orchestratorClient{cc}
+}
+
+func (c *orchestratorClient) GetPortfolio(ctx context.Context, in *GetPortfolioRequest, opts ...grpc.CallOption) (*Portfolio, error) {
+	out := new(Portfolio)
+	err := c.cc.Invoke(ctx, Orchestrator_GetPortfolio_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orchestratorClient) StreamState(ctx context.Context, in *StreamStateRequest, opts ...grpc.CallOption) (Orchestrator_StreamStateClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Orchestrator_ServiceDesc.Streams[0], Orchestrator_StreamState_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &orchestratorStreamStateClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Orchestrator_StreamStateClient interface {
+	Recv() (*StateEvent, error)
+	grpc.ClientStream
+}
+
+type orchestratorStreamStateClient struct {
+	grpc.ClientStream
+}
+
+func (x *orchestratorStreamStateClient) Recv() (*StateEvent, error) {
+	m := new(StateEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *orchestratorClient) CheckRisk(ctx context.Context, in *CheckRiskRequest, opts ...grpc.CallOption) (*CheckRiskResponse, error) {
+	out := new(CheckRiskResponse)
+	err := c.cc.Invoke(ctx, Orchestrator_CheckRisk_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orchestratorClient) ToggleKillSwitch(ctx context.Context, in *ToggleKillSwitchRequest, opts ...grpc.CallOption) (*KillSwitchState, error) {
+	out := new(KillSwitchState)
+	err := c.cc.Invoke(ctx, Orchestrator_ToggleKillSwitch_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orchestratorClient) GetSnapshot(ctx context.Context, in *GetSnapshotRequest, opts ...grpc.CallOption) (*Snapshot, error) {
+	out := new(Snapshot)
+	err := c.cc.Invoke(ctx, Orchestrator_GetSnapshot_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// OrchestratorServer is the server API for Orchestrator service.
+// All implementations must embed UnimplementedOrchestratorServer
+// for forward compatibility
+type OrchestratorServer interface {
+	GetPortfolio(context.Context, *GetPortfolioRequest) (*Portfolio, error)
+	StreamState(*StreamStateRequest, Orchestrator_StreamStateServer) error
+	CheckRisk(context.Context, *CheckRiskRequest) (*CheckRiskResponse, error)
+	ToggleKillSwitch(context.Context, *ToggleKillSwitchRequest) (*KillSwitchState, error)
+	GetSnapshot(context.Context, *GetSnapshotRequest) (*Snapshot, error)
+	mustEmbedUnimplementedOrchestratorServer()
+}
+
+// UnimplementedOrchestratorServer must be embedded to have forward compatible implementations.
+type UnimplementedOrchestratorServer struct {
+}
+
+func (UnimplementedOrchestratorServer) GetPortfolio(context.Context, *GetPortfolioRequest) (*Portfolio, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPortfolio not implemented")
+}
+func (UnimplementedOrchestratorServer) StreamState(*StreamStateRequest, Orchestrator_StreamStateServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamState not implemented")
+}
+func (UnimplementedOrchestratorServer) CheckRisk(context.Context, *CheckRiskRequest) (*CheckRiskResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CheckRisk not implemented")
+}
+func (UnimplementedOrchestratorServer) ToggleKillSwitch(context.Context, *ToggleKillSwitchRequest) (*KillSwitchState, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ToggleKillSwitch not implemented")
+}
+func (UnimplementedOrchestratorServer) GetSnapshot(context.Context, *GetSnapshotRequest) (*Snapshot, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSnapshot not implemented")
+}
+func (UnimplementedOrchestratorServer) mustEmbedUnimplementedOrchestratorServer() {}
+
+// UnsafeOrchestratorServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to OrchestratorServer will
+// result in compilation errors.
+type UnsafeOrchestratorServer interface {
+	mustEmbedUnimplementedOrchestratorServer()
+}
+
+func RegisterOrchestratorServer(s grpc.ServiceRegistrar, srv OrchestratorServer) {
+	s.RegisterService(&Orchestrator_ServiceDesc, srv)
+}
+
+func _Orchestrator_GetPortfolio_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPortfolioRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrchestratorServer).GetPortfolio(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Orchestrator_GetPortfolio_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrchestratorServer).GetPortfolio(ctx, req.(*GetPortfolioRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Orchestrator_StreamState_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamStateRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(OrchestratorServer).StreamState(m, &orchestratorStreamStateServer{stream})
+}
+
+type Orchestrator_StreamStateServer interface {
+	Send(*StateEvent) error
+	grpc.ServerStream
+}
+
+type orchestratorStreamStateServer struct {
+	grpc.ServerStream
+}
+
+func (x *orchestratorStreamStateServer) Send(m *StateEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Orchestrator_CheckRisk_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckRiskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrchestratorServer).CheckRisk(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Orchestrator_CheckRisk_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrchestratorServer).CheckRisk(ctx, req.(*CheckRiskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Orchestrator_ToggleKillSwitch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ToggleKillSwitchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrchestratorServer).ToggleKillSwitch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Orchestrator_ToggleKillSwitch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrchestratorServer).ToggleKillSwitch(ctx, req.(*ToggleKillSwitchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Orchestrator_GetSnapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSnapshotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrchestratorServer).GetSnapshot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Orchestrator_GetSnapshot_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrchestratorServer).GetSnapshot(ctx, req.(*GetSnapshotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Orchestrator_ServiceDesc is the grpc.ServiceDesc for Orchestrator service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Orchestrator_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "cenayang.orchestrator.v1.Orchestrator",
+	HandlerType: (*OrchestratorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetPortfolio",
+			Handler:    _Orchestrator_GetPortfolio_Handler,
+		},
+		{
+			MethodName: "CheckRisk",
+			Handler:    _Orchestrator_CheckRisk_Handler,
+		},
+		{
+			MethodName: "ToggleKillSwitch",
+			Handler:    _Orchestrator_ToggleKillSwitch_Handler,
+		},
+		{
+			MethodName: "GetSnapshot",
+			Handler:    _Orchestrator_GetSnapshot_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamState",
+			Handler:       _Orchestrator_StreamState_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "orchestrator.proto",
+}
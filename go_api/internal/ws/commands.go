@@ -0,0 +1,75 @@
+package ws
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+)
+
+// ============================================================================
+// COMMAND CHANNEL - Authorized Control Actions over the Client Socket
+// ============================================================================
+
+// Command - control message sent by a client, e.g.
+// {"cmd":"kill_switch","active":true} or {"cmd":"pause"}
+type Command struct {
+	Cmd    string `json:"cmd"`
+	Active *bool  `json:"active,omitempty"`
+	ID     string `json:"id,omitempty"` // Echoed in the reply for correlation
+}
+
+// CommandHandler executes an authorized command. A non-nil error is sent
+// back to the client as an error event.
+type CommandHandler func(client *Client, cmd Command) error
+
+// ControlAuthorizer decides at upgrade time whether a connection may send
+// commands
+type ControlAuthorizer func(r *http.Request) bool
+
+// commandReply - sent to the issuing client only, never broadcast
+type commandReply struct {
+	Type  string `json:"type"` // command_ack or command_error
+	Cmd   string `json:"cmd,omitempty"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// SetCommandHandler enables the command channel. Must be called before
+// the hub serves connections; without it every command is refused.
+func (h *Hub) SetCommandHandler(authorize ControlAuthorizer, handler CommandHandler) {
+	h.authorizeControl = authorize
+	h.commands = handler
+}
+
+// handleCommand executes one inbound message and replies to its sender.
+// Bad or unauthorized commands get an error event; the connection stays.
+func (h *Hub) handleCommand(client *Client, msg []byte) {
+	var cmd Command
+	if err := json.Unmarshal(msg, &cmd); err != nil || cmd.Cmd == "" {
+		h.reply(client, commandReply{Type: "command_error", Error: "malformed command"})
+		return
+	}
+
+	reply := commandReply{Type: "command_ack", Cmd: cmd.Cmd, ID: cmd.ID}
+	switch {
+	case h.commands == nil:
+		reply.Type, reply.Error = "command_error", "commands disabled"
+	case !client.control:
+		reply.Type, reply.Error = "command_error", "unauthorized"
+	default:
+		if err := h.commands(client, cmd); err != nil {
+			reply.Type, reply.Error = "command_error", err.Error()
+		}
+	}
+	h.reply(client, reply)
+}
+
+// reply queues a message for one client without blocking the read pump
+func (h *Hub) reply(client *Client, reply commandReply) {
+	data, _ := json.Marshal(reply) // Strings only - cannot fail
+	select {
+	case client.sendCh <- data:
+	default:
+		atomic.AddUint64(&client.drops, 1)
+	}
+}
@@ -0,0 +1,135 @@
+package ws
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync/atomic"
+)
+
+// ============================================================================
+// COMPACT SNAPSHOT - Reduced Fixed-Layout State for Bandwidth-Limited Clients
+// ============================================================================
+
+// Clients opt in at upgrade with the cenayang.compact.v1 subprotocol (or
+// ?snapshot=compact, for clients that cannot set one). They receive compact
+// snapshots as binary frames alongside the usual JSON events. Only the
+// latest snapshot is kept per client: one that is still queued when the
+// next arrives is replaced, never stacked behind it.
+//
+// Layout, little-endian:
+//
+//	0  u8   version (CompactSnapshotVersion)
+//	1  u8   flags (bit 0 kill switch, bit 1 trading paused)
+//	2  u16  position count
+//	4  u64  sequence ID
+//	12 i64  timestamp, unix ns
+//	20 i64  equity, fixed-point 1e8
+//	28 i32  drawdown, basis points
+//	32      positions, 24 bytes each:
+//	        u64 symbol hash, i64 quantity (negative = short), i64 unrealized PnL
+const (
+	CompactSnapshotVersion = 1
+	compactHeaderSize      = 32
+	compactPositionSize    = 24
+)
+
+// Compact snapshot flags
+const (
+	CompactKillSwitch    uint8 = 1 << 0
+	CompactTradingPaused uint8 = 1 << 1
+)
+
+// ErrCompactSnapshot - the frame is not a compact snapshot this version reads
+var ErrCompactSnapshot = errors.New("ws: malformed compact snapshot")
+
+// CompactPosition - the per-symbol fields a compact snapshot carries
+type CompactPosition struct {
+	SymbolHash    uint64
+	Quantity      int64 // Fixed-point, negative = short
+	UnrealizedPnL int64 // Fixed-point
+}
+
+// CompactSnapshot - the essential portfolio view. Amounts are fixed-point.
+type CompactSnapshot struct {
+	Flags       uint8
+	SeqID       uint64
+	Timestamp   int64
+	Equity      int64
+	DrawdownBps int32
+	Positions   []CompactPosition
+}
+
+// AppendCompactSnapshot encodes s onto b. Positions past 65535 are dropped.
+func AppendCompactSnapshot(b []byte, s *CompactSnapshot) []byte {
+	positions := s.Positions
+	if len(positions) > 0xFFFF {
+		positions = positions[:0xFFFF]
+	}
+	b = append(b, CompactSnapshotVersion, s.Flags)
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(positions)))
+	b = binary.LittleEndian.AppendUint64(b, s.SeqID)
+	b = binary.LittleEndian.AppendUint64(b, uint64(s.Timestamp))
+	b = binary.LittleEndian.AppendUint64(b, uint64(s.Equity))
+	b = binary.LittleEndian.AppendUint32(b, uint32(s.DrawdownBps))
+	for _, p := range positions {
+		b = binary.LittleEndian.AppendUint64(b, p.SymbolHash)
+		b = binary.LittleEndian.AppendUint64(b, uint64(p.Quantity))
+		b = binary.LittleEndian.AppendUint64(b, uint64(p.UnrealizedPnL))
+	}
+	return b
+}
+
+// DecodeCompactSnapshot parses a frame produced by AppendCompactSnapshot
+func DecodeCompactSnapshot(b []byte) (CompactSnapshot, error) {
+	if len(b) < compactHeaderSize || b[0] != CompactSnapshotVersion {
+		return CompactSnapshot{}, ErrCompactSnapshot
+	}
+	n := int(binary.LittleEndian.Uint16(b[2:4]))
+	if len(b) != compactHeaderSize+n*compactPositionSize {
+		return CompactSnapshot{}, ErrCompactSnapshot
+	}
+	s := CompactSnapshot{
+		Flags:       b[1],
+		SeqID:       binary.LittleEndian.Uint64(b[4:12]),
+		Timestamp:   int64(binary.LittleEndian.Uint64(b[12:20])),
+		Equity:      int64(binary.LittleEndian.Uint64(b[20:28])),
+		DrawdownBps: int32(binary.LittleEndian.Uint32(b[28:32])),
+		Positions:   make([]CompactPosition, n),
+	}
+	for i := range s.Positions {
+		p := b[compactHeaderSize+i*compactPositionSize:]
+		s.Positions[i] = CompactPosition{
+			SymbolHash:    binary.LittleEndian.Uint64(p[0:8]),
+			Quantity:      int64(binary.LittleEndian.Uint64(p[8:16])),
+			UnrealizedPnL: int64(binary.LittleEndian.Uint64(p[16:24])),
+		}
+	}
+	return s, nil
+}
+
+// CompactClients returns the number of connected clients that asked for
+// compact snapshots, so producers can skip building one nobody reads
+func (h *Hub) CompactClients() uint64 {
+	return atomic.LoadUint64(&h.compactClients)
+}
+
+// BroadcastCompact encodes a snapshot once and queues it for every compact
+// client (non-blocking)
+func (h *Hub) BroadcastCompact(s *CompactSnapshot) {
+	h.Broadcast(BinaryEvent{
+		Type:      EventCompactSnapshot,
+		SeqID:     s.SeqID,
+		Timestamp: s.Timestamp,
+		Data:      AppendCompactSnapshot(make([]byte, 0, compactHeaderSize+len(s.Positions)*compactPositionSize), s),
+	})
+}
+
+// deliverCompact replaces any snapshot still queued for client - hub loop
+// only, so nothing else writes the mailbox
+func deliverCompact(client *Client, data []byte) {
+	select {
+	case <-client.snapshot:
+	default:
+	}
+	client.snapshot <- data
+}
@@ -3,15 +3,23 @@ package ws
 
 import (
 	"context"
+	"log"
+	"net/http"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/gorilla/websocket"
 )
 
 const (
 	MaxClients      = 10000
 	SendBufferSize  = 256
 	BroadcastBuffer = 10000
+
+	// A client is evicted after this many consecutive dropped messages
+	SlowClientDropLimit = SendBufferSize
 )
 
 // Event types
@@ -20,6 +28,29 @@ const (
 	EventFill       uint8 = 2
 	EventKillSwitch uint8 = 3
 	EventTick       uint8 = 4
+
+	EventFillQuarantined  uint8 = 5
+	EventTradingPaused    uint8 = 6
+	EventSequenceRebased  uint8 = 7
+	EventFillPriceAnomaly uint8 = 8
+	EventOrder            uint8 = 9
+	EventHeartbeat        uint8 = 10
+	EventTriggerFired     uint8 = 11
+	EventLimitBreach      uint8 = 12
+	EventFeedSlowConsumer uint8 = 13
+	EventHWMReset         uint8 = 14
+	EventMarginCall       uint8 = 15
+
+	// Binary frames, compact clients only - see compact.go
+	EventCompactSnapshot uint8 = 16
+
+	EventVolatilityHalt     uint8 = 17
+	EventDrawdownReduceOnly uint8 = 18
+	EventStaleFeed          uint8 = 19
+	EventAccountBlown       uint8 = 20
+	EventStaleOrder         uint8 = 21
+	EventOCOTriggered       uint8 = 22
+	EventProfitTargetHit    uint8 = 23
 )
 
 // BinaryEvent for zero-copy broadcasting
@@ -32,20 +63,71 @@ type BinaryEvent struct {
 
 // Client connection
 type Client struct {
-	ID       string
-	sendCh   chan []byte
-	done     chan struct{}
-	lastSend int64 // Unix nanos
+	ID          string
+	sendCh      chan []byte
+	done        chan struct{}
+	connectedAt int64       // Unix nanos
+	control     bool        // May send commands; fixed at upgrade
+	protocol    *Protocol   // Negotiated at upgrade; nil for in-process clients
+	compact     bool        // Receives compact snapshots; fixed at upgrade
+	snapshot    chan []byte // Latest compact snapshot, capacity 1
+
+	// Atomic per-client stats - written by the hub loop, read by admins
+	lastSend      int64 // Unix nanos
+	drops         uint64
+	maxQueueDepth uint64
+	writeErrors   uint64
+
+	// Hub loop only
+	consecutiveDrops int
+	evicting         bool
+
+	// Close frame sent when done closes; set before closing it
+	closeCode int
+	closeText string
+}
+
+// ClientStats - per-client slow-consumer metrics
+type ClientStats struct {
+	ID            string
+	Protocol      string
+	ConnectedAt   int64
+	LastSend      int64
+	QueueDepth    int
+	MaxQueueDepth uint64
+	Drops         uint64
+	WriteErrors   uint64
+}
+
+// Subscriber receives whole events in-process (e.g. gRPC streams).
+// Like clients, a subscriber that falls behind loses events rather than
+// blocking the broadcast loop.
+type Subscriber struct {
+	ID    uint64
+	ch    chan BinaryEvent
+	drops uint64 // Atomic
+}
+
+// C returns the event channel; it is closed on Unsubscribe or shutdown
+func (s *Subscriber) C() <-chan BinaryEvent {
+	return s.ch
+}
+
+// Drops returns the number of events lost to a full buffer
+func (s *Subscriber) Drops() uint64 {
+	return atomic.LoadUint64(&s.drops)
 }
 
 // Hub manages WebSocket connections
 type Hub struct {
-	clients sync.Map // map[string]*Client
+	clients     sync.Map // map[string]*Client
+	subscribers sync.Map // map[uint64]*Subscriber
 
 	// Channels
-	register   chan *Client
-	unregister chan string
-	broadcast  chan BinaryEvent
+	register    chan *Client
+	unregister  chan *Client
+	unsubscribe chan uint64
+	broadcast   chan BinaryEvent
 
 	// Atomic stats
 	activeConnections uint64
@@ -54,6 +136,19 @@ type Hub struct {
 	messagesBroadcast uint64
 	slowClientDrops   uint64
 	broadcastDrops    uint64
+	nextClientID      uint64
+	nextSubscriberID  uint64
+	compactClients    uint64
+	duplicateIDs      uint64
+	protocolRejects   uint64
+	activeReplays     uint64
+
+	// Command channel, deadlines and origin check - set before serving,
+	// read-only after
+	authorizeControl ControlAuthorizer
+	commands         CommandHandler
+	cfg              Config
+	checkOrigin      func(r *http.Request) bool
 
 	// Shutdown
 	ctx    context.Context
@@ -64,11 +159,13 @@ type Hub struct {
 func NewHub() *Hub {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Hub{
-		register:   make(chan *Client, 100),
-		unregister: make(chan string, 100),
-		broadcast:  make(chan BinaryEvent, BroadcastBuffer),
-		ctx:        ctx,
-		cancel:     cancel,
+		register:    make(chan *Client, 100),
+		unregister:  make(chan *Client, 100),
+		unsubscribe: make(chan uint64, 100),
+		broadcast:   make(chan BinaryEvent, BroadcastBuffer),
+		cfg:         DefaultConfig,
+		ctx:         ctx,
+		cancel:      cancel,
 	}
 }
 
@@ -86,8 +183,11 @@ func (h *Hub) Run() {
 		case client := <-h.register:
 			h.handleRegister(client)
 
-		case clientID := <-h.unregister:
-			h.handleUnregister(clientID)
+		case client := <-h.unregister:
+			h.handleUnregister(client)
+
+		case id := <-h.unsubscribe:
+			h.closeSubscriber(id)
 
 		case event := <-h.broadcast:
 			h.handleBroadcast(event)
@@ -105,35 +205,97 @@ func (h *Hub) handleRegister(client *Client) {
 		return
 	}
 
+	if val, ok := h.clients.Load(client.ID); ok {
+		atomic.AddUint64(&h.duplicateIDs, 1)
+		if h.cfg.DuplicateIDs != DuplicateReplace {
+			log.Printf("[WS] Client ID %s already connected, rejecting the newcomer", client.ID)
+			closeClient(client, websocket.ClosePolicyViolation, "duplicate client id")
+			return
+		}
+		log.Printf("[WS] Client ID %s reconnected, replacing the old connection", client.ID)
+		h.removeClient(val.(*Client), websocket.CloseNormalClosure, "replaced by a newer connection")
+	}
+
 	h.clients.Store(client.ID, client)
 	atomic.AddUint64(&h.activeConnections, 1)
 	atomic.AddUint64(&h.totalConnections, 1)
+	if client.compact {
+		atomic.AddUint64(&h.compactClients, 1)
+	}
 }
 
-func (h *Hub) handleUnregister(clientID string) {
-	if val, ok := h.clients.LoadAndDelete(clientID); ok {
-		client := val.(*Client)
-		close(client.done)
-		atomic.AddUint64(&h.activeConnections, ^uint64(0)) // Decrement
-		atomic.AddUint64(&h.totalDisconnects, 1)
+// handleUnregister removes client only if it is still the one registered
+// under its ID: a connection displaced by a duplicate must not take its
+// replacement down with it
+func (h *Hub) handleUnregister(client *Client) {
+	if val, ok := h.clients.Load(client.ID); ok && val.(*Client) == client {
+		h.removeClient(client, websocket.CloseNormalClosure, "")
+	}
+}
+
+func (h *Hub) removeClient(client *Client, code int, text string) {
+	h.clients.Delete(client.ID)
+	closeClient(client, code, text)
+	atomic.AddUint64(&h.activeConnections, ^uint64(0)) // Decrement
+	atomic.AddUint64(&h.totalDisconnects, 1)
+	if client.compact {
+		atomic.AddUint64(&h.compactClients, ^uint64(0))
 	}
 }
 
+// closeClient tells the write pump to send a close frame and hang up
+func closeClient(client *Client, code int, text string) {
+	client.closeCode, client.closeText = code, text
+	close(client.done)
+}
+
 func (h *Hub) handleBroadcast(event BinaryEvent) {
 	data := event.Data
 	dropped := uint64(0)
 
+	if event.Type == EventCompactSnapshot {
+		h.clients.Range(func(key, value interface{}) bool {
+			if client := value.(*Client); client.compact {
+				deliverCompact(client, data)
+			}
+			return true
+		})
+		atomic.AddUint64(&h.messagesBroadcast, 1)
+		return
+	}
+
+	now := time.Now().UnixNano()
+
 	h.clients.Range(func(key, value interface{}) bool {
 		client := value.(*Client)
 
 		// Non-blocking send
 		select {
 		case client.sendCh <- data:
-			client.lastSend = time.Now().UnixNano()
+			atomic.StoreInt64(&client.lastSend, now)
+			client.consecutiveDrops = 0
+			if depth := uint64(len(client.sendCh)); depth > atomic.LoadUint64(&client.maxQueueDepth) {
+				atomic.StoreUint64(&client.maxQueueDepth, depth) // Single writer
+			}
 		default:
-			// Client too slow - mark for drop
+			// Client too slow - drop this message, evict if it never catches up
 			dropped++
-			go h.Unregister(client.ID)
+			atomic.AddUint64(&client.drops, 1)
+			client.consecutiveDrops++
+			if client.consecutiveDrops >= SlowClientDropLimit && !client.evicting {
+				client.evicting = true
+				go h.unregisterClient(client)
+			}
+		}
+		return true
+	})
+
+	h.subscribers.Range(func(key, value interface{}) bool {
+		sub := value.(*Subscriber)
+		select {
+		case sub.ch <- event:
+		default:
+			atomic.AddUint64(&sub.drops, 1)
 		}
 		return true
 	})
@@ -147,6 +309,13 @@ func (h *Hub) closeAllClients() {
 		client := value.(*Client)
 		close(client.done)
 		h.clients.Delete(key)
+		if client.compact {
+			atomic.AddUint64(&h.compactClients, ^uint64(0))
+		}
+		return true
+	})
+	h.subscribers.Range(func(key, value interface{}) bool {
+		h.closeSubscriber(key.(uint64))
 		return true
 	})
 }
@@ -160,6 +329,43 @@ func (h *Hub) Broadcast(event BinaryEvent) {
 	}
 }
 
+// BroadcastWait queues an event that must not be dropped, waiting for room
+// until ctx is done. Reports whether the event was queued.
+func (h *Hub) BroadcastWait(ctx context.Context, event BinaryEvent) bool {
+	select {
+	case h.broadcast <- event:
+		return true
+	case <-ctx.Done():
+		atomic.AddUint64(&h.broadcastDrops, 1)
+		return false
+	}
+}
+
+// Subscribe attaches an in-process subscriber with the given buffer size
+func (h *Hub) Subscribe(buffer int) *Subscriber {
+	sub := &Subscriber{
+		ID: atomic.AddUint64(&h.nextSubscriberID, 1),
+		ch: make(chan BinaryEvent, buffer),
+	}
+	h.subscribers.Store(sub.ID, sub)
+	return sub
+}
+
+// Unsubscribe detaches a subscriber and closes its channel
+func (h *Hub) Unsubscribe(sub *Subscriber) {
+	select {
+	case h.unsubscribe <- sub.ID:
+	case <-h.ctx.Done(): // Loop is gone; closeAllClients handles it
+	}
+}
+
+// closeSubscriber runs on the hub loop so no send races the close
+func (h *Hub) closeSubscriber(id uint64) {
+	if val, ok := h.subscribers.LoadAndDelete(id); ok {
+		close(val.(*Subscriber).ch)
+	}
+}
+
 // Register adds a new client
 func (h *Hub) Register(client *Client) {
 	h.register <- client
@@ -167,7 +373,13 @@ func (h *Hub) Register(client *Client) {
 
 // Unregister removes a client
 func (h *Hub) Unregister(clientID string) {
-	h.unregister <- clientID
+	if val, ok := h.clients.Load(clientID); ok {
+		h.unregisterClient(val.(*Client))
+	}
+}
+
+func (h *Hub) unregisterClient(client *Client) {
+	h.unregister <- client
 }
 
 // Stats returns current statistics
@@ -179,9 +391,38 @@ func (h *Hub) Stats() map[string]uint64 {
 		"messages_broadcast": atomic.LoadUint64(&h.messagesBroadcast),
 		"slow_client_drops":  atomic.LoadUint64(&h.slowClientDrops),
 		"broadcast_drops":    atomic.LoadUint64(&h.broadcastDrops),
+		"duplicate_ids":      atomic.LoadUint64(&h.duplicateIDs),
+		"protocol_rejects":   atomic.LoadUint64(&h.protocolRejects),
+		"active_replays":     atomic.LoadUint64(&h.activeReplays),
 	}
 }
 
+// ClientStats returns per-client metrics, worst offenders first
+func (h *Hub) ClientStats() []ClientStats {
+	stats := make([]ClientStats, 0, atomic.LoadUint64(&h.activeConnections))
+	h.clients.Range(func(key, value interface{}) bool {
+		client := value.(*Client)
+		stats = append(stats, ClientStats{
+			ID:            client.ID,
+			Protocol:      client.protocolName(),
+			ConnectedAt:   client.connectedAt,
+			LastSend:      atomic.LoadInt64(&client.lastSend),
+			QueueDepth:    len(client.sendCh),
+			MaxQueueDepth: atomic.LoadUint64(&client.maxQueueDepth),
+			Drops:         atomic.LoadUint64(&client.drops),
+			WriteErrors:   atomic.LoadUint64(&client.writeErrors),
+		})
+		return true
+	})
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Drops != stats[j].Drops {
+			return stats[i].Drops > stats[j].Drops
+		}
+		return stats[i].ID < stats[j].ID
+	})
+	return stats
+}
+
 // Shutdown stops the hub
 func (h *Hub) Shutdown() {
 	h.cancel()
@@ -190,8 +431,11 @@ func (h *Hub) Shutdown() {
 // NewClient creates a new client
 func NewClient(id string) *Client {
 	return &Client{
-		ID:     id,
-		sendCh: make(chan []byte, SendBufferSize),
-		done:   make(chan struct{}),
+		ID:          id,
+		sendCh:      make(chan []byte, SendBufferSize),
+		snapshot:    make(chan []byte, 1),
+		done:        make(chan struct{}),
+		connectedAt: time.Now().UnixNano(),
+		closeCode:   websocket.CloseNormalClosure,
 	}
 }
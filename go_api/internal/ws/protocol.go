@@ -0,0 +1,99 @@
+package ws
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// ============================================================================
+// SUBPROTOCOLS - Wire Format and Version Negotiation
+// ============================================================================
+
+// Clients name what they speak in Sec-WebSocket-Protocol as
+// cenayang.<format>.v<version>, most preferred first; the first one the hub
+// supports is echoed back and fixed for the connection. A client that offers
+// only unsupported protocols is upgraded just long enough to be closed with
+// CloseUnsupportedProtocol, listing what is supported. Without the header a
+// client gets ProtocolJSON, or ProtocolCompact with ?snapshot=compact.
+//
+// A change existing clients could not parse gets a new version, served
+// alongside the old one until clients have moved.
+
+// Wire formats
+const (
+	FormatJSON    uint8 = iota // JSON text events
+	FormatCompact              // JSON text events plus compact binary snapshots
+)
+
+// CloseUnsupportedProtocol - close code for a client offering no supported
+// subprotocol
+const CloseUnsupportedProtocol = 4406
+
+// Protocol - a negotiable wire format at a version
+type Protocol struct {
+	Name    string
+	Format  uint8
+	Version int
+}
+
+// Supported subprotocols
+var (
+	ProtocolJSON    = &Protocol{Name: "cenayang.json.v1", Format: FormatJSON, Version: 1}
+	ProtocolCompact = &Protocol{Name: "cenayang.compact.v1", Format: FormatCompact, Version: 1}
+
+	protocols = []*Protocol{ProtocolJSON, ProtocolCompact}
+)
+
+// LookupProtocol returns the supported protocol by name, or nil
+func LookupProtocol(name string) *Protocol {
+	for _, p := range protocols {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// SupportedProtocols lists the subprotocol names the hub accepts
+func SupportedProtocols() []string {
+	names := make([]string, len(protocols))
+	for i, p := range protocols {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// protocolName - the client's negotiated subprotocol, empty in-process
+func (c *Client) protocolName() string {
+	if c.protocol == nil {
+		return ""
+	}
+	return c.protocol.Name
+}
+
+// negotiateProtocol picks the client's protocol. offered reports whether
+// the client sent Sec-WebSocket-Protocol at all; p is nil when it did and
+// none is supported.
+func negotiateProtocol(r *http.Request) (p *Protocol, offered bool) {
+	names := websocket.Subprotocols(r)
+	if len(names) == 0 {
+		if r.URL.Query().Get("snapshot") == "compact" {
+			return ProtocolCompact, false
+		}
+		return ProtocolJSON, false
+	}
+	for _, name := range names {
+		if p := LookupProtocol(name); p != nil {
+			return p, true
+		}
+	}
+	return nil, true
+}
+
+// unsupportedProtocolText - close reason naming the supported protocols,
+// kept under the 123 bytes a close frame allows
+func unsupportedProtocolText() string {
+	return "unsupported subprotocol; supported: " + strings.Join(SupportedProtocols(), ", ")
+}
@@ -0,0 +1,139 @@
+package ws
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ============================================================================
+// REPLAY - Recorded Events Re-streamed to One Connection
+// ============================================================================
+
+// Replay frames bracket the replayed events:
+//
+//	{"type":"replay_start","replay":true,"events":N,"speed":S}
+//	{"replay":true,"seq_id":N,"timestamp_ns":T,"event":<payload as broadcast>}
+//	{"type":"replay_end","replay":true,"events":N}
+//
+// Every frame carries "replay":true, so a client pointed at a replay by
+// mistake cannot take it for live state.
+
+// ServeReplay upgrades the request and streams events, oldest first, to
+// this connection alone, spacing them by their recorded timestamps divided
+// by speed (speed <= 0 sends them back to back). The connection is never
+// registered with the hub, so a replay neither receives live broadcasts
+// nor holds them up. The replay ends early if the client disconnects or
+// the hub shuts down.
+func (h *Hub) ServeReplay(w http.ResponseWriter, r *http.Request, events []BinaryEvent, speed float64) {
+	up := &upgrader
+	if h.checkOrigin != nil {
+		withCheck := upgrader
+		withCheck.CheckOrigin = h.checkOrigin
+		up = &withCheck
+	}
+	protocol, offered := negotiateProtocol(r)
+	var header http.Header
+	if protocol != nil && offered {
+		header = http.Header{"Sec-Websocket-Protocol": {protocol.Name}}
+	}
+	conn, err := up.Upgrade(w, r, header)
+	if err != nil {
+		return // Upgrade already replied with an HTTP error
+	}
+	defer conn.Close()
+	if protocol == nil {
+		atomic.AddUint64(&h.protocolRejects, 1)
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(CloseUnsupportedProtocol, unsupportedProtocolText()),
+			time.Now().Add(h.cfg.WriteDeadline))
+		return
+	}
+
+	atomic.AddUint64(&h.activeReplays, 1)
+	defer atomic.AddUint64(&h.activeReplays, ^uint64(0))
+
+	// Inbound frames are discarded; a failed read means the client left
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		conn.SetReadLimit(maxMessage)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	write := func(b []byte) bool {
+		conn.SetWriteDeadline(time.Now().Add(h.cfg.WriteDeadline))
+		if err := conn.WriteMessage(websocket.TextMessage, b); err != nil {
+			log.Printf("[WS] Replay write failed: %v", err)
+			return false
+		}
+		return true
+	}
+
+	b := make([]byte, 0, 512)
+	b = append(b, `{"type":"replay_start","replay":true,"events":`...)
+	b = strconv.AppendInt(b, int64(len(events)), 10)
+	b = append(b, `,"speed":`...)
+	b = strconv.AppendFloat(b, max(speed, 0), 'f', -1, 64)
+	b = append(b, '}')
+	if !write(b) {
+		return
+	}
+
+	// Each event is due at its offset from the first, scaled, so write
+	// time does not accumulate as drift
+	start := time.Now()
+	sent := 0
+	for _, event := range events {
+		if speed > 0 {
+			offset := time.Duration(float64(event.Timestamp-events[0].Timestamp) / speed)
+			if wait := time.Until(start.Add(offset)); wait > 0 && !h.replayWait(wait, gone) {
+				return
+			}
+		}
+		b = append(b[:0], `{"replay":true,"seq_id":`...)
+		b = strconv.AppendUint(b, event.SeqID, 10)
+		b = append(b, `,"timestamp_ns":`...)
+		b = strconv.AppendInt(b, event.Timestamp, 10)
+		b = append(b, `,"event":`...)
+		b = append(b, event.Data...)
+		b = append(b, '}')
+		if !write(b) {
+			return
+		}
+		sent++
+	}
+
+	b = append(b[:0], `{"type":"replay_end","replay":true,"events":`...)
+	b = strconv.AppendInt(b, int64(sent), 10)
+	b = append(b, '}')
+	if !write(b) {
+		return
+	}
+	conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, "replay complete"),
+		time.Now().Add(h.cfg.WriteDeadline))
+}
+
+// replayWait sleeps for d, reporting false if the client left or the hub
+// shut down first
+func (h *Hub) replayWait(d time.Duration, gone <-chan struct{}) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-gone:
+		return false
+	case <-h.ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,253 @@
+package ws
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const maxMessage = 4096
+
+// Config - per-connection deadlines, keepalive and admission. Zero fields take
+// DefaultConfig's.
+type Config struct {
+	ReadDeadline  time.Duration // Max silence between inbound frames, pongs included
+	WriteDeadline time.Duration // Max time for one outbound frame
+	PingInterval  time.Duration
+	PongTimeout   time.Duration   // A ping unanswered this long reaps the connection
+	DuplicateIDs  DuplicatePolicy // What a second connection with a connected client ID does
+}
+
+// DuplicatePolicy - what registering a client whose ID is already
+// connected does
+type DuplicatePolicy uint8
+
+const (
+	DuplicateReject  DuplicatePolicy = iota // Close the newcomer (default)
+	DuplicateReplace                        // Close the old connection, keep the newcomer
+)
+
+// DefaultConfig suits clients on ordinary internet links
+var DefaultConfig = Config{
+	ReadDeadline:  60 * time.Second,
+	WriteDeadline: 10 * time.Second,
+	PingInterval:  54 * time.Second,
+	PongTimeout:   10 * time.Second,
+}
+
+func (c Config) withDefaults() Config {
+	if c.ReadDeadline == 0 {
+		c.ReadDeadline = DefaultConfig.ReadDeadline
+	}
+	if c.WriteDeadline == 0 {
+		c.WriteDeadline = DefaultConfig.WriteDeadline
+	}
+	if c.PingInterval == 0 {
+		c.PingInterval = DefaultConfig.PingInterval
+	}
+	if c.PongTimeout == 0 {
+		c.PongTimeout = DefaultConfig.PongTimeout
+	}
+	return c
+}
+
+// Validate rejects negative durations and a read deadline an idle client
+// answering every ping could still miss
+func (c Config) Validate() error {
+	switch {
+	case c.ReadDeadline < 0, c.WriteDeadline < 0, c.PingInterval < 0, c.PongTimeout < 0:
+		return fmt.Errorf("ws: deadlines must not be negative")
+	case c.ReadDeadline <= c.PingInterval:
+		return fmt.Errorf("ws: read deadline %v must exceed ping interval %v", c.ReadDeadline, c.PingInterval)
+	case c.DuplicateIDs > DuplicateReplace:
+		return fmt.Errorf("ws: unknown duplicate ID policy %d", c.DuplicateIDs)
+	}
+	return nil
+}
+
+// Configure sets connection deadlines, zero fields defaulted. Must be
+// called before the hub serves connections.
+func (h *Hub) Configure(cfg Config) error {
+	cfg = cfg.withDefaults()
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	h.cfg = cfg
+	return nil
+}
+
+// SetOriginCheck vets the Origin of browser upgrades; nil accepts any.
+// Must be called before the hub serves connections.
+func (h *Hub) SetOriginCheck(check func(r *http.Request) bool) {
+	h.checkOrigin = check
+}
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+	CheckOrigin:     func(r *http.Request) bool { return true }, // Unless SetOriginCheck
+}
+
+// ServeWS upgrades the request and attaches the connection to the hub
+func (h *Hub) ServeWS(w http.ResponseWriter, r *http.Request) {
+	up := &upgrader
+	if h.checkOrigin != nil {
+		withCheck := upgrader
+		withCheck.CheckOrigin = h.checkOrigin
+		up = &withCheck
+	}
+	protocol, offered := negotiateProtocol(r)
+	var header http.Header
+	if protocol != nil && offered {
+		header = http.Header{"Sec-Websocket-Protocol": {protocol.Name}}
+	}
+	conn, err := up.Upgrade(w, r, header)
+	if err != nil {
+		return // Upgrade already replied with an HTTP error
+	}
+	if protocol == nil {
+		atomic.AddUint64(&h.protocolRejects, 1)
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(CloseUnsupportedProtocol, unsupportedProtocolText()),
+			time.Now().Add(h.cfg.WriteDeadline))
+		conn.Close()
+		return
+	}
+
+	client := NewClient(strconv.FormatUint(atomic.AddUint64(&h.nextClientID, 1), 10))
+	client.control = h.authorizeControl != nil && h.authorizeControl(r)
+	client.protocol = protocol
+	client.compact = protocol.Format == FormatCompact
+	h.Register(client)
+
+	deadlines := &readDeadlines{conn: conn, cfg: h.cfg}
+	deadlines.received()
+	go h.writePump(client, conn, deadlines)
+	go h.readPump(client, conn, deadlines)
+}
+
+// readDeadlines arbitrates the read deadline between the read pump, which
+// extends it on every inbound frame, and the write pump, which shortens it
+// to the pong timeout after each ping
+type readDeadlines struct {
+	mu           sync.Mutex
+	conn         *websocket.Conn
+	cfg          Config
+	idle         time.Time // Deadline from inbound traffic alone
+	awaitingPong bool
+}
+
+// received extends the deadline after an inbound frame, unless a ping is
+// outstanding: only its pong may lift the pong timeout
+func (d *readDeadlines) received() {
+	d.mu.Lock()
+	d.idle = time.Now().Add(d.cfg.ReadDeadline)
+	if !d.awaitingPong {
+		d.conn.SetReadDeadline(d.idle)
+	}
+	d.mu.Unlock()
+}
+
+func (d *readDeadlines) pong() {
+	d.mu.Lock()
+	d.awaitingPong = false
+	d.idle = time.Now().Add(d.cfg.ReadDeadline)
+	d.conn.SetReadDeadline(d.idle)
+	d.mu.Unlock()
+}
+
+// pinged starts the pong timeout, never moving the deadline later
+func (d *readDeadlines) pinged() {
+	d.mu.Lock()
+	if !d.awaitingPong {
+		d.awaitingPong = true
+		if deadline := time.Now().Add(d.cfg.PongTimeout); deadline.Before(d.idle) {
+			d.conn.SetReadDeadline(deadline)
+		}
+	}
+	d.mu.Unlock()
+}
+
+// readPump executes inbound commands, consumes control frames and detects
+// disconnects. A read that times out - silence past the read deadline or a
+// ping unanswered past the pong timeout - unregisters the client.
+func (h *Hub) readPump(client *Client, conn *websocket.Conn, deadlines *readDeadlines) {
+	defer h.unregisterClient(client)
+
+	conn.SetReadLimit(maxMessage)
+	conn.SetPongHandler(func(string) error {
+		deadlines.pong()
+		return nil
+	})
+	for {
+		msgType, msg, err := conn.ReadMessage()
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				log.Printf("[WS] Client %s missed its read deadline, closing", client.ID)
+			}
+			return
+		}
+		deadlines.received()
+		if msgType == websocket.TextMessage {
+			h.handleCommand(client, msg)
+		}
+	}
+}
+
+// writePump drains the client's send channel onto the socket. A failed or
+// timed-out write may have left a partial frame on the wire, so the
+// connection is closed rather than reused.
+func (h *Hub) writePump(client *Client, conn *websocket.Conn, deadlines *readDeadlines) {
+	writeWait := h.cfg.WriteDeadline
+	ticker := time.NewTicker(h.cfg.PingInterval)
+	defer func() {
+		ticker.Stop()
+		conn.Close()
+	}()
+
+	for {
+		select {
+		case <-client.done:
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(client.closeCode, client.closeText))
+			return
+
+		case data := <-client.sendCh:
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				h.writeFailed(client, err)
+				return
+			}
+
+		case data := <-client.snapshot:
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteMessage(websocket.BinaryMessage, data); err != nil {
+				h.writeFailed(client, err)
+				return
+			}
+
+		case <-ticker.C:
+			deadlines.pinged() // Before the write: the pong may beat it back
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				h.writeFailed(client, err)
+				return
+			}
+		}
+	}
+}
+
+func (h *Hub) writeFailed(client *Client, err error) {
+	atomic.AddUint64(&client.writeErrors, 1)
+	log.Printf("[WS] Write to client %s failed, closing: %v", client.ID, err)
+	go h.unregisterClient(client)
+}